@@ -0,0 +1,414 @@
+package embedded
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+)
+
+// Ping checks that database isn't closed
+func (db *DB) Ping(ctx context.Context) error {
+	return db.check(ctx, 0)
+}
+
+// Set sets key with given value in database ind
+func (db *DB) Set(ctx context.Context, key string, value string, ind int) error {
+	return db.SetBytes(ctx, key, []byte(value), ind)
+}
+
+// SetBytes sets key with given binary value in database ind, TTL of the key is removed
+func (db *DB) SetBytes(ctx context.Context, key string, value []byte, ind int) error {
+	_, err := db.SetArgs(ctx, key, value, SetArgs{}, ind)
+	return err
+}
+
+// Get returns value of the key, ErrNotFound is returned if key doesn't exist
+func (db *DB) Get(ctx context.Context, key string, ind int) (string, error) {
+	val, err := db.GetBytes(ctx, key, ind)
+	if err != nil {
+		return "", err
+	}
+	return string(val), nil
+}
+
+// GetBytes returns binary value of the key, ErrNotFound is returned if key doesn't exist
+func (db *DB) GetBytes(ctx context.Context, key string, ind int) ([]byte, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return nil, err
+	}
+	val, ok := db.storage.Get([]byte(key), ind)
+	if !ok {
+		return nil, failure(storage.ErrKeyDoNotExists)
+	}
+	return bytes.Clone(val), nil
+}
+
+// MSet sets values of several keys in database ind
+func (db *DB) MSet(ctx context.Context, pairs map[string]string, ind int) error {
+	vals := make(map[string][]byte, len(pairs))
+	for key, val := range pairs {
+		vals[key] = []byte(val)
+	}
+	return db.MSetBytes(ctx, vals, ind)
+}
+
+// MSetBytes sets binary values of several keys in database ind, every key is logged as SET like server does
+func (db *DB) MSetBytes(ctx context.Context, pairs map[string][]byte, ind int) error {
+	if err := db.check(ctx, ind); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for key, val := range pairs {
+		if err := db.storage.Set([]byte(key), bytes.Clone(val), ind); err != nil {
+			return failure(err)
+		}
+		if err := db.writeLog(command.CommandSet, ind, []byte(key), val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MGet returns values of keys from database ind, keys that don't exist are missing in the result
+func (db *DB) MGet(ctx context.Context, keys []string, ind int) (map[string]string, error) {
+	vals, err := db.MGetBytes(ctx, keys, ind)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(vals))
+	for key, val := range vals {
+		res[key] = string(val)
+	}
+	return res, nil
+}
+
+// MGetBytes returns binary values of keys from database ind, keys that don't exist are missing in the result
+func (db *DB) MGetBytes(ctx context.Context, keys []string, ind int) (map[string][]byte, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return nil, err
+	}
+	res := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if val, ok := db.storage.Get([]byte(key), ind); ok {
+			res[key] = bytes.Clone(val)
+		}
+	}
+	return res, nil
+}
+
+// Delete deletes key of any type from database ind
+func (db *DB) Delete(ctx context.Context, key string, ind int) error {
+	if err := db.check(ctx, ind); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.storage.Delete([]byte(key), ind); err != nil {
+		return failure(err)
+	}
+	return db.writeLog(command.CommandDelete, ind, []byte(key))
+}
+
+// Unlink deletes key of any type from database ind, returns false if there was no such key
+func (db *DB) Unlink(ctx context.Context, key string, ind int) (bool, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return false, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	existed, err := db.storage.Unlink([]byte(key), ind)
+	if err != nil {
+		return false, failure(err)
+	}
+	if !existed {
+		return false, nil
+	}
+	return true, db.writeLog(command.CommandUnlink, ind, []byte(key))
+}
+
+// Has reports whether key of any type exists in database ind
+func (db *DB) Has(ctx context.Context, key string, ind int) (bool, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return false, err
+	}
+	return db.storage.Has([]byte(key), ind), nil
+}
+
+// Add increments key value by 1
+func (db *DB) Add(ctx context.Context, key string, ind int) error {
+	if err := db.check(ctx, ind); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.storage.Add([]byte(key), ind); err != nil {
+		return failure(err)
+	}
+	return db.writeLog(command.CommandAdd, ind, []byte(key))
+}
+
+// AddN increments key value by given value
+func (db *DB) AddN(ctx context.Context, key string, value string, ind int) error {
+	if err := db.check(ctx, ind); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.storage.AddN([]byte(key), []byte(value), ind); err != nil {
+		return failure(err)
+	}
+	return db.writeLog(command.CommandAddN, ind, []byte(key), []byte(value))
+}
+
+// Incr increments integer value of the key in database ind and returns the new value,
+// key that doesn't exist is set to 1, TTL of the key is kept
+func (db *DB) Incr(ctx context.Context, key string, ind int) (int64, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return 0, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	n, err := db.storage.Incr([]byte(key), ind)
+	if err != nil {
+		return 0, failure(err)
+	}
+	return n, db.writeLog(command.CommandIncr, ind, []byte(key))
+}
+
+// SetArgs sets key with value in database ind if conditions of args are met, returns false if key isn't set.
+// Expiration is logged as absolute PXAT, so the key expires at the same time after the log is loaded
+func (db *DB) SetArgs(ctx context.Context, key string, value []byte, args SetArgs, ind int) (bool, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return false, err
+	}
+	if args.NX && (args.XX || args.IfEq != nil) {
+		return false, syntaxError()
+	}
+	opts := storage.SetOptions{NX: args.NX, XX: args.XX, IfEq: args.IfEq}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	switch {
+	case args.TTL > 0:
+		opts.ExpireAt = db.storage.Now().Add(time.Duration(max(args.TTL.Milliseconds(), 1)) * time.Millisecond)
+	case !args.ExpireAt.IsZero():
+		if args.ExpireAt.UnixMilli() <= 0 {
+			return false, syntaxError()
+		}
+		opts.ExpireAt = time.UnixMilli(args.ExpireAt.UnixMilli())
+	}
+	set, err := db.storage.SetWith([]byte(key), bytes.Clone(value), opts, ind)
+	if err != nil {
+		return false, failure(err)
+	}
+	if !set {
+		return false, nil
+	}
+	logArgs := [][]byte{[]byte(key), value}
+	if !opts.ExpireAt.IsZero() {
+		logArgs = append(logArgs, []byte("PXAT"), strconv.AppendInt(nil, opts.ExpireAt.UnixMilli(), 10))
+	}
+	return true, db.writeLog(command.CommandSet, ind, logArgs...)
+}
+
+// SetNX sets key with value and ttl in database ind if key doesn't exist, returns false if key exists,
+// key doesn't expire if ttl is zero
+func (db *DB) SetNX(ctx context.Context, key string, value string, ttl time.Duration, ind int) (bool, error) {
+	return db.SetArgs(ctx, key, []byte(value), SetArgs{NX: true, TTL: ttl}, ind)
+}
+
+// DelIfEq deletes key from database ind if its value is equal to value, returns false if key isn't deleted
+func (db *DB) DelIfEq(ctx context.Context, key string, value []byte, ind int) (bool, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return false, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	deleted, err := db.storage.DelIfEq([]byte(key), value, ind)
+	if err != nil {
+		return false, failure(err)
+	}
+	if !deleted {
+		return false, nil
+	}
+	return true, db.writeLog(command.CommandDelete, ind, []byte(key))
+}
+
+// PExpire sets time to live of the key in database ind with millisecond precision,
+// returns false if key doesn't exist, only keys set with Set and SetArgs may expire
+func (db *DB) PExpire(ctx context.Context, key string, ttl time.Duration, ind int) (bool, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return false, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.expireAt(key, db.storage.Now().Add(time.Duration(max(ttl.Milliseconds(), 1))*time.Millisecond), ind)
+}
+
+// PExpireAt sets expiration time of the key in database ind, returns false if key doesn't exist
+func (db *DB) PExpireAt(ctx context.Context, key string, at time.Time, ind int) (bool, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return false, err
+	}
+	if at.UnixMilli() <= 0 {
+		return false, syntaxError()
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.expireAt(key, time.UnixMilli(at.UnixMilli()), ind)
+}
+
+// expireAt sets expiration of the key and logs it as PEXPIREAT, it must be called with mu held
+func (db *DB) expireAt(key string, at time.Time, ind int) (bool, error) {
+	set, err := db.storage.ExpireAt([]byte(key), at, ind)
+	if err != nil {
+		return false, failure(err)
+	}
+	if !set {
+		return false, nil
+	}
+	return true, db.writeLog(command.CommandPExpireAt, ind, []byte(key), strconv.AppendInt(nil, at.UnixMilli(), 10))
+}
+
+// PTTL returns time to live of the key in database ind, NoExpiration is returned for key without TTL
+// and ErrNotFound if key doesn't exist
+func (db *DB) PTTL(ctx context.Context, key string, ind int) (time.Duration, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return 0, err
+	}
+	ms, err := db.storage.PTTL([]byte(key), ind)
+	if err != nil {
+		return 0, failure(err)
+	}
+	switch ms {
+	case -2:
+		return 0, ErrNotFound
+	case -1:
+		return NoExpiration, nil
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// LPush pushes value to list key in database ind, if list doesn't exists it will be created
+func (db *DB) LPush(ctx context.Context, key string, value string, ind int) error {
+	return db.LPushBytes(ctx, key, []byte(value), ind)
+}
+
+// LPushBytes pushes binary value to list key in database ind, if list doesn't exists it will be created
+func (db *DB) LPushBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return db.listWrite(ctx, command.CommandLPush, key, value, ind, db.storage.LPush)
+}
+
+// GetL returns list key that contains strings
+func (db *DB) GetL(ctx context.Context, key string, ind int) ([]string, error) {
+	list, err := db.GetLBytes(ctx, key, ind)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(list))
+	for _, val := range list {
+		res = append(res, string(val))
+	}
+	return res, nil
+}
+
+// GetLBytes returns list key with binary values
+func (db *DB) GetLBytes(ctx context.Context, key string, ind int) ([][]byte, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return nil, err
+	}
+	list, err := db.storage.GetL([]byte(key), ind)
+	if err != nil {
+		return nil, failure(err)
+	}
+	res := make([][]byte, 0, len(list))
+	for _, val := range list {
+		res = append(res, bytes.Clone(val))
+	}
+	return res, nil
+}
+
+// DeleteL deletes whole list with name key from database ind
+func (db *DB) DeleteL(ctx context.Context, key string, ind int) error {
+	if err := db.check(ctx, ind); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.storage.DeleteL([]byte(key), ind); err != nil {
+		return failure(err)
+	}
+	return db.writeLog(command.CommandDeleteL, ind, []byte(key))
+}
+
+// DelElemL deletes only one element with value from list with key name in database ind
+func (db *DB) DelElemL(ctx context.Context, key string, value string, ind int) error {
+	return db.DelElemLBytes(ctx, key, []byte(value), ind)
+}
+
+// DelElemLBytes deletes only one element with binary value from list with key name in database ind
+func (db *DB) DelElemLBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return db.listWrite(ctx, command.CommandDelElemL, key, value, ind, db.storage.DelElemL)
+}
+
+// DelAll deletes all appearances of value in list with key name in database with index ind
+func (db *DB) DelAll(ctx context.Context, key string, value string, ind int) error {
+	return db.DelAllBytes(ctx, key, []byte(value), ind)
+}
+
+// DelAllBytes deletes all appearances of binary value in list with key name in database with index ind
+func (db *DB) DelAllBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return db.listWrite(ctx, command.CommandDelAll, key, value, ind, db.storage.DelAll)
+}
+
+// listWrite applies list operation with a value and logs it as cmd
+func (db *DB) listWrite(ctx context.Context, cmd string, key string, value []byte, ind int, fn func(key, value []byte, index int) error) error {
+	if err := db.check(ctx, ind); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := fn([]byte(key), bytes.Clone(value), ind); err != nil {
+		return failure(err)
+	}
+	return db.writeLog(cmd, ind, []byte(key), value)
+}
+
+// RateLimit atomically takes cost requests from the limiter stored in key of database ind, limiter allows
+// limit requests in period with millisecond precision. Denied requests don't change the limiter
+func (db *DB) RateLimit(ctx context.Context, alg RateLimitAlgorithm, key string, limit int64, period time.Duration, cost int64, ind int) (*RateLimitResult, error) {
+	if err := db.check(ctx, ind); err != nil {
+		return nil, err
+	}
+	algorithm, ok := command.RateLimitAlgorithms[strings.ToUpper(string(alg))]
+	if !ok || limit <= 0 || period.Milliseconds() <= 0 || cost <= 0 {
+		return nil, syntaxError()
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	period = time.Duration(period.Milliseconds()) * time.Millisecond
+	res, state, err := db.storage.RateLimit([]byte(key), algorithm, limit, period, cost, ind)
+	if err != nil {
+		return nil, failure(err)
+	}
+	if state != nil {
+		args := [][]byte{state.Key, state.Val}
+		if !state.ExpireAt.IsZero() {
+			args = append(args, []byte("PXAT"), strconv.AppendInt(nil, state.ExpireAt.UnixMilli(), 10))
+		}
+		if err := db.writeLog(command.CommandSet, ind, args...); err != nil {
+			return nil, err
+		}
+	}
+	return &RateLimitResult{
+		Allowed:    res.Allowed,
+		Remaining:  res.Remaining,
+		RetryAfter: time.Duration(res.RetryAfter.Milliseconds()) * time.Millisecond,
+		ResetAfter: time.Duration(res.ResetAfter.Milliseconds()) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,250 @@
+// Package embedded runs the database inside the process without a server. Operations of DB have the same
+// semantics and return the same errors as operations of the client, so code written against Backend works
+// with both. Writes are appended to the recovery log in the format of the server, so a log written by DB can
+// be loaded by the server and vice versa. Geo, JSON, pub/sub and replication need a server
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/ArtemNovok/simpleRedisCl/internal/errcode"
+	"github.com/ArtemNovok/simpleRedisCl/internal/reclogs"
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+)
+
+// expireInterval is an interval of deleting expired keys, keys are also checked on access
+const expireInterval = 100 * time.Millisecond
+
+// NoExpiration is returned by PTTL for keys without TTL
+const NoExpiration time.Duration = -1
+
+// SetArgs are conditions and expiration of SetArgs, TTL is removed from the key if neither TTL nor ExpireAt is set
+type SetArgs struct {
+	// NX sets the key only if it doesn't exist, XX only if it exists, NX can't be combined with XX and IfEq
+	NX, XX bool
+	// IfEq sets the key only if its current value is equal to IfEq, it's ignored if nil
+	IfEq []byte
+	// TTL is a time to live of the key with millisecond precision
+	TTL time.Duration
+	// ExpireAt is an expiration time of the key, it's ignored if TTL is set
+	ExpireAt time.Time
+}
+
+// RateLimitAlgorithm is an algorithm of the rate limiter
+type RateLimitAlgorithm string
+
+const (
+	// RateLimitFixedWindow counts requests of the current window, window starts with the first request
+	RateLimitFixedWindow RateLimitAlgorithm = "FIXED"
+	// RateLimitSlidingLog keeps times of requests of the last period
+	RateLimitSlidingLog RateLimitAlgorithm = "SLIDING"
+	// RateLimitTokenBucket keeps bucket of limit tokens that is refilled completely in period
+	RateLimitTokenBucket RateLimitAlgorithm = "BUCKET"
+)
+
+// RateLimitResult is a decision of the rate limiter
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining is a number of requests that are allowed right after this one
+	Remaining int64
+	// RetryAfter is a time after which denied request may be allowed, it's zero for allowed requests
+	RetryAfter time.Duration
+	// ResetAfter is a time after which limiter returns to its initial state
+	ResetAfter time.Duration
+}
+
+// errors are shared with the client, errors.Is works the same way for both
+var (
+	// ErrOperationFailed returned when operation failed not due to context cancel, it's true for all ServerErrors
+	ErrOperationFailed = errcode.ErrOperationFailed
+	// ErrTimeIsOut returned when operation failed due to context cancel
+	ErrTimeIsOut = errcode.ErrTimeIsOut
+	// ErrInvalidIndex returned when operation index value beyond 0 <= ind <= 39
+	ErrInvalidIndex = errcode.ErrInvalidIndex
+	// ErrClosed returned when database or client is used after Close
+	ErrClosed = errcode.ErrClosed
+	// ErrNotFound returned when key, list or member doesn't exist, e.g. by Get for missing key
+	ErrNotFound = errcode.ErrNotFound
+	// ErrWrongType returned when operation is used against value of wrong type
+	ErrWrongType = errcode.ErrWrongType
+	// ErrNotInteger returned when value or argument can't be used as integer, e.g. by Add
+	ErrNotInteger = errcode.ErrNotInteger
+	// ErrSyntax returned when arguments of operation are invalid
+	ErrSyntax = errcode.ErrSyntax
+)
+
+// ServerError is an error reported by storage, Code is the first word of the error message sent by server.
+// errors.Is(err, ErrOperationFailed) is true for it and so is errors.Is with sentinel error of its code,
+// e.g. errors.Is(err, ErrNotFound) for NOTFOUND
+type ServerError struct {
+	Code    string
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + " " + e.Message
+}
+
+func (e *ServerError) Is(target error) bool {
+	if target == ErrOperationFailed {
+		return true
+	}
+	sentinel := errcode.Sentinel(e.Code)
+	return sentinel != nil && target == sentinel
+}
+
+// Commands are operations on strings, counters and lists implemented by DB and by the client
+type Commands interface {
+	Set(ctx context.Context, key string, value string, ind int) error
+	SetBytes(ctx context.Context, key string, value []byte, ind int) error
+	Get(ctx context.Context, key string, ind int) (string, error)
+	GetBytes(ctx context.Context, key string, ind int) ([]byte, error)
+	MSet(ctx context.Context, pairs map[string]string, ind int) error
+	MSetBytes(ctx context.Context, pairs map[string][]byte, ind int) error
+	MGet(ctx context.Context, keys []string, ind int) (map[string]string, error)
+	MGetBytes(ctx context.Context, keys []string, ind int) (map[string][]byte, error)
+	Delete(ctx context.Context, key string, ind int) error
+
+	Add(ctx context.Context, key string, ind int) error
+	AddN(ctx context.Context, key string, value string, ind int) error
+	Incr(ctx context.Context, key string, ind int) (int64, error)
+
+	LPush(ctx context.Context, key string, value string, ind int) error
+	LPushBytes(ctx context.Context, key string, value []byte, ind int) error
+	GetL(ctx context.Context, key string, ind int) ([]string, error)
+	GetLBytes(ctx context.Context, key string, ind int) ([][]byte, error)
+	Has(ctx context.Context, key string, ind int) (bool, error)
+	DeleteL(ctx context.Context, key string, ind int) error
+	DelElemL(ctx context.Context, key string, value string, ind int) error
+	DelElemLBytes(ctx context.Context, key string, value []byte, ind int) error
+	DelAll(ctx context.Context, key string, value string, ind int) error
+	DelAllBytes(ctx context.Context, key string, value []byte, ind int) error
+}
+
+// Backend is a set of operations implemented both by DB and by the client
+type Backend interface {
+	Commands
+	Ping(ctx context.Context) error
+	Unlink(ctx context.Context, key string, ind int) (bool, error)
+	SetArgs(ctx context.Context, key string, value []byte, args SetArgs, ind int) (bool, error)
+	SetNX(ctx context.Context, key string, value string, ttl time.Duration, ind int) (bool, error)
+	DelIfEq(ctx context.Context, key string, value []byte, ind int) (bool, error)
+	PExpire(ctx context.Context, key string, ttl time.Duration, ind int) (bool, error)
+	PExpireAt(ctx context.Context, key string, at time.Time, ind int) (bool, error)
+	PTTL(ctx context.Context, key string, ind int) (time.Duration, error)
+	RateLimit(ctx context.Context, alg RateLimitAlgorithm, key string, limit int64, period time.Duration, cost int64, ind int) (*RateLimitResult, error)
+	Close() error
+}
+
+var _ Backend = (*DB)(nil)
+
+// DB is a database embedded into the process, it's safe for concurrent use
+type DB struct {
+	storage *storage.Storage
+	// log is nil if data isn't persisted
+	log *reclogs.RecoveryLogger
+
+	// mu serializes writes, so they are written to the log in the order they are applied
+	mu     sync.Mutex
+	closed atomic.Bool
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+// Open opens database persisted to the recovery log at path, data of the log is loaded before Open returns.
+// Data is kept only in memory if path is empty
+func Open(path string) (*DB, error) {
+	const op = "embedded.Open"
+	db := &DB{
+		storage: storage.NewStorage(),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if len(path) != 0 {
+		if err := db.recover(path); err != nil {
+			return nil, fmt.Errorf("%s:%w", op, err)
+		}
+	}
+	go db.expireLoop()
+	return db, nil
+}
+
+// Close stops deleting expired keys in background, operations called after Close return ErrClosed
+func (db *DB) Close() error {
+	if db.closed.Swap(true) {
+		return nil
+	}
+	close(db.quit)
+	<-db.done
+	return nil
+}
+
+// check returns error that the client returns before request is sent
+func (db *DB) check(ctx context.Context, ind int) error {
+	if ind > 39 || ind < 0 {
+		return ErrInvalidIndex
+	}
+	if db.closed.Load() {
+		return ErrClosed
+	}
+	if ctx.Err() != nil {
+		return ErrTimeIsOut
+	}
+	return nil
+}
+
+// writeLog appends write command to the recovery log, it must be called with mu held
+func (db *DB) writeLog(cmd string, ind int, args ...[]byte) error {
+	if db.log == nil {
+		return nil
+	}
+	return db.log.WriteLog(cmd, ind, args...)
+}
+
+// failure converts storage error to the error that the client gets from server for it
+func failure(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ServerError{Code: errcode.Code(err), Message: errcode.Text(err)}
+}
+
+// syntaxError is returned for arguments that server rejects while parsing the command
+func syntaxError() error {
+	return failure(command.ErrUnknownCommandArguments)
+}
+
+// expireLoop deletes expired keys until database is closed, deletions are logged,
+// so expired keys are not loaded again
+func (db *DB) expireLoop() {
+	defer close(db.done)
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-db.quit:
+			return
+		case <-ticker.C:
+			db.expire()
+		}
+	}
+}
+
+func (db *DB) expire() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for index := range db.storage.DBS {
+		for _, key := range db.storage.DeleteExpired(index) {
+			// key expires again after the log is loaded if deletion isn't logged
+			_ = db.writeLog(command.CommandDelete, index, key)
+		}
+	}
+}
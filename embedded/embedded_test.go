@@ -0,0 +1,109 @@
+package embedded_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/embedded"
+	"github.com/ArtemNovok/simpleRedisCl/internal/client/conformance"
+	"github.com/ArtemNovok/simpleRedisCl/internal/server"
+	"github.com/ArtemNovok/simpleRedisCl/internal/testserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Persistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "logs")
+	db, err := embedded.Open(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Set(ctx, "name", "val#\nue", 1))
+	require.NoError(t, db.MSet(ctx, map[string]string{"a": "1", "b": "2"}, 1))
+	_, err = db.Incr(ctx, "a", 1)
+	require.NoError(t, err)
+	require.NoError(t, db.Delete(ctx, "b", 1))
+	_, err = db.SetArgs(ctx, "session", []byte("x"), embedded.SetArgs{TTL: time.Hour}, 1)
+	require.NoError(t, err)
+	_, err = db.SetArgs(ctx, "short", []byte("x"), embedded.SetArgs{TTL: 50 * time.Millisecond}, 1)
+	require.NoError(t, err)
+	require.NoError(t, db.LPush(ctx, "list", "one", 2))
+	require.NoError(t, db.LPush(ctx, "list", "two", 2))
+	require.NoError(t, db.DelElemL(ctx, "list", "one", 2))
+	res, err := db.RateLimit(ctx, embedded.RateLimitFixedWindow, "limiter", 2, time.Hour, 1, 3)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, db.Close())
+
+	db, err = embedded.Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+	val, err := db.Get(ctx, "name", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "val#\nue", val)
+	vals, err := db.MGet(ctx, []string{"a", "b"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "2"}, vals)
+	ttl, err := db.PTTL(ctx, "session", 1)
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute)
+	_, err = db.Get(ctx, "short", 1)
+	assert.ErrorIs(t, err, embedded.ErrNotFound)
+	list, err := db.GetL(ctx, "list", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two"}, list)
+	res, err = db.RateLimit(ctx, embedded.RateLimitFixedWindow, "limiter", 2, time.Hour, 1, 3)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(0), res.Remaining)
+}
+
+func Test_Errors(t *testing.T) {
+	ctx := context.Background()
+	db, err := embedded.Open("")
+	require.NoError(t, err)
+	_, err = db.Get(ctx, "missing", 0)
+	assert.ErrorIs(t, err, embedded.ErrNotFound)
+	assert.ErrorIs(t, err, embedded.ErrOperationFailed)
+	var serverErr *embedded.ServerError
+	require.ErrorAs(t, err, &serverErr)
+	assert.Equal(t, "NOTFOUND", serverErr.Code)
+	require.NoError(t, db.Set(ctx, "text", "abc", 0))
+	assert.ErrorIs(t, db.Add(ctx, "text", 0), embedded.ErrNotInteger)
+	_, err = db.SetArgs(ctx, "key", []byte("val"), embedded.SetArgs{NX: true, XX: true}, 0)
+	assert.ErrorIs(t, err, embedded.ErrSyntax)
+	assert.ErrorIs(t, db.Set(ctx, "key", "val", 40), embedded.ErrInvalidIndex)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, db.Set(cancelled, "key", "val", 0), embedded.ErrTimeIsOut)
+	require.NoError(t, db.Close())
+	assert.True(t, errors.Is(db.Ping(ctx), embedded.ErrClosed))
+}
+
+func Test_ServerLoadsLog(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "logs")
+	db, err := embedded.Open(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Set(ctx, "key", "value", 0))
+	require.NoError(t, db.LPush(ctx, "list", "elem", 0))
+	require.NoError(t, db.Close())
+
+	srv := testserver.Start(t, server.Config{RecoveryLog: path})
+	val, err := srv.Client.Get(ctx, "key", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "value", val)
+	list, err := srv.Client.GetL(ctx, "list", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"elem"}, list)
+}
+
+func Test_Conformance(t *testing.T) {
+	db, err := embedded.Open(filepath.Join(t.TempDir(), "logs"))
+	require.NoError(t, err)
+	defer db.Close()
+	conformance.Run(t, db)
+}
@@ -0,0 +1,60 @@
+package embedded
+
+import (
+	"fmt"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/ArtemNovok/simpleRedisCl/internal/reclogs"
+)
+
+// recover loads commands of the recovery log at path and keeps the log for writes
+func (db *DB) recover(path string) error {
+	const op = "embedded.recover"
+	ch := make(chan command.Command)
+	db.log = reclogs.New(path, ch)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- db.log.ReadLog()
+	}()
+	for {
+		select {
+		case cmd := <-ch:
+			if _, ok := cmd.(command.StopCommand); ok {
+				return nil
+			}
+			if err := db.apply(cmd); err != nil {
+				go drain(ch, errCh)
+				return fmt.Errorf("%s:%w", op, err)
+			}
+		case err := <-errCh:
+			// ReadLog stops without StopCommand if the log can't be read
+			if err != nil {
+				return fmt.Errorf("%s:%w", op, err)
+			}
+		}
+	}
+}
+
+// drain receives commands until reading of the log is finished, so reader doesn't block forever
+func drain(ch chan command.Command, errCh chan error) {
+	for {
+		select {
+		case cmd := <-ch:
+			if _, ok := cmd.(command.StopCommand); ok {
+				return
+			}
+		case <-errCh:
+			return
+		}
+	}
+}
+
+// apply executes write command of the log, it supports every command written by server,
+// so logs of the server can be loaded too
+func (db *DB) apply(cmd command.Command) error {
+	spec := command.Lookup(cmd.Name())
+	if spec == nil || spec.Apply == nil {
+		return nil
+	}
+	return spec.Apply(db.storage, cmd)
+}
@@ -0,0 +1,425 @@
+package acl
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/ArtemNovok/simpleRedisCl/internal/glob"
+)
+
+var (
+	ErrWrongPass   = errors.New("invalid username-password pair or user is disabled")
+	ErrNoPerm      = errors.New("user has no permissions to run the command")
+	ErrNoPermKey   = errors.New("user has no permissions to access one of the keys")
+	ErrUnknownUser = errors.New("user doesn't exist")
+	ErrInvalidRule = errors.New("invalid ACL rule")
+	ErrDefaultUser = errors.New("default user can't be deleted")
+	ErrInvalidFile = errors.New("invalid ACL file")
+)
+
+// categories are command categories of rules like +@read, commands are added to them by flags of their specs
+var categories = map[string]command.Flags{
+	"read":       command.FlagReadOnly,
+	"write":      command.FlagWrite,
+	"admin":      command.FlagAdmin,
+	"blocking":   command.FlagBlocking,
+	"pubsub":     command.FlagPubSub,
+	"connection": command.FlagConnection,
+}
+
+// User describes user reported by ACL GETUSER, Passwords are hex encoded SHA-256 hashes
+// and Commands are command rules, e.g. "+@read -pttl"
+type User struct {
+	Name      string
+	Flags     []string
+	Passwords []string
+	Commands  string
+	Keys      []string
+}
+
+type user struct {
+	name    string
+	enabled bool
+	nopass  bool
+	// passwords are hex encoded SHA-256 hashes of passwords
+	passwords []string
+	// commands are names of permitted commands, cmdRules are rules they are built from
+	commands map[string]bool
+	cmdRules []string
+	keys     []string
+}
+
+// ACL contains users of the server, it's safe for concurrent use
+type ACL struct {
+	mu    sync.RWMutex
+	users map[string]*user
+}
+
+// New returns ACL with the default user that may run all commands against all keys, the default user
+// requires password if it's not empty and accepts any password otherwise
+func New(password string) *ACL {
+	def := newUser(command.DefaultUser)
+	rules := []string{"on", "~*", "+@all", "nopass"}
+	if len(password) != 0 {
+		rules[3] = ">" + password
+	}
+	for _, rule := range rules {
+		// rules of the default user are valid
+		_ = def.apply(rule)
+	}
+	return &ACL{users: map[string]*user{def.name: def}}
+}
+
+func newUser(name string) *user {
+	return &user{name: name, commands: make(map[string]bool)}
+}
+
+// Authenticate checks that user is enabled and password is one of its passwords
+func (a *ACL) Authenticate(name, password string) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	u, ok := a.users[name]
+	if !ok || !u.enabled {
+		return ErrWrongPass
+	}
+	if u.nopass {
+		return nil
+	}
+	hash := hashPassword(password)
+	for _, p := range u.passwords {
+		if subtle.ConstantTimeCompare([]byte(p), []byte(hash)) == 1 {
+			return nil
+		}
+	}
+	return ErrWrongPass
+}
+
+// Check reports whether user may run the command of req against its keys, AUTH, HELLO and PING are permitted
+// to everyone so any connection can switch user and check the server. Connections of deleted user can't run
+// other commands
+func (a *ACL) Check(name string, req command.Request) error {
+	switch req.Spec.Name {
+	case command.CommandAuth, command.CommandHello, command.CommandPing:
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	u, ok := a.users[name]
+	if !ok || !u.commands[req.Spec.Name] {
+		return ErrNoPerm
+	}
+	for _, key := range req.Keys() {
+		if !u.matchKey(string(key)) {
+			return ErrNoPermKey
+		}
+	}
+	return nil
+}
+
+// SetUser creates user if it doesn't exist and applies rules to it in order, new user is disabled
+// and may run nothing. Supported rules:
+// on, off, >password, <password, #hash, !hash, nopass, resetpass, ~pattern, allkeys, resetkeys,
+// +command, -command, +@category, -@category, allcommands, nocommands and reset.
+// Categories are read, write, admin, blocking, pubsub, connection and all, AUTH, HELLO and PING are permitted anyway.
+// User isn't changed if any rule is invalid
+func (a *ACL) SetUser(name string, rules ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.users[name]
+	if ok {
+		u = u.clone()
+	} else {
+		u = newUser(name)
+	}
+	for _, rule := range rules {
+		if err := u.apply(rule); err != nil {
+			return err
+		}
+	}
+	a.users[name] = u
+	return nil
+}
+
+// GetUser returns description of user
+func (a *ACL) GetUser(name string) (User, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	u, ok := a.users[name]
+	if !ok {
+		return User{}, ErrUnknownUser
+	}
+	flags := []string{"off"}
+	if u.enabled {
+		flags[0] = "on"
+	}
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+	return User{
+		Name:      u.name,
+		Flags:     flags,
+		Passwords: slices.Clone(u.passwords),
+		Commands:  u.commandRules(),
+		Keys:      slices.Clone(u.keys),
+	}, nil
+}
+
+// DelUser deletes users and returns number of deleted ones, the default user can't be deleted
+func (a *ACL) DelUser(names ...string) (int, error) {
+	if slices.Contains(names, command.DefaultUser) {
+		return 0, ErrDefaultUser
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for _, name := range names {
+		if _, ok := a.users[name]; ok {
+			delete(a.users, name)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// List returns rules of all users sorted by name in the format of ACL file,
+// e.g. "user alice on #<hash> ~cache:* +@read"
+func (a *ACL) List() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	res := make([]string, 0, len(names))
+	for _, name := range names {
+		res = append(res, a.users[name].String())
+	}
+	return res
+}
+
+// LoadFile loads users from the ACL file, see Load
+func (a *ACL) LoadFile(path string) error {
+	const op = "acl.LoadFile"
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	defer f.Close()
+	if err := a.Load(f); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+// Load reads users from r, every line is "user <name> [rule ...]" as it's written by List,
+// empty lines and lines starting with # are skipped. Users of the file replace other users
+// except the default one which is kept if it isn't in the file. Nothing is changed if the file is invalid
+func (a *ACL) Load(r io.Reader) error {
+	users := make(map[string]*user)
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 || fields[0] != "user" {
+			return fmt.Errorf("%w: line %d", ErrInvalidFile, line)
+		}
+		if _, ok := users[fields[1]]; ok {
+			return fmt.Errorf("%w: duplicate user %s at line %d", ErrInvalidFile, fields[1], line)
+		}
+		u := newUser(fields[1])
+		for _, rule := range fields[2:] {
+			if err := u.apply(rule); err != nil {
+				return fmt.Errorf("%w: line %d", err, line)
+			}
+		}
+		users[u.name] = u
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := users[command.DefaultUser]; !ok {
+		users[command.DefaultUser] = a.users[command.DefaultUser]
+	}
+	a.users = users
+	return nil
+}
+
+// String returns rules of the user in the format of ACL file
+func (u *user) String() string {
+	parts := []string{"user", u.name, "off"}
+	if u.enabled {
+		parts[2] = "on"
+	}
+	if u.nopass {
+		parts = append(parts, "nopass")
+	}
+	for _, p := range u.passwords {
+		parts = append(parts, "#"+p)
+	}
+	for _, k := range u.keys {
+		parts = append(parts, "~"+k)
+	}
+	parts = append(parts, u.commandRules())
+	return strings.Join(parts, " ")
+}
+
+func (u *user) clone() *user {
+	c := *u
+	c.passwords = slices.Clone(u.passwords)
+	c.cmdRules = slices.Clone(u.cmdRules)
+	c.keys = slices.Clone(u.keys)
+	c.commands = make(map[string]bool, len(u.commands))
+	for name, ok := range u.commands {
+		c.commands[name] = ok
+	}
+	return &c
+}
+
+// commandRules returns command rules of the user, -@all is returned if no command is permitted
+func (u *user) commandRules() string {
+	if len(u.cmdRules) == 0 {
+		return "-@all"
+	}
+	return strings.Join(u.cmdRules, " ")
+}
+
+func (u *user) matchKey(key string) bool {
+	for _, pattern := range u.keys {
+		if glob.Match(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *user) apply(rule string) error {
+	switch rule {
+	case "on":
+		u.enabled = true
+	case "off":
+		u.enabled = false
+	case "nopass":
+		u.nopass = true
+		u.passwords = nil
+	case "resetpass":
+		u.nopass = false
+		u.passwords = nil
+	case "allkeys":
+		u.keys = []string{"*"}
+	case "resetkeys":
+		u.keys = nil
+	case "allcommands":
+		return u.apply("+@all")
+	case "nocommands":
+		return u.apply("-@all")
+	case "reset":
+		*u = *newUser(u.name)
+	default:
+		return u.applyPrefixed(rule)
+	}
+	return nil
+}
+
+// applyPrefixed applies rules with argument after prefix, e.g. >password or +@read
+func (u *user) applyPrefixed(rule string) error {
+	if len(rule) < 2 {
+		return ErrInvalidRule
+	}
+	arg := rule[1:]
+	switch rule[0] {
+	case '>':
+		u.addPassword(hashPassword(arg))
+	case '<':
+		u.removePassword(hashPassword(arg))
+	case '#':
+		if !validHash(arg) {
+			return ErrInvalidRule
+		}
+		u.addPassword(strings.ToLower(arg))
+	case '!':
+		if !validHash(arg) {
+			return ErrInvalidRule
+		}
+		u.removePassword(strings.ToLower(arg))
+	case '~':
+		if !slices.Contains(u.keys, arg) {
+			u.keys = append(u.keys, arg)
+		}
+	case '+', '-':
+		return u.applyCommand(rule[0] == '+', arg)
+	default:
+		return ErrInvalidRule
+	}
+	return nil
+}
+
+// applyCommand permits or forbids command or category of commands if name starts with @,
+// previous command rules are dropped by @all as it overrides them
+func (u *user) applyCommand(allow bool, name string) error {
+	sign := "-"
+	if allow {
+		sign = "+"
+	}
+	if category, ok := strings.CutPrefix(name, "@"); ok {
+		flags, ok := categories[category]
+		if !ok && category != "all" {
+			return ErrInvalidRule
+		}
+		for _, spec := range command.Specs() {
+			if category == "all" || spec.Flags&flags != 0 {
+				u.commands[spec.Name] = allow
+			}
+		}
+		if category == "all" {
+			u.cmdRules = nil
+		}
+		u.cmdRules = append(u.cmdRules, sign+"@"+category)
+		return nil
+	}
+	spec := command.Lookup(strings.ToUpper(name))
+	if spec == nil {
+		return ErrInvalidRule
+	}
+	u.commands[spec.Name] = allow
+	u.cmdRules = append(u.cmdRules, sign+strings.ToLower(spec.Name))
+	return nil
+}
+
+func (u *user) addPassword(hash string) {
+	u.nopass = false
+	if !slices.Contains(u.passwords, hash) {
+		u.passwords = append(u.passwords, hash)
+	}
+}
+
+func (u *user) removePassword(hash string) {
+	u.passwords = slices.DeleteFunc(u.passwords, func(p string) bool { return p == hash })
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func validHash(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
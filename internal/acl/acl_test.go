@@ -0,0 +1,108 @@
+package acl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/resp"
+)
+
+func request(t *testing.T, args ...string) command.Request {
+	vals := make([]resp.Value, 0, len(args))
+	for _, arg := range args {
+		vals = append(vals, resp.StringValue(arg))
+	}
+	req, err := command.NewRequest(vals)
+	require.NoError(t, err)
+	return req
+}
+
+func Test_Authenticate(t *testing.T) {
+	a := New("")
+	require.NoError(t, a.Authenticate(command.DefaultUser, "anything"))
+	a = New("pass")
+	require.NoError(t, a.Authenticate(command.DefaultUser, "pass"))
+	require.ErrorIs(t, a.Authenticate(command.DefaultUser, "wrong"), ErrWrongPass)
+	require.ErrorIs(t, a.Authenticate("alice", "pass"), ErrWrongPass)
+
+	require.NoError(t, a.SetUser("alice", ">one", ">two"))
+	// new user is disabled
+	require.ErrorIs(t, a.Authenticate("alice", "one"), ErrWrongPass)
+	require.NoError(t, a.SetUser("alice", "on", "<one"))
+	require.ErrorIs(t, a.Authenticate("alice", "one"), ErrWrongPass)
+	require.NoError(t, a.Authenticate("alice", "two"))
+	user, err := a.GetUser("alice")
+	require.NoError(t, err)
+	require.Equal(t, []string{hashPassword("two")}, user.Passwords)
+	require.NoError(t, a.SetUser("bob", "on", "#"+hashPassword("three")))
+	require.NoError(t, a.Authenticate("bob", "three"))
+}
+
+func Test_Check(t *testing.T) {
+	a := New("")
+	require.NoError(t, a.SetUser("alice", "on", "nopass", "~cache:*", "+@read"))
+	require.NoError(t, a.Check("alice", request(t, "GET", "cache:1", "0")))
+	// connection is checked and greeted by clients of every user
+	require.NoError(t, a.Check("alice", request(t, "PING")))
+	require.NoError(t, a.Check("alice", request(t, "HELLO", "v")))
+	require.NoError(t, a.Check("alice", request(t, "AUTH", "pass")))
+	require.ErrorIs(t, a.Check("alice", request(t, "PUBLISH", "news", "hi")), ErrNoPerm)
+	require.ErrorIs(t, a.Check("alice", request(t, "GET", "user:1", "0")), ErrNoPermKey)
+	require.ErrorIs(t, a.Check("alice", request(t, "MGET", "cache:1", "user:1", "0")), ErrNoPermKey)
+	require.ErrorIs(t, a.Check("alice", request(t, "SET", "cache:1", "v", "0")), ErrNoPerm)
+	require.ErrorIs(t, a.Check("alice", request(t, "ACL", "WHOAMI")), ErrNoPerm)
+	require.NoError(t, a.SetUser("alice", "+@pubsub", "+@connection"))
+	require.NoError(t, a.Check("alice", request(t, "PUBLISH", "news", "hi")))
+	require.NoError(t, a.Check("alice", request(t, "CLIENT", "ID")))
+
+	require.NoError(t, a.SetUser("alice", "+@all", "-@admin", "-get"))
+	require.NoError(t, a.Check("alice", request(t, "SET", "cache:1", "v", "0")))
+	require.ErrorIs(t, a.Check("alice", request(t, "GET", "cache:1", "0")), ErrNoPerm)
+	require.ErrorIs(t, a.Check("alice", request(t, "ACL", "LIST")), ErrNoPerm)
+	user, err := a.GetUser("alice")
+	require.NoError(t, err)
+	require.Equal(t, "+@all -@admin -get", user.Commands)
+
+	require.NoError(t, a.Check(command.DefaultUser, request(t, "ACL", "LIST")))
+	n, err := a.DelUser("alice", "bob")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.ErrorIs(t, a.Check("alice", request(t, "SET", "cache:1", "v", "0")), ErrNoPerm)
+	_, err = a.DelUser(command.DefaultUser)
+	require.ErrorIs(t, err, ErrDefaultUser)
+}
+
+func Test_SetUserInvalidRule(t *testing.T) {
+	a := New("")
+	require.NoError(t, a.SetUser("alice", "on", "+get"))
+	for _, rule := range []string{"+nope", "+@nope", "#short", "bogus", "~"} {
+		require.ErrorIs(t, a.SetUser("alice", "off", rule), ErrInvalidRule, rule)
+	}
+	user, err := a.GetUser("alice")
+	require.NoError(t, err)
+	require.Equal(t, []string{"on"}, user.Flags)
+}
+
+func Test_Load(t *testing.T) {
+	a := New("")
+	require.NoError(t, a.SetUser("alice", "on", ">pass", "~cache:*", "+@read", "-get"))
+	require.NoError(t, a.SetUser("bob", "off", "nopass", "+@all"))
+	list := a.List()
+	require.Equal(t, []string{
+		"user alice on #" + hashPassword("pass") + " ~cache:* +@read -get",
+		"user bob off nopass +@all",
+		"user default on nopass ~* +@all",
+	}, list)
+
+	loaded := New("")
+	require.NoError(t, loaded.Load(strings.NewReader("# users\n\n"+strings.Join(list[:2], "\n"))))
+	require.Equal(t, list, loaded.List())
+	require.NoError(t, loaded.Authenticate("alice", "pass"))
+
+	require.ErrorIs(t, loaded.Load(strings.NewReader("user carol on\nuser carol off")), ErrInvalidFile)
+	require.ErrorIs(t, loaded.Load(strings.NewReader("carol on")), ErrInvalidFile)
+	require.ErrorIs(t, loaded.Load(strings.NewReader("user carol +nope")), ErrInvalidRule)
+	require.Equal(t, list, loaded.List())
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+var (
+	CommandAuth = "AUTH"
+	CommandACL  = "ACL"
+)
+
+// ACLUser describes user of the server, Passwords are hex encoded SHA-256 hashes and Commands
+// are command rules, e.g. "+@read -pttl"
+type ACLUser struct {
+	Flags     []string
+	Passwords []string
+	Commands  string
+	Keys      []string
+}
+
+// Auth authenticates connection as user, the default user is authenticated if user is empty.
+// Connection dialed again is authenticated with the same user and password
+func (c *Client) Auth(ctx context.Context, user, password string) error {
+	args := []string{password}
+	if len(user) != 0 {
+		args = []string{user, password}
+	}
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandAuth, args...); err != nil {
+		return err
+	}
+	if err := c.waitForResponse(ctx); err != nil {
+		return err
+	}
+	c.user, c.password = user, password
+	return nil
+}
+
+// ACLSetUser creates or modifies user with rules, e.g. ACLSetUser(ctx, "alice", "on", ">pass", "~cache:*", "+@read")
+func (c *Client) ACLSetUser(ctx context.Context, name string, rules ...string) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandACL, append([]string{"SETUSER", name}, rules...)...); err != nil {
+		return err
+	}
+	return c.waitForResponse(ctx)
+}
+
+// ACLGetUser returns description of user, ErrOperationFailed is returned if it doesn't exist
+func (c *Client) ACLGetUser(ctx context.Context, name string) (ACLUser, error) {
+	list, err := c.aclList(ctx, "GETUSER", name)
+	if err != nil {
+		return ACLUser{}, err
+	}
+	fields := make(map[string]string, len(list)/2)
+	for i := 0; i+1 < len(list); i += 2 {
+		fields[string(list[i])] = string(list[i+1])
+	}
+	return ACLUser{
+		Flags:     strings.Fields(fields["flags"]),
+		Passwords: strings.Fields(fields["passwords"]),
+		Commands:  fields["commands"],
+		Keys:      strings.Fields(fields["keys"]),
+	}, nil
+}
+
+// ACLList returns rules of all users, e.g. "user default on nopass ~* +@all"
+func (c *Client) ACLList(ctx context.Context) ([]string, error) {
+	list, err := c.aclList(ctx, "LIST")
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(list))
+	for _, rules := range list {
+		res = append(res, string(rules))
+	}
+	return res, nil
+}
+
+// ACLDelUser deletes users and returns number of deleted ones
+func (c *Client) ACLDelUser(ctx context.Context, names ...string) (int, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandACL, append([]string{"DELUSER"}, names...)...); err != nil {
+		return 0, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(res)
+	if err != nil {
+		return 0, ErrOperationFailed
+	}
+	return n, nil
+}
+
+// ACLWhoAmI returns name of the user authenticated by connection
+func (c *Client) ACLWhoAmI(ctx context.Context) (string, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandACL, "WHOAMI"); err != nil {
+		return "", err
+	}
+	return c.waitForResult(ctx)
+}
+
+func (c *Client) aclList(ctx context.Context, args ...string) ([][]byte, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandACL, args...); err != nil {
+		return nil, err
+	}
+	return c.waitForList(ctx)
+}
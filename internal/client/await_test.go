@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AwaitOnceAfterCancel(t *testing.T) {
+	serverErr := &ServerError{Code: "NOTFOUND", Message: "key doesn't exist"}
+	tests := []struct {
+		name    string
+		val     string
+		err     error
+		wantErr error
+		kept    bool
+	}{
+		{name: "value", val: "v", kept: true},
+		{name: "server error", err: serverErr, wantErr: ErrNotFound, kept: true},
+		{name: "incomplete reply", err: io.ErrUnexpectedEOF, wantErr: ErrTimeIsOut},
+		{name: "deadline", err: &net.OpError{Op: "read", Err: errors.New("i/o timeout")}, wantErr: ErrTimeIsOut},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, other := net.Pipe()
+			defer other.Close()
+			c := &Client{conn: conn}
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			// read finishes after ctx is done as if reply arrived while deadline was being set
+			val, err := awaitOnce(ctx, c, func(r io.Reader) (string, error) {
+				<-ctx.Done()
+				return tt.val, tt.err
+			})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.val, val)
+			}
+			require.Equal(t, tt.kept, c.conn != nil)
+		})
+	}
+}
@@ -0,0 +1,284 @@
+// Package cache provides cache-aside on top of the client: values are read from the server and loaded
+// with a loader function on miss, concurrent misses of a key are collapsed into a single load, missing values
+// may be cached too and stale values are served while they are refreshed in background
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+)
+
+const (
+	defaultTTL         = time.Minute
+	defaultLoadTimeout = 10 * time.Second
+)
+
+// entry kinds, entry is a kind byte, fresh until time in unix milliseconds and encoded value
+const (
+	entryValue byte = iota
+	entryMissing
+	entryHeaderSize = 9
+)
+
+var (
+	// ErrNotFound is returned by loader if value doesn't exist, it's cached for NegativeTTL
+	// and returned by Get while it's cached
+	ErrNotFound = errors.New("value is not found")
+)
+
+// Commands are commands cache is built on, they are implemented by Client, Pool and ClusterClient
+type Commands interface {
+	GetBytes(ctx context.Context, key string, ind int) ([]byte, error)
+	SetArgs(ctx context.Context, key string, value []byte, args client.SetArgs, ind int) (bool, error)
+	Delete(ctx context.Context, key string, ind int) error
+}
+
+// Loader loads value of the key from the source of truth, e.g. database, ErrNotFound is returned if it doesn't exist
+type Loader[T any] func(ctx context.Context, key string) (T, error)
+
+// Options describes the cache
+type Options struct {
+	// TTL is a time value is fresh, 1 minute by default
+	TTL time.Duration
+	// Jitter adds random part up to Jitter*TTL to TTL of every value, so values loaded at once don't expire at once
+	Jitter float64
+	// NegativeTTL is a time ErrNotFound of the loader is cached, missing values are not cached if it's 0
+	NegativeTTL time.Duration
+	// StaleTTL is a time value is kept after it becomes stale, stale value is returned by Get
+	// and refreshed in background, stale values are not served if it's 0
+	StaleTTL time.Duration
+	// LoadTimeout is a timeout of background refresh, 10 seconds by default
+	LoadTimeout time.Duration
+	// Prefix is added to keys passed to Get, e.g. "users:"
+	Prefix string
+	// Index is a database index of cached values
+	Index int
+	// OnLoad is called after every call of the loader with its duration and error, e.g. to record latency histogram
+	OnLoad func(key string, d time.Duration, err error)
+}
+
+// Stats are counters of the cache
+type Stats struct {
+	// Hits is a number of Get calls that returned fresh cached value
+	Hits int64
+	// StaleHits is a number of Get calls that returned stale value
+	StaleHits int64
+	// NegativeHits is a number of Get calls that returned cached ErrNotFound
+	NegativeHits int64
+	// Misses is a number of Get calls that didn't find the key
+	Misses int64
+	// Loads and LoadErrors are numbers of loader calls and their failures, ErrNotFound is not a failure
+	Loads      int64
+	LoadErrors int64
+	// LoadTime is a total duration of loader calls
+	LoadTime time.Duration
+	// Errors is a number of failed reads and writes of the server, such reads are handled as misses
+	Errors int64
+}
+
+type stats struct {
+	hits, staleHits, negativeHits, misses, loads, loadErrors, loadTime, errors atomic.Int64
+}
+
+// Cache caches values of type T encoded with codec
+type Cache[T any] struct {
+	cl    Commands
+	codec client.Codec[T]
+	load  Loader[T]
+	opts  Options
+	stats stats
+	// loads and refreshes are loads in progress, gets wait for loads and refresh runs once per key
+	mu        sync.Mutex
+	loads     map[string]*call[T]
+	refreshes map[string]struct{}
+}
+
+// call is a load in progress, its result is shared by all callers
+type call[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// New returns cache that stores values with cl, cl may be Client, Pool or ClusterClient
+func New[T any](cl Commands, codec client.Codec[T], load Loader[T], opts Options) *Cache[T] {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultTTL
+	}
+	if opts.LoadTimeout <= 0 {
+		opts.LoadTimeout = defaultLoadTimeout
+	}
+	return &Cache[T]{
+		cl:        cl,
+		codec:     codec,
+		load:      load,
+		opts:      opts,
+		loads:     make(map[string]*call[T]),
+		refreshes: make(map[string]struct{}),
+	}
+}
+
+// Get returns cached value of the key, value is loaded and cached on miss. Concurrent misses of the key
+// wait for a single load. Stale value is returned as is and refreshed in background
+func (c *Cache[T]) Get(ctx context.Context, key string) (T, error) {
+	var zero T
+	data, err := c.cl.GetBytes(ctx, c.opts.Prefix+key, c.opts.Index)
+	if err != nil && !errors.Is(err, client.ErrNotFound) {
+		c.stats.errors.Add(1)
+	}
+	if err == nil && len(data) >= entryHeaderSize {
+		freshUntil := time.UnixMilli(int64(binary.BigEndian.Uint64(data[1:entryHeaderSize])))
+		fresh := time.Now().Before(freshUntil)
+		switch data[0] {
+		case entryMissing:
+			if fresh {
+				c.stats.negativeHits.Add(1)
+				return zero, ErrNotFound
+			}
+		case entryValue:
+			val, err := c.codec.Decode(data[entryHeaderSize:])
+			if err != nil {
+				c.stats.errors.Add(1)
+				break
+			}
+			if fresh {
+				c.stats.hits.Add(1)
+				return val, nil
+			}
+			c.stats.staleHits.Add(1)
+			c.refresh(key)
+			return val, nil
+		}
+	}
+	c.stats.misses.Add(1)
+	return c.loadOnce(ctx, key)
+}
+
+// Set caches value of the key, e.g. after it's changed in the source of truth
+func (c *Cache[T]) Set(ctx context.Context, key string, value T) error {
+	data, err := c.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	return c.store(ctx, key, entryValue, data, c.ttl())
+}
+
+// Delete removes cached value of the key, the next Get loads it
+func (c *Cache[T]) Delete(ctx context.Context, key string) error {
+	return c.cl.Delete(ctx, c.opts.Prefix+key, c.opts.Index)
+}
+
+// Stats returns counters of the cache
+func (c *Cache[T]) Stats() Stats {
+	return Stats{
+		Hits:         c.stats.hits.Load(),
+		StaleHits:    c.stats.staleHits.Load(),
+		NegativeHits: c.stats.negativeHits.Load(),
+		Misses:       c.stats.misses.Load(),
+		Loads:        c.stats.loads.Load(),
+		LoadErrors:   c.stats.loadErrors.Load(),
+		LoadTime:     time.Duration(c.stats.loadTime.Load()),
+		Errors:       c.stats.errors.Load(),
+	}
+}
+
+// loadOnce loads value of the key or waits for the load in progress
+func (c *Cache[T]) loadOnce(ctx context.Context, key string) (T, error) {
+	c.mu.Lock()
+	if cl, ok := c.loads[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-cl.done:
+			return cl.val, cl.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+	cl := &call[T]{done: make(chan struct{})}
+	c.loads[key] = cl
+	c.mu.Unlock()
+
+	cl.val, cl.err = c.loadAndStore(ctx, key)
+	c.mu.Lock()
+	delete(c.loads, key)
+	c.mu.Unlock()
+	close(cl.done)
+	return cl.val, cl.err
+}
+
+// refresh loads stale value of the key in background, only one refresh of the key runs at once
+func (c *Cache[T]) refresh(key string) {
+	c.mu.Lock()
+	if _, ok := c.refreshes[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshes[key] = struct{}{}
+	c.mu.Unlock()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.LoadTimeout)
+		defer cancel()
+		c.loadAndStore(ctx, key)
+		c.mu.Lock()
+		delete(c.refreshes, key)
+		c.mu.Unlock()
+	}()
+}
+
+// loadAndStore calls the loader and caches its result, failure to cache the result is not returned
+func (c *Cache[T]) loadAndStore(ctx context.Context, key string) (T, error) {
+	var zero T
+	start := time.Now()
+	val, err := c.load(ctx, key)
+	d := time.Since(start)
+	c.stats.loads.Add(1)
+	c.stats.loadTime.Add(int64(d))
+	if c.opts.OnLoad != nil {
+		c.opts.OnLoad(key, d, err)
+	}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		if c.opts.NegativeTTL > 0 {
+			c.store(ctx, key, entryMissing, nil, c.opts.NegativeTTL)
+		}
+		return zero, ErrNotFound
+	case err != nil:
+		c.stats.loadErrors.Add(1)
+		return zero, err
+	}
+	data, err := c.codec.Encode(val)
+	if err != nil {
+		return zero, err
+	}
+	c.store(ctx, key, entryValue, data, c.ttl())
+	return val, nil
+}
+
+// store writes entry that is fresh for ttl and kept for StaleTTL more
+func (c *Cache[T]) store(ctx context.Context, key string, kind byte, data []byte, ttl time.Duration) error {
+	entry := make([]byte, entryHeaderSize, entryHeaderSize+len(data))
+	entry[0] = kind
+	binary.BigEndian.PutUint64(entry[1:], uint64(time.Now().Add(ttl).UnixMilli()))
+	entry = append(entry, data...)
+	_, err := c.cl.SetArgs(ctx, c.opts.Prefix+key, entry, client.SetArgs{TTL: ttl + c.opts.StaleTTL}, c.opts.Index)
+	if err != nil {
+		c.stats.errors.Add(1)
+	}
+	return err
+}
+
+// ttl returns TTL with random jitter
+func (c *Cache[T]) ttl() time.Duration {
+	if c.opts.Jitter <= 0 {
+		return c.opts.TTL
+	}
+	return c.opts.TTL + time.Duration(rand.Int63n(int64(float64(c.opts.TTL)*c.opts.Jitter)+1))
+}
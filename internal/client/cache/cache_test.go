@@ -0,0 +1,109 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+	"github.com/ArtemNovok/simpleRedisCl/internal/client/cache"
+	"github.com/ArtemNovok/simpleRedisCl/internal/server"
+	"github.com/ArtemNovok/simpleRedisCl/internal/testserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Cache(t *testing.T) {
+	ctx := context.Background()
+	ind := 16
+	cl := testserver.Start(t, server.Config{}).Client
+
+	type user struct {
+		Name    string
+		Version int
+	}
+	var loads atomic.Int64
+	var latencies atomic.Int64
+	release := make(chan struct{})
+	users := cache.New(cl, client.JSONCodec[user]{}, func(ctx context.Context, key string) (user, error) {
+		n := loads.Add(1)
+		if key == "missing" {
+			return user{}, cache.ErrNotFound
+		}
+		if n == 1 {
+			<-release
+		}
+		return user{Name: key, Version: int(n)}, nil
+	}, cache.Options{
+		TTL:         300 * time.Millisecond,
+		Jitter:      0.1,
+		NegativeTTL: time.Minute,
+		StaleTTL:    time.Minute,
+		Prefix:      "users:",
+		Index:       ind,
+		OnLoad: func(key string, d time.Duration, err error) {
+			latencies.Add(1)
+		},
+	})
+
+	// concurrent misses are collapsed into a single load
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := users.Get(ctx, "bob")
+			assert.Nil(t, err)
+			assert.Equal(t, user{Name: "bob", Version: 1}, u)
+		}()
+	}
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.Equal(t, int64(1), loads.Load())
+	u, err := users.Get(ctx, "bob")
+	require.Nil(t, err)
+	require.Equal(t, 1, u.Version)
+	ttl, err := cl.PTTL(ctx, "users:bob", ind)
+	require.Nil(t, err)
+	require.True(t, ttl > time.Minute)
+
+	// stale value is returned and refreshed in background
+	time.Sleep(400 * time.Millisecond)
+	u, err = users.Get(ctx, "bob")
+	require.Nil(t, err)
+	require.Equal(t, 1, u.Version)
+	require.Eventually(t, func() bool {
+		u, err := users.Get(ctx, "bob")
+		return err == nil && u.Version == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// missing values are cached
+	for i := 0; i < 2; i++ {
+		_, err = users.Get(ctx, "missing")
+		require.ErrorIs(t, err, cache.ErrNotFound)
+	}
+	require.Equal(t, int64(3), loads.Load())
+
+	require.Nil(t, users.Set(ctx, "alice", user{Name: "alice", Version: 10}))
+	u, err = users.Get(ctx, "alice")
+	require.Nil(t, err)
+	require.Equal(t, 10, u.Version)
+	require.Nil(t, users.Delete(ctx, "alice"))
+	u, err = users.Get(ctx, "alice")
+	require.Nil(t, err)
+	require.Equal(t, 4, u.Version)
+
+	stats := users.Stats()
+	require.Equal(t, int64(4), stats.Loads)
+	require.Equal(t, int64(4), latencies.Load())
+	require.True(t, stats.StaleHits >= 1)
+	require.Equal(t, int64(1), stats.NegativeHits)
+	require.Equal(t, int64(12), stats.Misses)
+	require.True(t, stats.Hits >= 3)
+	require.True(t, stats.LoadTime > 0)
+	require.Zero(t, stats.LoadErrors)
+	require.Zero(t, stats.Errors)
+}
@@ -8,14 +8,32 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"strconv"
 	"sync"
+	"time"
 
+	"github.com/ArtemNovok/simpleRedisCl/internal/errcode"
 	"github.com/tidwall/resp"
 )
 
 const (
 	defaultPassword = "secret"
+	// maxValueSize is a limit of a single value of the reply, it's the same as the limit of recovery log record
+	maxValueSize = 1 << 30
+	// maxListPrealloc is a limit of elements allocated before list reply is read
+	maxListPrealloc = 1024
+)
+
+// errValueSize is returned when reply carries invalid value length, connection is discarded after it
+// since the rest of the reply can't be read
+var errValueSize = errors.New("invalid value size")
+
+// response status bytes, error response carries error message
+const (
+	statusFailed uint8 = iota
+	statusOK
+	statusError
 )
 
 var (
@@ -31,340 +49,639 @@ var (
 	CommandDeleteL  = "DELL"
 	CommandDelElemL = "DELELEML"
 	CommandDelAll   = "DELALL"
+	CommandPing     = "PING"
+	CommandMSet     = "MSET"
+	CommandMGet     = "MGET"
 	// ErrOperationFailed returned when operation failed not due to context cancel
-	ErrOperationFailed = errors.New("operation failed")
+	ErrOperationFailed = errcode.ErrOperationFailed
 	// ErrTimeIsOut returned when operation failed due to context cancel
-	ErrTimeIsOut = errors.New("time is out")
+	ErrTimeIsOut = errcode.ErrTimeIsOut
 	// ErrInvalidIndex returned when operation index value  beyond  0 <= ind <= 39
-	ErrInvalidIndex = errors.New("invalid index value")
+	ErrInvalidIndex = errcode.ErrInvalidIndex
 	// ErrInvalidPassword returned when wrong password is used to connect to a server
-	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidPassword = errcode.ErrInvalidPassword
+	// ErrClientClosed returned when client is used after Close
+	ErrClientClosed = errcode.ErrClosed
 )
 
-// Client used for communication between app and server, it supports concurrent operations
+// Client used for communication between app and server, it supports concurrent operations.
+// Connection that is broken or left in unknown state by canceled operation is closed and
+// dialed again by the next operation
 type Client struct {
 	addr     string
 	connLock sync.Mutex
+	// conn is nil after it's discarded
 	conn     net.Conn
+	user     string
 	password string
-}
-type GetResult struct {
-	value string
-	err   error
+	closed   bool
+	opts     Options
+	breaker  breaker
+	// cmd and req are the command in progress and its encoded request, they are kept to retry it
+	cmd        string
+	req        []byte
+	idempotent bool
+	retries    int
+	// local is a local cache, it's nil if client-side caching is disabled
+	local *localCache
 }
 
 // New create connection  to the server and returns client with that connection and  error if occurs
 func New(ctx context.Context, addr string, password string) (*Client, error) {
-	conn, err := net.Dial("tcp", addr)
+	return NewWithOptions(ctx, Options{Addr: addr, Password: password})
+}
+
+// NewWithOptions creates connection to the server described by opts and returns client with that connection
+func NewWithOptions(ctx context.Context, opts Options) (*Client, error) {
+	if len(opts.Password) == 0 {
+		opts.Password = defaultPassword
+	}
+	if opts.Retry.MinBackoff <= 0 {
+		opts.Retry.MinBackoff = defaultMinBackoff
+	}
+	if opts.Retry.MaxBackoff <= 0 {
+		opts.Retry.MaxBackoff = defaultMaxBackoff
+	}
+	if opts.Breaker.Cooldown <= 0 {
+		opts.Breaker.Cooldown = defaultBreakerCooldown
+	}
+	if len(opts.Addr) == 0 && opts.Resolve != nil {
+		addr, err := opts.Resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts.Addr = addr
+	}
+	conn, err := dial(ctx, opts.Addr, credentials(opts.User, opts.Password))
 	if err != nil {
 		return nil, err
 	}
-	if len(password) == 0 {
-		password = defaultPassword
+	c := &Client{
+		addr:     opts.Addr,
+		conn:     conn,
+		user:     opts.User,
+		password: opts.Password,
+		opts:     opts,
+		breaker:  breaker{opts: opts.Breaker, onChange: opts.Hooks.OnBreakerStateChange},
+	}
+	if opts.LocalCache.Size > 0 {
+		c.local = newLocalCache(opts.LocalCache)
 	}
-	_, err = conn.Write([]byte(password))
+	return c, nil
+}
+
+// credentials returns AUTH request sent as the first request of connection,
+// the default user is authenticated if user is empty
+func credentials(user, password string) []byte {
+	args := []string{password}
+	if len(user) != 0 {
+		args = []string{user, password}
+	}
+	buf := &bytes.Buffer{}
+	// writing to buffer doesn't fail
+	_ = writeCommand(buf, CommandAuth, args...)
+	return buf.Bytes()
+}
+
+// dial creates connection to the server and authenticates it with credentials
+func dial(ctx context.Context, addr string, creds []byte) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
 	if err != nil {
+		// dialer may hit deadline of ctx before ctx reports it, dialer has no timeout of its own
+		var netErr net.Error
+		if ctx.Err() != nil || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return nil, ErrTimeIsOut
+		}
 		return nil, err
 	}
-	ch := make(chan bool)
+	_, err = conn.Write(creds)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ch := make(chan asyncResult[bool], 1)
 	go func() {
 		var res bool
-		err = binary.Read(conn, binary.BigEndian, &res)
-		if err != nil {
-			ch <- false
-
-		} else {
-			ch <- res
-		}
+		err := binary.Read(conn, binary.BigEndian, &res)
+		ch <- asyncResult[bool]{val: res, err: err}
 	}()
 	select {
 	case <-ctx.Done():
+		conn.Close()
 		return nil, ErrTimeIsOut
 	case res := <-ch:
-		if !res {
+		if res.err != nil {
+			conn.Close()
+			return nil, res.err
+		}
+		if !res.val {
+			conn.Close()
 			return nil, ErrInvalidPassword
 		}
-		return &Client{
-			addr:     addr,
-			conn:     conn,
-			password: password,
-		}, nil
+		return conn, nil
 	}
 }
 
-// writeRequest writes request with given ind and argument to the server
-func (c *Client) writeRequest(cmd string, ind int, args ...string) error {
-	index := strconv.Itoa(ind)
-	respReq := []resp.Value{resp.StringValue(cmd)}
-	for _, val := range args {
-		respReq = append(respReq, resp.StringValue(val))
+// prepare dials connection again if it's discarded and sets its deadline to the ctx deadline,
+// it must be called with connLock held before request is written
+func (c *Client) prepare(ctx context.Context) error {
+	if c.closed {
+		return ErrClientClosed
 	}
-	respReq = append(respReq, resp.StringValue(index))
-	buf := &bytes.Buffer{}
-	wr := resp.NewWriter(buf)
-	err := wr.WriteArray(respReq)
-	if err != nil {
+	if err := c.breaker.allow(); err != nil {
 		return err
 	}
-	_, err = io.Copy(c.conn, buf)
+	if c.conn == nil {
+		if err := c.redial(ctx); err != nil {
+			return err
+		}
+	}
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return c.broken(err)
+	}
+	return nil
+}
+
+// redial resolves address of the server if Resolve is set, then dials and authenticates new connection
+func (c *Client) redial(ctx context.Context) error {
+	if c.opts.Resolve != nil {
+		addr, err := c.opts.Resolve(ctx)
+		if err != nil {
+			c.breaker.failure()
+			return err
+		}
+		c.addr = addr
+	}
+	conn, err := dial(ctx, c.addr, credentials(c.user, c.password))
 	if err != nil {
+		if !errors.Is(err, ErrTimeIsOut) {
+			c.breaker.failure()
+		}
 		return err
 	}
+	c.conn = conn
+	c.discardLocal()
+	if c.opts.Hooks.OnReconnect != nil {
+		c.opts.Hooks.OnReconnect(c.addr)
+	}
 	return nil
 }
 
-// readResponse reads response from server and puts it to the ch chanel for further communication
-// cause this function meant to run in goroutine
-func (c *Client) readResponse(ch chan error) {
-	var res bool
-	err := binary.Read(c.conn, binary.BigEndian, &res)
-	if err != nil {
-		ch <- err
+// discard closes connection that can't be used anymore due to err, the next operation dials new one
+func (c *Client) discard(err error) {
+	if c.conn == nil {
 		return
 	}
-	if !res {
-		ch <- ErrOperationFailed
-		return
+	c.conn.Close()
+	c.conn = nil
+	if c.opts.Hooks.OnDisconnect != nil {
+		c.opts.Hooks.OnDisconnect(c.addr, err)
 	}
-	ch <- nil
 }
 
-// waitForResponse waits for response from server or for context cancellation,
-// returns error if operation failed of context canceled before response is accepted
-func (c *Client) waitForResponse(ch chan error, ctx context.Context) error {
+// broken discards connection after I/O error, deadline errors are reported as ErrTimeIsOut
+// and are not counted by circuit breaker
+func (c *Client) broken(err error) error {
+	c.discard(err)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrTimeIsOut
+	}
+	c.breaker.failure()
+	return err
+}
+
+// send writes encoded request of cmd to the server, request is sent again according
+// to retry policy if connection is broken and it's idempotent
+func (c *Client) send(ctx context.Context, cmd string, req []byte, idempotent bool) error {
+	c.cmd, c.req, c.idempotent, c.retries = cmd, req, idempotent, 0
+	if c.local != nil && cmd != CommandGet {
+		c.local.evictArgs(req)
+	}
+	if err := c.write(ctx); err != nil {
+		return c.retry(ctx, err)
+	}
+	return nil
+}
+
+// write writes request in progress to the connection
+func (c *Client) write(ctx context.Context) error {
+	if err := c.prepare(ctx); err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(c.req); err != nil {
+		return c.broken(err)
+	}
+	return nil
+}
+
+// writeCommand writes command without index to the server
+func (c *Client) writeCommand(ctx context.Context, cmd string, args ...string) error {
+	buf := &bytes.Buffer{}
+	if err := writeCommand(buf, cmd, args...); err != nil {
+		return err
+	}
+	return c.send(ctx, cmd, buf.Bytes(), idempotentCommands[cmd])
+}
+
+// writeRequest writes request with given ind and argument to the server
+func (c *Client) writeRequest(ctx context.Context, cmd string, ind int, args ...string) error {
+	req, err := encodeRequest(cmd, ind, args...)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, cmd, req, idempotentCommands[cmd])
+}
+
+// writeRequestBytes sends command with binary arguments and index of the database
+func (c *Client) writeRequestBytes(ctx context.Context, cmd string, ind int, args ...[]byte) error {
+	req, err := encodeRequestBytes(cmd, ind, args...)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, cmd, req, idempotentCommands[cmd])
+}
+
+// encodeRequest encodes command with arguments and index of the database
+func encodeRequest(cmd string, ind int, args ...string) ([]byte, error) {
+	vals := make([]resp.Value, 0, len(args))
+	for _, val := range args {
+		vals = append(vals, resp.StringValue(val))
+	}
+	return encodeValues(cmd, ind, vals)
+}
+
+// encodeRequestBytes encodes command with binary arguments and index of the database,
+// arguments are sent as bulk strings so they may contain any bytes
+func encodeRequestBytes(cmd string, ind int, args ...[]byte) ([]byte, error) {
+	vals := make([]resp.Value, 0, len(args))
+	for _, val := range args {
+		vals = append(vals, resp.BytesValue(val))
+	}
+	return encodeValues(cmd, ind, vals)
+}
+
+func encodeValues(cmd string, ind int, args []resp.Value) ([]byte, error) {
+	respReq := make([]resp.Value, 0, len(args)+2)
+	respReq = append(respReq, resp.StringValue(cmd))
+	respReq = append(respReq, args...)
+	respReq = append(respReq, resp.StringValue(strconv.Itoa(ind)))
+	buf := &bytes.Buffer{}
+	wr := resp.NewWriter(buf)
+	if err := wr.WriteArray(respReq); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type asyncResult[T any] struct {
+	val T
+	err error
+}
+
+// await reads reply of the request in progress, request is sent again if connection
+// is broken and the command can be retried
+func await[T any](ctx context.Context, c *Client, read func(r io.Reader) (T, error)) (T, error) {
+	for {
+		res, err := awaitOnce(ctx, c, read)
+		if !isConnError(err) {
+			return res, err
+		}
+		if err := c.retry(ctx, err); err != nil {
+			return res, err
+		}
+	}
+}
+
+// awaitOnce runs read in goroutine and waits for it or for context cancellation. Blocked read is
+// interrupted with connection deadline when ctx is done, connection is discarded if reply
+// isn't read completely, so stale reply is never read as a reply of the next operation.
+// Reply that is read completely after ctx is done is returned as is, including errors reported by server,
+// since the command is applied and connection stays in sync
+func awaitOnce[T any](ctx context.Context, c *Client, read func(r io.Reader) (T, error)) (T, error) {
+	var zero T
+	conn := c.conn
+	ch := make(chan asyncResult[T], 1)
+	go func() {
+		val, err := read(conn)
+		ch <- asyncResult[T]{val: val, err: err}
+	}()
+	var res asyncResult[T]
 	select {
+	case res = <-ch:
 	case <-ctx.Done():
-		return ErrTimeIsOut
-	case err := <-ch:
-		if err != nil {
-			return ErrOperationFailed
+		conn.SetDeadline(time.Now())
+		if res = <-ch; isConnError(res.err) {
+			c.discard(ErrTimeIsOut)
+			return zero, ErrTimeIsOut
 		}
-		return nil
+		conn.SetDeadline(time.Time{})
+	}
+	if res.err != nil && isConnError(res.err) {
+		return zero, c.broken(res.err)
+	}
+	c.breaker.success()
+	return res.val, res.err
+}
+
+// waitForResponse waits for response status from server or for context cancellation,
+// returns error if operation failed of context canceled before response is accepted
+func (c *Client) waitForResponse(ctx context.Context) error {
+	_, err := await(ctx, c, func(r io.Reader) (struct{}, error) {
+		return struct{}{}, readStatus(r)
+	})
+	return failure(err)
+}
+
+// isConnError reports whether error is caused by broken connection or malformed reply rather than by server
+func isConnError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, errValueSize) || errors.As(err, &netErr)
+}
+
+// failure returns errors reported by server as is, other errors are wrapped with ErrOperationFailed
+func failure(err error) error {
+	if err == nil || errors.Is(err, ErrOperationFailed) {
+		return err
 	}
+	return fmt.Errorf("%w: %w", ErrOperationFailed, err)
 }
 
 // DelAll deletes all appearances of value in list with key name in database with index ind
 func (c *Client) DelAll(ctx context.Context, key string, value string, ind int) error {
+	return c.DelAllBytes(ctx, key, []byte(value), ind)
+}
+
+// DelAllBytes deletes all appearances of binary value in list with key name in database with index ind
+func (c *Client) DelAllBytes(ctx context.Context, key string, value []byte, ind int) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandDelAll, ind, key, value); err != nil {
+	if err := c.writeRequestBytes(ctx, CommandDelAll, ind, []byte(key), value); err != nil {
 		return err
 	}
-	ch := make(chan error)
-	go c.readResponse(ch)
-	return c.waitForResponse(ch, ctx)
+	return c.waitForResponse(ctx)
 }
 
 // DelElemL deletes only one element with value from list with key name in database ind
 func (c *Client) DelElemL(ctx context.Context, key string, value string, ind int) error {
+	return c.DelElemLBytes(ctx, key, []byte(value), ind)
+}
+
+// DelElemLBytes deletes only one element with binary value from list with key name in database ind
+func (c *Client) DelElemLBytes(ctx context.Context, key string, value []byte, ind int) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandDelElemL, ind, key, value); err != nil {
+	if err := c.writeRequestBytes(ctx, CommandDelElemL, ind, []byte(key), value); err != nil {
 		return err
 	}
-	ch := make(chan error)
-	go c.readResponse(ch)
-	return c.waitForResponse(ch, ctx)
+	return c.waitForResponse(ctx)
 }
 
 // DeleteL deletes whole list with name key from database ind
 func (c *Client) DeleteL(ctx context.Context, key string, ind int) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandDeleteL, ind, key); err != nil {
+	if err := c.writeRequest(ctx, CommandDeleteL, ind, key); err != nil {
 		return err
 	}
-	ch := make(chan error)
-	go c.readResponse(ch)
-	return c.waitForResponse(ch, ctx)
+	return c.waitForResponse(ctx)
 }
 
-// Delete deletes key from database ind
+// Delete deletes key of any type from database ind
 func (c *Client) Delete(ctx context.Context, key string, ind int) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandDelete, ind, key); err != nil {
+	if err := c.writeRequest(ctx, CommandDelete, ind, key); err != nil {
 		return err
 	}
-	ch := make(chan error)
-	go c.readResponse(ch)
-	return c.waitForResponse(ch, ctx)
+	return c.waitForResponse(ctx)
 }
 
 // GetL returns list key that contains strings
 func (c *Client) GetL(ctx context.Context, key string, ind int) ([]string, error) {
+	list, err := c.GetLBytes(ctx, key, ind)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(list))
+	for _, val := range list {
+		res = append(res, string(val))
+	}
+	return res, nil
+}
+
+// GetLBytes returns list key with binary values
+func (c *Client) GetLBytes(ctx context.Context, key string, ind int) ([][]byte, error) {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return nil, ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandGetL, ind, key); err != nil {
+	if err := c.writeRequest(ctx, CommandGetL, ind, key); err != nil {
 		return nil, err
 	}
-	ch := make(chan []string)
-	go func() {
-		var res bool
-		binary.Read(c.conn, binary.BigEndian, &res)
-		if !res {
-			ch <- nil
-			return
-		}
-		var lenSL int64
-		binary.Read(c.conn, binary.BigEndian, &lenSL)
-		slice := make([]string, 0, lenSL)
-		for i := 0; i < int(lenSL); i++ {
-			var size int64
-			err := binary.Read(c.conn, binary.BigEndian, &size)
-			if err != nil {
-				ch <- nil
-				return
-			}
-			buf := make([]byte, size)
-			if _, err := c.conn.Read(buf); err != nil {
-				ch <- nil
-				return
-			}
-			slice = append(slice, string(buf))
-		}
-		ch <- slice
-	}()
-
-	select {
-	case <-ctx.Done():
-		return nil, ErrTimeIsOut
-	case res := <-ch:
-		if res == nil {
-			return nil, ErrOperationFailed
-		}
-		return res, nil
-	}
+	return c.waitForList(ctx)
 }
 
-// Has returns bool that indicate whether list key exist in database ind
+// Has reports whether key of any type exists in database ind
 func (c *Client) Has(ctx context.Context, key string, ind int) (bool, error) {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return false, ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandHas, ind, key); err != nil {
+	if err := c.writeRequest(ctx, CommandHas, ind, key); err != nil {
 		return false, err
 	}
-	ch := make(chan bool)
-	go func() {
-		var res bool
-		err := binary.Read(c.conn, binary.BigEndian, &res)
-		if err != nil {
-			ch <- false
-			return
-		}
-		ch <- res
-	}()
-	select {
-	case <-ctx.Done():
-		return false, ErrTimeIsOut
-	case res := <-ch:
-		return res, nil
+	err := c.waitForResponse(ctx)
+	switch {
+	case err == nil:
+		return true, nil
+	case err == ErrOperationFailed:
+		return false, nil
 	}
-
+	return false, err
 }
 
 // LPush pushes value to list key in database ind, if list doesn't exists it will be created
 func (c *Client) LPush(ctx context.Context, key string, value string, ind int) error {
+	return c.LPushBytes(ctx, key, []byte(value), ind)
+}
+
+// LPushBytes pushes binary value to list key in database ind, if list doesn't exists it will be created
+func (c *Client) LPushBytes(ctx context.Context, key string, value []byte, ind int) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandLPush, ind, key, value); err != nil {
+	if err := c.writeRequestBytes(ctx, CommandLPush, ind, []byte(key), value); err != nil {
 		return err
 	}
-	ch := make(chan error)
-	go c.readResponse(ch)
-	return c.waitForResponse(ch, ctx)
+	return c.waitForResponse(ctx)
 }
 
 // Set sets key with given value it returns error if ctx is done or operation failed
 func (c *Client) Set(ctx context.Context, key string, value string, ind int) error {
+	return c.SetBytes(ctx, key, []byte(value), ind)
+}
+
+// SetBytes sets key with given binary value, value may contain any bytes
+func (c *Client) SetBytes(ctx context.Context, key string, value []byte, ind int) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandSet, ind, key, value); err != nil {
+	if err := c.writeRequestBytes(ctx, CommandSet, ind, []byte(key), value); err != nil {
 		return err
 	}
-	ch := make(chan error)
-	go c.readResponse(ch)
-	return c.waitForResponse(ch, ctx)
+	return c.waitForResponse(ctx)
 }
 
-// Get reruns key value and  error if ctx is done or operation failed
+// Get reruns key value and  error if ctx is done or operation failed,
+// ErrNotFound is returned if key doesn't exist
 func (c *Client) Get(ctx context.Context, key string, ind int) (string, error) {
+	val, err := c.GetBytes(ctx, key, ind)
+	if err != nil {
+		return "", err
+	}
+	return string(val), nil
+}
+
+// GetBytes returns binary value of the key, ErrNotFound is returned if key doesn't exist
+func (c *Client) GetBytes(ctx context.Context, key string, ind int) ([]byte, error) {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
-		return "", ErrInvalidIndex
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandGet, ind, key); err != nil {
-		return "", err
+	if c.local != nil {
+		return c.getLocal(ctx, key, ind)
 	}
-	ch := make(chan GetResult)
-	go func() {
-		res, err := c.readResult()
-		ch <- GetResult{
-			value: res,
-			err:   err,
-		}
-	}()
-	select {
-	case <-ctx.Done():
-		return "", ErrTimeIsOut
-	case resp := <-ch:
-		return resp.value, resp.err
+	if err := c.writeRequest(ctx, CommandGet, ind, key); err != nil {
+		return nil, err
 	}
+	return c.waitForValue(ctx)
+}
+
+// MSet sets values of several keys in database ind
+func (c *Client) MSet(ctx context.Context, pairs map[string]string, ind int) error {
+	vals := make(map[string][]byte, len(pairs))
+	for key, val := range pairs {
+		vals[key] = []byte(val)
+	}
+	return c.MSetBytes(ctx, vals, ind)
+}
+
+// MSetBytes sets binary values of several keys in database ind
+func (c *Client) MSetBytes(ctx context.Context, pairs map[string][]byte, ind int) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return ErrInvalidIndex
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	args := make([][]byte, 0, 2*len(pairs))
+	for key, val := range pairs {
+		args = append(args, []byte(key), val)
+	}
+	if err := c.writeRequestBytes(ctx, CommandMSet, ind, args...); err != nil {
+		return err
+	}
+	return c.waitForResponse(ctx)
+}
+
+// MGet returns values of keys from database ind, keys that don't exist are missing in the result
+func (c *Client) MGet(ctx context.Context, keys []string, ind int) (map[string]string, error) {
+	vals, err := c.MGetBytes(ctx, keys, ind)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(vals))
+	for key, val := range vals {
+		res[key] = string(val)
+	}
+	return res, nil
+}
+
+// MGetBytes returns binary values of keys from database ind, keys that don't exist are missing in the result
+func (c *Client) MGetBytes(ctx context.Context, keys []string, ind int) (map[string][]byte, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	res := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return res, nil
+	}
+	if err := c.writeRequest(ctx, CommandMGet, ind, keys...); err != nil {
+		return nil, err
+	}
+	list, err := c.waitForList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(list)%2 != 0 {
+		return nil, ErrOperationFailed
+	}
+	for i := 0; i < len(list); i += 2 {
+		res[string(list[i])] = list[i+1]
+	}
+	return res, nil
 }
 
 // Add increment key value by 1 and  returns error if ctx is done or operation failed
 func (c *Client) Add(ctx context.Context, key string, ind int) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandAdd, ind, key); err != nil {
+	if err := c.writeRequest(ctx, CommandAdd, ind, key); err != nil {
 		return err
 	}
-	ch := make(chan error)
-	go c.readResponse(ch)
-	return c.waitForResponse(ch, ctx)
+	return c.waitForResponse(ctx)
 }
 
 // AddN increment key value by given value and  returns error if ctx is done or operation failed
 func (c *Client) AddN(ctx context.Context, key string, value string, ind int) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
-	if ind > 39 && ind < 0 {
+	if ind > 39 || ind < 0 {
 		return ErrInvalidIndex
 	}
-	if err := c.writeRequest(CommandAddN, ind, key, value); err != nil {
+	if err := c.writeRequest(ctx, CommandAddN, ind, key, value); err != nil {
+		return err
+	}
+	return c.waitForResponse(ctx)
+}
+
+// Ping checks that server is alive
+func (c *Client) Ping(ctx context.Context) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandPing); err != nil {
 		return err
 	}
-	ch := make(chan error)
-	go c.readResponse(ch)
-	return c.waitForResponse(ch, ctx)
+	return c.waitForResponse(ctx)
 }
 
 func (c *Client) Hello(ctx context.Context, m map[string]string) error {
@@ -376,33 +693,73 @@ func (c *Client) Hello(ctx context.Context, m map[string]string) error {
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(c.conn, buf)
-	if err != nil {
-		return err
-	}
-	return nil
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	return c.send(ctx, CommandHello, buf.Bytes(), false)
 }
-func (c *Client) readResult() (string, error) {
-	var res bool
-	err := binary.Read(c.conn, binary.BigEndian, &res)
+
+// readResult reads single value response
+func readResult(r io.Reader) (string, error) {
+	val, err := readValue(r)
 	if err != nil {
 		return "", err
 	}
-	if !res {
-		return "", ErrOperationFailed
+	return string(val), nil
+}
+
+// readValue reads single binary value response
+func readValue(r io.Reader) ([]byte, error) {
+	if err := readStatus(r); err != nil {
+		return nil, err
 	}
+	return readBytes(r)
+}
+
+// readStatus reads response status, error response is decoded with parseServerError
+func readStatus(r io.Reader) error {
+	var status uint8
+	if err := binary.Read(r, binary.BigEndian, &status); err != nil {
+		return err
+	}
+	switch status {
+	case statusOK:
+		return nil
+	case statusError:
+		msg, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		return parseServerError(string(msg))
+	}
+	return ErrOperationFailed
+}
+
+// readBytes reads value prefixed with its length
+func readBytes(r io.Reader) ([]byte, error) {
 	var size int64
-	if err := binary.Read(c.conn, binary.BigEndian, &size); err != nil {
-		return "", err
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
 	}
-	valueBuf := make([]byte, size)
-	if _, err := c.conn.Read(valueBuf); err != nil {
-		return "", nil
+	if size < 0 || size > maxValueSize {
+		return nil, fmt.Errorf("%w: %d", errValueSize, size)
 	}
-	return string(valueBuf), nil
+	val := make([]byte, size)
+	if _, err := io.ReadFull(r, val); err != nil {
+		return nil, err
+	}
+	return val, nil
 }
 
 func (c *Client) Close() error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	c.closed = true
+	if c.local != nil {
+		c.local.close()
+	}
+	if c.conn == nil {
+		return nil
+	}
 	return c.conn.Close()
 }
 
@@ -1,23 +1,37 @@
-package client
+package client_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"math/rand"
+	"net"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+	"github.com/ArtemNovok/simpleRedisCl/internal/client/conformance"
+	"github.com/ArtemNovok/simpleRedisCl/internal/server"
+	"github.com/ArtemNovok/simpleRedisCl/internal/testserver"
 	"github.com/stretchr/testify/require"
 )
 
-// if you wanna run this tests, you need running instance of the server
-// on right address (localhost:6666), or change address manually in every test
+// every test starts its own server on ephemeral port with testserver
 var ctx context.Context = context.Background()
 
 func Test_CLient3(t *testing.T) {
-	cl, err := New(ctx, "localhost:6666", "")
+	addr := testserver.Start(t, server.Config{}).Addr
+	cl, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
 	key := "one"
 	value := "value_one"
@@ -51,7 +65,8 @@ func Test_CLient3(t *testing.T) {
 	require.Nil(t, err)
 }
 func Test_Client2(t *testing.T) {
-	cl, err := New(ctx, "localhost:6666", "")
+	addr := testserver.Start(t, server.Config{}).Addr
+	cl, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
 	key := "one"
 	value := "value_one"
@@ -83,13 +98,14 @@ func Test_Client2(t *testing.T) {
 	require.Equal(t, isExist, false)
 }
 func Test_Client(t *testing.T) {
-	cl, err := New(ctx, "localhost:6666", "")
+	addr := testserver.Start(t, server.Config{}).Addr
+	cl, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
-	_, err = New(ctx, "localhost:6666", "wrongPassword")
-	require.ErrorIs(t, err, ErrInvalidPassword)
+	_, err = client.New(ctx, addr, "wrongPassword")
+	require.ErrorIs(t, err, client.ErrInvalidPassword)
 	ctx2, _ := context.WithTimeout(ctx, 1*time.Microsecond)
-	_, err = New(ctx2, "localhost:6666", "wrongPassword")
-	require.ErrorIs(t, err, ErrTimeIsOut)
+	_, err = client.New(ctx2, addr, "wrongPassword")
+	require.ErrorIs(t, err, client.ErrTimeIsOut)
 	ctx, _ := context.WithTimeout(context.Background(), 2*time.Second)
 	err = cl.Set(ctx, "foo", "bar", 0)
 	require.Nil(t, err)
@@ -100,18 +116,20 @@ func Test_Client(t *testing.T) {
 
 }
 func Test_WriteMapResp(t *testing.T) {
+	addr := testserver.Start(t, server.Config{}).Addr
 	m := map[string]string{
 		"foo":  "bar",
 		"foo2": "bar2",
 	}
-	cl, err := New(ctx, "localhost:6666", "")
+	cl, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
 	err = cl.Hello(context.Background(), m)
 	require.Nil(t, err)
 }
 
 func Test_ADD(t *testing.T) {
-	cl, err := New(ctx, "localhost:6666", "")
+	addr := testserver.Start(t, server.Config{}).Addr
+	cl, err := client.New(ctx, addr, "")
 	key := "one"
 	require.Nil(t, err)
 	ctx, _ := context.WithTimeout(context.Background(), 50*time.Millisecond)
@@ -132,7 +150,8 @@ func Test_ADD(t *testing.T) {
 }
 
 func Test_BADVALUE(t *testing.T) {
-	cl, err := New(ctx, "localhost:6666", "")
+	addr := testserver.Start(t, server.Config{}).Addr
+	cl, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
 	key := "one"
 	err = cl.Set(context.Background(), key, "badValue", 0)
@@ -144,7 +163,8 @@ func Test_BADVALUE(t *testing.T) {
 }
 
 func Test_AddN(t *testing.T) {
-	cl, err := New(ctx, "localhost:6666", "")
+	addr := testserver.Start(t, server.Config{}).Addr
+	cl, err := client.New(ctx, addr, "")
 	key := "one"
 	value := "2"
 	require.Nil(t, err)
@@ -161,9 +181,10 @@ func Test_AddN(t *testing.T) {
 	require.Equal(t, val, "3")
 }
 func Test_ADD_ADDN(t *testing.T) {
-	cl, err := New(ctx, "localhost:6666", "")
+	addr := testserver.Start(t, server.Config{}).Addr
+	cl, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
-	cl2, err := New(ctx, "localhost:6666", "")
+	cl2, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
 	key := "one"
 	cl.Set(context.Background(), key, "1", 0)
@@ -200,7 +221,8 @@ func Test_ADD_ADDN(t *testing.T) {
 	fmt.Println(val)
 }
 func Test_Delete(t *testing.T) {
-	cl, err := New(ctx, "localhost:6666", "")
+	addr := testserver.Start(t, server.Config{}).Addr
+	cl, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
 	key := "one"
 	ctx, _ := context.WithTimeout(context.Background(), 500*time.Microsecond)
@@ -212,9 +234,10 @@ func Test_Delete(t *testing.T) {
 	require.NotNil(t, err)
 }
 func Test_DataBaseSupport(t *testing.T) {
-	cl, err := New(ctx, "localhost:6666", "")
+	addr := testserver.Start(t, server.Config{}).Addr
+	cl, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
-	cl2, err := New(ctx, "localhost:6666", "")
+	cl2, err := client.New(ctx, addr, "")
 	require.Nil(t, err)
 	key1 := "one"
 	val1 := "value_one"
@@ -233,11 +256,11 @@ func Test_DataBaseSupport(t *testing.T) {
 	require.Equal(t, val, val2)
 }
 func Test_DataBaseSupport2(t *testing.T) {
-	address := "localhost:6666"
+	address := testserver.Start(t, server.Config{}).Addr
 	wg := sync.WaitGroup{}
 	start := time.Now()
 	for i := 0; i < 400; i++ {
-		cl, err := New(ctx, address, "")
+		cl, err := client.New(ctx, address, "")
 		require.Nil(t, err)
 		wg.Add(1)
 		go func() {
@@ -256,7 +279,7 @@ func Test_DataBaseSupport2(t *testing.T) {
 	}
 	wg.Wait()
 	for i := 0; i < 400; i++ {
-		cl, err := New(ctx, address, "")
+		cl, err := client.New(ctx, address, "")
 		require.Nil(t, err)
 		wg.Add(1)
 		go func() {
@@ -277,3 +300,804 @@ func Test_DataBaseSupport2(t *testing.T) {
 	wg.Wait()
 	fmt.Println(time.Since(start))
 }
+
+func Test_Conformance(t *testing.T) {
+	srv := testserver.Start(t, server.Config{})
+	t.Run("Client", func(t *testing.T) {
+		conformance.Run(t, srv.Client)
+	})
+	t.Run("Pool", func(t *testing.T) {
+		pool, err := client.NewPool(ctx, client.PoolOptions{Addr: srv.Addr})
+		require.NoError(t, err)
+		defer pool.Close()
+		conformance.Run(t, pool)
+	})
+	t.Run("ClusterClient", func(t *testing.T) {
+		addrs, _ := startCluster(t)
+		cc, err := client.NewCluster(ctx, client.ClusterOptions{Addrs: addrs[:1]})
+		require.NoError(t, err)
+		defer cc.Close()
+		conformance.Run(t, cc)
+	})
+}
+
+func Test_Pool(t *testing.T) {
+	ind := 6
+	addr := testserver.Start(t, server.Config{}).Addr
+
+	p, err := client.NewPool(ctx, client.PoolOptions{
+		Addr:        addr,
+		PoolSize:    4,
+		MinIdle:     2,
+		MaxIdle:     3,
+		IdleTimeout: 300 * time.Millisecond,
+	})
+	require.Nil(t, err)
+	defer p.Close()
+	require.Equal(t, client.PoolStats{TotalConns: 2, IdleConns: 2}, p.Stats())
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("key%d", i)
+			require.Nil(t, p.Set(ctx, key, strconv.Itoa(i), ind))
+			val, err := p.Get(ctx, key, ind)
+			require.Nil(t, err)
+			require.Equal(t, strconv.Itoa(i), val)
+		}()
+	}
+	wg.Wait()
+	stats := p.Stats()
+	require.Equal(t, uint64(100), stats.Hits+stats.Misses)
+	require.LessOrEqual(t, stats.TotalConns, 3)
+	require.Equal(t, stats.TotalConns, stats.IdleConns)
+
+	// caller waits for connection until ctx is done when all connections are in use
+	release := make(chan struct{})
+	busy := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		busy.Add(1)
+		go p.Conn(ctx, func(cl *client.Client) error {
+			busy.Done()
+			<-release
+			return nil
+		})
+	}
+	busy.Wait()
+	require.Equal(t, 4, p.Stats().TotalConns)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, p.Ping(timeoutCtx), client.ErrPoolTimeout)
+	require.Equal(t, uint64(1), p.Stats().Timeouts)
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+	require.Nil(t, p.Ping(waitCtx))
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, 3, p.Stats().IdleConns)
+
+	// idle connections are closed after IdleTimeout, MinIdle connections are opened again
+	time.Sleep(2 * time.Second)
+	stats = p.Stats()
+	require.Equal(t, 2, stats.TotalConns)
+	require.Equal(t, 2, stats.IdleConns)
+
+	require.Nil(t, p.Close())
+	require.ErrorIs(t, p.Ping(ctx), client.ErrClientClosed)
+}
+
+func Test_Pipeline(t *testing.T) {
+	ind := 9
+	cl := testserver.Start(t, server.Config{}).Client
+
+	p := cl.Pipeline()
+	for i := 0; i < 10000; i++ {
+		p.Set(fmt.Sprintf("key%d", i), strconv.Itoa(i), ind)
+	}
+	require.Equal(t, 10000, p.Len())
+	require.Nil(t, p.Exec(ctx))
+	require.Equal(t, 0, p.Len())
+
+	get := p.Get("key42", ind)
+	missing := p.Get("missing", ind)
+	push := p.LPush("list", "a", ind)
+	list := p.GetL("list", ind)
+	has := p.Has("list", ind)
+	hasNot := p.Has("missing", ind)
+	invalid := p.Set("key", "value", 40)
+	incr := p.Add("key42", ind)
+	vals := p.MGet([]string{"key1", "key42", "missing"}, ind)
+	unlinked := p.Unlink("key2", ind)
+	ping := p.Ping()
+	require.ErrorIs(t, p.Exec(ctx), client.ErrOperationFailed)
+	require.Equal(t, "42", get.Val())
+	require.ErrorIs(t, missing.Err(), client.ErrOperationFailed)
+	require.Nil(t, push.Err())
+	require.Equal(t, []string{"a"}, list.Val())
+	require.True(t, has.Val())
+	val, err := hasNot.Result()
+	require.Nil(t, err)
+	require.False(t, val)
+	require.ErrorIs(t, invalid.Err(), client.ErrInvalidIndex)
+	require.Nil(t, incr.Err())
+	require.Equal(t, map[string]string{"key1": "1", "key42": "43"}, vals.Val())
+	require.True(t, unlinked.Val())
+	require.Nil(t, ping.Err())
+	res, err := cl.Get(ctx, "key42", ind)
+	require.Nil(t, err)
+	require.Equal(t, "43", res)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	get = p.Get("key42", ind)
+	require.ErrorIs(t, p.Exec(canceled), client.ErrTimeIsOut)
+	require.ErrorIs(t, get.Err(), client.ErrTimeIsOut)
+	res, err = cl.Get(ctx, "key3", ind)
+	require.Nil(t, err)
+	require.Equal(t, "3", res)
+
+	// concurrent calls are coalesced into pipelines
+	b := cl.AutoBatch(client.BatchOptions{MaxBatch: 64})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				key := fmt.Sprintf("batch%d-%d", i, j)
+				require.Nil(t, b.Set(ctx, key, key, ind))
+				val, err := b.Get(ctx, key, ind)
+				require.Nil(t, err)
+				require.Equal(t, key, val)
+			}
+		}()
+	}
+	wg.Wait()
+	_, err = b.Get(ctx, "missing", ind)
+	require.ErrorIs(t, err, client.ErrOperationFailed)
+	require.Nil(t, b.Close())
+	require.ErrorIs(t, b.Ping(ctx), client.ErrClientClosed)
+	require.Nil(t, cl.Ping(ctx))
+}
+
+type typedUser struct {
+	Name    string            `msgpack:"name"`
+	Age     int               `msgpack:"age"`
+	Score   float64           `msgpack:"score"`
+	Tags    []string          `msgpack:"tags"`
+	Attrs   map[string]int64  `msgpack:"attrs"`
+	Avatar  []byte            `msgpack:"avatar"`
+	Friend  *typedUser        `msgpack:"friend"`
+	Extra   any               `msgpack:"extra"`
+	Skipped string            `msgpack:"-"`
+	Counts  map[int8]uint16   `msgpack:"counts"`
+	Nested  []map[string]bool `msgpack:"nested"`
+}
+
+// protoUser mimics protobuf message with generated Marshal and Unmarshal methods
+type protoUser struct {
+	ID   uint32
+	Name string
+}
+
+func (u *protoUser) Marshal() ([]byte, error) {
+	return binary.BigEndian.AppendUint32(nil, u.ID), nil
+}
+
+func (u *protoUser) Unmarshal(data []byte) error {
+	if len(data) != 4 {
+		return errors.New("invalid message")
+	}
+	u.ID = binary.BigEndian.Uint32(data)
+	return nil
+}
+
+func Test_TypedValues(t *testing.T) {
+	ind := 12
+	s := testserver.Start(t, server.Config{})
+	cl := s.Client
+	pool, err := client.NewPool(ctx, client.PoolOptions{Addr: s.Addr})
+	require.Nil(t, err)
+	defer pool.Close()
+
+	user := typedUser{
+		Name:   "bob",
+		Age:    -42,
+		Score:  1.5,
+		Tags:   []string{"a", "b"},
+		Attrs:  map[string]int64{"big": math.MaxInt64, "small": math.MinInt64},
+		Avatar: []byte{0, 1, 2, 255},
+		Friend: &typedUser{Name: "alice", Age: 300},
+		Counts: map[int8]uint16{-1: 65535},
+		Nested: []map[string]bool{{"ok": true}},
+	}
+	codecs := map[string]client.Codec[typedUser]{
+		"json":    client.JSONCodec[typedUser]{},
+		"gob":     client.GobCodec[typedUser]{},
+		"msgpack": client.MsgpackCodec[typedUser]{},
+	}
+	for name, codec := range codecs {
+		for _, cmds := range []client.BytesCommands{cl, pool} {
+			users := client.NewTyped(cmds, codec)
+			key := "user:" + name
+			require.Nil(t, users.Set(ctx, key, user, ind), name)
+			got, err := users.Get(ctx, key, ind)
+			require.Nil(t, err, name)
+			require.Equal(t, user, got, name)
+
+			require.Nil(t, users.MSet(ctx, map[string]typedUser{key + ":1": user, key + ":2": {Name: "eve"}}, ind))
+			vals, err := users.MGet(ctx, []string{key + ":1", key + ":2", "missing"}, ind)
+			require.Nil(t, err)
+			require.Equal(t, map[string]typedUser{key + ":1": user, key + ":2": {Name: "eve"}}, vals)
+
+			list := key + ":list" + strconv.Itoa(rand.Int())
+			require.Nil(t, users.LPush(ctx, list, user, ind))
+			require.Nil(t, users.LPush(ctx, list, typedUser{Name: "eve"}, ind))
+			res, err := users.GetL(ctx, list, ind)
+			require.Nil(t, err)
+			require.ElementsMatch(t, []typedUser{user, {Name: "eve"}}, res)
+
+			_, err = users.Get(ctx, "missing", ind)
+			require.ErrorIs(t, err, client.ErrNotFound)
+		}
+	}
+
+	// msgpack decodes into interface values and keeps skipped fields empty
+	dyn := client.NewTyped(cl, client.MsgpackCodec[typedUser]{})
+	require.Nil(t, dyn.Set(ctx, "dyn", typedUser{Extra: []any{"x", int64(-7), 2.5, nil, map[string]any{"k": true}}, Skipped: "x"}, ind))
+	got, err := dyn.Get(ctx, "dyn", ind)
+	require.Nil(t, err)
+	require.Equal(t, []any{"x", int64(-7), 2.5, nil, map[string]any{"k": true}}, got.Extra)
+	require.Empty(t, got.Skipped)
+
+	protos := client.NewTyped(cl, client.ProtoCodec[*protoUser]{})
+	require.Nil(t, protos.Set(ctx, "proto", &protoUser{ID: 7}, ind))
+	pu, err := protos.Get(ctx, "proto", ind)
+	require.Nil(t, err)
+	require.Equal(t, &protoUser{ID: 7}, pu)
+
+	// values that can't be decoded are reported with key
+	require.Nil(t, cl.Set(ctx, "broken", "{not json", ind))
+	for name, codec := range codecs {
+		_, err := client.NewTyped(cl, codec).Get(ctx, "broken", ind)
+		var derr *client.DecodeError
+		require.True(t, errors.As(err, &derr), name)
+		require.Equal(t, "broken", derr.Key)
+		require.NotErrorIs(t, err, client.ErrNotFound)
+	}
+	_, err = protos.Get(ctx, "broken", ind)
+	require.ErrorAs(t, err, new(*client.DecodeError))
+	ints := client.NewTyped(cl, client.MsgpackCodec[int8]{})
+	require.Nil(t, client.NewTyped(cl, client.MsgpackCodec[int]{}).Set(ctx, "int", 1000, ind))
+	_, err = ints.Get(ctx, "int", ind)
+	require.ErrorAs(t, err, new(*client.DecodeError))
+}
+
+func Test_Do(t *testing.T) {
+	ind := 13
+	cl := testserver.Start(t, server.Config{}).Client
+
+	reply, err := cl.Do(ctx, "set", "key", 41, ind)
+	require.Nil(t, err)
+	status, err := reply.String()
+	require.Nil(t, err)
+	require.Equal(t, "OK", status)
+	_, err = cl.Do(ctx, "ADD", "key", ind)
+	require.Nil(t, err)
+	reply, err = cl.Do(ctx, "GET", []byte("key"), ind)
+	require.Nil(t, err)
+	n, err := reply.Int64()
+	require.Nil(t, err)
+	require.Equal(t, int64(42), n)
+	_, err = reply.Slice()
+	require.ErrorIs(t, err, client.ErrReplyType)
+	_, err = cl.Do(ctx, "GET", "missing", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	_, err = cl.Do(ctx, "MSET", "k1", "v1", "k2", 2.5, ind)
+	require.Nil(t, err)
+	reply, err = cl.Do(ctx, "MGET", "k1", "k2", "missing", ind)
+	require.Nil(t, err)
+	vals, err := reply.Map()
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"k1": "v1", "k2": "2.5"}, vals)
+
+	_, err = cl.Do(ctx, "LPUSH", "list", true, ind)
+	require.Nil(t, err)
+	reply, err = cl.Do(ctx, "GETL", "list", ind)
+	require.Nil(t, err)
+	list, err := reply.Slice()
+	require.Nil(t, err)
+	require.Equal(t, []string{"1"}, list)
+	for key, want := range map[string]bool{"list": true, "missing": false} {
+		reply, err = cl.Do(ctx, "HAS", key, ind)
+		require.Nil(t, err)
+		ok, err := reply.Bool()
+		require.Nil(t, err)
+		require.Equal(t, want, ok)
+	}
+
+	// admin commands
+	reply, err = cl.Do(ctx, "ROLE")
+	require.Nil(t, err)
+	role, err := reply.Slice()
+	require.Nil(t, err)
+	require.Equal(t, client.RoleMaster, role[0])
+	reply, err = cl.Do(ctx, "INFO", "replication")
+	require.Nil(t, err)
+	info, err := reply.Map()
+	require.Nil(t, err)
+	require.Equal(t, "master", info["role"])
+	_, err = cl.Do(ctx, "CLUSTER", "NODES")
+	require.ErrorIs(t, err, client.ErrOperationFailed)
+	reply, err = cl.Do(ctx, "PING")
+	require.Nil(t, err)
+	ok, err := reply.Bool()
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	_, err = cl.Do(ctx, "NOPE")
+	require.ErrorIs(t, err, client.ErrUnknownReply)
+	_, err = cl.Do(ctx, "SET", "key", struct{}{}, ind)
+	require.ErrorIs(t, err, client.ErrInvalidArgument)
+	_, err = cl.Do(ctx)
+	require.ErrorIs(t, err, client.ErrInvalidArgument)
+	client.RegisterReplyKind("NOPE", client.ReplyValue)
+	_, err = cl.Do(ctx, "NOPE")
+	var serr *client.ServerError
+	require.ErrorAs(t, err, &serr)
+	require.Equal(t, "ERR", serr.Code)
+	require.Nil(t, cl.Ping(ctx))
+}
+
+// startCluster starts cluster of three nodes that serve all slots and returns their addresses and clients,
+// the first node serves slots 0-5460, the second one 5461-10922 and the third one 10923-16383
+func startCluster(t *testing.T) ([]string, []*client.Client) {
+	t.Helper()
+	addrs := make([]string, 3)
+	ports := make([]string, 3)
+	nodes := make([]*client.Client, 3)
+	for i := range nodes {
+		s := testserver.Start(t, server.Config{ClusterEnabled: true, ClusterPingInterval: 100 * time.Millisecond})
+		addrs[i], nodes[i] = s.Addr, s.Client
+		_, ports[i], _ = net.SplitHostPort(s.Addr)
+	}
+	require.Nil(t, nodes[0].ClusterMeet(ctx, "127.0.0.1", ports[1]))
+	require.Nil(t, nodes[0].ClusterMeet(ctx, "127.0.0.1", ports[2]))
+	require.Nil(t, nodes[0].ClusterAddSlotsRange(ctx, 0, 5460))
+	require.Nil(t, nodes[1].ClusterAddSlotsRange(ctx, 5461, 10922))
+	require.Nil(t, nodes[2].ClusterAddSlotsRange(ctx, 10923, 16383))
+	for _, cl := range nodes {
+		require.Eventually(t, func() bool {
+			info, err := cl.ClusterInfo(ctx)
+			require.Nil(t, err)
+			return strings.Contains(info, "cluster_state:ok\r\n") && strings.Contains(info, "cluster_known_nodes:3\r\n")
+		}, 10*time.Second, 100*time.Millisecond)
+	}
+	return addrs, nodes
+}
+
+func Test_ClusterClient(t *testing.T) {
+	ind := 5
+	addrs, nodes := startCluster(t)
+	ports := make([]string, len(addrs))
+	ids := make([]string, len(addrs))
+	for i := range nodes {
+		_, ports[i], _ = net.SplitHostPort(addrs[i])
+		var err error
+		ids[i], err = nodes[i].ClusterMyID(ctx)
+		require.Nil(t, err)
+	}
+
+	cc, err := client.NewCluster(ctx, client.ClusterOptions{Addrs: []string{addrs[1]}})
+	require.Nil(t, err)
+	defer cc.Close()
+	masters := append([]string(nil), addrs...)
+	sort.Strings(masters)
+	require.Equal(t, masters, cc.Masters())
+	require.Nil(t, cc.Ping(ctx))
+
+	// "foo" is served by the third node, "bar" by the second one and "baz" by the first one
+	require.Nil(t, cc.Set(ctx, "foo", "1", ind))
+	require.Nil(t, cc.LPush(ctx, "bar", "a", ind))
+	val, err := nodes[2].Get(ctx, "foo", ind)
+	require.Nil(t, err)
+	require.Equal(t, "1", val)
+	list, err := cc.GetL(ctx, "bar", ind)
+	require.Nil(t, err)
+	require.Equal(t, []string{"a"}, list)
+
+	pairs := map[string]string{"baz": "2", "{foo}.a": "3", "{foo}.b": "4", "qux": "5"}
+	require.Nil(t, cc.MSet(ctx, pairs, ind))
+	vals, err := cc.MGet(ctx, []string{"foo", "baz", "{foo}.a", "{foo}.b", "qux", "missing"}, ind)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"foo": "1", "baz": "2", "{foo}.a": "3", "{foo}.b": "4", "qux": "5"}, vals)
+	val, err = nodes[0].Get(ctx, "baz", ind)
+	require.Nil(t, err)
+	require.Equal(t, "2", val)
+
+	sub, err := cc.Subscribe(ctx, "news")
+	require.Nil(t, err)
+	defer sub.Close()
+	// subscription is made on every node, so messages published through any of them are received
+	_, err = cc.Publish(ctx, "news", "hello")
+	require.Nil(t, err)
+	select {
+	case msg := <-sub.Channel():
+		require.Equal(t, &client.Message{Channel: "news", Payload: "hello"}, msg)
+	case <-time.After(time.Second):
+		t.Fatal("message is not received")
+	}
+	_, err = nodes[0].Publish(ctx, "news", "direct")
+	require.Nil(t, err)
+	select {
+	case msg := <-sub.Channel():
+		require.Equal(t, &client.Message{Channel: "news", Payload: "direct"}, msg)
+	case <-time.After(time.Second):
+		t.Fatal("message is not received")
+	}
+
+	// during migration ASK redirect is followed for moved key, others are still served by the third node
+	slot, err := cc.ClusterKeySlot(ctx, "foo")
+	require.Nil(t, err)
+	require.Nil(t, nodes[0].ClusterSetSlot(ctx, slot, client.SlotImporting, ids[2]))
+	require.Nil(t, nodes[2].ClusterSetSlot(ctx, slot, client.SlotMigrating, ids[0]))
+	moved, err := nodes[2].Migrate(ctx, "127.0.0.1", ports[0], "foo", ind, time.Second)
+	require.Nil(t, err)
+	require.True(t, moved)
+	val, err = cc.Get(ctx, "foo", ind)
+	require.Nil(t, err)
+	require.Equal(t, "1", val)
+	val, err = cc.Get(ctx, "{foo}.a", ind)
+	require.Nil(t, err)
+	require.Equal(t, "3", val)
+	for _, key := range []string{"{foo}.a", "{foo}.b"} {
+		moved, err := nodes[2].Migrate(ctx, "127.0.0.1", ports[0], key, ind, time.Second)
+		require.Nil(t, err)
+		require.True(t, moved)
+	}
+	require.Nil(t, nodes[0].ClusterSetSlot(ctx, slot, client.SlotNode, ids[0]))
+	require.Nil(t, nodes[2].ClusterSetSlot(ctx, slot, client.SlotNode, ids[0]))
+
+	// MOVED redirect updates slot map of the client
+	require.Nil(t, cc.AddN(ctx, "foo", "10", ind))
+	val, err = nodes[0].Get(ctx, "foo", ind)
+	require.Nil(t, err)
+	require.Equal(t, "11", val)
+	vals, err = cc.MGet(ctx, []string{"foo", "{foo}.a", "{foo}.b"}, ind)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"foo": "11", "{foo}.a": "3", "{foo}.b": "4"}, vals)
+	ok, err := cc.Unlink(ctx, "{foo}.b", ind)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// nodes and subscriptions are authenticated as the user of the cluster client
+	for _, cl := range nodes {
+		require.Nil(t, cl.ACLSetUser(ctx, "app", "on", ">apppass", "~*", "+@all"))
+	}
+	_, err = client.NewCluster(ctx, client.ClusterOptions{Addrs: []string{addrs[0]}, User: "app", Password: "secret"})
+	require.ErrorIs(t, err, client.ErrInvalidPassword)
+	app, err := client.NewCluster(ctx, client.ClusterOptions{Addrs: []string{addrs[0]}, User: "app", Password: "apppass"})
+	require.Nil(t, err)
+	defer app.Close()
+	vals, err = app.MGet(ctx, []string{"foo", "baz", "bar"}, ind)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"foo": "11", "baz": "2"}, vals)
+	for _, addr := range masters {
+		node, err := app.Node(ctx, addr)
+		require.Nil(t, err)
+		who, err := node.ACLWhoAmI(ctx)
+		require.Nil(t, err)
+		require.Equal(t, "app", who)
+	}
+	appSub, err := app.Subscribe(ctx, "app")
+	require.Nil(t, err)
+	defer appSub.Close()
+	_, err = nodes[1].Publish(ctx, "app", "hello")
+	require.Nil(t, err)
+	select {
+	case msg := <-appSub.Channel():
+		require.Equal(t, &client.Message{Channel: "app", Payload: "hello"}, msg)
+	case <-time.After(time.Second):
+		t.Fatal("message is not received")
+	}
+}
+
+func Test_ClientCancel(t *testing.T) {
+	ind := 7
+	cl := testserver.Start(t, server.Config{}).Client
+	big := strings.Repeat("x", 8<<20)
+	require.Nil(t, cl.Set(ctx, "big", big, ind))
+	require.Nil(t, cl.Set(ctx, "small", "value", ind))
+
+	// reply of canceled operation is never read as a reply of the next one
+	for i := 0; i < 20; i++ {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(i)*time.Millisecond/4)
+		_, err := cl.Get(timeoutCtx, "big", ind)
+		cancel()
+		if err != nil {
+			require.ErrorIs(t, err, client.ErrTimeIsOut)
+		}
+		val, err := cl.Get(ctx, "small", ind)
+		require.Nil(t, err)
+		require.Equal(t, "value", val)
+	}
+	cancelCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(time.Millisecond)
+		cancel()
+	}()
+	_, err := cl.GetL(cancelCtx, "big", ind)
+	require.NotNil(t, err)
+	val, err := cl.Get(ctx, "big", ind)
+	require.Nil(t, err)
+	require.Equal(t, len(big), len(val))
+	canceled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	require.ErrorIs(t, cl.Ping(canceled), client.ErrTimeIsOut)
+	require.Nil(t, cl.Ping(ctx))
+
+	require.Nil(t, cl.Close())
+	require.ErrorIs(t, cl.Ping(ctx), client.ErrClientClosed)
+}
+
+func Test_ClientReconnect(t *testing.T) {
+	ind := 8
+	logPath := filepath.Join(t.TempDir(), "logs")
+	first := testserver.Start(t, server.Config{RecoveryLog: logPath})
+	addr := first.Addr
+	// start restarts server on the same address, it's ready to accept connections when it returns
+	start := func() *server.Server {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Errorf("failed to listen: %v", err)
+			return first.Server
+		}
+		s := server.NewServer(server.Config{
+			Log:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Password:    testserver.Password,
+			RecoveryLog: logPath,
+		})
+		go s.Serve(ln)
+		t.Cleanup(s.Stop)
+		return s
+	}
+	s := first.Server
+
+	mu := sync.Mutex{}
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	cl, err := client.NewWithOptions(ctx, client.Options{
+		Addr: addr,
+		Retry: client.RetryPolicy{
+			MaxRetries: 8,
+			MinBackoff: 50 * time.Millisecond,
+			MaxBackoff: 200 * time.Millisecond,
+		},
+		Breaker: client.BreakerOptions{Failures: 10, Cooldown: 300 * time.Millisecond},
+		Hooks: client.Hooks{
+			OnDisconnect: func(addr string, err error) { record("disconnect") },
+			OnReconnect:  func(addr string) { record("reconnect") },
+			OnRetry: func(cmd string, retry int, err error) {
+				if retry == 1 {
+					record("retry " + cmd)
+				}
+			},
+			OnBreakerStateChange: func(state client.BreakerState) { record(state.String()) },
+		},
+	})
+	require.Nil(t, err)
+	defer cl.Close()
+	require.Nil(t, cl.Set(ctx, "foo", "bar", ind))
+
+	// idempotent command is retried until server is restarted
+	s.Stop()
+	time.Sleep(100 * time.Millisecond)
+	restarted := make(chan *server.Server)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		restarted <- start()
+	}()
+	val, err := cl.Get(ctx, "foo", ind)
+	require.Nil(t, err)
+	require.Equal(t, "bar", val)
+	s = <-restarted
+	mu.Lock()
+	require.Contains(t, events, "retry GET")
+	require.Contains(t, events, "reconnect")
+	require.NotContains(t, events, "open")
+	events = nil
+	mu.Unlock()
+
+	// not idempotent command is not retried, circuit is opened after connection errors in a row
+	s.Stop()
+	time.Sleep(100 * time.Millisecond)
+	require.NotNil(t, cl.Add(ctx, "counter", ind))
+	for i := 0; i < 5 && !errors.Is(err, client.ErrCircuitOpen); i++ {
+		err = cl.Ping(ctx)
+		require.NotNil(t, err)
+	}
+	require.ErrorIs(t, cl.Ping(ctx), client.ErrCircuitOpen)
+	mu.Lock()
+	require.NotContains(t, events, "retry ADD")
+	require.Contains(t, events, "open")
+	events = nil
+	mu.Unlock()
+
+	s = start()
+	// circuit is half-open after cooldown
+	time.Sleep(300 * time.Millisecond)
+	require.Nil(t, cl.Ping(ctx))
+	val, err = cl.Get(ctx, "foo", ind)
+	require.Nil(t, err)
+	require.Equal(t, "bar", val)
+	mu.Lock()
+	require.Equal(t, []string{"half-open", "reconnect", "closed"}, events)
+	mu.Unlock()
+}
+
+func Test_TypedErrors(t *testing.T) {
+	ind := 10
+	cl := testserver.Start(t, server.Config{}).Client
+
+	_, err := cl.Get(ctx, "missing", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+	require.ErrorIs(t, err, client.ErrOperationFailed)
+	var serr *client.ServerError
+	require.True(t, errors.As(err, &serr))
+	require.Equal(t, "NOTFOUND", serr.Code)
+	require.Equal(t, "key doesn't exist", serr.Message)
+	_, err = cl.GetL(ctx, "missing", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+	require.ErrorIs(t, cl.Add(ctx, "missing", ind), client.ErrNotFound)
+
+	require.Nil(t, cl.Set(ctx, "str", "value", ind))
+	err = cl.Add(ctx, "str", ind)
+	require.ErrorIs(t, err, client.ErrNotInteger)
+	require.NotErrorIs(t, err, client.ErrNotFound)
+	require.Nil(t, cl.Set(ctx, "num", "1", ind))
+	require.ErrorIs(t, cl.AddN(ctx, "num", "x", ind), client.ErrNotInteger)
+	require.ErrorIs(t, cl.Set(ctx, "key", "value", 40), client.ErrInvalidIndex)
+
+	require.Nil(t, cl.JSONSet(ctx, "doc", "$", `{"a":"x"}`, ind))
+	_, err = cl.JSONNumIncrBy(ctx, "doc", ".a", 1, ind)
+	require.ErrorIs(t, err, client.ErrWrongType)
+	require.ErrorIs(t, cl.JSONSet(ctx, "doc", "$", `{"a":`, ind), client.ErrSyntax)
+	_, err = cl.GeoDist(ctx, "missing", "a", "b", "m", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func Test_BinaryValues(t *testing.T) {
+	ind := 11
+	logs := filepath.Join(t.TempDir(), "logs")
+	cl := testserver.Start(t, server.Config{RecoveryLog: logs}).Client
+
+	// values with every byte, RESP delimiters and empty value
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	for _, val := range [][]byte{all, []byte("a\r\nb\x00c"), []byte("$3\r\n*1\r\n"), {}} {
+		require.Nil(t, cl.SetBytes(ctx, "bin", val, ind))
+		got, err := cl.GetBytes(ctx, "bin", ind)
+		require.Nil(t, err)
+		require.Equal(t, val, got)
+	}
+	require.Nil(t, cl.LPushBytes(ctx, "list", all, ind))
+	require.Nil(t, cl.LPushBytes(ctx, "list", []byte{0, '\r', '\n'}, ind))
+	list, err := cl.GetLBytes(ctx, "list", ind)
+	require.Nil(t, err)
+	require.ElementsMatch(t, [][]byte{all, {0, '\r', '\n'}}, list)
+	require.Nil(t, cl.DelElemLBytes(ctx, "list", []byte{0, '\r', '\n'}, ind))
+	list, err = cl.GetLBytes(ctx, "list", ind)
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{all}, list)
+	require.Nil(t, cl.MSetBytes(ctx, map[string][]byte{"m1": all, "m2": {0}}, ind))
+	vals, err := cl.MGetBytes(ctx, []string{"m1", "m2", "missing"}, ind)
+	require.Nil(t, err)
+	require.Equal(t, map[string][]byte{"m1": all, "m2": {0}}, vals)
+	_, err = cl.GetBytes(ctx, "missing", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	sizes := []int{8<<20 + 3}
+	// value of hundreds of MB doesn't fit into memory of short and race runs
+	if !testing.Short() && !raceEnabled {
+		sizes = append(sizes, 300<<20)
+	}
+	var val []byte
+	for _, size := range sizes {
+		val = make([]byte, size)
+		_, err := rand.Read(val)
+		require.Nil(t, err)
+		require.Nil(t, cl.SetBytes(ctx, "large", val, ind))
+		got, err := cl.GetBytes(ctx, "large", ind)
+		require.Nil(t, err)
+		require.Equal(t, len(val), len(got))
+		require.True(t, bytes.Equal(val, got))
+	}
+
+	// records of large values are longer than default limit of the log scanner
+	rcl := testserver.Start(t, server.Config{RecoveryLog: logs}).Client
+	got, err := rcl.GetBytes(ctx, "large", ind)
+	require.Nil(t, err)
+	require.True(t, bytes.Equal(val, got))
+	got, err = rcl.GetBytes(ctx, "bin", ind)
+	require.Nil(t, err)
+	require.Empty(t, got)
+}
+
+func Test_ClientTracking(t *testing.T) {
+	ind := 17
+	s := testserver.Start(t, server.Config{})
+	writer := s.Client
+	cl := s.NewClient(client.Options{LocalCache: client.LocalCacheOptions{Size: 100}})
+
+	// waitInvalidation waits until client receives n invalidations
+	waitInvalidation := func(cl *client.Client, n int64) {
+		require.Eventually(t, func() bool {
+			return cl.LocalCacheStats().Invalidations >= n
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	require.Nil(t, writer.Set(ctx, "tracked", "v1", ind))
+	for range 3 {
+		val, err := cl.Get(ctx, "tracked", ind)
+		require.Nil(t, err)
+		require.Equal(t, "v1", val)
+	}
+	stats := cl.LocalCacheStats()
+	require.Equal(t, int64(2), stats.Hits)
+	require.Equal(t, 1, stats.Entries)
+
+	require.Nil(t, writer.Set(ctx, "tracked", "v2", ind))
+	waitInvalidation(cl, 1)
+	val, err := cl.Get(ctx, "tracked", ind)
+	require.Nil(t, err)
+	require.Equal(t, "v2", val)
+
+	// own writes are visible at once
+	require.Nil(t, cl.Set(ctx, "tracked", "v3", ind))
+	val, err = cl.Get(ctx, "tracked", ind)
+	require.Nil(t, err)
+	require.Equal(t, "v3", val)
+
+	// key that is not read is not reported
+	before := cl.LocalCacheStats().Invalidations
+	require.Nil(t, writer.Set(ctx, "other", "v", ind))
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, before, cl.LocalCacheStats().Invalidations)
+
+	// expiration is a change of the key and expired keys are invalidated too
+	_, err = writer.PExpire(ctx, "tracked", 100*time.Millisecond, ind)
+	require.Nil(t, err)
+	waitInvalidation(cl, before+1)
+	_, err = cl.Get(ctx, "tracked", ind)
+	require.Nil(t, err)
+	waitInvalidation(cl, before+2)
+	_, err = cl.Get(ctx, "tracked", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	bcast := s.NewClient(client.Options{
+		LocalCache: client.LocalCacheOptions{Size: 100, BCast: true, Prefixes: []string{"user:"}},
+	})
+	_, err = bcast.Get(ctx, "missing", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+	require.Nil(t, writer.Set(ctx, "user:1", "a", ind))
+	waitInvalidation(bcast, 1)
+	require.Nil(t, writer.Set(ctx, "item:1", "a", ind))
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, int64(1), bcast.LocalCacheStats().Invalidations)
+
+	// tracking can't be redirected to a connection that doesn't receive invalidations
+	_, err = writer.Do(ctx, "CLIENT", "TRACKING", "ON", "REDIRECT", "unknown")
+	require.NotNil(t, err)
+}
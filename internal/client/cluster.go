@@ -0,0 +1,243 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+var (
+	CommandCluster = "CLUSTER"
+	CommandAsking  = "ASKING"
+	CommandMigrate = "MIGRATE"
+	CommandUnlink  = "UNLINK"
+)
+
+// Slot states for ClusterSetSlot
+const (
+	SlotImporting = "IMPORTING"
+	SlotMigrating = "MIGRATING"
+	SlotNode      = "NODE"
+	SlotStable    = "STABLE"
+)
+
+// RedirectError is returned when key is served by other node of the cluster,
+// errors.Is(err, ErrOperationFailed) is true for it
+type RedirectError struct {
+	// Ask is set for ASK redirection, only the next command is sent to Addr with ASKING,
+	// otherwise slot is moved to Addr permanently
+	Ask  bool
+	Slot int
+	Addr string
+}
+
+func (e *RedirectError) Error() string {
+	kind := "MOVED"
+	if e.Ask {
+		kind = "ASK"
+	}
+	return fmt.Sprintf("%s %d %s", kind, e.Slot, e.Addr)
+}
+
+func (e *RedirectError) Is(target error) bool {
+	return target == ErrOperationFailed
+}
+
+// SlotRange is a range of slots served by one node
+type SlotRange struct {
+	Start, End int
+	Addr       string
+	ID         string
+}
+
+// SlotKey is a key of the slot in the database with Index
+type SlotKey struct {
+	Key   string
+	Index int
+}
+
+// ClusterMeet adds node with given host and port to the cluster
+func (c *Client) ClusterMeet(ctx context.Context, host string, port string) error {
+	return c.clusterStatus(ctx, "MEET", host, port)
+}
+
+// ClusterAddSlots assigns slots to the node
+func (c *Client) ClusterAddSlots(ctx context.Context, slots ...int) error {
+	args := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		args = append(args, strconv.Itoa(slot))
+	}
+	return c.clusterStatus(ctx, "ADDSLOTS", args...)
+}
+
+// ClusterAddSlotsRange assigns slots from start to end inclusive to the node
+func (c *Client) ClusterAddSlotsRange(ctx context.Context, start, end int) error {
+	return c.clusterStatus(ctx, "ADDSLOTSRANGE", strconv.Itoa(start), strconv.Itoa(end))
+}
+
+// ClusterSetSlot changes state of the slot, nodeID is ignored for SlotStable
+func (c *Client) ClusterSetSlot(ctx context.Context, slot int, state string, nodeID string) error {
+	args := []string{strconv.Itoa(slot), state}
+	if state != SlotStable {
+		args = append(args, nodeID)
+	}
+	return c.clusterStatus(ctx, "SETSLOT", args...)
+}
+
+// ClusterNodes returns cluster configuration as it's seen by the node, in Redis CLUSTER NODES format
+func (c *Client) ClusterNodes(ctx context.Context) (string, error) {
+	return c.clusterValue(ctx, "NODES")
+}
+
+// ClusterInfo returns cluster state in Redis CLUSTER INFO format
+func (c *Client) ClusterInfo(ctx context.Context) (string, error) {
+	return c.clusterValue(ctx, "INFO")
+}
+
+// ClusterMyID returns id of the node
+func (c *Client) ClusterMyID(ctx context.Context) (string, error) {
+	return c.clusterValue(ctx, "MYID")
+}
+
+// ClusterKeySlot returns hash slot of the key
+func (c *Client) ClusterKeySlot(ctx context.Context, key string) (int, error) {
+	val, err := c.clusterValue(ctx, "KEYSLOT", key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(val)
+}
+
+// ClusterCountKeysInSlot returns number of keys of the slot in all databases of the node
+func (c *Client) ClusterCountKeysInSlot(ctx context.Context, slot int) (int64, error) {
+	val, err := c.clusterValue(ctx, "COUNTKEYSINSLOT", strconv.Itoa(slot))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+// ClusterGetKeysInSlot returns at most count keys of the slot from all databases of the node
+func (c *Client) ClusterGetKeysInSlot(ctx context.Context, slot int, count int) ([]SlotKey, error) {
+	list, err := c.clusterList(ctx, "GETKEYSINSLOT", strconv.Itoa(slot), strconv.Itoa(count))
+	if err != nil {
+		return nil, err
+	}
+	if len(list)%2 != 0 {
+		return nil, ErrOperationFailed
+	}
+	res := make([]SlotKey, 0, len(list)/2)
+	for i := 0; i < len(list); i += 2 {
+		ind, err := strconv.Atoi(string(list[i]))
+		if err != nil {
+			return nil, ErrOperationFailed
+		}
+		res = append(res, SlotKey{Key: string(list[i+1]), Index: ind})
+	}
+	return res, nil
+}
+
+// ClusterSlots returns ranges of slots and nodes that serve them
+func (c *Client) ClusterSlots(ctx context.Context) ([]SlotRange, error) {
+	list, err := c.clusterList(ctx, "SLOTS")
+	if err != nil {
+		return nil, err
+	}
+	if len(list)%5 != 0 {
+		return nil, ErrOperationFailed
+	}
+	res := make([]SlotRange, 0, len(list)/5)
+	for i := 0; i < len(list); i += 5 {
+		start, err := strconv.Atoi(string(list[i]))
+		if err != nil {
+			return nil, ErrOperationFailed
+		}
+		end, err := strconv.Atoi(string(list[i+1]))
+		if err != nil {
+			return nil, ErrOperationFailed
+		}
+		res = append(res, SlotRange{
+			Start: start,
+			End:   end,
+			Addr:  net.JoinHostPort(string(list[i+2]), string(list[i+3])),
+			ID:    string(list[i+4]),
+		})
+	}
+	return res, nil
+}
+
+// Asking allows the next command to access key of the slot that is being imported by the node,
+// it's used to follow ASK redirection
+func (c *Client) Asking(ctx context.Context) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandAsking); err != nil {
+		return err
+	}
+	return c.waitForResponse(ctx)
+}
+
+// Migrate moves key of database ind to the node with given host and port, key is replaced on the target node,
+// returns false if there is no such key
+func (c *Client) Migrate(ctx context.Context, host string, port string, key string, ind int, timeout time.Duration) (bool, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return false, ErrInvalidIndex
+	}
+	err := c.writeCommand(ctx, CommandMigrate, host, port, key, strconv.Itoa(ind), strconv.FormatInt(timeout.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return false, err
+	}
+	return res == "OK", nil
+}
+
+// Unlink deletes key of any type from database ind, returns false if there was no such key
+func (c *Client) Unlink(ctx context.Context, key string, ind int) (bool, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return false, ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandUnlink, ind, key); err != nil {
+		return false, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return false, err
+	}
+	return res == "1", nil
+}
+
+func (c *Client) clusterStatus(ctx context.Context, subcommand string, args ...string) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandCluster, append([]string{subcommand}, args...)...); err != nil {
+		return err
+	}
+	return c.waitForResponse(ctx)
+}
+
+func (c *Client) clusterValue(ctx context.Context, subcommand string, args ...string) (string, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandCluster, append([]string{subcommand}, args...)...); err != nil {
+		return "", err
+	}
+	return c.waitForResult(ctx)
+}
+
+func (c *Client) clusterList(ctx context.Context, subcommand string, args ...string) ([][]byte, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandCluster, append([]string{subcommand}, args...)...); err != nil {
+		return nil, err
+	}
+	return c.waitForList(ctx)
+}
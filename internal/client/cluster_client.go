@@ -0,0 +1,702 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/hashslot"
+)
+
+const (
+	defaultMaxRedirects = 3
+	// minRefreshInterval limits how often slot map is fetched when many commands are redirected at once
+	minRefreshInterval = 100 * time.Millisecond
+)
+
+var (
+	// ErrNoClusterNodes returned when none of the nodes can return slot map
+	ErrNoClusterNodes = errors.New("no reachable cluster nodes")
+	// ErrTooManyRedirects returned when command is redirected more than MaxRedirects times
+	ErrTooManyRedirects = errors.New("too many cluster redirects")
+	// ErrSlotNotServed returned when no node serves slot of the key
+	ErrSlotNotServed = errors.New("slot is not served")
+)
+
+// ClusterOptions describes how to connect to the cluster
+type ClusterOptions struct {
+	// Addrs are addresses of some nodes of the cluster, the first reachable one returns slot map
+	Addrs []string
+	// Password is used to connect to all nodes
+	Password string
+	// User is a name of the user authenticated with Password, the default user is authenticated if it's empty
+	User string
+	// MaxRedirects is a number of MOVED and ASK redirects a command follows, 3 by default
+	MaxRedirects int
+}
+
+// ClusterClient routes commands to the nodes that serve slots of their keys, it follows MOVED and ASK
+// redirects and refreshes slot map when slots are moved, it supports concurrent operations
+type ClusterClient struct {
+	opts        ClusterOptions
+	mu          sync.RWMutex
+	nodes       map[string]*Client
+	slots       [hashslot.Count]string
+	closed      bool
+	refreshMu   sync.Mutex
+	lastRefresh time.Time
+}
+
+// NewCluster fetches slot map from one of the nodes and returns client of the cluster,
+// connections to the nodes are created on first use
+func NewCluster(ctx context.Context, opts ClusterOptions) (*ClusterClient, error) {
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = defaultMaxRedirects
+	}
+	if len(opts.Password) == 0 {
+		opts.Password = defaultPassword
+	}
+	c := &ClusterClient{
+		opts:  opts,
+		nodes: make(map[string]*Client),
+	}
+	if err := c.refresh(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// dial creates client of the node authenticated with credentials of the options
+func (c *ClusterClient) dial(ctx context.Context, addr string) (*Client, error) {
+	return NewWithOptions(ctx, Options{Addr: addr, User: c.opts.User, Password: c.opts.Password})
+}
+
+// Node returns client connected to the node with given address, it's used
+// for commands of a single node, e.g. Role, Info or cluster management
+func (c *ClusterClient) Node(ctx context.Context, addr string) (*Client, error) {
+	c.mu.RLock()
+	cl, ok := c.nodes[addr]
+	closed := c.closed
+	c.mu.RUnlock()
+	if closed {
+		return nil, ErrClientClosed
+	}
+	if ok {
+		return cl, nil
+	}
+	cl, err := c.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		cl.Close()
+		return nil, ErrClientClosed
+	}
+	if existing, ok := c.nodes[addr]; ok {
+		cl.Close()
+		return existing, nil
+	}
+	c.nodes[addr] = cl
+	return cl, nil
+}
+
+// Masters returns sorted addresses of the nodes that serve slots
+func (c *ClusterClient) Masters() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	seen := make(map[string]struct{})
+	var res []string
+	for _, addr := range c.slots {
+		if _, ok := seen[addr]; !ok && len(addr) != 0 {
+			seen[addr] = struct{}{}
+			res = append(res, addr)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// ForEachNode runs fn for every node that serves slots, the first error is returned
+func (c *ClusterClient) ForEachNode(ctx context.Context, fn func(*Client) error) error {
+	masters := c.Masters()
+	errs := make([]error, len(masters))
+	wg := sync.WaitGroup{}
+	for i, addr := range masters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cl, err := c.Node(ctx, addr)
+			if err == nil {
+				err = fn(cl)
+				c.checkConn(addr, cl, err)
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropNode closes connection that is broken, the next command dials the node again
+func (c *ClusterClient) dropNode(addr string, cl *Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes[addr] == cl {
+		delete(c.nodes, addr)
+		cl.Close()
+	}
+}
+
+// checkConn drops connection if command failed due to connection error
+func (c *ClusterClient) checkConn(addr string, cl *Client, err error) bool {
+	if isConnError(err) {
+		c.dropNode(addr, cl)
+		return true
+	}
+	return false
+}
+
+// knownAddrs returns addresses of the nodes from slot map and connected nodes, then seed addresses
+func (c *ClusterClient) knownAddrs() []string {
+	addrs := c.Masters()
+	c.mu.RLock()
+	for addr := range c.nodes {
+		addrs = append(addrs, addr)
+	}
+	c.mu.RUnlock()
+	return append(addrs, c.opts.Addrs...)
+}
+
+// refresh fetches slot map from the first node that returns it
+func (c *ClusterClient) refresh(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if time.Since(c.lastRefresh) < minRefreshInterval {
+		return nil
+	}
+	err := ErrNoClusterNodes
+	tried := make(map[string]struct{})
+	for _, addr := range c.knownAddrs() {
+		if _, ok := tried[addr]; ok {
+			continue
+		}
+		tried[addr] = struct{}{}
+		cl, nodeErr := c.Node(ctx, addr)
+		if nodeErr != nil {
+			err = nodeErr
+			continue
+		}
+		ranges, slotsErr := cl.ClusterSlots(ctx)
+		if slotsErr != nil {
+			c.checkConn(addr, cl, slotsErr)
+			err = slotsErr
+			continue
+		}
+		var slots [hashslot.Count]string
+		for _, r := range ranges {
+			for slot := max(r.Start, 0); slot <= r.End && slot < hashslot.Count; slot++ {
+				slots[slot] = r.Addr
+			}
+		}
+		c.mu.Lock()
+		c.slots = slots
+		c.mu.Unlock()
+		c.lastRefresh = time.Now()
+		return nil
+	}
+	return err
+}
+
+func (c *ClusterClient) slotAddr(slot int) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.slots[slot]
+}
+
+func (c *ClusterClient) setSlotAddr(slot int, addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slots[slot] = addr
+}
+
+// clusterDo runs fn on the node that serves slot of the key, MOVED redirect updates slot map
+// and ASK redirect runs fn once on the target node after ASKING
+func clusterDo[T any](ctx context.Context, c *ClusterClient, key string, fn func(*Client) (T, error)) (T, error) {
+	var zero T
+	slot := hashslot.Slot([]byte(key))
+	addr := c.slotAddr(slot)
+	ask := false
+	var err error
+	for i := 0; i <= c.opts.MaxRedirects; i++ {
+		if len(addr) == 0 {
+			if err := c.refresh(ctx); err != nil {
+				return zero, err
+			}
+			if addr = c.slotAddr(slot); len(addr) == 0 {
+				return zero, ErrSlotNotServed
+			}
+		}
+		var res T
+		if ask {
+			res, err = clusterAsking(ctx, c, addr, fn)
+		} else {
+			var cl *Client
+			cl, err = c.Node(ctx, addr)
+			if err != nil {
+				if errors.Is(err, ErrClientClosed) {
+					return zero, err
+				}
+				// node is not reachable, slot may be served by other node already
+				c.refresh(ctx)
+				addr = c.slotAddr(slot)
+				continue
+			}
+			res, err = fn(cl)
+			if c.checkConn(addr, cl, err) {
+				// node may be down, the next command uses slot map of the nodes that are alive
+				c.refresh(ctx)
+			}
+		}
+		var redirect *RedirectError
+		if !errors.As(err, &redirect) {
+			return res, err
+		}
+		addr, ask = redirect.Addr, redirect.Ask
+		if !ask {
+			c.setSlotAddr(redirect.Slot, redirect.Addr)
+			c.refresh(ctx)
+		}
+	}
+	return zero, fmt.Errorf("%w: %w", ErrTooManyRedirects, err)
+}
+
+// clusterAsking runs fn with a new connection to the node after ASKING, so ASKING
+// isn't consumed by commands of other goroutines
+func clusterAsking[T any](ctx context.Context, c *ClusterClient, addr string, fn func(*Client) (T, error)) (T, error) {
+	var zero T
+	cl, err := c.dial(ctx, addr)
+	if err != nil {
+		return zero, err
+	}
+	defer cl.Close()
+	if err := cl.Asking(ctx); err != nil {
+		return zero, err
+	}
+	return fn(cl)
+}
+
+// clusterExec is clusterDo for commands without result
+func clusterExec(ctx context.Context, c *ClusterClient, key string, fn func(*Client) error) error {
+	_, err := clusterDo(ctx, c, key, func(cl *Client) (struct{}, error) {
+		return struct{}{}, fn(cl)
+	})
+	return err
+}
+
+// groupBySlot splits keys into groups of keys with the same slot
+func groupBySlot(keys []string) [][]string {
+	groups := make(map[int][]string)
+	var order []int
+	for _, key := range keys {
+		slot := hashslot.Slot([]byte(key))
+		if _, ok := groups[slot]; !ok {
+			order = append(order, slot)
+		}
+		groups[slot] = append(groups[slot], key)
+	}
+	res := make([][]string, 0, len(order))
+	for _, slot := range order {
+		res = append(res, groups[slot])
+	}
+	return res
+}
+
+// forEachGroup runs fn concurrently for every group, the first error is returned
+func forEachGroup(groups [][]string, fn func(keys []string) error) error {
+	errs := make([]error, len(groups))
+	wg := sync.WaitGroup{}
+	for i, keys := range groups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(keys)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes connections to all nodes
+func (c *ClusterClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	var res error
+	for addr, cl := range c.nodes {
+		if err := cl.Close(); err != nil && res == nil {
+			res = err
+		}
+		delete(c.nodes, addr)
+	}
+	return res
+}
+
+// Ping checks that all nodes that serve slots are alive
+func (c *ClusterClient) Ping(ctx context.Context) error {
+	return c.ForEachNode(ctx, func(cl *Client) error {
+		return cl.Ping(ctx)
+	})
+}
+
+func (c *ClusterClient) Hello(ctx context.Context, m map[string]string) error {
+	return c.ForEachNode(ctx, func(cl *Client) error {
+		return cl.Hello(ctx, m)
+	})
+}
+
+// ClusterSlots returns ranges of slots and nodes that serve them
+func (c *ClusterClient) ClusterSlots(ctx context.Context) ([]SlotRange, error) {
+	return clusterDo(ctx, c, "", func(cl *Client) ([]SlotRange, error) {
+		return cl.ClusterSlots(ctx)
+	})
+}
+
+// ClusterNodes returns cluster configuration as it's seen by the node that serves slot 0
+func (c *ClusterClient) ClusterNodes(ctx context.Context) (string, error) {
+	return clusterDo(ctx, c, "", func(cl *Client) (string, error) {
+		return cl.ClusterNodes(ctx)
+	})
+}
+
+// ClusterInfo returns cluster state as it's seen by the node that serves slot 0
+func (c *ClusterClient) ClusterInfo(ctx context.Context) (string, error) {
+	return clusterDo(ctx, c, "", func(cl *Client) (string, error) {
+		return cl.ClusterInfo(ctx)
+	})
+}
+
+// ClusterKeySlot returns hash slot of the key, it's computed without requests to the nodes
+func (c *ClusterClient) ClusterKeySlot(ctx context.Context, key string) (int, error) {
+	return hashslot.Slot([]byte(key)), nil
+}
+
+// Set sets key with given value it returns error if ctx is done or operation failed
+func (c *ClusterClient) Set(ctx context.Context, key string, value string, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.Set(ctx, key, value, ind)
+	})
+}
+
+// SetBytes sets key with given binary value, value may contain any bytes
+func (c *ClusterClient) SetBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.SetBytes(ctx, key, value, ind)
+	})
+}
+
+// Get returns key value and error if ctx is done or operation failed
+func (c *ClusterClient) Get(ctx context.Context, key string, ind int) (string, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (string, error) {
+		return cl.Get(ctx, key, ind)
+	})
+}
+
+// GetBytes returns binary value of the key, ErrNotFound is returned if key doesn't exist
+func (c *ClusterClient) GetBytes(ctx context.Context, key string, ind int) ([]byte, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) ([]byte, error) {
+		return cl.GetBytes(ctx, key, ind)
+	})
+}
+
+// MSet sets values of several keys in database ind, keys are grouped by slot and groups
+// are set concurrently, so keys of different slots are not set atomically
+func (c *ClusterClient) MSet(ctx context.Context, pairs map[string]string, ind int) error {
+	return clusterMSet(ctx, c, pairs, func(cl *Client, group map[string]string) error {
+		return cl.MSet(ctx, group, ind)
+	})
+}
+
+// MSetBytes sets binary values of several keys in database ind, keys are grouped by slot
+// like in MSet
+func (c *ClusterClient) MSetBytes(ctx context.Context, pairs map[string][]byte, ind int) error {
+	return clusterMSet(ctx, c, pairs, func(cl *Client, group map[string][]byte) error {
+		return cl.MSetBytes(ctx, group, ind)
+	})
+}
+
+// MGet returns values of keys from database ind, keys that don't exist are missing in the result,
+// keys are grouped by slot and groups are requested concurrently
+func (c *ClusterClient) MGet(ctx context.Context, keys []string, ind int) (map[string]string, error) {
+	return clusterMGet(ctx, c, keys, func(cl *Client, keys []string) (map[string]string, error) {
+		return cl.MGet(ctx, keys, ind)
+	})
+}
+
+// MGetBytes returns binary values of keys from database ind, keys that don't exist are missing
+// in the result, keys are grouped by slot like in MGet
+func (c *ClusterClient) MGetBytes(ctx context.Context, keys []string, ind int) (map[string][]byte, error) {
+	return clusterMGet(ctx, c, keys, func(cl *Client, keys []string) (map[string][]byte, error) {
+		return cl.MGetBytes(ctx, keys, ind)
+	})
+}
+
+// clusterMSet splits pairs by slot and sets every group on the node serving its slot
+func clusterMSet[V any](ctx context.Context, c *ClusterClient, pairs map[string]V, mset func(cl *Client, group map[string]V) error) error {
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	return forEachGroup(groupBySlot(keys), func(keys []string) error {
+		group := make(map[string]V, len(keys))
+		for _, key := range keys {
+			group[key] = pairs[key]
+		}
+		return clusterExec(ctx, c, keys[0], func(cl *Client) error {
+			return mset(cl, group)
+		})
+	})
+}
+
+// clusterMGet splits keys by slot, requests every group from the node serving its slot and merges results
+func clusterMGet[V any](ctx context.Context, c *ClusterClient, keys []string, mget func(cl *Client, keys []string) (map[string]V, error)) (map[string]V, error) {
+	res := make(map[string]V, len(keys))
+	mu := sync.Mutex{}
+	err := forEachGroup(groupBySlot(keys), func(keys []string) error {
+		vals, err := clusterDo(ctx, c, keys[0], func(cl *Client) (map[string]V, error) {
+			return mget(cl, keys)
+		})
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for key, val := range vals {
+			res[key] = val
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Add increment key value by 1 and returns error if ctx is done or operation failed
+func (c *ClusterClient) Add(ctx context.Context, key string, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.Add(ctx, key, ind)
+	})
+}
+
+// AddN increment key value by given value and returns error if ctx is done or operation failed
+func (c *ClusterClient) AddN(ctx context.Context, key string, value string, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.AddN(ctx, key, value, ind)
+	})
+}
+
+// Delete deletes key of any type from database ind
+func (c *ClusterClient) Delete(ctx context.Context, key string, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.Delete(ctx, key, ind)
+	})
+}
+
+// Unlink deletes key of any type from database ind, returns false if there was no such key
+func (c *ClusterClient) Unlink(ctx context.Context, key string, ind int) (bool, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (bool, error) {
+		return cl.Unlink(ctx, key, ind)
+	})
+}
+
+// LPush pushes value to list key in database ind, if list doesn't exists it will be created
+func (c *ClusterClient) LPush(ctx context.Context, key string, value string, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.LPush(ctx, key, value, ind)
+	})
+}
+
+// LPushBytes pushes binary value to list key in database ind, if list doesn't exists it will be created
+func (c *ClusterClient) LPushBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.LPushBytes(ctx, key, value, ind)
+	})
+}
+
+// GetL returns list key that contains strings
+func (c *ClusterClient) GetL(ctx context.Context, key string, ind int) ([]string, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) ([]string, error) {
+		return cl.GetL(ctx, key, ind)
+	})
+}
+
+// GetLBytes returns list key with binary values
+func (c *ClusterClient) GetLBytes(ctx context.Context, key string, ind int) ([][]byte, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) ([][]byte, error) {
+		return cl.GetLBytes(ctx, key, ind)
+	})
+}
+
+// Has reports whether key of any type exists in database ind
+func (c *ClusterClient) Has(ctx context.Context, key string, ind int) (bool, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (bool, error) {
+		return cl.Has(ctx, key, ind)
+	})
+}
+
+// DeleteL deletes whole list with name key from database ind
+func (c *ClusterClient) DeleteL(ctx context.Context, key string, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.DeleteL(ctx, key, ind)
+	})
+}
+
+// DelElemL deletes only one element with value from list with key name in database ind
+func (c *ClusterClient) DelElemL(ctx context.Context, key string, value string, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.DelElemL(ctx, key, value, ind)
+	})
+}
+
+// DelElemLBytes deletes only one element with binary value from list with key name in database ind
+func (c *ClusterClient) DelElemLBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.DelElemLBytes(ctx, key, value, ind)
+	})
+}
+
+// DelAll deletes all appearances of value in list with key name in database with index ind
+func (c *ClusterClient) DelAll(ctx context.Context, key string, value string, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.DelAll(ctx, key, value, ind)
+	})
+}
+
+// DelAllBytes deletes all appearances of binary value in list with key name in database with index ind
+func (c *ClusterClient) DelAllBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.DelAllBytes(ctx, key, value, ind)
+	})
+}
+
+// GeoAdd adds locations to geo set key in database ind, existing members are moved to the new location
+func (c *ClusterClient) GeoAdd(ctx context.Context, key string, locations []GeoLocation, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.GeoAdd(ctx, key, locations, ind)
+	})
+}
+
+// GeoDist returns distance between two members of geo set key in given unit
+func (c *ClusterClient) GeoDist(ctx context.Context, key, member1, member2, unit string, ind int) (float64, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (float64, error) {
+		return cl.GeoDist(ctx, key, member1, member2, unit, ind)
+	})
+}
+
+// GeoPos returns positions of members of geo set key, position is nil for missing member
+func (c *ClusterClient) GeoPos(ctx context.Context, key string, members []string, ind int) ([]*GeoPos, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) ([]*GeoPos, error) {
+		return cl.GeoPos(ctx, key, members, ind)
+	})
+}
+
+// GeoSearch returns members of geo set key that are within the area described by the query
+func (c *ClusterClient) GeoSearch(ctx context.Context, key string, q *GeoSearchQuery, ind int) ([]GeoLocation, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) ([]GeoLocation, error) {
+		return cl.GeoSearch(ctx, key, q, ind)
+	})
+}
+
+// JSONSet sets JSON value at the path of document key in database ind
+func (c *ClusterClient) JSONSet(ctx context.Context, key string, path string, value string, ind int) error {
+	return clusterExec(ctx, c, key, func(cl *Client) error {
+		return cl.JSONSet(ctx, key, path, value, ind)
+	})
+}
+
+// JSONGet returns JSON values matched by the paths of document key in database ind
+func (c *ClusterClient) JSONGet(ctx context.Context, key string, ind int, paths ...string) (string, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (string, error) {
+		return cl.JSONGet(ctx, key, ind, paths...)
+	})
+}
+
+// JSONDel deletes JSON values matched by the path of document key in database ind
+func (c *ClusterClient) JSONDel(ctx context.Context, key string, path string, ind int) (int64, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (int64, error) {
+		return cl.JSONDel(ctx, key, path, ind)
+	})
+}
+
+// JSONNumIncrBy increments numbers matched by the path of document key in database ind
+func (c *ClusterClient) JSONNumIncrBy(ctx context.Context, key string, path string, value float64, ind int) (string, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (string, error) {
+		return cl.JSONNumIncrBy(ctx, key, path, value, ind)
+	})
+}
+
+// JSONArrAppend appends values to arrays matched by the path of document key in database ind
+func (c *ClusterClient) JSONArrAppend(ctx context.Context, key string, path string, values []string, ind int) ([]int64, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) ([]int64, error) {
+		return cl.JSONArrAppend(ctx, key, path, values, ind)
+	})
+}
+
+// JSONType returns types of JSON values matched by the path of document key in database ind
+func (c *ClusterClient) JSONType(ctx context.Context, key string, path string, ind int) ([]string, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) ([]string, error) {
+		return cl.JSONType(ctx, key, path, ind)
+	})
+}
+
+// Subscribe creates subscription to the channels on every node that serves slots,
+// so messages published to any of them are received
+func (c *ClusterClient) Subscribe(ctx context.Context, channels ...string) (*PubSub, error) {
+	return subscribe(ctx, c.Masters(), credentials(c.opts.User, c.opts.Password), CommandSubscribe, channels)
+}
+
+// PSubscribe creates subscription to the channels that match glob-style patterns on every node that serves slots
+func (c *ClusterClient) PSubscribe(ctx context.Context, patterns ...string) (*PubSub, error) {
+	return subscribe(ctx, c.Masters(), credentials(c.opts.User, c.opts.Password), CommandPSubscribe, patterns)
+}
+
+// SubscribeKeyspace subscribes to keyspace notifications of all nodes about keys of database ind
+// that match glob-style pattern
+func (c *ClusterClient) SubscribeKeyspace(ctx context.Context, keyPattern string, ind int) (*PubSub, error) {
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	return c.PSubscribe(ctx, keyspacePattern(keyPattern, ind))
+}
+
+// SubscribeKeyevent subscribes to keyevent notifications of all nodes about events of database ind
+func (c *ClusterClient) SubscribeKeyevent(ctx context.Context, ind int, events ...string) (*PubSub, error) {
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	return c.PSubscribe(ctx, keyeventPatterns(ind, events)...)
+}
+
+// Publish sends message to the channel through the node that serves slot of the channel name,
+// subscribers of ClusterClient receive it once as they are subscribed on every node
+func (c *ClusterClient) Publish(ctx context.Context, channel string, message string) (int64, error) {
+	return clusterDo(ctx, c, channel, func(cl *Client) (int64, error) {
+		return cl.Publish(ctx, channel, message)
+	})
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Codec encodes values of type T to bytes stored on the server and decodes them back
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// JSONCodec encodes values with encoding/json
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// GobCodec encodes values with encoding/gob, every value is encoded with its own type description
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Encode(v T) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// MsgpackCodec encodes values in msgpack format, structs are encoded as maps of exported fields,
+// field name may be changed with `msgpack:"name"` tag and field is skipped with `msgpack:"-"`
+type MsgpackCodec[T any] struct{}
+
+func (MsgpackCodec[T]) Encode(v T) ([]byte, error) {
+	return marshalMsgpack(v)
+}
+
+func (MsgpackCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := unmarshalMsgpack(data, &v)
+	return v, err
+}
+
+// ProtoMessage is implemented by protobuf messages with generated Marshal and Unmarshal methods,
+// e.g. by gogoproto, messages of other generators can be used through a small wrapper type
+type ProtoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+// ProtoCodec encodes protobuf messages with their own methods, T must be a pointer type, e.g. *pb.User
+type ProtoCodec[T ProtoMessage] struct{}
+
+func (ProtoCodec[T]) Encode(v T) ([]byte, error) {
+	return v.Marshal()
+}
+
+func (ProtoCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Pointer {
+		return v, fmt.Errorf("proto: %s is not a pointer type", t)
+	}
+	v = reflect.New(t.Elem()).Interface().(T)
+	err := v.Unmarshal(data)
+	return v, err
+}
@@ -0,0 +1,17 @@
+package client
+
+import "github.com/ArtemNovok/simpleRedisCl/embedded"
+
+// Commands are operations on strings, counters and lists shared by all clients and by the embedded database,
+// business logic written against Commands can be tested with the embedded database and run against a server.
+// Implementations must pass the suite of internal/client/conformance
+type Commands = embedded.Commands
+
+var (
+	_ Commands = (*Client)(nil)
+	_ Commands = (*Pool)(nil)
+	_ Commands = (*ClusterClient)(nil)
+
+	_ embedded.Backend = (*Client)(nil)
+	_ embedded.Backend = (*Pool)(nil)
+)
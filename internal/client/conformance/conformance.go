@@ -0,0 +1,194 @@
+// Package conformance is a test suite that every implementation of client.Commands must pass, so code written
+// against the interface behaves the same with a server and with the embedded database
+package conformance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+)
+
+// db is a database index used by the suite, keys of the suite are prefixed with the name of the test
+const db = 7
+
+// Run runs the suite against cmds, every test uses its own keys, so cmds may hold other data
+func Run(t *testing.T, cmds client.Commands) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, cmds client.Commands, key func(string) string)
+	}{
+		{"Strings", testStrings},
+		{"BinaryValues", testBinaryValues},
+		{"MultiKey", testMultiKey},
+		{"Counters", testCounters},
+		{"Lists", testLists},
+		{"InvalidIndex", testInvalidIndex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, cmds, func(name string) string { return t.Name() + ":" + name })
+		})
+	}
+}
+
+func testStrings(t *testing.T, cmds client.Commands, key func(string) string) {
+	ctx := context.Background()
+	_, err := cmds.Get(ctx, key("name"), db)
+	expectErr(t, "Get of missing key", err, client.ErrNotFound)
+	expectNoErr(t, "Set", cmds.Set(ctx, key("name"), "first", db))
+	expectNoErr(t, "Set of existing key", cmds.Set(ctx, key("name"), "second", db))
+	val, err := cmds.Get(ctx, key("name"), db)
+	expectNoErr(t, "Get", err)
+	expectEqual(t, "Get", val, "second")
+	expectNoErr(t, "Delete", cmds.Delete(ctx, key("name"), db))
+	_, err = cmds.Get(ctx, key("name"), db)
+	expectErr(t, "Get of deleted key", err, client.ErrNotFound)
+	expectNoErr(t, "Delete of missing key", cmds.Delete(ctx, key("name"), db))
+}
+
+func testBinaryValues(t *testing.T, cmds client.Commands, key func(string) string) {
+	ctx := context.Background()
+	value := []byte("a\x00b#c\r\nd")
+	expectNoErr(t, "SetBytes", cmds.SetBytes(ctx, key("bin"), value, db))
+	val, err := cmds.GetBytes(ctx, key("bin"), db)
+	expectNoErr(t, "GetBytes", err)
+	expectEqual(t, "GetBytes", string(val), string(value))
+	expectNoErr(t, "SetBytes of empty value", cmds.SetBytes(ctx, key("empty"), []byte{}, db))
+	val, err = cmds.GetBytes(ctx, key("empty"), db)
+	expectNoErr(t, "GetBytes of empty value", err)
+	expectEqual(t, "GetBytes of empty value", len(val), 0)
+	value[0] = 'z'
+	val, err = cmds.GetBytes(ctx, key("bin"), db)
+	expectNoErr(t, "GetBytes after argument is changed", err)
+	expectEqual(t, "GetBytes after argument is changed", val[0], byte('a'))
+}
+
+func testMultiKey(t *testing.T, cmds client.Commands, key func(string) string) {
+	ctx := context.Background()
+	expectNoErr(t, "MSet of no keys", cmds.MSet(ctx, nil, db))
+	expectNoErr(t, "MSet", cmds.MSet(ctx, map[string]string{key("a"): "1", key("b"): "2"}, db))
+	vals, err := cmds.MGet(ctx, []string{key("a"), key("b"), key("missing")}, db)
+	expectNoErr(t, "MGet", err)
+	expectEqual(t, "MGet length", len(vals), 2)
+	expectEqual(t, "MGet of a", vals[key("a")], "1")
+	expectEqual(t, "MGet of b", vals[key("b")], "2")
+	vals, err = cmds.MGet(ctx, nil, db)
+	expectNoErr(t, "MGet of no keys", err)
+	expectEqual(t, "MGet of no keys", len(vals), 0)
+	expectNoErr(t, "MSetBytes", cmds.MSetBytes(ctx, map[string][]byte{key("c"): []byte("x\ny")}, db))
+	bin, err := cmds.MGetBytes(ctx, []string{key("c")}, db)
+	expectNoErr(t, "MGetBytes", err)
+	expectEqual(t, "MGetBytes", string(bin[key("c")]), "x\ny")
+}
+
+func testCounters(t *testing.T, cmds client.Commands, key func(string) string) {
+	ctx := context.Background()
+	expectErr(t, "Add of missing key", cmds.Add(ctx, key("counter"), db), client.ErrNotFound)
+	n, err := cmds.Incr(ctx, key("counter"), db)
+	expectNoErr(t, "Incr of missing key", err)
+	expectEqual(t, "Incr of missing key", n, int64(1))
+	expectNoErr(t, "Add", cmds.Add(ctx, key("counter"), db))
+	expectNoErr(t, "AddN", cmds.AddN(ctx, key("counter"), "10", db))
+	expectNoErr(t, "AddN of negative value", cmds.AddN(ctx, key("counter"), "-3", db))
+	n, err = cmds.Incr(ctx, key("counter"), db)
+	expectNoErr(t, "Incr", err)
+	expectEqual(t, "Incr", n, int64(10))
+	val, err := cmds.Get(ctx, key("counter"), db)
+	expectNoErr(t, "Get of counter", err)
+	expectEqual(t, "Get of counter", val, "10")
+	expectErr(t, "AddN of non integer value", cmds.AddN(ctx, key("counter"), "ten", db), client.ErrNotInteger)
+	expectNoErr(t, "Set", cmds.Set(ctx, key("text"), "abc", db))
+	expectErr(t, "Add of non integer", cmds.Add(ctx, key("text"), db), client.ErrNotInteger)
+	_, err = cmds.Incr(ctx, key("text"), db)
+	expectErr(t, "Incr of non integer", err, client.ErrNotInteger)
+}
+
+func testLists(t *testing.T, cmds client.Commands, key func(string) string) {
+	ctx := context.Background()
+	has, err := cmds.Has(ctx, key("list"), db)
+	expectNoErr(t, "Has of missing list", err)
+	expectEqual(t, "Has of missing list", has, false)
+	_, err = cmds.GetL(ctx, key("list"), db)
+	expectErr(t, "GetL of missing list", err, client.ErrNotFound)
+	expectErr(t, "DelElemL of missing list", cmds.DelElemL(ctx, key("list"), "a", db), client.ErrNotFound)
+	for _, val := range []string{"a", "b", "a", "c", "a"} {
+		expectNoErr(t, "LPush", cmds.LPush(ctx, key("list"), val, db))
+	}
+	has, err = cmds.Has(ctx, key("list"), db)
+	expectNoErr(t, "Has", err)
+	expectEqual(t, "Has", has, true)
+	expectList(t, cmds, key("list"), "a", "b", "a", "c", "a")
+	expectNoErr(t, "DelElemL", cmds.DelElemL(ctx, key("list"), "a", db))
+	expectList(t, cmds, key("list"), "b", "a", "c", "a")
+	expectNoErr(t, "DelAll", cmds.DelAll(ctx, key("list"), "a", db))
+	expectList(t, cmds, key("list"), "b", "c")
+	expectNoErr(t, "LPushBytes", cmds.LPushBytes(ctx, key("list"), []byte("d\x00#"), db))
+	list, err := cmds.GetLBytes(ctx, key("list"), db)
+	expectNoErr(t, "GetLBytes", err)
+	expectEqual(t, "GetLBytes length", len(list), 3)
+	expectEqual(t, "GetLBytes", string(list[2]), "d\x00#")
+	expectNoErr(t, "DelElemLBytes", cmds.DelElemLBytes(ctx, key("list"), []byte("d\x00#"), db))
+	expectNoErr(t, "DelAllBytes", cmds.DelAllBytes(ctx, key("list"), []byte("c"), db))
+	expectList(t, cmds, key("list"), "b")
+	expectNoErr(t, "DeleteL", cmds.DeleteL(ctx, key("list"), db))
+	has, err = cmds.Has(ctx, key("list"), db)
+	expectNoErr(t, "Has of deleted list", err)
+	expectEqual(t, "Has of deleted list", has, false)
+	expectNoErr(t, "Set of string with name of list", cmds.Set(ctx, key("list"), "value", db))
+	has, err = cmds.Has(ctx, key("list"), db)
+	expectNoErr(t, "Has of string", err)
+	expectEqual(t, "Has of string", has, true)
+}
+
+func testInvalidIndex(t *testing.T, cmds client.Commands, key func(string) string) {
+	ctx := context.Background()
+	for _, ind := range []int{-1, 40} {
+		expectErr(t, "Set", cmds.Set(ctx, key("name"), "value", ind), client.ErrInvalidIndex)
+		_, err := cmds.Get(ctx, key("name"), ind)
+		expectErr(t, "Get", err, client.ErrInvalidIndex)
+		_, err = cmds.MGet(ctx, []string{key("name")}, ind)
+		expectErr(t, "MGet", err, client.ErrInvalidIndex)
+		_, err = cmds.Incr(ctx, key("name"), ind)
+		expectErr(t, "Incr", err, client.ErrInvalidIndex)
+		expectErr(t, "LPush", cmds.LPush(ctx, key("name"), "value", ind), client.ErrInvalidIndex)
+		_, err = cmds.Has(ctx, key("name"), ind)
+		expectErr(t, "Has", err, client.ErrInvalidIndex)
+	}
+}
+
+func expectList(t *testing.T, cmds client.Commands, key string, want ...string) {
+	t.Helper()
+	list, err := cmds.GetL(context.Background(), key, db)
+	expectNoErr(t, "GetL", err)
+	if len(list) != len(want) {
+		t.Fatalf("GetL: got %q, want %q", list, want)
+	}
+	for i := range want {
+		if list[i] != want[i] {
+			t.Fatalf("GetL: got %q, want %q", list, want)
+		}
+	}
+}
+
+func expectNoErr(t *testing.T, op string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", op, err)
+	}
+}
+
+func expectErr(t *testing.T, op string, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("%s: got error %v, want %v", op, err, target)
+	}
+}
+
+func expectEqual[T comparable](t *testing.T, op string, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("%s: got %v, want %v", op, got, want)
+	}
+}
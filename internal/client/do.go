@@ -0,0 +1,349 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/resp"
+)
+
+// ReplyKind is a shape of the command reply on the wire
+type ReplyKind int
+
+const (
+	// ReplyStatus is a reply with status only, failure is returned as error
+	ReplyStatus ReplyKind = iota
+	// ReplyBool is a reply with status only, failure means false, e.g. reply to HAS
+	ReplyBool
+	// ReplyValue is a reply with a single value
+	ReplyValue
+	// ReplyList is a reply with a list of values
+	ReplyList
+	// ReplyNone is used for commands without reply, e.g. HELLO
+	ReplyNone
+)
+
+var (
+	// ErrUnknownReply returned by Do for commands with unknown reply kind, see RegisterReplyKind
+	ErrUnknownReply = errors.New("reply kind of the command is unknown")
+	// ErrInvalidArgument returned by Do for arguments that can't be sent
+	ErrInvalidArgument = errors.New("unsupported argument")
+	// ErrReplyType returned by Reply helpers when reply can't be converted to requested type
+	ErrReplyType = errors.New("reply has different type")
+)
+
+var (
+	replyKindsMu sync.RWMutex
+	// replyKinds are reply kinds of commands, subcommands are stored after command name and space
+	replyKinds = map[string]ReplyKind{
+		CommandSet:                ReplyStatus,
+		CommandGet:                ReplyValue,
+		CommandHello:              ReplyNone,
+		CommandAdd:                ReplyStatus,
+		CommandAddN:               ReplyStatus,
+		CommandDelete:             ReplyStatus,
+		CommandLPush:              ReplyStatus,
+		CommandGetL:               ReplyList,
+		CommandHas:                ReplyBool,
+		CommandDeleteL:            ReplyStatus,
+		CommandDelElemL:           ReplyStatus,
+		CommandDelAll:             ReplyStatus,
+		CommandPing:               ReplyStatus,
+		CommandMSet:               ReplyStatus,
+		CommandMGet:               ReplyList,
+		CommandUnlink:             ReplyValue,
+		CommandMigrate:            ReplyValue,
+		CommandAsking:             ReplyStatus,
+		CommandGeoAdd:             ReplyStatus,
+		CommandGeoDist:            ReplyValue,
+		CommandGeoPos:             ReplyList,
+		CommandGeoSearch:          ReplyList,
+		CommandJSONSet:            ReplyStatus,
+		CommandJSONGet:            ReplyValue,
+		CommandJSONDel:            ReplyValue,
+		CommandJSONNumIncrBy:      ReplyValue,
+		CommandJSONArrAppend:      ReplyList,
+		CommandJSONType:           ReplyList,
+		CommandPublish:            ReplyValue,
+		CommandReplicaOf:          ReplyStatus,
+		CommandRole:               ReplyList,
+		CommandInfo:               ReplyValue,
+		CommandSentinel:           ReplyList,
+		CommandDelIfEq:            ReplyValue,
+		CommandPExpire:            ReplyValue,
+		CommandPExpireAt:          ReplyValue,
+		CommandPTTL:               ReplyValue,
+		CommandIncr:               ReplyValue,
+		CommandRateLimit:          ReplyList,
+		"CLUSTER PING":            ReplyValue,
+		"CLUSTER MEET":            ReplyStatus,
+		"CLUSTER ADDSLOTS":        ReplyStatus,
+		"CLUSTER ADDSLOTSRANGE":   ReplyStatus,
+		"CLUSTER NODES":           ReplyValue,
+		"CLUSTER SLOTS":           ReplyList,
+		"CLUSTER INFO":            ReplyValue,
+		"CLUSTER MYID":            ReplyValue,
+		"CLUSTER KEYSLOT":         ReplyValue,
+		"CLUSTER COUNTKEYSINSLOT": ReplyValue,
+		"CLUSTER GETKEYSINSLOT":   ReplyList,
+		"CLUSTER SETSLOT":         ReplyStatus,
+		"CLIENT ID":               ReplyValue,
+		"CLIENT TRACKING":         ReplyValue,
+		CommandCommand:            ReplyList,
+		"COMMAND COUNT":           ReplyValue,
+		CommandAuth:               ReplyStatus,
+		"ACL SETUSER":             ReplyStatus,
+		"ACL GETUSER":             ReplyList,
+		"ACL LIST":                ReplyList,
+		"ACL DELUSER":             ReplyValue,
+		"ACL WHOAMI":              ReplyValue,
+	}
+)
+
+// RegisterReplyKind sets reply kind of the command so it can be sent with Do before client has a method for it,
+// cmd is a command name or command name and subcommand separated by space, e.g. "CLUSTER NODES"
+func RegisterReplyKind(cmd string, kind ReplyKind) {
+	replyKindsMu.Lock()
+	defer replyKindsMu.Unlock()
+	replyKinds[strings.ToUpper(cmd)] = kind
+}
+
+// replyKind returns reply kind of the command with upper case name, subcommand kind is preferred over command kind
+func replyKind(args [][]byte) (ReplyKind, bool) {
+	replyKindsMu.RLock()
+	defer replyKindsMu.RUnlock()
+	name := string(args[0])
+	if len(args) > 1 {
+		if kind, ok := replyKinds[name+" "+strings.ToUpper(string(args[1]))]; ok {
+			return kind, true
+		}
+	}
+	kind, ok := replyKinds[name]
+	return kind, ok
+}
+
+// Reply is a reply to command sent with Do
+type Reply struct {
+	Kind ReplyKind
+	ok   bool
+	val  []byte
+	list [][]byte
+}
+
+// Bool returns status of status reply or value of single value reply parsed with strconv.ParseBool
+func (r *Reply) Bool() (bool, error) {
+	switch r.Kind {
+	case ReplyStatus, ReplyBool, ReplyNone:
+		return r.ok, nil
+	case ReplyValue:
+		return strconv.ParseBool(string(r.val))
+	}
+	return false, ErrReplyType
+}
+
+// Bytes returns value of single value reply
+func (r *Reply) Bytes() ([]byte, error) {
+	if r.Kind != ReplyValue {
+		return nil, ErrReplyType
+	}
+	return r.val, nil
+}
+
+// String returns value of single value reply, "OK" is returned for successful status reply
+func (r *Reply) String() (string, error) {
+	switch r.Kind {
+	case ReplyValue:
+		return string(r.val), nil
+	case ReplyStatus, ReplyNone:
+		return "OK", nil
+	}
+	return "", ErrReplyType
+}
+
+// Int64 returns value of single value reply as integer
+func (r *Reply) Int64() (int64, error) {
+	if r.Kind != ReplyValue {
+		return 0, ErrReplyType
+	}
+	return strconv.ParseInt(string(r.val), 10, 64)
+}
+
+// Float64 returns value of single value reply as float
+func (r *Reply) Float64() (float64, error) {
+	if r.Kind != ReplyValue {
+		return 0, ErrReplyType
+	}
+	return strconv.ParseFloat(string(r.val), 64)
+}
+
+// Slice returns values of list reply
+func (r *Reply) Slice() ([]string, error) {
+	if r.Kind != ReplyList {
+		return nil, ErrReplyType
+	}
+	res := make([]string, 0, len(r.list))
+	for _, val := range r.list {
+		res = append(res, string(val))
+	}
+	return res, nil
+}
+
+// Map returns field-value pairs of list reply, e.g. reply to MGET, or "field:value" lines of single value
+// reply, e.g. reply to INFO, empty lines and lines starting with # are skipped
+func (r *Reply) Map() (map[string]string, error) {
+	switch r.Kind {
+	case ReplyList:
+		if len(r.list)%2 != 0 {
+			return nil, ErrReplyType
+		}
+		res := make(map[string]string, len(r.list)/2)
+		for i := 0; i < len(r.list); i += 2 {
+			res[string(r.list[i])] = string(r.list[i+1])
+		}
+		return res, nil
+	case ReplyValue:
+		res := make(map[string]string)
+		for _, line := range strings.Split(string(r.val), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			field, val, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, ErrReplyType
+			}
+			res[field] = val
+		}
+		return res, nil
+	}
+	return nil, ErrReplyType
+}
+
+// Do sends command with arguments as is and returns its reply, so commands without client method can be used,
+// e.g. Do(ctx, "SET", "key", "value", 0). Command name is sent in upper case and commands working with data
+// take database index as the last argument. Arguments may be strings, byte slices, numbers, bools and
+// fmt.Stringer values. Reply kind is taken from commands known to the client and RegisterReplyKind,
+// ErrUnknownReply is returned for other commands. Errors reported by server are returned as error, e.g. ServerError
+func (c *Client) Do(ctx context.Context, args ...any) (*Reply, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: command is missing", ErrInvalidArgument)
+	}
+	vals, err := formatArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	cmd := strings.ToUpper(string(vals[0]))
+	vals[0] = []byte(cmd)
+	kind, ok := replyKind(vals)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownReply, cmd)
+	}
+	req, err := encodeArgs(vals)
+	if err != nil {
+		return nil, err
+	}
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.send(ctx, cmd, req, idempotent(vals)); err != nil {
+		return nil, err
+	}
+	if kind == ReplyNone {
+		return &Reply{Kind: kind, ok: true}, nil
+	}
+	reply, err := await(ctx, c, func(r io.Reader) (*Reply, error) {
+		return readReply(r, kind)
+	})
+	if err != nil {
+		return nil, failure(err)
+	}
+	return reply, nil
+}
+
+// readReply reads reply of given kind
+func readReply(r io.Reader, kind ReplyKind) (*Reply, error) {
+	reply := &Reply{Kind: kind, ok: true}
+	var err error
+	switch kind {
+	case ReplyValue:
+		reply.val, err = readValue(r)
+	case ReplyList:
+		reply.list, err = readList(r)
+	default:
+		err = readStatus(r)
+		if kind == ReplyBool && err == ErrOperationFailed {
+			reply.ok, err = false, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// formatArgs converts arguments of Do to bytes
+func formatArgs(args []any) ([][]byte, error) {
+	res := make([][]byte, 0, len(args))
+	for _, arg := range args {
+		var val []byte
+		switch v := arg.(type) {
+		case string:
+			val = []byte(v)
+		case []byte:
+			val = v
+		case int:
+			val = strconv.AppendInt(nil, int64(v), 10)
+		case int8:
+			val = strconv.AppendInt(nil, int64(v), 10)
+		case int16:
+			val = strconv.AppendInt(nil, int64(v), 10)
+		case int32:
+			val = strconv.AppendInt(nil, int64(v), 10)
+		case int64:
+			val = strconv.AppendInt(nil, v, 10)
+		case uint:
+			val = strconv.AppendUint(nil, uint64(v), 10)
+		case uint8:
+			val = strconv.AppendUint(nil, uint64(v), 10)
+		case uint16:
+			val = strconv.AppendUint(nil, uint64(v), 10)
+		case uint32:
+			val = strconv.AppendUint(nil, uint64(v), 10)
+		case uint64:
+			val = strconv.AppendUint(nil, v, 10)
+		case float32:
+			val = strconv.AppendFloat(nil, float64(v), 'f', -1, 32)
+		case float64:
+			val = strconv.AppendFloat(nil, v, 'f', -1, 64)
+		case bool:
+			val = []byte("0")
+			if v {
+				val = []byte("1")
+			}
+		case fmt.Stringer:
+			val = []byte(v.String())
+		default:
+			return nil, fmt.Errorf("%w: %T", ErrInvalidArgument, arg)
+		}
+		res = append(res, val)
+	}
+	return res, nil
+}
+
+// encodeArgs encodes command and its arguments as array of bulk strings
+func encodeArgs(args [][]byte) ([]byte, error) {
+	vals := make([]resp.Value, 0, len(args))
+	for _, arg := range args {
+		vals = append(vals, resp.BytesValue(arg))
+	}
+	buf := &bytes.Buffer{}
+	if err := resp.NewWriter(buf).WriteArray(vals); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ArtemNovok/simpleRedisCl/embedded"
+	"github.com/ArtemNovok/simpleRedisCl/internal/errcode"
+)
+
+// errors reported by server, errors.Is(err, ErrOperationFailed) is true for all of them
+var (
+	// ErrNotFound returned when key, list, member or json path doesn't exist, e.g. by Get for missing key
+	ErrNotFound = errcode.ErrNotFound
+	// ErrWrongType returned when operation is used against value of wrong type
+	ErrWrongType = errcode.ErrWrongType
+	// ErrNotInteger returned when value or argument can't be used as integer, e.g. by Add
+	ErrNotInteger = errcode.ErrNotInteger
+	// ErrSyntax returned when server can't parse command or its arguments
+	ErrSyntax = errcode.ErrSyntax
+	// ErrReadOnly returned when write command is sent to a replica
+	ErrReadOnly = errcode.ErrReadOnly
+	// ErrNoPerm returned when user isn't permitted to run the command or to access its keys
+	ErrNoPerm = errcode.ErrNoPerm
+)
+
+// ServerError is an error reported by server, Code is the first word of the error message.
+// errors.Is(err, ErrOperationFailed) is true for it and so is errors.Is with sentinel error of its code,
+// e.g. errors.Is(err, ErrNotFound) for NOTFOUND
+type ServerError = embedded.ServerError
+
+// parseServerError decodes error message sent by server, MOVED and ASK errors become RedirectError,
+// other errors become ServerError
+func parseServerError(msg string) error {
+	fields := strings.Fields(msg)
+	if len(fields) == 3 && (fields[0] == "MOVED" || fields[0] == "ASK") {
+		if slot, err := strconv.Atoi(fields[1]); err == nil {
+			return &RedirectError{Ask: fields[0] == "ASK", Slot: slot, Addr: fields[2]}
+		}
+	}
+	code, message, _ := strings.Cut(msg, " ")
+	return &ServerError{Code: code, Message: message}
+}
@@ -0,0 +1,271 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/embedded"
+)
+
+var (
+	CommandDelIfEq   = "DELIFEQ"
+	CommandPExpire   = "PEXPIRE"
+	CommandPExpireAt = "PEXPIREAT"
+	CommandPTTL      = "PTTL"
+	CommandIncr      = "INCR"
+)
+
+// NoExpiration is returned by PTTL for keys without TTL
+const NoExpiration = embedded.NoExpiration
+
+// SetArgs are conditions and expiration of SetArgs, TTL is removed from the key if neither TTL nor ExpireAt is set
+type SetArgs = embedded.SetArgs
+
+// conditional reports whether result of SET depends on the current value, such requests are not retried
+func conditional(a SetArgs) bool {
+	return a.NX || a.XX || a.IfEq != nil
+}
+
+// encodeSetArgs encodes arguments of SET with conditions and expiration of a
+func encodeSetArgs(key string, value []byte, a SetArgs) [][]byte {
+	args := [][]byte{[]byte(key), value}
+	if a.NX {
+		args = append(args, []byte("NX"))
+	}
+	if a.XX {
+		args = append(args, []byte("XX"))
+	}
+	if a.IfEq != nil {
+		args = append(args, []byte("IFEQ"), a.IfEq)
+	}
+	switch {
+	case a.TTL > 0:
+		args = append(args, []byte("PX"), strconv.AppendInt(nil, max(a.TTL.Milliseconds(), 1), 10))
+	case !a.ExpireAt.IsZero():
+		args = append(args, []byte("PXAT"), strconv.AppendInt(nil, a.ExpireAt.UnixMilli(), 10))
+	}
+	return args
+}
+
+// SetArgs sets key with value in database ind if conditions of args are met, returns false if key isn't set
+func (c *Client) SetArgs(ctx context.Context, key string, value []byte, args SetArgs, ind int) (bool, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return false, ErrInvalidIndex
+	}
+	req, err := encodeRequestBytes(CommandSet, ind, encodeSetArgs(key, value, args)...)
+	if err != nil {
+		return false, err
+	}
+	if err := c.send(ctx, CommandSet, req, !conditional(args)); err != nil {
+		return false, err
+	}
+	err = c.waitForResponse(ctx)
+	switch {
+	case err == nil:
+		return true, nil
+	case err == ErrOperationFailed:
+		return false, nil
+	}
+	return false, err
+}
+
+// SetNX sets key with value and ttl in database ind if key doesn't exist, returns false if key exists,
+// key doesn't expire if ttl is zero
+func (c *Client) SetNX(ctx context.Context, key string, value string, ttl time.Duration, ind int) (bool, error) {
+	return c.SetArgs(ctx, key, []byte(value), SetArgs{NX: true, TTL: ttl}, ind)
+}
+
+// DelIfEq deletes key from database ind if its value is equal to value, returns false if key isn't deleted
+func (c *Client) DelIfEq(ctx context.Context, key string, value []byte, ind int) (bool, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return false, ErrInvalidIndex
+	}
+	if err := c.writeRequestBytes(ctx, CommandDelIfEq, ind, []byte(key), value); err != nil {
+		return false, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return false, failure(err)
+	}
+	return res == "1", nil
+}
+
+// PExpire sets time to live of the key in database ind with millisecond precision,
+// returns false if key doesn't exist, only keys set with Set and SetArgs may expire
+func (c *Client) PExpire(ctx context.Context, key string, ttl time.Duration, ind int) (bool, error) {
+	return c.expire(ctx, CommandPExpire, key, max(ttl.Milliseconds(), 1), ind)
+}
+
+// PExpireAt sets expiration time of the key in database ind, returns false if key doesn't exist
+func (c *Client) PExpireAt(ctx context.Context, key string, at time.Time, ind int) (bool, error) {
+	return c.expire(ctx, CommandPExpireAt, key, at.UnixMilli(), ind)
+}
+
+func (c *Client) expire(ctx context.Context, cmd string, key string, ms int64, ind int) (bool, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return false, ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, cmd, ind, key, strconv.FormatInt(ms, 10)); err != nil {
+		return false, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return false, failure(err)
+	}
+	return res == "1", nil
+}
+
+// PTTL returns time to live of the key in database ind, NoExpiration is returned for key without TTL
+// and ErrNotFound if key doesn't exist
+func (c *Client) PTTL(ctx context.Context, key string, ind int) (time.Duration, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return 0, ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandPTTL, ind, key); err != nil {
+		return 0, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return 0, failure(err)
+	}
+	ms, err := strconv.ParseInt(res, 10, 64)
+	if err != nil {
+		return 0, failure(err)
+	}
+	switch ms {
+	case -2:
+		return 0, ErrNotFound
+	case -1:
+		return NoExpiration, nil
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// Incr increments integer value of the key in database ind and returns the new value,
+// key that doesn't exist is set to 1, TTL of the key is kept
+func (c *Client) Incr(ctx context.Context, key string, ind int) (int64, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return 0, ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandIncr, ind, key); err != nil {
+		return 0, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return 0, failure(err)
+	}
+	n, err := strconv.ParseInt(res, 10, 64)
+	if err != nil {
+		return 0, failure(err)
+	}
+	return n, nil
+}
+
+// SetArgs sets key with value in database ind if conditions of args are met, returns false if key isn't set
+func (p *Pool) SetArgs(ctx context.Context, key string, value []byte, args SetArgs, ind int) (bool, error) {
+	return poolDo(ctx, p, func(cl *Client) (bool, error) {
+		return cl.SetArgs(ctx, key, value, args, ind)
+	})
+}
+
+// SetNX sets key with value and ttl in database ind if key doesn't exist, returns false if key exists
+func (p *Pool) SetNX(ctx context.Context, key string, value string, ttl time.Duration, ind int) (bool, error) {
+	return poolDo(ctx, p, func(cl *Client) (bool, error) {
+		return cl.SetNX(ctx, key, value, ttl, ind)
+	})
+}
+
+// DelIfEq deletes key from database ind if its value is equal to value, returns false if key isn't deleted
+func (p *Pool) DelIfEq(ctx context.Context, key string, value []byte, ind int) (bool, error) {
+	return poolDo(ctx, p, func(cl *Client) (bool, error) {
+		return cl.DelIfEq(ctx, key, value, ind)
+	})
+}
+
+// PExpire sets time to live of the key in database ind, returns false if key doesn't exist
+func (p *Pool) PExpire(ctx context.Context, key string, ttl time.Duration, ind int) (bool, error) {
+	return poolDo(ctx, p, func(cl *Client) (bool, error) {
+		return cl.PExpire(ctx, key, ttl, ind)
+	})
+}
+
+// PExpireAt sets expiration time of the key in database ind, returns false if key doesn't exist
+func (p *Pool) PExpireAt(ctx context.Context, key string, at time.Time, ind int) (bool, error) {
+	return poolDo(ctx, p, func(cl *Client) (bool, error) {
+		return cl.PExpireAt(ctx, key, at, ind)
+	})
+}
+
+// PTTL returns time to live of the key in database ind, see Client.PTTL
+func (p *Pool) PTTL(ctx context.Context, key string, ind int) (time.Duration, error) {
+	return poolDo(ctx, p, func(cl *Client) (time.Duration, error) {
+		return cl.PTTL(ctx, key, ind)
+	})
+}
+
+// Incr increments integer value of the key in database ind and returns the new value
+func (p *Pool) Incr(ctx context.Context, key string, ind int) (int64, error) {
+	return poolDo(ctx, p, func(cl *Client) (int64, error) {
+		return cl.Incr(ctx, key, ind)
+	})
+}
+
+// SetArgs sets key with value in database ind if conditions of args are met, returns false if key isn't set
+func (c *ClusterClient) SetArgs(ctx context.Context, key string, value []byte, args SetArgs, ind int) (bool, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (bool, error) {
+		return cl.SetArgs(ctx, key, value, args, ind)
+	})
+}
+
+// SetNX sets key with value and ttl in database ind if key doesn't exist, returns false if key exists
+func (c *ClusterClient) SetNX(ctx context.Context, key string, value string, ttl time.Duration, ind int) (bool, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (bool, error) {
+		return cl.SetNX(ctx, key, value, ttl, ind)
+	})
+}
+
+// DelIfEq deletes key from database ind if its value is equal to value, returns false if key isn't deleted
+func (c *ClusterClient) DelIfEq(ctx context.Context, key string, value []byte, ind int) (bool, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (bool, error) {
+		return cl.DelIfEq(ctx, key, value, ind)
+	})
+}
+
+// PExpire sets time to live of the key in database ind, returns false if key doesn't exist
+func (c *ClusterClient) PExpire(ctx context.Context, key string, ttl time.Duration, ind int) (bool, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (bool, error) {
+		return cl.PExpire(ctx, key, ttl, ind)
+	})
+}
+
+// PExpireAt sets expiration time of the key in database ind, returns false if key doesn't exist
+func (c *ClusterClient) PExpireAt(ctx context.Context, key string, at time.Time, ind int) (bool, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (bool, error) {
+		return cl.PExpireAt(ctx, key, at, ind)
+	})
+}
+
+// PTTL returns time to live of the key in database ind, see Client.PTTL
+func (c *ClusterClient) PTTL(ctx context.Context, key string, ind int) (time.Duration, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (time.Duration, error) {
+		return cl.PTTL(ctx, key, ind)
+	})
+}
+
+// Incr increments integer value of the key in database ind and returns the new value
+func (c *ClusterClient) Incr(ctx context.Context, key string, ind int) (int64, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (int64, error) {
+		return cl.Incr(ctx, key, ind)
+	})
+}
@@ -0,0 +1,309 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	CommandGeoAdd    = "GEOADD"
+	CommandGeoDist   = "GEODIST"
+	CommandGeoPos    = "GEOPOS"
+	CommandGeoSearch = "GEOSEARCH"
+	// ErrInvalidGeoQuery returned when geo search query is incomplete or contains unknown unit
+	ErrInvalidGeoQuery = errors.New("invalid geo query")
+)
+
+// Distance units supported by geo commands
+const (
+	Meters     = "m"
+	Kilometers = "km"
+	Miles      = "mi"
+	Feet       = "ft"
+)
+
+// GeoLocation is a named point, Dist and GeoHash are set only by GeoSearch if they were requested
+type GeoLocation struct {
+	Name      string
+	Longitude float64
+	Latitude  float64
+	Dist      float64
+	GeoHash   int64
+}
+
+// GeoPos is a position of the member
+type GeoPos struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// GeoSearchQuery describes geo search, center is Member if it isn't empty,
+// otherwise Longitude and Latitude, area is a circle if Radius isn't zero, otherwise a box
+type GeoSearchQuery struct {
+	Member    string
+	Longitude float64
+	Latitude  float64
+	Radius    float64
+	BoxWidth  float64
+	BoxHeight float64
+	// Unit of Radius, BoxWidth, BoxHeight and returned distances, meters by default
+	Unit string
+	// Sort is "ASC", "DESC" or empty for unsorted result
+	Sort string
+	// Count limits number of results, when Any is set first Count found members are returned
+	Count     int
+	Any       bool
+	WithCoord bool
+	WithDist  bool
+	WithHash  bool
+}
+
+func validUnit(unit string) bool {
+	switch unit {
+	case Meters, Kilometers, Miles, Feet:
+		return true
+	}
+	return false
+}
+
+// args returns GEOSEARCH arguments that follow the key
+func (q *GeoSearchQuery) args() ([]string, error) {
+	unit := q.Unit
+	if len(unit) == 0 {
+		unit = Meters
+	}
+	if !validUnit(unit) {
+		return nil, ErrInvalidGeoQuery
+	}
+	var args []string
+	if len(q.Member) != 0 {
+		args = append(args, "FROMMEMBER", q.Member)
+	} else {
+		args = append(args, "FROMLONLAT", formatFloat(q.Longitude), formatFloat(q.Latitude))
+	}
+	switch {
+	case q.Radius > 0:
+		args = append(args, "BYRADIUS", formatFloat(q.Radius), unit)
+	case q.BoxWidth > 0 && q.BoxHeight > 0:
+		args = append(args, "BYBOX", formatFloat(q.BoxWidth), formatFloat(q.BoxHeight), unit)
+	default:
+		return nil, ErrInvalidGeoQuery
+	}
+	switch strings.ToUpper(q.Sort) {
+	case "":
+	case "ASC", "DESC":
+		args = append(args, strings.ToUpper(q.Sort))
+	default:
+		return nil, ErrInvalidGeoQuery
+	}
+	if q.Count > 0 {
+		args = append(args, "COUNT", strconv.Itoa(q.Count))
+		if q.Any {
+			args = append(args, "ANY")
+		}
+	}
+	if q.WithCoord {
+		args = append(args, "WITHCOORD")
+	}
+	if q.WithDist {
+		args = append(args, "WITHDIST")
+	}
+	if q.WithHash {
+		args = append(args, "WITHHASH")
+	}
+	return args, nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// readList reads list response
+func readList(r io.Reader) ([][]byte, error) {
+	if err := readStatus(r); err != nil {
+		return nil, err
+	}
+	var lenSL int64
+	if err := binary.Read(r, binary.BigEndian, &lenSL); err != nil {
+		return nil, err
+	}
+	// every element is prefixed with 8 bytes of its length
+	if lenSL < 0 || lenSL > maxValueSize/8 {
+		return nil, fmt.Errorf("%w: list of %d elements", errValueSize, lenSL)
+	}
+	list := make([][]byte, 0, min(lenSL, maxListPrealloc))
+	for i := 0; i < int(lenSL); i++ {
+		buf, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, buf)
+	}
+	return list, nil
+}
+
+// waitForList reads list response in goroutine and waits for it or for context cancellation
+func (c *Client) waitForList(ctx context.Context) ([][]byte, error) {
+	list, err := await(ctx, c, readList)
+	if err != nil {
+		return nil, failure(err)
+	}
+	return list, nil
+}
+
+// waitForResult reads single value response in goroutine and waits for it or for context cancellation
+func (c *Client) waitForResult(ctx context.Context) (string, error) {
+	return await(ctx, c, readResult)
+}
+
+// waitForValue reads single binary value response in goroutine and waits for it or for context cancellation
+func (c *Client) waitForValue(ctx context.Context) ([]byte, error) {
+	val, err := await(ctx, c, readValue)
+	if err != nil {
+		return nil, failure(err)
+	}
+	return val, nil
+}
+
+// GeoAdd adds locations to geo set key in database ind, existing members are moved to the new location
+func (c *Client) GeoAdd(ctx context.Context, key string, locations []GeoLocation, ind int) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return ErrInvalidIndex
+	}
+	args := []string{key}
+	for _, l := range locations {
+		args = append(args, formatFloat(l.Longitude), formatFloat(l.Latitude), l.Name)
+	}
+	if err := c.writeRequest(ctx, CommandGeoAdd, ind, args...); err != nil {
+		return err
+	}
+	return c.waitForResponse(ctx)
+}
+
+// GeoDist returns distance between two members of geo set key in given unit (meters if unit is empty)
+func (c *Client) GeoDist(ctx context.Context, key, member1, member2, unit string, ind int) (float64, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return 0, ErrInvalidIndex
+	}
+	if len(unit) == 0 {
+		unit = Meters
+	}
+	if !validUnit(unit) {
+		return 0, ErrInvalidGeoQuery
+	}
+	if err := c.writeRequest(ctx, CommandGeoDist, ind, key, member1, member2, unit); err != nil {
+		return 0, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(res, 64)
+}
+
+// GeoPos returns positions of the members of geo set key, position is nil for unknown member
+func (c *Client) GeoPos(ctx context.Context, key string, members []string, ind int) ([]*GeoPos, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandGeoPos, ind, append([]string{key}, members...)...); err != nil {
+		return nil, err
+	}
+	list, err := c.waitForList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) != 2*len(members) {
+		return nil, ErrOperationFailed
+	}
+	res := make([]*GeoPos, len(members))
+	for i := range members {
+		lon, lat := list[2*i], list[2*i+1]
+		if len(lon) == 0 {
+			continue
+		}
+		pos := &GeoPos{}
+		if pos.Longitude, err = strconv.ParseFloat(string(lon), 64); err != nil {
+			return nil, ErrOperationFailed
+		}
+		if pos.Latitude, err = strconv.ParseFloat(string(lat), 64); err != nil {
+			return nil, ErrOperationFailed
+		}
+		res[i] = pos
+	}
+	return res, nil
+}
+
+// GeoSearch returns members of geo set key located within the area described by query, result is empty if key doesn't exist
+func (c *Client) GeoSearch(ctx context.Context, key string, q *GeoSearchQuery, ind int) ([]GeoLocation, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	args, err := q.args()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.writeRequest(ctx, CommandGeoSearch, ind, append([]string{key}, args...)...); err != nil {
+		return nil, err
+	}
+	list, err := c.waitForList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseGeoLocations(list, q)
+}
+
+// parseGeoLocations parses GEOSEARCH response, every member is followed by fields requested in query
+func parseGeoLocations(list [][]byte, q *GeoSearchQuery) ([]GeoLocation, error) {
+	var res []GeoLocation
+	for i := 0; i < len(list); {
+		loc := GeoLocation{Name: string(list[i])}
+		i++
+		var err error
+		if q.WithDist {
+			if i >= len(list) {
+				return nil, ErrOperationFailed
+			}
+			if loc.Dist, err = strconv.ParseFloat(string(list[i]), 64); err != nil {
+				return nil, ErrOperationFailed
+			}
+			i++
+		}
+		if q.WithHash {
+			if i >= len(list) {
+				return nil, ErrOperationFailed
+			}
+			if loc.GeoHash, err = strconv.ParseInt(string(list[i]), 10, 64); err != nil {
+				return nil, ErrOperationFailed
+			}
+			i++
+		}
+		if q.WithCoord {
+			if i+1 >= len(list) {
+				return nil, ErrOperationFailed
+			}
+			if loc.Longitude, err = strconv.ParseFloat(string(list[i]), 64); err != nil {
+				return nil, ErrOperationFailed
+			}
+			if loc.Latitude, err = strconv.ParseFloat(string(list[i+1]), 64); err != nil {
+				return nil, ErrOperationFailed
+			}
+			i += 2
+		}
+		res = append(res, loc)
+	}
+	return res, nil
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+var (
+	CommandCommand = "COMMAND"
+)
+
+// commandSpecFields and commandDocFields are numbers of values that describe a command
+// in replies to COMMAND INFO and COMMAND DOCS
+const (
+	commandSpecFields = 6
+	commandDocFields  = 3
+)
+
+// CommandSpec describes a command supported by the server
+type CommandSpec struct {
+	Name string
+	// Arity is a number of arguments including the name, negative arity is a minimal number of arguments
+	Arity int
+	// Flags are "write", "readonly", "admin", "blocking", "pubsub", "connection" and "noreply"
+	Flags []string
+	// FirstKey, LastKey and KeyStep are positions of keys in arguments, negative LastKey is counted
+	// from the end, e.g. -2 is the argument before index. FirstKey is 0 for commands without keys
+	FirstKey, LastKey, KeyStep int
+}
+
+// CommandDoc is a documentation of a command supported by the server
+type CommandDoc struct {
+	Name, Summary, Syntax string
+}
+
+// Command returns specs of all commands supported by the server sorted by name
+func (c *Client) Command(ctx context.Context) ([]CommandSpec, error) {
+	list, err := c.commandList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommandSpecs(list)
+}
+
+// CommandCount returns number of commands supported by the server
+func (c *Client) CommandCount(ctx context.Context) (int, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandCommand, "COUNT"); err != nil {
+		return 0, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(res)
+	if err != nil {
+		return 0, ErrOperationFailed
+	}
+	return n, nil
+}
+
+// CommandInfo returns specs of commands with names, specs of all commands are returned if no name is given
+func (c *Client) CommandInfo(ctx context.Context, names ...string) ([]CommandSpec, error) {
+	list, err := c.commandList(ctx, append([]string{"INFO"}, names...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommandSpecs(list)
+}
+
+// CommandDocs returns documentation of commands with names, documentation of all commands
+// is returned if no name is given
+func (c *Client) CommandDocs(ctx context.Context, names ...string) ([]CommandDoc, error) {
+	list, err := c.commandList(ctx, append([]string{"DOCS"}, names...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(list)%commandDocFields != 0 {
+		return nil, ErrOperationFailed
+	}
+	res := make([]CommandDoc, 0, len(list)/commandDocFields)
+	for i := 0; i < len(list); i += commandDocFields {
+		res = append(res, CommandDoc{
+			Name:    string(list[i]),
+			Summary: string(list[i+1]),
+			Syntax:  string(list[i+2]),
+		})
+	}
+	return res, nil
+}
+
+// CommandGetKeys returns keys of the command args, e.g. CommandGetKeys(ctx, "MSET", "a", "1", "b", "2", "0")
+// returns a and b
+func (c *Client) CommandGetKeys(ctx context.Context, args ...string) ([]string, error) {
+	list, err := c.commandList(ctx, append([]string{"GETKEYS"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(list))
+	for _, key := range list {
+		res = append(res, string(key))
+	}
+	return res, nil
+}
+
+func (c *Client) commandList(ctx context.Context, args ...string) ([][]byte, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandCommand, args...); err != nil {
+		return nil, err
+	}
+	return c.waitForList(ctx)
+}
+
+func parseCommandSpecs(list [][]byte) ([]CommandSpec, error) {
+	if len(list)%commandSpecFields != 0 {
+		return nil, ErrOperationFailed
+	}
+	res := make([]CommandSpec, 0, len(list)/commandSpecFields)
+	for i := 0; i < len(list); i += commandSpecFields {
+		// arity, first key, last key and key step
+		nums := make([]int, 0, 4)
+		for _, j := range []int{1, 3, 4, 5} {
+			n, err := strconv.Atoi(string(list[i+j]))
+			if err != nil {
+				return nil, ErrOperationFailed
+			}
+			nums = append(nums, n)
+		}
+		res = append(res, CommandSpec{
+			Name:     string(list[i]),
+			Arity:    nums[0],
+			Flags:    strings.Fields(string(list[i+2])),
+			FirstKey: nums[1],
+			LastKey:  nums[2],
+			KeyStep:  nums[3],
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"strconv"
+)
+
+var (
+	CommandJSONSet       = "JSON.SET"
+	CommandJSONGet       = "JSON.GET"
+	CommandJSONDel       = "JSON.DEL"
+	CommandJSONNumIncrBy = "JSON.NUMINCRBY"
+	CommandJSONArrAppend = "JSON.ARRAPPEND"
+	CommandJSONType      = "JSON.TYPE"
+)
+
+// JSONSet sets JSON value at the path of document key in database ind, new document
+// can be created only with the root path ("$"), new object keys only as the last segment of the path
+func (c *Client) JSONSet(ctx context.Context, key string, path string, value string, ind int) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandJSONSet, ind, key, path, value); err != nil {
+		return err
+	}
+	return c.waitForResponse(ctx)
+}
+
+// JSONGet returns JSON values matched by the paths of document key in database ind,
+// "$" paths return array of matches, legacy paths (".a.b") return single value,
+// several paths return object that maps every path to its result, whole document is returned without paths
+func (c *Client) JSONGet(ctx context.Context, key string, ind int, paths ...string) (string, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return "", ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandJSONGet, ind, append([]string{key}, paths...)...); err != nil {
+		return "", err
+	}
+	return c.waitForResult(ctx)
+}
+
+// JSONDel deletes JSON values matched by the path of document key in database ind
+// and returns number of deleted values, root path deletes the whole document
+func (c *Client) JSONDel(ctx context.Context, key string, path string, ind int) (int64, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return 0, ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandJSONDel, ind, key, path); err != nil {
+		return 0, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(res, 10, 64)
+}
+
+// JSONNumIncrBy increments numbers matched by the path of document key in database ind by value
+// and returns new values as JSON, for "$" paths it's an array with null for values that are not numbers
+func (c *Client) JSONNumIncrBy(ctx context.Context, key string, path string, value float64, ind int) (string, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return "", ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandJSONNumIncrBy, ind, key, path, formatFloat(value)); err != nil {
+		return "", err
+	}
+	return c.waitForResult(ctx)
+}
+
+// JSONArrAppend appends JSON values to arrays matched by the path of document key in database ind
+// and returns their new lengths, -1 is returned for matched values that are not arrays
+func (c *Client) JSONArrAppend(ctx context.Context, key string, path string, values []string, ind int) ([]int64, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandJSONArrAppend, ind, append([]string{key, path}, values...)...); err != nil {
+		return nil, err
+	}
+	list, err := c.waitForList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int64, 0, len(list))
+	for _, val := range list {
+		n, err := strconv.ParseInt(string(val), 10, 64)
+		if err != nil {
+			return nil, ErrOperationFailed
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+// JSONType returns types of JSON values matched by the path of document key in database ind:
+// "object", "array", "string", "integer", "number", "boolean" or "null"
+func (c *Client) JSONType(ctx context.Context, key string, path string, ind int) ([]string, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	if err := c.writeRequest(ctx, CommandJSONType, ind, key, path); err != nil {
+		return nil, err
+	}
+	list, err := c.waitForList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(list))
+	for _, val := range list {
+		res = append(res, string(val))
+	}
+	return res, nil
+}
@@ -0,0 +1,214 @@
+// Package lock provides a distributed mutex built on the client. Lock is a key with a random owner token
+// and TTL, it's renewed while held and released only by its owner, every acquisition gets a fencing token
+// from a monotonic counter, so storage guarded by the lock can reject writes of owners that lost it
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+)
+
+const (
+	defaultTTL = 10 * time.Second
+)
+
+var (
+	// ErrNotHeld returned by Unlock if mutex isn't locked
+	ErrNotHeld = errors.New("lock is not held")
+	// ErrHeld returned by TryLock and Lock if mutex is already locked
+	ErrHeld = errors.New("lock is already held")
+	// ErrLockLost returned by Unlock if lock expired or was taken by another owner while it was held
+	ErrLockLost = errors.New("lock is lost")
+	// ErrNotAcquired returned by Lock if lock is held by another owner after all retries
+	ErrNotAcquired = errors.New("lock is not acquired")
+)
+
+// Commands are commands the mutex is built on, they are implemented by Client, Pool and ClusterClient
+type Commands interface {
+	SetArgs(ctx context.Context, key string, value []byte, args client.SetArgs, ind int) (bool, error)
+	DelIfEq(ctx context.Context, key string, value []byte, ind int) (bool, error)
+	Incr(ctx context.Context, key string, ind int) (int64, error)
+}
+
+// Options describes the mutex
+type Options struct {
+	// TTL is a time lock is kept after its owner stops renewing it, 10s by default
+	TTL time.Duration
+	// RenewInterval is an interval of lease renewal while lock is held, TTL/3 by default
+	RenewInterval time.Duration
+	// Retry describes delays between attempts of Lock, the same as retries of the client. Lock is attempted
+	// until ctx is done if MaxRetries is 0
+	Retry client.RetryPolicy
+	// FencingKey is a key of the fencing counter, lock key with ":fencing" suffix by default
+	FencingKey string
+	// Index is a database index of the lock and the fencing counter
+	Index int
+}
+
+// Mutex is a distributed mutex, it's safe for concurrent use, but it's held by the mutex value
+// rather than by goroutine, so different processes must use different mutexes
+type Mutex struct {
+	cl   Commands
+	key  string
+	opts Options
+
+	mu    sync.Mutex
+	token []byte
+	fence int64
+	stop  chan struct{}
+	done  chan struct{}
+	lost  chan struct{}
+}
+
+// New returns mutex that uses key in database opts.Index as a lock
+func New(cl Commands, key string, opts Options) *Mutex {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultTTL
+	}
+	if opts.RenewInterval <= 0 {
+		opts.RenewInterval = opts.TTL / 3
+	}
+	if len(opts.FencingKey) == 0 {
+		opts.FencingKey = key + ":fencing"
+	}
+	return &Mutex{cl: cl, key: key, opts: opts}
+}
+
+// TryLock makes a single attempt to acquire the lock, it returns false if lock is held by another owner
+func (m *Mutex) TryLock(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token != nil {
+		return false, ErrHeld
+	}
+	token, err := newToken()
+	if err != nil {
+		return false, err
+	}
+	start := time.Now()
+	ok, err := m.cl.SetArgs(ctx, m.key, token, client.SetArgs{NX: true, TTL: m.opts.TTL}, m.opts.Index)
+	if err != nil || !ok {
+		return false, err
+	}
+	fence, err := m.cl.Incr(ctx, m.opts.FencingKey, m.opts.Index)
+	if err != nil {
+		m.cl.DelIfEq(ctx, m.key, token, m.opts.Index)
+		return false, err
+	}
+	// lock is checked after fencing token is taken, so owner that lost the lock before
+	// it got the token never holds it with a token greater than the token of the next owner
+	ok, err = m.cl.SetArgs(ctx, m.key, token, client.SetArgs{IfEq: token, TTL: m.opts.TTL}, m.opts.Index)
+	if err != nil || !ok {
+		m.cl.DelIfEq(ctx, m.key, token, m.opts.Index)
+		return false, err
+	}
+	m.token, m.fence = token, fence
+	m.stop, m.done, m.lost = make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go m.renewLoop(token, start.Add(m.opts.TTL), m.stop, m.done, m.lost)
+	return true, nil
+}
+
+// Lock acquires the lock, attempts are repeated with backoff of opts.Retry until lock is acquired
+// or ctx is done, client.ErrTimeIsOut is returned in the latter case. ErrNotAcquired is returned
+// if lock isn't acquired after opts.Retry.MaxRetries retries
+func (m *Mutex) Lock(ctx context.Context) error {
+	for retry := 0; ; retry++ {
+		ok, err := m.TryLock(ctx)
+		if err != nil || ok {
+			return err
+		}
+		if m.opts.Retry.MaxRetries > 0 && retry == m.opts.Retry.MaxRetries {
+			return ErrNotAcquired
+		}
+		t := time.NewTimer(m.opts.Retry.Backoff(retry))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return client.ErrTimeIsOut
+		case <-t.C:
+		}
+	}
+}
+
+// Unlock stops lease renewal and releases the lock, ErrLockLost is returned if lock
+// isn't held by this mutex anymore
+func (m *Mutex) Unlock(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token == nil {
+		return ErrNotHeld
+	}
+	close(m.stop)
+	<-m.done
+	token := m.token
+	m.token, m.fence = nil, 0
+	ok, err := m.cl.DelIfEq(ctx, m.key, token, m.opts.Index)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// FencingToken returns fencing token of the current lock, tokens grow with every acquisition of the lock,
+// 0 is returned if mutex isn't locked
+func (m *Mutex) FencingToken() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fence
+}
+
+// Lost returns channel that is closed when lease of the current lock can't be renewed, after that
+// lock may be held by another owner and Unlock must be called before the mutex is locked again.
+// Nil channel is returned if mutex isn't locked
+func (m *Mutex) Lost() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token == nil {
+		return nil
+	}
+	return m.lost
+}
+
+// renewLoop extends TTL of the lock while it's held by token, lock is considered lost when another owner
+// holds it or when lease deadline passes without successful renewal
+func (m *Mutex) renewLoop(token []byte, deadline time.Time, stop, done, lost chan struct{}) {
+	defer close(done)
+	t := time.NewTicker(m.opts.RenewInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		start := time.Now()
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		ok, err := m.cl.SetArgs(ctx, m.key, token, client.SetArgs{IfEq: token, TTL: m.opts.TTL}, m.opts.Index)
+		cancel()
+		switch {
+		case err == nil && ok:
+			deadline = start.Add(m.opts.TTL)
+		case err == nil, !time.Now().Before(deadline):
+			close(lost)
+			return
+		}
+	}
+}
+
+// newToken returns random owner token
+func newToken() ([]byte, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return []byte(hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,143 @@
+package lock_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+	"github.com/ArtemNovok/simpleRedisCl/internal/client/lock"
+	"github.com/ArtemNovok/simpleRedisCl/internal/server"
+	"github.com/ArtemNovok/simpleRedisCl/internal/testserver"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Lock(t *testing.T) {
+	ctx := context.Background()
+	ind := 14
+	logs := filepath.Join(t.TempDir(), "logs")
+	// server clock is moved forward to expire keys without waiting
+	s := testserver.Start(t, server.Config{RecoveryLog: logs})
+	cl := s.Client
+
+	// atomic primitives
+	ok, err := cl.SetNX(ctx, "nx", "a", time.Minute, ind)
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = cl.SetNX(ctx, "nx", "b", time.Minute, ind)
+	require.Nil(t, err)
+	require.False(t, ok)
+	ttl, err := cl.PTTL(ctx, "nx", ind)
+	require.Nil(t, err)
+	require.True(t, ttl > 59*time.Second && ttl <= time.Minute)
+	ok, err = cl.SetArgs(ctx, "nx", []byte("c"), client.SetArgs{IfEq: []byte("b")}, ind)
+	require.Nil(t, err)
+	require.False(t, ok)
+	ok, err = cl.SetArgs(ctx, "nx", []byte("c"), client.SetArgs{IfEq: []byte("a"), TTL: time.Hour}, ind)
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = cl.SetArgs(ctx, "missing", []byte("c"), client.SetArgs{XX: true}, ind)
+	require.Nil(t, err)
+	require.False(t, ok)
+	_, err = cl.SetArgs(ctx, "nx", []byte("c"), client.SetArgs{NX: true, XX: true}, ind)
+	require.ErrorIs(t, err, client.ErrSyntax)
+	ok, err = cl.DelIfEq(ctx, "nx", []byte("a"), ind)
+	require.Nil(t, err)
+	require.False(t, ok)
+	ok, err = cl.DelIfEq(ctx, "nx", []byte("c"), ind)
+	require.Nil(t, err)
+	require.True(t, ok)
+	_, err = cl.PTTL(ctx, "nx", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	require.Nil(t, cl.Set(ctx, "plain", "1", ind))
+	ttl, err = cl.PTTL(ctx, "plain", ind)
+	require.Nil(t, err)
+	require.Equal(t, client.NoExpiration, ttl)
+	ok, err = cl.PExpire(ctx, "plain", time.Second, ind)
+	require.Nil(t, err)
+	require.True(t, ok)
+	n, err := cl.Incr(ctx, "plain", ind)
+	require.Nil(t, err)
+	require.Equal(t, int64(2), n)
+	ttl, err = cl.PTTL(ctx, "plain", ind)
+	require.Nil(t, err)
+	require.True(t, ttl > 0)
+	require.Nil(t, cl.Set(ctx, "text", "a", ind))
+	_, err = cl.Incr(ctx, "text", ind)
+	require.ErrorIs(t, err, client.ErrNotInteger)
+
+	s.Advance(2 * time.Second)
+	_, err = cl.Get(ctx, "plain", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+	ok, err = cl.SetNX(ctx, "plain", "again", 0, ind)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	// expiration survives data recovery
+	ok, err = cl.SetArgs(ctx, "recovered", []byte("v"), client.SetArgs{TTL: time.Hour}, ind)
+	require.Nil(t, err)
+	require.True(t, ok)
+	n, err = cl.Incr(ctx, "counter", ind)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), n)
+	s2 := testserver.Start(t, server.Config{RecoveryLog: logs})
+	s2.Advance(2 * time.Second)
+	cl2 := s2.Client
+	ttl, err = cl2.PTTL(ctx, "recovered", ind)
+	require.Nil(t, err)
+	require.True(t, ttl > 59*time.Minute && ttl <= time.Hour)
+	n, err = cl2.Incr(ctx, "counter", ind)
+	require.Nil(t, err)
+	require.Equal(t, int64(2), n)
+	_, err = cl2.Get(ctx, "nx", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	// mutex
+	opts := lock.Options{TTL: 300 * time.Millisecond, RenewInterval: 50 * time.Millisecond, Index: ind}
+	m1 := lock.New(cl, "lock", opts)
+	m2 := lock.New(cl, "lock", opts)
+	require.ErrorIs(t, m1.Unlock(ctx), lock.ErrNotHeld)
+	ok, err = m1.TryLock(ctx)
+	require.Nil(t, err)
+	require.True(t, ok)
+	_, err = m1.TryLock(ctx)
+	require.ErrorIs(t, err, lock.ErrHeld)
+	fence := m1.FencingToken()
+	require.True(t, fence > 0)
+	ok, err = m2.TryLock(ctx)
+	require.Nil(t, err)
+	require.False(t, ok)
+	// lease is renewed while lock is held
+	time.Sleep(600 * time.Millisecond)
+	lockCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	require.ErrorIs(t, m2.Lock(lockCtx), client.ErrTimeIsOut)
+	cancel()
+	retry := client.RetryPolicy{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	require.ErrorIs(t, lock.New(cl, "lock", lock.Options{Retry: retry, Index: ind}).Lock(ctx), lock.ErrNotAcquired)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		m1.Unlock(ctx)
+	}()
+	lockCtx, cancel = context.WithTimeout(ctx, 2*time.Second)
+	require.Nil(t, m2.Lock(lockCtx))
+	cancel()
+	require.True(t, m2.FencingToken() > fence)
+	// lock expires and is taken by another owner, so m2 can't renew it
+	lost := m2.Lost()
+	s.Advance(time.Second)
+	lockCtx, cancel = context.WithTimeout(ctx, 2*time.Second)
+	require.Nil(t, m1.Lock(lockCtx))
+	cancel()
+	require.True(t, m1.FencingToken() > fence+1)
+	select {
+	case <-lost:
+	case <-time.After(time.Second):
+		t.Fatal("lost lock is not reported")
+	}
+	require.ErrorIs(t, m2.Unlock(ctx), lock.ErrLockLost)
+	require.Nil(t, m1.Unlock(ctx))
+	_, err = cl.Get(ctx, "lock", ind)
+	require.ErrorIs(t, err, client.ErrNotFound)
+}
@@ -0,0 +1,612 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// msgpack format bytes, see https://github.com/msgpack/msgpack/blob/master/spec.md
+const (
+	mpNil      = 0xc0
+	mpFalse    = 0xc2
+	mpTrue     = 0xc3
+	mpBin8     = 0xc4
+	mpBin16    = 0xc5
+	mpBin32    = 0xc6
+	mpFloat32  = 0xca
+	mpFloat64  = 0xcb
+	mpUint8    = 0xcc
+	mpUint16   = 0xcd
+	mpUint32   = 0xce
+	mpUint64   = 0xcf
+	mpInt8     = 0xd0
+	mpInt16    = 0xd1
+	mpInt32    = 0xd2
+	mpInt64    = 0xd3
+	mpStr8     = 0xd9
+	mpStr16    = 0xda
+	mpStr32    = 0xdb
+	mpArray16  = 0xdc
+	mpArray32  = 0xdd
+	mpMap16    = 0xde
+	mpMap32    = 0xdf
+	mpFixMap   = 0x80
+	mpFixArray = 0x90
+	mpFixStr   = 0xa0
+)
+
+var errMsgpackTrailingData = errors.New("msgpack: trailing data after value")
+
+// marshalMsgpack encodes v in msgpack format, structs are encoded as maps of exported fields,
+// field name may be changed with `msgpack:"name"` tag and field is skipped with `msgpack:"-"`
+func marshalMsgpack(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := encodeMsgpack(buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalMsgpack decodes msgpack data into value pointed by v
+func unmarshalMsgpack(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("msgpack: can't decode into %T", v)
+	}
+	d := &msgpackDecoder{data: data}
+	if err := d.decode(rv.Elem()); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return errMsgpackTrailingData
+	}
+	return nil
+}
+
+func encodeMsgpack(buf *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		buf.WriteByte(mpNil)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteByte(mpNil)
+			return nil
+		}
+		return encodeMsgpack(buf, v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(mpTrue)
+		} else {
+			buf.WriteByte(mpFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeMsgpackInt(buf, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeMsgpackUint(buf, v.Uint())
+	case reflect.Float32:
+		buf.WriteByte(mpFloat32)
+		binary.Write(buf, binary.BigEndian, float32(v.Float()))
+	case reflect.Float64:
+		buf.WriteByte(mpFloat64)
+		binary.Write(buf, binary.BigEndian, v.Float())
+	case reflect.String:
+		writeMsgpackHeader(buf, len(v.String()), mpFixStr, 32, mpStr8, mpStr16, mpStr32)
+		buf.WriteString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteByte(mpNil)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			writeMsgpackHeader(buf, v.Len(), 0, 0, mpBin8, mpBin16, mpBin32)
+			buf.Write(v.Bytes())
+			return nil
+		}
+		return encodeMsgpackArray(buf, v)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			writeMsgpackHeader(buf, v.Len(), 0, 0, mpBin8, mpBin16, mpBin32)
+			for i := 0; i < v.Len(); i++ {
+				buf.WriteByte(byte(v.Index(i).Uint()))
+			}
+			return nil
+		}
+		return encodeMsgpackArray(buf, v)
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteByte(mpNil)
+			return nil
+		}
+		writeMsgpackHeader(buf, v.Len(), mpFixMap, 16, 0, mpMap16, mpMap32)
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := encodeMsgpack(buf, iter.Key()); err != nil {
+				return err
+			}
+			if err := encodeMsgpack(buf, iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := msgpackFields(v.Type())
+		writeMsgpackHeader(buf, len(fields), mpFixMap, 16, 0, mpMap16, mpMap32)
+		for _, f := range fields {
+			writeMsgpackHeader(buf, len(f.name), mpFixStr, 32, mpStr8, mpStr16, mpStr32)
+			buf.WriteString(f.name)
+			if err := encodeMsgpack(buf, v.Field(f.index)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func encodeMsgpackArray(buf *bytes.Buffer, v reflect.Value) error {
+	writeMsgpackHeader(buf, v.Len(), mpFixArray, 16, 0, mpArray16, mpArray32)
+	for i := 0; i < v.Len(); i++ {
+		if err := encodeMsgpack(buf, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMsgpackHeader writes header of string, binary, array or map with n elements, fixed format
+// is used if n is less than fixMax, 8 bit format is used if it's not 0
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, f8, f16, f32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case f8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(f8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(f16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(f32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0:
+		writeMsgpackUint(buf, uint64(n))
+	case n >= -32:
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8:
+		buf.WriteByte(mpInt8)
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt16:
+		buf.WriteByte(mpInt16)
+		binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32:
+		buf.WriteByte(mpInt32)
+		binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(mpInt64)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func writeMsgpackUint(buf *bytes.Buffer, n uint64) {
+	switch {
+	case n <= 0x7f:
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(mpUint8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(mpUint16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(mpUint32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(mpUint64)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+type msgpackField struct {
+	name  string
+	index int
+}
+
+// msgpackFields returns exported fields of struct type t with their names
+func msgpackFields(t reflect.Type) []msgpackField {
+	fields := make([]msgpackField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, _, _ := strings.Cut(f.Tag.Get("msgpack"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields = append(fields, msgpackField{name: name, index: i})
+	}
+	return fields
+}
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, fmt.Errorf("msgpack: %w", io.ErrUnexpectedEOF)
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) peek() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("msgpack: %w", io.ErrUnexpectedEOF)
+	}
+	return d.data[d.pos], nil
+}
+
+// uint reads big endian unsigned integer of n bytes
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var res uint64
+	for _, c := range b {
+		res = res<<8 | uint64(c)
+	}
+	return res, nil
+}
+
+func (d *msgpackDecoder) decode(v reflect.Value) error {
+	b, err := d.peek()
+	if err != nil {
+		return err
+	}
+	if b == mpNil {
+		d.pos++
+		v.SetZero()
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("msgpack: can't decode into %s", v.Type())
+		}
+		val, err := d.decodeAny()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(val))
+	case reflect.Bool:
+		d.pos++
+		switch b {
+		case mpTrue:
+			v.SetBool(true)
+		case mpFalse:
+			v.SetBool(false)
+		default:
+			return d.typeError(b, v.Type())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := d.readInt(v.Type())
+		if err != nil {
+			return err
+		}
+		if n < 0 && b == mpUint64 || v.OverflowInt(n) {
+			return fmt.Errorf("msgpack: %d overflows %s", n, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := d.readInt(v.Type())
+		if err != nil {
+			return err
+		}
+		if n < 0 && b != mpUint64 || v.OverflowUint(uint64(n)) {
+			return fmt.Errorf("msgpack: %d overflows %s", n, v.Type())
+		}
+		v.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := d.readFloat(v.Type())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		s, err := d.readBytes(v.Type())
+		if err != nil {
+			return err
+		}
+		v.SetString(string(s))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			s, err := d.readBytes(v.Type())
+			if err != nil {
+				return err
+			}
+			v.SetBytes(bytes.Clone(s))
+			return nil
+		}
+		n, err := d.readArrayLen(v.Type())
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+		for i := 0; i < n; i++ {
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			s, err := d.readBytes(v.Type())
+			if err != nil {
+				return err
+			}
+			if len(s) != v.Len() {
+				return fmt.Errorf("msgpack: %d bytes can't be decoded into %s", len(s), v.Type())
+			}
+			reflect.Copy(v, reflect.ValueOf(s))
+			return nil
+		}
+		n, err := d.readArrayLen(v.Type())
+		if err != nil {
+			return err
+		}
+		if n != v.Len() {
+			return fmt.Errorf("msgpack: array of %d elements can't be decoded into %s", n, v.Type())
+		}
+		for i := 0; i < n; i++ {
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		n, err := d.readMapLen(v.Type())
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.MakeMapWithSize(v.Type(), n))
+		for i := 0; i < n; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := d.decode(key); err != nil {
+				return err
+			}
+			val := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(val); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, val)
+		}
+	case reflect.Struct:
+		n, err := d.readMapLen(v.Type())
+		if err != nil {
+			return err
+		}
+		fields := make(map[string]int)
+		for _, f := range msgpackFields(v.Type()) {
+			fields[f.name] = f.index
+		}
+		for i := 0; i < n; i++ {
+			name, err := d.readBytes(v.Type())
+			if err != nil {
+				return err
+			}
+			index, ok := fields[string(name)]
+			if !ok {
+				if _, err := d.decodeAny(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(v.Field(index)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// decodeAny decodes value into nil, bool, int64, uint64, float64, string, []byte, []any or map[string]any
+func (d *msgpackDecoder) decodeAny() (any, error) {
+	b, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b == mpNil:
+		d.pos++
+		return nil, nil
+	case b == mpTrue || b == mpFalse:
+		d.pos++
+		return b == mpTrue, nil
+	case b <= 0x7f || b >= 0xe0 || b >= mpUint8 && b <= mpInt64:
+		if b == mpUint64 {
+			d.pos++
+			n, err := d.uint(8)
+			if err != nil {
+				return nil, err
+			}
+			if n > math.MaxInt64 {
+				return n, nil
+			}
+			return int64(n), nil
+		}
+		return d.readInt(nil)
+	case b == mpFloat32 || b == mpFloat64:
+		return d.readFloat(nil)
+	case b&0xe0 == mpFixStr || b >= mpStr8 && b <= mpStr32:
+		s, err := d.readBytes(nil)
+		return string(s), err
+	case b >= mpBin8 && b <= mpBin32:
+		s, err := d.readBytes(nil)
+		return bytes.Clone(s), err
+	case b&0xf0 == mpFixArray || b == mpArray16 || b == mpArray32:
+		n, err := d.readArrayLen(nil)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]any, 0, n)
+		for i := 0; i < n; i++ {
+			val, err := d.decodeAny()
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, val)
+		}
+		return res, nil
+	case b&0xf0 == mpFixMap || b == mpMap16 || b == mpMap32:
+		n, err := d.readMapLen(nil)
+		if err != nil {
+			return nil, err
+		}
+		res := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			key, err := d.decodeAny()
+			if err != nil {
+				return nil, err
+			}
+			val, err := d.decodeAny()
+			if err != nil {
+				return nil, err
+			}
+			res[fmt.Sprint(key)] = val
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("msgpack: unknown format byte 0x%02x", b)
+}
+
+func (d *msgpackDecoder) typeError(b byte, t reflect.Type) error {
+	if t == nil {
+		return fmt.Errorf("msgpack: unexpected format byte 0x%02x", b)
+	}
+	return fmt.Errorf("msgpack: format byte 0x%02x can't be decoded into %s", b, t)
+}
+
+// readInt reads integer of any size, uint64 values above max int64 are returned as negative numbers
+func (d *msgpackDecoder) readInt(t reflect.Type) (int64, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+	switch c := b[0]; {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c >= mpUint8 && c <= mpUint64:
+		n, err := d.uint(1 << (c - mpUint8))
+		return int64(n), err
+	case c >= mpInt8 && c <= mpInt64:
+		size := 1 << (c - mpInt8)
+		n, err := d.uint(size)
+		// sign extension of size bytes integer
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	default:
+		return 0, d.typeError(c, t)
+	}
+}
+
+func (d *msgpackDecoder) readFloat(t reflect.Type) (float64, error) {
+	b, err := d.peek()
+	if err != nil {
+		return 0, err
+	}
+	switch b {
+	case mpFloat32:
+		d.pos++
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case mpFloat64:
+		d.pos++
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	}
+	n, err := d.readInt(t)
+	return float64(n), err
+}
+
+// readBytes reads string or binary value, returned slice refers to decoded data
+func (d *msgpackDecoder) readBytes(t reflect.Type) ([]byte, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	var n uint64
+	switch c := b[0]; {
+	case c&0xe0 == mpFixStr:
+		n = uint64(c &^ 0xe0)
+	case c == mpStr8 || c == mpBin8:
+		n, err = d.uint(1)
+	case c == mpStr16 || c == mpBin16:
+		n, err = d.uint(2)
+	case c == mpStr32 || c == mpBin32:
+		n, err = d.uint(4)
+	default:
+		return nil, d.typeError(c, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.next(int(n))
+}
+
+func (d *msgpackDecoder) readArrayLen(t reflect.Type) (int, error) {
+	return d.readLen(t, mpFixArray, mpArray16, mpArray32)
+}
+
+func (d *msgpackDecoder) readMapLen(t reflect.Type) (int, error) {
+	return d.readLen(t, mpFixMap, mpMap16, mpMap32)
+}
+
+func (d *msgpackDecoder) readLen(t reflect.Type, fix, f16, f32 byte) (int, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	switch c := b[0]; {
+	case c&0xf0 == fix:
+		n = uint64(c & 0x0f)
+	case c == f16:
+		n, err = d.uint(2)
+	case c == f32:
+		n, err = d.uint(4)
+	default:
+		return 0, d.typeError(c, t)
+	}
+	if err != nil {
+		return 0, err
+	}
+	// every element takes at least one byte, so longer collections are malformed
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, fmt.Errorf("msgpack: %w", io.ErrUnexpectedEOF)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type msgpackItem struct {
+	ID    uint16            `msgpack:"id"`
+	Name  string            `msgpack:"name"`
+	Attrs map[string]string `msgpack:"attrs"`
+	Next  *msgpackItem      `msgpack:"next"`
+	Skip  int               `msgpack:"-"`
+}
+
+func Test_MsgpackEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		val  any
+		want []byte
+	}{
+		{name: "nil", val: nil, want: []byte{mpNil}},
+		{name: "nil pointer", val: (*int)(nil), want: []byte{mpNil}},
+		{name: "nil slice", val: []string(nil), want: []byte{mpNil}},
+		{name: "nil map", val: map[string]int(nil), want: []byte{mpNil}},
+		{name: "true", val: true, want: []byte{mpTrue}},
+		{name: "false", val: false, want: []byte{mpFalse}},
+		{name: "zero", val: 0, want: []byte{0x00}},
+		{name: "max positive fixint", val: 127, want: []byte{0x7f}},
+		{name: "uint8", val: 128, want: []byte{mpUint8, 0x80}},
+		{name: "uint16", val: 256, want: []byte{mpUint16, 0x01, 0x00}},
+		{name: "uint32", val: 65536, want: []byte{mpUint32, 0x00, 0x01, 0x00, 0x00}},
+		{name: "uint64", val: uint64(math.MaxUint64), want: []byte{mpUint64, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "negative fixint", val: -1, want: []byte{0xff}},
+		{name: "min negative fixint", val: -32, want: []byte{0xe0}},
+		{name: "int8", val: -33, want: []byte{mpInt8, 0xdf}},
+		{name: "int16", val: math.MinInt8 - 1, want: []byte{mpInt16, 0xff, 0x7f}},
+		{name: "int32", val: math.MinInt16 - 1, want: []byte{mpInt32, 0xff, 0xff, 0x7f, 0xff}},
+		{name: "int64", val: int64(math.MinInt64), want: []byte{mpInt64, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "float32", val: float32(1.5), want: []byte{mpFloat32, 0x3f, 0xc0, 0x00, 0x00}},
+		{name: "float64", val: 1.5, want: []byte{mpFloat64, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{name: "fixstr", val: "ab", want: []byte{0xa2, 'a', 'b'}},
+		{name: "str8", val: strings.Repeat("a", 32), want: append([]byte{mpStr8, 32}, strings.Repeat("a", 32)...)},
+		{name: "empty bin", val: []byte{}, want: []byte{mpBin8, 0}},
+		{name: "byte array", val: [2]byte{1, 2}, want: []byte{mpBin8, 2, 1, 2}},
+		{name: "fixarray", val: []int{1, -1}, want: []byte{0x92, 0x01, 0xff}},
+		{name: "struct", val: msgpackItem{ID: 1, Skip: 5}, want: []byte{
+			0x84, 0xa2, 'i', 'd', 0x01, 0xa4, 'n', 'a', 'm', 'e', 0xa0,
+			0xa5, 'a', 't', 't', 'r', 's', mpNil, 0xa4, 'n', 'e', 'x', 't', mpNil,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalMsgpack(tt.val)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+	_, err := marshalMsgpack(make(chan int))
+	require.Error(t, err)
+}
+
+func Test_MsgpackRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		val  any
+	}{
+		{name: "max int64", val: int64(math.MaxInt64)},
+		{name: "min int64", val: int64(math.MinInt64)},
+		{name: "max uint64", val: uint64(math.MaxUint64)},
+		{name: "min int32", val: int32(math.MinInt32)},
+		{name: "max uint32", val: uint32(math.MaxUint32)},
+		{name: "int8 bounds", val: []int8{math.MinInt8, -33, -32, 0, math.MaxInt8}},
+		{name: "max float32", val: float32(math.MaxFloat32)},
+		{name: "smallest float64", val: math.SmallestNonzeroFloat64},
+		{name: "infinities", val: []float64{math.Inf(1), math.Inf(-1)}},
+		{name: "empty string", val: ""},
+		{name: "str16", val: strings.Repeat("x", 256)},
+		{name: "str32", val: strings.Repeat("x", 1<<16)},
+		{name: "bin16", val: bytes.Repeat([]byte{0, 255}, 128)},
+		{name: "bin32", val: bytes.Repeat([]byte{1}, 1<<16)},
+		{name: "array16", val: make([]bool, 16)},
+		{name: "map16", val: map[int]bool{0: true, 1: false, 2: true, 3: false, 4: true, 5: false, 6: true, 7: false,
+			8: true, 9: false, 10: true, 11: false, 12: true, 13: false, 14: true, 15: false}},
+		{name: "nested maps", val: map[string]map[string][]int{"a": {"b": {1, 2}, "c": nil}, "d": {}}},
+		{name: "nested structs", val: msgpackItem{ID: 1, Name: "a", Attrs: map[string]string{"k": "v"},
+			Next: &msgpackItem{ID: math.MaxUint16, Next: &msgpackItem{Name: "c"}}}},
+		{name: "any", val: map[string]any{"i": int64(-7), "u": uint64(math.MaxUint64), "f": 2.5, "s": "x", "b": []byte{0},
+			"nil": nil, "list": []any{true, map[string]any{"k": int64(1)}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := marshalMsgpack(tt.val)
+			require.NoError(t, err)
+			got := reflect.New(reflect.TypeOf(tt.val))
+			require.NoError(t, unmarshalMsgpack(data, got.Interface()))
+			require.Equal(t, tt.val, got.Elem().Interface())
+		})
+	}
+}
+
+func Test_MsgpackDecodeErrors(t *testing.T) {
+	data, err := marshalMsgpack(msgpackItem{ID: 300, Name: "name", Attrs: map[string]string{"k": "v"}, Next: &msgpackItem{}})
+	require.NoError(t, err)
+	// every prefix of encoded value is truncated input
+	for i := 0; i < len(data); i++ {
+		var item msgpackItem
+		require.ErrorIs(t, unmarshalMsgpack(data[:i], &item), io.ErrUnexpectedEOF, i)
+	}
+	var anyVal any
+	require.ErrorIs(t, unmarshalMsgpack(data[:len(data)-1], &anyVal), io.ErrUnexpectedEOF)
+	require.ErrorIs(t, unmarshalMsgpack([]byte{mpMap32, 0xff, 0xff, 0xff, 0xff}, &anyVal), io.ErrUnexpectedEOF)
+	require.ErrorIs(t, unmarshalMsgpack([]byte{mpBin32, 0xff, 0xff, 0xff, 0xff}, new([]byte)), io.ErrUnexpectedEOF)
+
+	tests := []struct {
+		name string
+		data []byte
+		into any
+	}{
+		{name: "trailing data", data: []byte{0x01, 0x02}, into: new(int)},
+		{name: "int8 overflow", data: []byte{mpUint16, 0x01, 0x00}, into: new(int8)},
+		{name: "negative into uint", data: []byte{0xff}, into: new(uint)},
+		{name: "uint64 into int64", data: []byte{mpUint64, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, into: new(int64)},
+		{name: "string into int", data: []byte{0xa1, 'a'}, into: new(int)},
+		{name: "int into string", data: []byte{0x01}, into: new(string)},
+		{name: "array length", data: []byte{0x91, 0x01}, into: new([2]int)},
+		{name: "unknown format", data: []byte{0xc1}, into: &anyVal},
+		{name: "non pointer", data: []byte{0x01}, into: 0},
+		{name: "nil pointer", data: []byte{0x01}, into: (*int)(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, unmarshalMsgpack(tt.data, tt.into))
+		})
+	}
+
+	// nil resets decoded value
+	n := 5
+	require.NoError(t, unmarshalMsgpack([]byte{mpNil}, &n))
+	require.Zero(t, n)
+}
@@ -0,0 +1,508 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// CommandPipeline is a name of the pipeline passed to OnRetry hook
+	CommandPipeline = "PIPELINE"
+
+	defaultMaxBatch = 128
+)
+
+// Result is a result of the command queued to the pipeline, it's set by Exec
+type Result[T any] struct {
+	val T
+	err error
+}
+
+// Val returns value of the command
+func (r *Result[T]) Val() T {
+	return r.val
+}
+
+// Err returns error of the command
+func (r *Result[T]) Err() error {
+	return r.err
+}
+
+// Result returns value and error of the command
+func (r *Result[T]) Result() (T, error) {
+	return r.val, r.err
+}
+
+type pipelineCmd struct {
+	// req is nil for command that failed before it was sent
+	req        []byte
+	idempotent bool
+	// read reads reply of the command and sets its result, connection error is returned
+	read func(r io.Reader) error
+	fail func(err error)
+	err  func() error
+}
+
+// Pipeline queues commands and sends them to the server in one write, replies are read in the same order
+// after that. Pipeline is not safe for concurrent use, it can be used again after Exec
+type Pipeline struct {
+	c    *Client
+	cmds []pipelineCmd
+}
+
+// Pipeline returns new empty pipeline of the client
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{c: c}
+}
+
+// Len returns number of queued commands
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Exec sends queued commands and reads their replies, it returns error of the first failed command.
+// Results of commands whose replies aren't read are set to the error if connection is broken or ctx is done, pipeline
+// is sent again after connection error only if all its commands are idempotent
+func (p *Pipeline) Exec(ctx context.Context) error {
+	cmds := p.cmds
+	p.cmds = nil
+	var req []byte
+	idempotent := true
+	sent := cmds[:0:0]
+	for _, cmd := range cmds {
+		if cmd.req != nil {
+			req = append(req, cmd.req...)
+			idempotent = idempotent && cmd.idempotent
+			sent = append(sent, cmd)
+		}
+	}
+	if len(sent) != 0 {
+		p.exec(ctx, sent, req, idempotent)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) exec(ctx context.Context, sent []pipelineCmd, req []byte, idempotent bool) {
+	c := p.c
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	// read is a number of commands whose replies are read, they keep their results if reading fails
+	read := 0
+	err := c.send(ctx, CommandPipeline, req, idempotent)
+	if err == nil {
+		_, err = await(ctx, c, func(r io.Reader) (struct{}, error) {
+			for read = 0; read < len(sent); read++ {
+				if err := sent[read].read(r); err != nil {
+					return struct{}{}, err
+				}
+			}
+			return struct{}{}, nil
+		})
+	}
+	if err != nil {
+		err = failure(err)
+		for _, cmd := range sent[read:] {
+			cmd.fail(err)
+		}
+	}
+}
+
+// pipelineQueue queues encoded command with decode that reads its reply,
+// request is not sent if err is set and the result fails with it
+func pipelineQueue[T any](p *Pipeline, cmd string, req []byte, err error, decode func(r io.Reader) (T, error)) *Result[T] {
+	res := &Result[T]{err: err}
+	p.cmds = append(p.cmds, pipelineCmd{
+		req:        req,
+		idempotent: idempotentCommands[cmd],
+		read: func(r io.Reader) error {
+			res.val, res.err = decode(r)
+			if isConnError(res.err) {
+				return res.err
+			}
+			return nil
+		},
+		fail: func(err error) {
+			var zero T
+			res.val, res.err = zero, err
+		},
+		err: res.Err,
+	})
+	return res
+}
+
+// pipelineRequest queues command with database index
+func pipelineRequest[T any](p *Pipeline, cmd string, ind int, decode func(r io.Reader) (T, error), args ...string) *Result[T] {
+	if ind > 39 || ind < 0 {
+		return pipelineQueue(p, cmd, nil, ErrInvalidIndex, decode)
+	}
+	req, err := encodeRequest(cmd, ind, args...)
+	if err != nil {
+		req = nil
+	}
+	return pipelineQueue(p, cmd, req, err, decode)
+}
+
+func decodeStatus(r io.Reader) (struct{}, error) {
+	return struct{}{}, failure(readStatus(r))
+}
+
+func decodeStrings(r io.Reader) ([]string, error) {
+	list, err := readList(r)
+	if err != nil {
+		return nil, failure(err)
+	}
+	res := make([]string, 0, len(list))
+	for _, val := range list {
+		res = append(res, string(val))
+	}
+	return res, nil
+}
+
+// Ping queues PING
+func (p *Pipeline) Ping() *Result[struct{}] {
+	buf := &bytes.Buffer{}
+	if err := writeCommand(buf, CommandPing); err != nil {
+		return pipelineQueue(p, CommandPing, nil, err, decodeStatus)
+	}
+	return pipelineQueue(p, CommandPing, buf.Bytes(), nil, decodeStatus)
+}
+
+// Set queues setting key with given value
+func (p *Pipeline) Set(key string, value string, ind int) *Result[struct{}] {
+	return pipelineRequest(p, CommandSet, ind, decodeStatus, key, value)
+}
+
+// Get queues getting key value
+func (p *Pipeline) Get(key string, ind int) *Result[string] {
+	return pipelineRequest(p, CommandGet, ind, readResult, key)
+}
+
+// MSet queues setting values of several keys
+func (p *Pipeline) MSet(pairs map[string]string, ind int) *Result[struct{}] {
+	args := make([]string, 0, 2*len(pairs))
+	for key, val := range pairs {
+		args = append(args, key, val)
+	}
+	return pipelineRequest(p, CommandMSet, ind, decodeStatus, args...)
+}
+
+// MGet queues getting values of keys, keys that don't exist are missing in the result
+func (p *Pipeline) MGet(keys []string, ind int) *Result[map[string]string] {
+	return pipelineRequest(p, CommandMGet, ind, func(r io.Reader) (map[string]string, error) {
+		list, err := readList(r)
+		if err != nil {
+			return nil, failure(err)
+		}
+		if len(list)%2 != 0 {
+			return nil, ErrOperationFailed
+		}
+		res := make(map[string]string, len(list)/2)
+		for i := 0; i < len(list); i += 2 {
+			res[string(list[i])] = string(list[i+1])
+		}
+		return res, nil
+	}, keys...)
+}
+
+// Add queues incrementing key value by 1
+func (p *Pipeline) Add(key string, ind int) *Result[struct{}] {
+	return pipelineRequest(p, CommandAdd, ind, decodeStatus, key)
+}
+
+// AddN queues incrementing key value by given value
+func (p *Pipeline) AddN(key string, value string, ind int) *Result[struct{}] {
+	return pipelineRequest(p, CommandAddN, ind, decodeStatus, key, value)
+}
+
+// Delete queues deleting key
+func (p *Pipeline) Delete(key string, ind int) *Result[struct{}] {
+	return pipelineRequest(p, CommandDelete, ind, decodeStatus, key)
+}
+
+// Unlink queues deleting key of any type, result is false if there was no such key
+func (p *Pipeline) Unlink(key string, ind int) *Result[bool] {
+	return pipelineRequest(p, CommandUnlink, ind, func(r io.Reader) (bool, error) {
+		res, err := readResult(r)
+		return res == "1", err
+	}, key)
+}
+
+// LPush queues pushing value to list key
+func (p *Pipeline) LPush(key string, value string, ind int) *Result[struct{}] {
+	return pipelineRequest(p, CommandLPush, ind, decodeStatus, key, value)
+}
+
+// GetL queues getting list key
+func (p *Pipeline) GetL(key string, ind int) *Result[[]string] {
+	return pipelineRequest(p, CommandGetL, ind, decodeStrings, key)
+}
+
+// Has queues checking whether key of any type exists
+func (p *Pipeline) Has(key string, ind int) *Result[bool] {
+	return pipelineRequest(p, CommandHas, ind, func(r io.Reader) (bool, error) {
+		err := failure(readStatus(r))
+		switch {
+		case err == nil:
+			return true, nil
+		case err == ErrOperationFailed:
+			return false, nil
+		}
+		return false, err
+	}, key)
+}
+
+// DeleteL queues deleting list key
+func (p *Pipeline) DeleteL(key string, ind int) *Result[struct{}] {
+	return pipelineRequest(p, CommandDeleteL, ind, decodeStatus, key)
+}
+
+// DelElemL queues deleting one element with value from list key
+func (p *Pipeline) DelElemL(key string, value string, ind int) *Result[struct{}] {
+	return pipelineRequest(p, CommandDelElemL, ind, decodeStatus, key, value)
+}
+
+// DelAll queues deleting all appearances of value in list key
+func (p *Pipeline) DelAll(key string, value string, ind int) *Result[struct{}] {
+	return pipelineRequest(p, CommandDelAll, ind, decodeStatus, key, value)
+}
+
+// JSONSet queues setting JSON value at the path of document key
+func (p *Pipeline) JSONSet(key string, path string, value string, ind int) *Result[struct{}] {
+	return pipelineRequest(p, CommandJSONSet, ind, decodeStatus, key, path, value)
+}
+
+// JSONGet queues getting JSON values matched by the paths of document key
+func (p *Pipeline) JSONGet(key string, ind int, paths ...string) *Result[string] {
+	return pipelineRequest(p, CommandJSONGet, ind, readResult, append([]string{key}, paths...)...)
+}
+
+// JSONNumIncrBy queues incrementing numbers matched by the path of document key
+func (p *Pipeline) JSONNumIncrBy(key string, path string, value float64, ind int) *Result[string] {
+	return pipelineRequest(p, CommandJSONNumIncrBy, ind, readResult, key, path, formatFloat(value))
+}
+
+// BatchOptions describes how concurrent calls are coalesced by Batcher
+type BatchOptions struct {
+	// MaxBatch is a maximum number of commands sent in one write, 128 by default
+	MaxBatch int
+	// MaxDelay is a time batch waits for more commands after the first one, commands
+	// that are already waiting are sent at once if it's 0
+	MaxDelay time.Duration
+}
+
+type batchReq struct {
+	ctx   context.Context
+	queue func(p *Pipeline)
+	done  chan struct{}
+}
+
+// Batcher coalesces commands of concurrent callers into pipelines, so callers that come while
+// previous batch is in progress are sent in one write. It's safe for concurrent use
+type Batcher struct {
+	c      *Client
+	opts   BatchOptions
+	reqCh  chan *batchReq
+	closed chan struct{}
+	once   sync.Once
+}
+
+// AutoBatch starts batching of commands of the client, Batcher must be closed to stop it
+func (c *Client) AutoBatch(opts BatchOptions) *Batcher {
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = defaultMaxBatch
+	}
+	b := &Batcher{
+		c:      c,
+		opts:   opts,
+		reqCh:  make(chan *batchReq),
+		closed: make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// Close stops batching, commands that are not sent yet fail with ErrClientClosed, client is not closed
+func (b *Batcher) Close() error {
+	b.once.Do(func() {
+		close(b.closed)
+	})
+	return nil
+}
+
+func (b *Batcher) loop() {
+	for {
+		var batch []*batchReq
+		select {
+		case <-b.closed:
+			return
+		case req := <-b.reqCh:
+			batch = append(batch, req)
+		}
+		batch = b.collect(batch)
+		b.exec(batch)
+	}
+}
+
+// collect adds waiting commands to the batch until it's full
+func (b *Batcher) collect(batch []*batchReq) []*batchReq {
+	var timeout <-chan time.Time
+	if b.opts.MaxDelay > 0 {
+		t := time.NewTimer(b.opts.MaxDelay)
+		defer t.Stop()
+		timeout = t.C
+	}
+	for len(batch) < b.opts.MaxBatch {
+		if timeout == nil {
+			select {
+			case req := <-b.reqCh:
+				batch = append(batch, req)
+				continue
+			default:
+				return batch
+			}
+		}
+		select {
+		case req := <-b.reqCh:
+			batch = append(batch, req)
+		case <-timeout:
+			return batch
+		}
+	}
+	return batch
+}
+
+// exec sends batch as a pipeline, pipeline is canceled when all callers of the batch are gone
+func (b *Batcher) exec(batch []*batchReq) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remaining := atomic.Int64{}
+	remaining.Store(int64(len(batch)))
+	p := b.c.Pipeline()
+	for _, req := range batch {
+		stop := context.AfterFunc(req.ctx, func() {
+			if remaining.Add(-1) == 0 {
+				cancel()
+			}
+		})
+		defer stop()
+		req.queue(p)
+	}
+	p.Exec(ctx)
+	for _, req := range batch {
+		close(req.done)
+	}
+}
+
+// batchDo queues command to the next batch and waits for its result
+func batchDo[T any](ctx context.Context, b *Batcher, queue func(p *Pipeline) *Result[T]) (T, error) {
+	var zero T
+	var res *Result[T]
+	req := &batchReq{
+		ctx:   ctx,
+		queue: func(p *Pipeline) { res = queue(p) },
+		done:  make(chan struct{}),
+	}
+	select {
+	case b.reqCh <- req:
+	case <-b.closed:
+		return zero, ErrClientClosed
+	case <-ctx.Done():
+		return zero, ErrTimeIsOut
+	}
+	select {
+	case <-req.done:
+		return res.Result()
+	case <-ctx.Done():
+		return zero, ErrTimeIsOut
+	}
+}
+
+// batchExec is batchDo for commands without result
+func batchExec(ctx context.Context, b *Batcher, queue func(p *Pipeline) *Result[struct{}]) error {
+	_, err := batchDo(ctx, b, queue)
+	return err
+}
+
+// Ping checks that server is alive
+func (b *Batcher) Ping(ctx context.Context) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.Ping() })
+}
+
+// Set sets key with given value it returns error if ctx is done or operation failed
+func (b *Batcher) Set(ctx context.Context, key string, value string, ind int) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.Set(key, value, ind) })
+}
+
+// Get returns key value and error if ctx is done or operation failed
+func (b *Batcher) Get(ctx context.Context, key string, ind int) (string, error) {
+	return batchDo(ctx, b, func(p *Pipeline) *Result[string] { return p.Get(key, ind) })
+}
+
+// MSet sets values of several keys in database ind
+func (b *Batcher) MSet(ctx context.Context, pairs map[string]string, ind int) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.MSet(pairs, ind) })
+}
+
+// MGet returns values of keys from database ind, keys that don't exist are missing in the result
+func (b *Batcher) MGet(ctx context.Context, keys []string, ind int) (map[string]string, error) {
+	return batchDo(ctx, b, func(p *Pipeline) *Result[map[string]string] { return p.MGet(keys, ind) })
+}
+
+// Add increment key value by 1 and returns error if ctx is done or operation failed
+func (b *Batcher) Add(ctx context.Context, key string, ind int) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.Add(key, ind) })
+}
+
+// AddN increment key value by given value and returns error if ctx is done or operation failed
+func (b *Batcher) AddN(ctx context.Context, key string, value string, ind int) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.AddN(key, value, ind) })
+}
+
+// Delete deletes key of any type from database ind
+func (b *Batcher) Delete(ctx context.Context, key string, ind int) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.Delete(key, ind) })
+}
+
+// Unlink deletes key of any type from database ind, returns false if there was no such key
+func (b *Batcher) Unlink(ctx context.Context, key string, ind int) (bool, error) {
+	return batchDo(ctx, b, func(p *Pipeline) *Result[bool] { return p.Unlink(key, ind) })
+}
+
+// LPush pushes value to list key in database ind, if list doesn't exists it will be created
+func (b *Batcher) LPush(ctx context.Context, key string, value string, ind int) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.LPush(key, value, ind) })
+}
+
+// GetL returns list key that contains strings
+func (b *Batcher) GetL(ctx context.Context, key string, ind int) ([]string, error) {
+	return batchDo(ctx, b, func(p *Pipeline) *Result[[]string] { return p.GetL(key, ind) })
+}
+
+// Has reports whether key of any type exists in database ind
+func (b *Batcher) Has(ctx context.Context, key string, ind int) (bool, error) {
+	return batchDo(ctx, b, func(p *Pipeline) *Result[bool] { return p.Has(key, ind) })
+}
+
+// DeleteL deletes whole list with name key from database ind
+func (b *Batcher) DeleteL(ctx context.Context, key string, ind int) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.DeleteL(key, ind) })
+}
+
+// DelElemL deletes only one element with value from list with key name in database ind
+func (b *Batcher) DelElemL(ctx context.Context, key string, value string, ind int) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.DelElemL(key, value, ind) })
+}
+
+// DelAll deletes all appearances of value in list with key name in database with index ind
+func (b *Batcher) DelAll(ctx context.Context, key string, value string, ind int) error {
+	return batchExec(ctx, b, func(p *Pipeline) *Result[struct{}] { return p.DelAll(key, value, ind) })
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PipelineConnectionDrop(t *testing.T) {
+	conn, other := net.Pipe()
+	c := &Client{conn: conn}
+	go io.Copy(io.Discard, other)
+	go func() {
+		// connection drops after replies of the first two commands
+		other.Write([]byte{statusOK, statusOK})
+		other.Close()
+	}()
+	p := c.Pipeline()
+	results := []*Result[struct{}]{p.Add("a", 0), p.Add("b", 0), p.Add("c", 0), p.Add("d", 0)}
+	require.ErrorIs(t, p.Exec(context.Background()), ErrOperationFailed)
+	require.NoError(t, results[0].Err())
+	require.NoError(t, results[1].Err())
+	require.ErrorIs(t, results[2].Err(), ErrOperationFailed)
+	require.ErrorIs(t, results[3].Err(), ErrOperationFailed)
+}
@@ -0,0 +1,635 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPoolSize            = 10
+	defaultHealthCheckInterval = time.Second
+	// poolReapInterval is how often idle connections are checked for expiration and MinIdle is restored
+	poolReapInterval = time.Second
+)
+
+var (
+	// ErrPoolTimeout returned when ctx is done before connection is released by other caller
+	ErrPoolTimeout = errors.New("connection pool timeout")
+)
+
+// PoolOptions describes connections of the pool
+type PoolOptions struct {
+	Addr     string
+	Password string
+	// User is a name of the user authenticated with Password, the default user is authenticated if it's empty
+	User string
+	// PoolSize is a maximum number of open connections, 10 by default
+	PoolSize int
+	// MinIdle is a number of idle connections kept open in background
+	MinIdle int
+	// MaxIdle is a maximum number of idle connections, extra connections are closed when returned to the pool,
+	// PoolSize by default
+	MaxIdle int
+	// MaxLifetime is a time after which connection is closed, connections are not closed due to age if it's 0
+	MaxLifetime time.Duration
+	// IdleTimeout is a time after which idle connection is closed, connections are not closed due to idleness if it's 0
+	IdleTimeout time.Duration
+	// HealthCheckInterval is an idle time after which connection is pinged on checkout, 1s by default,
+	// connections are not pinged if it's negative
+	HealthCheckInterval time.Duration
+}
+
+// PoolStats describes usage of the pool
+type PoolStats struct {
+	// Hits is a number of times idle connection was reused
+	Hits uint64
+	// Misses is a number of times new connection was created on checkout
+	Misses uint64
+	// Timeouts is a number of times ctx was done while waiting for connection
+	Timeouts uint64
+	// TotalConns is a number of open connections
+	TotalConns int
+	// IdleConns is a number of idle connections
+	IdleConns int
+}
+
+type poolConn struct {
+	cl       *Client
+	created  time.Time
+	lastUsed time.Time
+}
+
+// Pool is a client that runs every command on a connection of the pool, so concurrent
+// commands don't wait for each other until all PoolSize connections are busy
+type Pool struct {
+	opts PoolOptions
+	// sem holds a token for every checked out connection
+	sem    chan struct{}
+	mu     sync.Mutex
+	idle   []*poolConn
+	open   int
+	closed bool
+	done   chan struct{}
+
+	hits     atomic.Uint64
+	misses   atomic.Uint64
+	timeouts atomic.Uint64
+}
+
+// NewPool creates pool and opens MinIdle connections, the first connection
+// is created even if MinIdle is 0 to check address and password
+func NewPool(ctx context.Context, opts PoolOptions) (*Pool, error) {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = defaultPoolSize
+	}
+	if opts.MaxIdle <= 0 || opts.MaxIdle > opts.PoolSize {
+		opts.MaxIdle = opts.PoolSize
+	}
+	if opts.MinIdle > opts.MaxIdle {
+		opts.MinIdle = opts.MaxIdle
+	}
+	if opts.HealthCheckInterval == 0 {
+		opts.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if len(opts.Password) == 0 {
+		opts.Password = defaultPassword
+	}
+	p := &Pool{
+		opts: opts,
+		sem:  make(chan struct{}, opts.PoolSize),
+		done: make(chan struct{}),
+	}
+	for i := 0; i < max(opts.MinIdle, 1); i++ {
+		pc, err := p.dial(ctx)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.mu.Lock()
+		p.idle = append(p.idle, pc)
+		p.mu.Unlock()
+	}
+	go p.reapLoop()
+	return p, nil
+}
+
+// Stats returns statistics of the pool
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{
+		Hits:       p.hits.Load(),
+		Misses:     p.misses.Load(),
+		Timeouts:   p.timeouts.Load(),
+		TotalConns: p.open,
+		IdleConns:  len(p.idle),
+	}
+}
+
+// Close closes idle connections, connections that are in use are closed when they are returned
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	var res error
+	for _, pc := range p.idle {
+		if err := pc.cl.Close(); err != nil && res == nil {
+			res = err
+		}
+	}
+	p.open -= len(p.idle)
+	p.idle = nil
+	return res
+}
+
+// Conn runs fn with a connection of the pool, it's used for sequences of commands
+// that need the same connection, connection must not be used after fn returns.
+// Connection authenticated by fn as other user is closed when it's returned
+func (p *Pool) Conn(ctx context.Context, fn func(*Client) error) error {
+	return poolExec(ctx, p, fn)
+}
+
+// dial opens new connection, it's counted as open before it's created, so PoolSize isn't exceeded by reaper
+func (p *Pool) dial(ctx context.Context) (*poolConn, error) {
+	p.mu.Lock()
+	p.open++
+	p.mu.Unlock()
+	cl, err := NewWithOptions(ctx, Options{Addr: p.opts.Addr, User: p.opts.User, Password: p.opts.Password})
+	if err != nil {
+		p.mu.Lock()
+		p.open--
+		p.mu.Unlock()
+		return nil, err
+	}
+	now := time.Now()
+	return &poolConn{cl: cl, created: now, lastUsed: now}, nil
+}
+
+func (p *Pool) closeConn(pc *poolConn) {
+	pc.cl.Close()
+	p.mu.Lock()
+	p.open--
+	p.mu.Unlock()
+}
+
+func (p *Pool) expired(pc *poolConn, now time.Time) bool {
+	return (p.opts.MaxLifetime > 0 && now.Sub(pc.created) >= p.opts.MaxLifetime) ||
+		(p.opts.IdleTimeout > 0 && now.Sub(pc.lastUsed) >= p.opts.IdleTimeout)
+}
+
+func (p *Pool) popIdle() (*poolConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrClientClosed
+	}
+	if len(p.idle) == 0 {
+		return nil, nil
+	}
+	pc := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+	return pc, nil
+}
+
+// get waits until connection can be checked out, idle connections are reused
+// and new connection is opened if there are none
+func (p *Pool) get(ctx context.Context) (*poolConn, error) {
+	select {
+	case p.sem <- struct{}{}:
+	default:
+		select {
+		case p.sem <- struct{}{}:
+		case <-ctx.Done():
+			p.timeouts.Add(1)
+			return nil, ErrPoolTimeout
+		}
+	}
+	for {
+		pc, err := p.popIdle()
+		if err != nil {
+			<-p.sem
+			return nil, err
+		}
+		if pc == nil {
+			break
+		}
+		now := time.Now()
+		if p.expired(pc, now) {
+			p.closeConn(pc)
+			continue
+		}
+		if p.opts.HealthCheckInterval > 0 && now.Sub(pc.lastUsed) >= p.opts.HealthCheckInterval {
+			if err := pc.cl.Ping(ctx); err != nil {
+				p.closeConn(pc)
+				continue
+			}
+		}
+		p.hits.Add(1)
+		return pc, nil
+	}
+	p.misses.Add(1)
+	pc, err := p.dial(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return pc, nil
+}
+
+// put returns connection to the pool, connection is closed if it's broken by err, expired,
+// authenticated as other user or there are MaxIdle idle connections already
+func (p *Pool) put(pc *poolConn, err error) {
+	defer func() { <-p.sem }()
+	now := time.Now()
+	pc.lastUsed = now
+	if isConnError(err) || p.expired(pc, now) || p.reauthenticated(pc) {
+		p.closeConn(pc)
+		return
+	}
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.opts.MaxIdle {
+		p.mu.Unlock()
+		p.closeConn(pc)
+		return
+	}
+	p.idle = append(p.idle, pc)
+	p.mu.Unlock()
+}
+
+// reapLoop closes expired idle connections and opens new ones until there are MinIdle idle connections
+func (p *Pool) reapLoop() {
+	ticker := time.NewTicker(poolReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.reap()
+		}
+	}
+}
+
+func (p *Pool) reap() {
+	now := time.Now()
+	p.mu.Lock()
+	var expired []*poolConn
+	alive := p.idle[:0]
+	for _, pc := range p.idle {
+		if p.expired(pc, now) {
+			expired = append(expired, pc)
+		} else {
+			alive = append(alive, pc)
+		}
+	}
+	p.idle = alive
+	missing := min(p.opts.MinIdle-len(p.idle), p.opts.PoolSize-p.open+len(expired))
+	p.mu.Unlock()
+	for _, pc := range expired {
+		p.closeConn(pc)
+	}
+	for i := 0; i < missing; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), poolReapInterval)
+		pc, err := p.dial(ctx)
+		cancel()
+		if err != nil {
+			return
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			p.closeConn(pc)
+			return
+		}
+		p.idle = append(p.idle, pc)
+		p.mu.Unlock()
+	}
+}
+
+// poolDo runs fn with a connection of the pool
+func poolDo[T any](ctx context.Context, p *Pool, fn func(*Client) (T, error)) (T, error) {
+	var zero T
+	pc, err := p.get(ctx)
+	if err != nil {
+		return zero, err
+	}
+	res, err := fn(pc.cl)
+	p.put(pc, err)
+	return res, err
+}
+
+// poolExec is poolDo for commands without result
+func poolExec(ctx context.Context, p *Pool, fn func(*Client) error) error {
+	_, err := poolDo(ctx, p, func(cl *Client) (struct{}, error) {
+		return struct{}{}, fn(cl)
+	})
+	return err
+}
+
+// Ping checks that server is alive
+func (p *Pool) Ping(ctx context.Context) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.Ping(ctx)
+	})
+}
+
+// Do sends command with arguments as is and returns its reply, see Client.Do
+func (p *Pool) Do(ctx context.Context, args ...any) (*Reply, error) {
+	return poolDo(ctx, p, func(cl *Client) (*Reply, error) {
+		return cl.Do(ctx, args...)
+	})
+}
+
+func (p *Pool) Hello(ctx context.Context, m map[string]string) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.Hello(ctx, m)
+	})
+}
+
+// Info returns server information of the section in Redis INFO format
+func (p *Pool) Info(ctx context.Context, section string) (string, error) {
+	return poolDo(ctx, p, func(cl *Client) (string, error) {
+		return cl.Info(ctx, section)
+	})
+}
+
+// Role returns replication role of the server
+func (p *Pool) Role(ctx context.Context) (*Role, error) {
+	return poolDo(ctx, p, func(cl *Client) (*Role, error) {
+		return cl.Role(ctx)
+	})
+}
+
+// Set sets key with given value it returns error if ctx is done or operation failed
+func (p *Pool) Set(ctx context.Context, key string, value string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.Set(ctx, key, value, ind)
+	})
+}
+
+// SetBytes sets key with given binary value, value may contain any bytes
+func (p *Pool) SetBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.SetBytes(ctx, key, value, ind)
+	})
+}
+
+// Get returns key value and error if ctx is done or operation failed
+func (p *Pool) Get(ctx context.Context, key string, ind int) (string, error) {
+	return poolDo(ctx, p, func(cl *Client) (string, error) {
+		return cl.Get(ctx, key, ind)
+	})
+}
+
+// GetBytes returns binary value of the key, ErrNotFound is returned if key doesn't exist
+func (p *Pool) GetBytes(ctx context.Context, key string, ind int) ([]byte, error) {
+	return poolDo(ctx, p, func(cl *Client) ([]byte, error) {
+		return cl.GetBytes(ctx, key, ind)
+	})
+}
+
+// MSet sets values of several keys in database ind
+func (p *Pool) MSet(ctx context.Context, pairs map[string]string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.MSet(ctx, pairs, ind)
+	})
+}
+
+// MSetBytes sets binary values of several keys in database ind
+func (p *Pool) MSetBytes(ctx context.Context, pairs map[string][]byte, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.MSetBytes(ctx, pairs, ind)
+	})
+}
+
+// MGet returns values of keys from database ind, keys that don't exist are missing in the result
+func (p *Pool) MGet(ctx context.Context, keys []string, ind int) (map[string]string, error) {
+	return poolDo(ctx, p, func(cl *Client) (map[string]string, error) {
+		return cl.MGet(ctx, keys, ind)
+	})
+}
+
+// MGetBytes returns binary values of keys from database ind, keys that don't exist are missing in the result
+func (p *Pool) MGetBytes(ctx context.Context, keys []string, ind int) (map[string][]byte, error) {
+	return poolDo(ctx, p, func(cl *Client) (map[string][]byte, error) {
+		return cl.MGetBytes(ctx, keys, ind)
+	})
+}
+
+// Add increment key value by 1 and returns error if ctx is done or operation failed
+func (p *Pool) Add(ctx context.Context, key string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.Add(ctx, key, ind)
+	})
+}
+
+// AddN increment key value by given value and returns error if ctx is done or operation failed
+func (p *Pool) AddN(ctx context.Context, key string, value string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.AddN(ctx, key, value, ind)
+	})
+}
+
+// Delete deletes key of any type from database ind
+func (p *Pool) Delete(ctx context.Context, key string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.Delete(ctx, key, ind)
+	})
+}
+
+// Unlink deletes key of any type from database ind, returns false if there was no such key
+func (p *Pool) Unlink(ctx context.Context, key string, ind int) (bool, error) {
+	return poolDo(ctx, p, func(cl *Client) (bool, error) {
+		return cl.Unlink(ctx, key, ind)
+	})
+}
+
+// LPush pushes value to list key in database ind, if list doesn't exists it will be created
+func (p *Pool) LPush(ctx context.Context, key string, value string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.LPush(ctx, key, value, ind)
+	})
+}
+
+// LPushBytes pushes binary value to list key in database ind, if list doesn't exists it will be created
+func (p *Pool) LPushBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.LPushBytes(ctx, key, value, ind)
+	})
+}
+
+// GetL returns list key that contains strings
+func (p *Pool) GetL(ctx context.Context, key string, ind int) ([]string, error) {
+	return poolDo(ctx, p, func(cl *Client) ([]string, error) {
+		return cl.GetL(ctx, key, ind)
+	})
+}
+
+// GetLBytes returns list key with binary values
+func (p *Pool) GetLBytes(ctx context.Context, key string, ind int) ([][]byte, error) {
+	return poolDo(ctx, p, func(cl *Client) ([][]byte, error) {
+		return cl.GetLBytes(ctx, key, ind)
+	})
+}
+
+// Has reports whether key of any type exists in database ind
+func (p *Pool) Has(ctx context.Context, key string, ind int) (bool, error) {
+	return poolDo(ctx, p, func(cl *Client) (bool, error) {
+		return cl.Has(ctx, key, ind)
+	})
+}
+
+// DeleteL deletes whole list with name key from database ind
+func (p *Pool) DeleteL(ctx context.Context, key string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.DeleteL(ctx, key, ind)
+	})
+}
+
+// DelElemL deletes only one element with value from list with key name in database ind
+func (p *Pool) DelElemL(ctx context.Context, key string, value string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.DelElemL(ctx, key, value, ind)
+	})
+}
+
+// DelElemLBytes deletes only one element with binary value from list with key name in database ind
+func (p *Pool) DelElemLBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.DelElemLBytes(ctx, key, value, ind)
+	})
+}
+
+// DelAll deletes all appearances of value in list with key name in database with index ind
+func (p *Pool) DelAll(ctx context.Context, key string, value string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.DelAll(ctx, key, value, ind)
+	})
+}
+
+// DelAllBytes deletes all appearances of binary value in list with key name in database with index ind
+func (p *Pool) DelAllBytes(ctx context.Context, key string, value []byte, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.DelAllBytes(ctx, key, value, ind)
+	})
+}
+
+// GeoAdd adds locations to geo set key in database ind, existing members are moved to the new location
+func (p *Pool) GeoAdd(ctx context.Context, key string, locations []GeoLocation, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.GeoAdd(ctx, key, locations, ind)
+	})
+}
+
+// GeoDist returns distance between two members of geo set key in given unit
+func (p *Pool) GeoDist(ctx context.Context, key, member1, member2, unit string, ind int) (float64, error) {
+	return poolDo(ctx, p, func(cl *Client) (float64, error) {
+		return cl.GeoDist(ctx, key, member1, member2, unit, ind)
+	})
+}
+
+// GeoPos returns positions of members of geo set key, position is nil for missing member
+func (p *Pool) GeoPos(ctx context.Context, key string, members []string, ind int) ([]*GeoPos, error) {
+	return poolDo(ctx, p, func(cl *Client) ([]*GeoPos, error) {
+		return cl.GeoPos(ctx, key, members, ind)
+	})
+}
+
+// GeoSearch returns members of geo set key that are within the area described by the query
+func (p *Pool) GeoSearch(ctx context.Context, key string, q *GeoSearchQuery, ind int) ([]GeoLocation, error) {
+	return poolDo(ctx, p, func(cl *Client) ([]GeoLocation, error) {
+		return cl.GeoSearch(ctx, key, q, ind)
+	})
+}
+
+// JSONSet sets JSON value at the path of document key in database ind
+func (p *Pool) JSONSet(ctx context.Context, key string, path string, value string, ind int) error {
+	return poolExec(ctx, p, func(cl *Client) error {
+		return cl.JSONSet(ctx, key, path, value, ind)
+	})
+}
+
+// JSONGet returns JSON values matched by the paths of document key in database ind
+func (p *Pool) JSONGet(ctx context.Context, key string, ind int, paths ...string) (string, error) {
+	return poolDo(ctx, p, func(cl *Client) (string, error) {
+		return cl.JSONGet(ctx, key, ind, paths...)
+	})
+}
+
+// JSONDel deletes JSON values matched by the path of document key in database ind
+func (p *Pool) JSONDel(ctx context.Context, key string, path string, ind int) (int64, error) {
+	return poolDo(ctx, p, func(cl *Client) (int64, error) {
+		return cl.JSONDel(ctx, key, path, ind)
+	})
+}
+
+// JSONNumIncrBy increments numbers matched by the path of document key in database ind
+func (p *Pool) JSONNumIncrBy(ctx context.Context, key string, path string, value float64, ind int) (string, error) {
+	return poolDo(ctx, p, func(cl *Client) (string, error) {
+		return cl.JSONNumIncrBy(ctx, key, path, value, ind)
+	})
+}
+
+// JSONArrAppend appends values to arrays matched by the path of document key in database ind
+func (p *Pool) JSONArrAppend(ctx context.Context, key string, path string, values []string, ind int) ([]int64, error) {
+	return poolDo(ctx, p, func(cl *Client) ([]int64, error) {
+		return cl.JSONArrAppend(ctx, key, path, values, ind)
+	})
+}
+
+// JSONType returns types of JSON values matched by the path of document key in database ind
+func (p *Pool) JSONType(ctx context.Context, key string, path string, ind int) ([]string, error) {
+	return poolDo(ctx, p, func(cl *Client) ([]string, error) {
+		return cl.JSONType(ctx, key, path, ind)
+	})
+}
+
+// Subscribe creates subscription to the channels, subscription uses its own connection
+// that is not taken from the pool
+func (p *Pool) Subscribe(ctx context.Context, channels ...string) (*PubSub, error) {
+	return subscribe(ctx, []string{p.opts.Addr}, credentials(p.opts.User, p.opts.Password), CommandSubscribe, channels)
+}
+
+// PSubscribe creates subscription to the channels that match glob-style patterns,
+// subscription uses its own connection that is not taken from the pool
+func (p *Pool) PSubscribe(ctx context.Context, patterns ...string) (*PubSub, error) {
+	return subscribe(ctx, []string{p.opts.Addr}, credentials(p.opts.User, p.opts.Password), CommandPSubscribe, patterns)
+}
+
+// SubscribeKeyspace subscribes to keyspace notifications about keys of database ind that match glob-style pattern
+func (p *Pool) SubscribeKeyspace(ctx context.Context, keyPattern string, ind int) (*PubSub, error) {
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	return p.PSubscribe(ctx, keyspacePattern(keyPattern, ind))
+}
+
+// SubscribeKeyevent subscribes to keyevent notifications about events of database ind
+func (p *Pool) SubscribeKeyevent(ctx context.Context, ind int, events ...string) (*PubSub, error) {
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	return p.PSubscribe(ctx, keyeventPatterns(ind, events)...)
+}
+
+// Publish sends message to the channel and returns number of subscribers that received it
+func (p *Pool) Publish(ctx context.Context, channel string, message string) (int64, error) {
+	return poolDo(ctx, p, func(cl *Client) (int64, error) {
+		return cl.Publish(ctx, channel, message)
+	})
+}
+
+// reauthenticated reports whether connection was authenticated with other credentials by Auth,
+// such connection must not be reused by other callers
+func (p *Pool) reauthenticated(pc *poolConn) bool {
+	pc.cl.connLock.Lock()
+	defer pc.cl.connLock.Unlock()
+	return pc.cl.user != p.opts.User || pc.cl.password != p.opts.Password
+}
@@ -0,0 +1,315 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/resp"
+)
+
+var (
+	CommandSubscribe    = "SUBSCRIBE"
+	CommandPSubscribe   = "PSUBSCRIBE"
+	CommandUnsubscribe  = "UNSUBSCRIBE"
+	CommandPUnsubscribe = "PUNSUBSCRIBE"
+	CommandPublish      = "PUBLISH"
+	// ErrPubSubClosed returned when PubSub is used after Close
+	ErrPubSubClosed = errors.New("pubsub is closed")
+)
+
+const (
+	keyspacePrefix = "__keyspace@"
+	keyeventPrefix = "__keyevent@"
+	pubSubMessage  = "message"
+	pubSubPMessage = "pmessage"
+	// messageBufferSize is a number of messages that PubSub keeps until they are received
+	messageBufferSize = 100
+)
+
+// Message is a message received from the channel, Pattern is set when
+// message is received due to pattern subscription
+type Message struct {
+	Channel string
+	Pattern string
+	Payload string
+}
+
+// KeyspaceEvent is a parsed keyspace or keyevent notification
+type KeyspaceEvent struct {
+	Index int
+	Key   string
+	Event string
+}
+
+// KeyspaceEvent parses keyspace (__keyspace@<db>__:<key>) or keyevent (__keyevent@<db>__:<event>)
+// notification, returns false if message is not a notification
+func (m *Message) KeyspaceEvent() (KeyspaceEvent, bool) {
+	var isKeyspace bool
+	var rest string
+	switch {
+	case strings.HasPrefix(m.Channel, keyspacePrefix):
+		isKeyspace = true
+		rest = strings.TrimPrefix(m.Channel, keyspacePrefix)
+	case strings.HasPrefix(m.Channel, keyeventPrefix):
+		rest = strings.TrimPrefix(m.Channel, keyeventPrefix)
+	default:
+		return KeyspaceEvent{}, false
+	}
+	db, name, ok := strings.Cut(rest, "__:")
+	if !ok {
+		return KeyspaceEvent{}, false
+	}
+	ind, err := strconv.Atoi(db)
+	if err != nil {
+		return KeyspaceEvent{}, false
+	}
+	if isKeyspace {
+		return KeyspaceEvent{Index: ind, Key: name, Event: m.Payload}, true
+	}
+	return KeyspaceEvent{Index: ind, Key: m.Payload, Event: name}, true
+}
+
+// PubSub is a subscription that uses its own connection to the server (a connection per node
+// for ClusterClient), messages are delivered to the channel returned by Channel
+type PubSub struct {
+	mu        sync.Mutex
+	conns     []net.Conn
+	msgCh     chan *Message
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// writeCommand writes command that is not bound to a database
+func writeCommand(w io.Writer, cmd string, args ...string) error {
+	respReq := []resp.Value{resp.StringValue(cmd)}
+	for _, val := range args {
+		respReq = append(respReq, resp.StringValue(val))
+	}
+	buf := &bytes.Buffer{}
+	wr := resp.NewWriter(buf)
+	if err := wr.WriteArray(respReq); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, buf)
+	return err
+}
+
+// Subscribe creates subscription to the channels
+func (c *Client) Subscribe(ctx context.Context, channels ...string) (*PubSub, error) {
+	return c.newPubSub(ctx, CommandSubscribe, channels)
+}
+
+// PSubscribe creates subscription to the channels that match glob-style patterns
+func (c *Client) PSubscribe(ctx context.Context, patterns ...string) (*PubSub, error) {
+	return c.newPubSub(ctx, CommandPSubscribe, patterns)
+}
+
+// SubscribeKeyspace subscribes to keyspace notifications about keys of database ind that match
+// glob-style pattern, Payload of received messages is an event name (e.g. "set", "del", "lpush"),
+// server must be configured to publish keyspace events ("K" flag)
+func (c *Client) SubscribeKeyspace(ctx context.Context, keyPattern string, ind int) (*PubSub, error) {
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	return c.PSubscribe(ctx, keyspacePattern(keyPattern, ind))
+}
+
+// SubscribeKeyevent subscribes to keyevent notifications about events of database ind
+// (or all events if none is given), Payload of received messages is a key,
+// server must be configured to publish keyevent events ("E" flag)
+func (c *Client) SubscribeKeyevent(ctx context.Context, ind int, events ...string) (*PubSub, error) {
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	return c.PSubscribe(ctx, keyeventPatterns(ind, events)...)
+}
+
+func keyspacePattern(keyPattern string, ind int) string {
+	return fmt.Sprintf("%s%d__:%s", keyspacePrefix, ind, keyPattern)
+}
+
+func keyeventPatterns(ind int, events []string) []string {
+	if len(events) == 0 {
+		events = []string{"*"}
+	}
+	patterns := make([]string, 0, len(events))
+	for _, event := range events {
+		patterns = append(patterns, fmt.Sprintf("%s%d__:%s", keyeventPrefix, ind, event))
+	}
+	return patterns
+}
+
+// Publish sends message to the channel and returns number of subscribers that received it
+func (c *Client) Publish(ctx context.Context, channel string, message string) (int64, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandPublish, channel, message); err != nil {
+		return 0, err
+	}
+	res, err := c.waitForResult(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(res, 10, 64)
+}
+
+// newPubSub dials new connection and waits until all subscriptions are confirmed
+func (c *Client) newPubSub(ctx context.Context, cmd string, names []string) (*PubSub, error) {
+	return subscribe(ctx, []string{c.addr}, credentials(c.user, c.password), cmd, names)
+}
+
+// subscribe dials connection to every server and waits until all subscriptions are confirmed
+func subscribe(ctx context.Context, addrs []string, creds []byte, cmd string, names []string) (*PubSub, error) {
+	if len(names) == 0 || len(addrs) == 0 {
+		return nil, ErrOperationFailed
+	}
+	p := &PubSub{
+		msgCh:  make(chan *Message, messageBufferSize),
+		closed: make(chan struct{}),
+	}
+	for _, addr := range addrs {
+		conn, err := dial(ctx, addr, creds)
+		if err != nil {
+			p.closeConns()
+			return nil, err
+		}
+		p.conns = append(p.conns, conn)
+		if err := confirmSubscription(ctx, conn, cmd, names); err != nil {
+			p.closeConns()
+			return nil, err
+		}
+	}
+	p.wg.Add(len(p.conns))
+	for _, conn := range p.conns {
+		go p.readLoop(conn)
+	}
+	go func() {
+		p.wg.Wait()
+		close(p.msgCh)
+	}()
+	return p, nil
+}
+
+// confirmSubscription sends subscription command and waits for confirmation of every name
+func confirmSubscription(ctx context.Context, conn net.Conn, cmd string, names []string) error {
+	if err := writeCommand(conn, cmd, names...); err != nil {
+		return err
+	}
+	ch := make(chan error, 1)
+	go func() {
+		for range names {
+			if _, err := readList(conn); err != nil {
+				ch <- err
+				return
+			}
+		}
+		ch <- nil
+	}()
+	select {
+	case <-ctx.Done():
+		return ErrTimeIsOut
+	case err := <-ch:
+		if err != nil {
+			return ErrOperationFailed
+		}
+		return nil
+	}
+}
+
+// readLoop reads messages until connection is closed, confirmations of
+// subscription changes are skipped
+func (p *PubSub) readLoop(conn net.Conn) {
+	defer p.wg.Done()
+	for {
+		list, err := readList(conn)
+		if err != nil {
+			return
+		}
+		var msg *Message
+		switch {
+		case len(list) == 3 && string(list[0]) == pubSubMessage:
+			msg = &Message{Channel: string(list[1]), Payload: string(list[2])}
+		case len(list) == 4 && string(list[0]) == pubSubPMessage:
+			msg = &Message{Pattern: string(list[1]), Channel: string(list[2]), Payload: string(list[3])}
+		default:
+			continue
+		}
+		select {
+		case p.msgCh <- msg:
+		case <-p.closed:
+			return
+		}
+	}
+}
+
+// Channel returns channel with received messages, it's closed when PubSub is closed
+// or all connections are lost
+func (p *PubSub) Channel() <-chan *Message {
+	return p.msgCh
+}
+
+func (p *PubSub) write(cmd string, names []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.closed:
+		return ErrPubSubClosed
+	default:
+	}
+	for _, conn := range p.conns {
+		if err := writeCommand(conn, cmd, names...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe adds channels to the subscription
+func (p *PubSub) Subscribe(channels ...string) error {
+	return p.write(CommandSubscribe, channels)
+}
+
+// PSubscribe adds patterns to the subscription
+func (p *PubSub) PSubscribe(patterns ...string) error {
+	return p.write(CommandPSubscribe, patterns)
+}
+
+// Unsubscribe removes channels from the subscription or all channels if none is given
+func (p *PubSub) Unsubscribe(channels ...string) error {
+	return p.write(CommandUnsubscribe, channels)
+}
+
+// PUnsubscribe removes patterns from the subscription or all patterns if none is given
+func (p *PubSub) PUnsubscribe(patterns ...string) error {
+	return p.write(CommandPUnsubscribe, patterns)
+}
+
+// Close closes subscription connections
+func (p *PubSub) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		close(p.closed)
+		err = p.closeConns()
+	})
+	return err
+}
+
+func (p *PubSub) closeConns() error {
+	var res error
+	for _, conn := range p.conns {
+		if err := conn.Close(); err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
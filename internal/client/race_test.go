@@ -0,0 +1,6 @@
+//go:build race
+
+package client_test
+
+// raceEnabled is set when tests are run with race detector
+const raceEnabled = true
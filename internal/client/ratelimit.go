@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/embedded"
+)
+
+var (
+	CommandRateLimit = "RATELIMIT"
+)
+
+// RateLimitAlgorithm is an algorithm of the server side rate limiter
+type RateLimitAlgorithm = embedded.RateLimitAlgorithm
+
+const (
+	RateLimitFixedWindow = embedded.RateLimitFixedWindow
+	RateLimitSlidingLog  = embedded.RateLimitSlidingLog
+	RateLimitTokenBucket = embedded.RateLimitTokenBucket
+)
+
+// RateLimitResult is a decision of the rate limiter
+type RateLimitResult = embedded.RateLimitResult
+
+// RateLimit atomically takes cost requests from the limiter stored in key of database ind, limiter allows
+// limit requests in period with millisecond precision. Denied requests don't change the limiter
+func (c *Client) RateLimit(ctx context.Context, alg RateLimitAlgorithm, key string, limit int64, period time.Duration, cost int64, ind int) (*RateLimitResult, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if ind > 39 || ind < 0 {
+		return nil, ErrInvalidIndex
+	}
+	args := []string{string(alg), key, strconv.FormatInt(limit, 10), strconv.FormatInt(period.Milliseconds(), 10), strconv.FormatInt(cost, 10)}
+	if err := c.writeRequest(ctx, CommandRateLimit, ind, args...); err != nil {
+		return nil, err
+	}
+	list, err := c.waitForList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) != 4 {
+		return nil, ErrOperationFailed
+	}
+	var nums [3]int64
+	for i := range nums {
+		if nums[i], err = strconv.ParseInt(string(list[i+1]), 10, 64); err != nil {
+			return nil, failure(err)
+		}
+	}
+	return &RateLimitResult{
+		Allowed:    string(list[0]) == "1",
+		Remaining:  nums[0],
+		RetryAfter: time.Duration(nums[1]) * time.Millisecond,
+		ResetAfter: time.Duration(nums[2]) * time.Millisecond,
+	}, nil
+}
+
+// RateLimit atomically takes cost requests from the limiter stored in key of database ind, see Client.RateLimit
+func (p *Pool) RateLimit(ctx context.Context, alg RateLimitAlgorithm, key string, limit int64, period time.Duration, cost int64, ind int) (*RateLimitResult, error) {
+	return poolDo(ctx, p, func(cl *Client) (*RateLimitResult, error) {
+		return cl.RateLimit(ctx, alg, key, limit, period, cost, ind)
+	})
+}
+
+// RateLimit atomically takes cost requests from the limiter stored in key of database ind, see Client.RateLimit
+func (c *ClusterClient) RateLimit(ctx context.Context, alg RateLimitAlgorithm, key string, limit int64, period time.Duration, cost int64, ind int) (*RateLimitResult, error) {
+	return clusterDo(ctx, c, key, func(cl *Client) (*RateLimitResult, error) {
+		return cl.RateLimit(ctx, alg, key, limit, period, cost, ind)
+	})
+}
@@ -0,0 +1,78 @@
+// Package ratelimit provides rate limiters backed by the server, every decision is made atomically
+// by the server in a single round trip, so limiters may be shared by any number of processes
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+)
+
+var (
+	// ErrInvalidLimit returned by Allow if limiter is created with non positive limit or period
+	ErrInvalidLimit = errors.New("limit and period must be positive")
+)
+
+// Commands are commands limiters are built on, they are implemented by Client, Pool and ClusterClient
+type Commands interface {
+	RateLimit(ctx context.Context, alg client.RateLimitAlgorithm, key string, limit int64, period time.Duration, cost int64, ind int) (*client.RateLimitResult, error)
+}
+
+// Result is a decision of the limiter: whether request is allowed, how many requests remain
+// and when denied request may be retried
+type Result = client.RateLimitResult
+
+// Options describes keys of the limiter
+type Options struct {
+	// Prefix is added to keys passed to Allow, e.g. "ratelimit:", limiters of different algorithms
+	// must not share keys, client.ErrWrongType is returned for key with state of another algorithm
+	Prefix string
+	// Index is a database index of limiter keys
+	Index int
+}
+
+// Limiter limits rate of requests per key, each key has its own state on the server
+type Limiter struct {
+	cl     Commands
+	alg    client.RateLimitAlgorithm
+	limit  int64
+	period time.Duration
+	opts   Options
+}
+
+// NewFixedWindow returns limiter that allows limit requests per window, window of the key starts with its first request,
+// bursts up to 2*limit are possible at the border of two windows
+func NewFixedWindow(cl Commands, limit int64, window time.Duration, opts Options) *Limiter {
+	return &Limiter{cl: cl, alg: client.RateLimitFixedWindow, limit: limit, period: window, opts: opts}
+}
+
+// NewSlidingLog returns limiter that allows limit requests in any window of given length,
+// server keeps times of allowed requests of the last window
+func NewSlidingLog(cl Commands, limit int64, window time.Duration, opts Options) *Limiter {
+	return &Limiter{cl: cl, alg: client.RateLimitSlidingLog, limit: limit, period: window, opts: opts}
+}
+
+// NewTokenBucket returns limiter that allows rate requests per given interval on average and bursts of up to burst requests
+func NewTokenBucket(cl Commands, rate int64, per time.Duration, burst int64, opts Options) *Limiter {
+	l := &Limiter{cl: cl, alg: client.RateLimitTokenBucket, limit: burst, opts: opts}
+	if rate > 0 {
+		// period is a time the empty bucket is refilled in
+		l.period = time.Duration(float64(per) * float64(burst) / float64(rate))
+	}
+	return l
+}
+
+// Allow takes a single request of the key
+func (l *Limiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN takes n requests of the key at once, they are either all allowed or all denied
+func (l *Limiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	if l.limit <= 0 || l.period < time.Millisecond {
+		return nil, ErrInvalidLimit
+	}
+	return l.cl.RateLimit(ctx, l.alg, l.opts.Prefix+key, l.limit, l.period, n, l.opts.Index)
+}
@@ -0,0 +1,82 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+	"github.com/ArtemNovok/simpleRedisCl/internal/client/ratelimit"
+	"github.com/ArtemNovok/simpleRedisCl/internal/server"
+	"github.com/ArtemNovok/simpleRedisCl/internal/testserver"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	ind := 15
+	s := testserver.Start(t, server.Config{})
+	s.Freeze()
+	cl := s.Client
+	opts := ratelimit.Options{Prefix: "rl:", Index: ind}
+
+	fixed := ratelimit.NewFixedWindow(cl, 2, time.Second, opts)
+	for i := int64(1); i >= 0; i-- {
+		res, err := fixed.Allow(ctx, "user")
+		require.Nil(t, err)
+		require.True(t, res.Allowed)
+		require.Equal(t, i, res.Remaining)
+	}
+	res, err := fixed.Allow(ctx, "user")
+	require.Nil(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, time.Second, res.RetryAfter)
+	s.Advance(time.Second)
+	res, err = fixed.Allow(ctx, "user")
+	require.Nil(t, err)
+	require.True(t, res.Allowed)
+
+	sliding := ratelimit.NewSlidingLog(cl, 3, time.Second, opts)
+	res, err = sliding.AllowN(ctx, "sliding", 2)
+	require.Nil(t, err)
+	require.True(t, res.Allowed)
+	s.Advance(500 * time.Millisecond)
+	res, err = sliding.AllowN(ctx, "sliding", 2)
+	require.Nil(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, int64(1), res.Remaining)
+	require.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	s.Advance(500 * time.Millisecond)
+	res, err = sliding.AllowN(ctx, "sliding", 2)
+	require.Nil(t, err)
+	require.True(t, res.Allowed)
+
+	// 10 requests per second with bursts of 5
+	bucket := ratelimit.NewTokenBucket(cl, 10, time.Second, 5, opts)
+	res, err = bucket.AllowN(ctx, "bucket", 5)
+	require.Nil(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, 500*time.Millisecond, res.ResetAfter)
+	res, err = bucket.Allow(ctx, "bucket")
+	require.Nil(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, 100*time.Millisecond, res.RetryAfter)
+	s.Advance(200 * time.Millisecond)
+	res, err = bucket.AllowN(ctx, "bucket", 2)
+	require.Nil(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, int64(0), res.Remaining)
+
+	_, err = ratelimit.NewFixedWindow(cl, 0, time.Second, opts).Allow(ctx, "user")
+	require.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+	require.Nil(t, cl.Set(ctx, "rl:text", "a", ind))
+	_, err = fixed.Allow(ctx, "text")
+	require.ErrorIs(t, err, client.ErrWrongType)
+	_, err = cl.Do(ctx, "RATELIMIT", "LEAKY", "key", 1, 1000, ind)
+	require.ErrorIs(t, err, client.ErrSyntax)
+	reply, err := cl.Do(ctx, "RATELIMIT", "fixed", "do", 1, 1000, ind)
+	require.Nil(t, err)
+	list, err := reply.Slice()
+	require.Nil(t, err)
+	require.Equal(t, []string{"1", "0", "0", "1000"}, list)
+}
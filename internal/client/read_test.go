@@ -0,0 +1,29 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReadInvalidSize(t *testing.T) {
+	for _, size := range []int64{-1, maxValueSize + 1} {
+		buf := binary.BigEndian.AppendUint64(nil, uint64(size))
+		_, err := readBytes(bytes.NewReader(buf))
+		require.ErrorIs(t, err, errValueSize)
+		require.True(t, isConnError(err))
+
+		buf = binary.BigEndian.AppendUint64([]byte{statusOK}, uint64(size))
+		_, err = readList(bytes.NewReader(buf))
+		require.ErrorIs(t, err, errValueSize)
+	}
+	buf := binary.BigEndian.AppendUint64([]byte{statusOK}, 2)
+	buf = binary.BigEndian.AppendUint64(buf, 1)
+	buf = append(buf, 'a')
+	buf = binary.BigEndian.AppendUint64(buf, 0)
+	list, err := readList(bytes.NewReader(buf))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("a"), {}}, list)
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"strconv"
+)
+
+var (
+	CommandReplicaOf = "REPLICAOF"
+	CommandRole      = "ROLE"
+	CommandInfo      = "INFO"
+)
+
+const (
+	// RoleMaster is a role of the primary
+	RoleMaster = "master"
+	// RoleReplica is a role of the replica
+	RoleReplica = "slave"
+)
+
+// Role describes replication role of the server
+type Role struct {
+	// Role is RoleMaster or RoleReplica
+	Role string
+	// Offset is replication offset of the server
+	Offset int64
+	// Replicas are replicas connected to the primary
+	Replicas []ReplicaInfo
+	// MasterHost and MasterPort are address of the primary of a replica
+	MasterHost, MasterPort string
+	// State is a state of replica's connection with the primary: "connect", "connecting", "sync" or "connected"
+	State string
+}
+
+// ReplicaInfo describes replica connected to the primary
+type ReplicaInfo struct {
+	Host, Port string
+	// Offset is replication offset acknowledged by the replica
+	Offset int64
+}
+
+// ReplicaOf makes the server a replica of the primary with given host and port,
+// replication is started in background, data of the replica is replaced by data of the primary
+func (c *Client) ReplicaOf(ctx context.Context, host string, port string) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandReplicaOf, host, port); err != nil {
+		return err
+	}
+	return c.waitForResponse(ctx)
+}
+
+// ReplicaOfNoOne stops replication and turns the replica into a primary, data set is kept
+func (c *Client) ReplicaOfNoOne(ctx context.Context) error {
+	return c.ReplicaOf(ctx, "NO", "ONE")
+}
+
+// Role returns replication role of the server
+func (c *Client) Role(ctx context.Context) (*Role, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandRole); err != nil {
+		return nil, err
+	}
+	list, err := c.waitForList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseRole(list)
+}
+
+func parseRole(list [][]byte) (*Role, error) {
+	if len(list) < 2 {
+		return nil, ErrOperationFailed
+	}
+	role := &Role{Role: string(list[0])}
+	switch role.Role {
+	case RoleMaster:
+		if (len(list)-2)%3 != 0 {
+			return nil, ErrOperationFailed
+		}
+		offset, err := strconv.ParseInt(string(list[1]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		role.Offset = offset
+		for i := 2; i < len(list); i += 3 {
+			offset, err := strconv.ParseInt(string(list[i+2]), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			role.Replicas = append(role.Replicas, ReplicaInfo{
+				Host:   string(list[i]),
+				Port:   string(list[i+1]),
+				Offset: offset,
+			})
+		}
+	case RoleReplica:
+		if len(list) != 5 {
+			return nil, ErrOperationFailed
+		}
+		offset, err := strconv.ParseInt(string(list[4]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		role.MasterHost = string(list[1])
+		role.MasterPort = string(list[2])
+		role.State = string(list[3])
+		role.Offset = offset
+	default:
+		return nil, ErrOperationFailed
+	}
+	return role, nil
+}
+
+// Info returns information about the server in Redis INFO format, only "replication"
+// section is supported, all sections are returned if section is empty
+func (c *Client) Info(ctx context.Context, section string) (string, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	var args []string
+	if len(section) != 0 {
+		args = append(args, section)
+	}
+	if err := c.writeCommand(ctx, CommandInfo, args...); err != nil {
+		return "", err
+	}
+	return c.waitForResult(ctx)
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMinBackoff      = 8 * time.Millisecond
+	defaultMaxBackoff      = 512 * time.Millisecond
+	defaultBreakerCooldown = time.Second
+)
+
+var (
+	// ErrCircuitOpen returned without contacting the server while circuit breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+// idempotentCommands are commands that are retried after connection error, repeating them
+// doesn't change the result if the first attempt reached the server
+var idempotentCommands = map[string]bool{
+	CommandGet:       true,
+	CommandSet:       true,
+	CommandMGet:      true,
+	CommandMSet:      true,
+	CommandGetL:      true,
+	CommandHas:       true,
+	CommandDelete:    true,
+	CommandDeleteL:   true,
+	CommandDelAll:    true,
+	CommandPing:      true,
+	CommandUnlink:    true,
+	CommandGeoAdd:    true,
+	CommandGeoDist:   true,
+	CommandGeoPos:    true,
+	CommandGeoSearch: true,
+	CommandJSONSet:   true,
+	CommandJSONGet:   true,
+	CommandJSONType:  true,
+	CommandRole:      true,
+	CommandInfo:      true,
+	CommandPTTL:      true,
+	CommandCommand:   true,
+}
+
+// idempotent reports whether command sent by Do with arguments vals can be retried, SET is retried only
+// without NX, XX and IFEQ, the same as SetArgs
+func idempotent(vals [][]byte) bool {
+	cmd := string(vals[0])
+	if !idempotentCommands[cmd] {
+		return false
+	}
+	if cmd != CommandSet {
+		return true
+	}
+	for _, arg := range vals[1:] {
+		switch strings.ToUpper(string(arg)) {
+		case "NX", "XX", "IFEQ":
+			return false
+		}
+	}
+	return true
+}
+
+// Options describes connection of the client
+type Options struct {
+	Addr     string
+	Password string
+	// User is a name of the user authenticated with Password, the default user is authenticated if it's empty
+	User string
+	// Resolve returns address of the server before connection is dialed again and before the first dial
+	// if Addr is empty, Addr is used if it's nil
+	Resolve func(ctx context.Context) (string, error)
+	Retry   RetryPolicy
+	Breaker BreakerOptions
+	Hooks   Hooks
+	// LocalCache enables client-side caching of values read with Get and GetBytes
+	LocalCache LocalCacheOptions
+}
+
+// RetryPolicy describes how idempotent commands are retried after connection errors,
+// other commands are never retried as they may be applied twice
+type RetryPolicy struct {
+	// MaxRetries is a number of retries of a command, commands are not retried if it's 0
+	MaxRetries int
+	// MinBackoff is a delay before the first retry, 8ms by default
+	MinBackoff time.Duration
+	// MaxBackoff is a maximum delay between retries, 512ms by default
+	MaxBackoff time.Duration
+}
+
+// Backoff returns exponential delay before retry with given number starting from 0, delay is randomized
+// within its upper half so clients that lost connection at once don't retry at once.
+// Default delays are used if MinBackoff or MaxBackoff is 0
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	if p.MinBackoff <= 0 {
+		p.MinBackoff = defaultMinBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultMaxBackoff
+	}
+	d := p.MaxBackoff
+	if retry < 32 {
+		d = min(p.MinBackoff<<retry, p.MaxBackoff)
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// BreakerOptions describes circuit breaker that fails operations without contacting
+// the server after several connection errors in a row
+type BreakerOptions struct {
+	// Failures is a number of connection errors in a row that opens circuit, breaker is disabled if it's 0
+	Failures int
+	// Cooldown is a time circuit stays open before trial operation is allowed, 1s by default
+	Cooldown time.Duration
+}
+
+// BreakerState is a state of the circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed allows all operations
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails operations with ErrCircuitOpen
+	BreakerOpen
+	// BreakerHalfOpen allows trial operation, circuit is closed if it succeeds and opened again otherwise
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Hooks are called on connection events, they are called synchronously
+// while operation is in progress, so they must not use the client
+type Hooks struct {
+	// OnDisconnect is called when connection is closed due to err
+	OnDisconnect func(addr string, err error)
+	// OnReconnect is called when connection is dialed again and authenticated
+	OnReconnect func(addr string)
+	// OnRetry is called before command is retried after err, retry starts from 1
+	OnRetry func(cmd string, retry int, err error)
+	// OnBreakerStateChange is called when circuit breaker changes its state
+	OnBreakerStateChange func(state BreakerState)
+}
+
+// breaker is a circuit breaker, it's used with connLock held
+type breaker struct {
+	opts     BreakerOptions
+	onChange func(BreakerState)
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+// allow returns ErrCircuitOpen if circuit is open, after cooldown circuit becomes half-open and operation is allowed
+func (b *breaker) allow() error {
+	if b.state != BreakerOpen {
+		return nil
+	}
+	if time.Since(b.openedAt) < b.opts.Cooldown {
+		return ErrCircuitOpen
+	}
+	b.setState(BreakerHalfOpen)
+	return nil
+}
+
+func (b *breaker) success() {
+	b.failures = 0
+	if b.state != BreakerClosed {
+		b.setState(BreakerClosed)
+	}
+}
+
+func (b *breaker) failure() {
+	if b.opts.Failures <= 0 {
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.opts.Failures {
+		b.openedAt = time.Now()
+		if b.state != BreakerOpen {
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+func (b *breaker) setState(state BreakerState) {
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
+
+// retryable reports whether command can be sent again after err
+func (c *Client) retryable(err error) bool {
+	return isConnError(err) && c.idempotent && c.retries < c.opts.Retry.MaxRetries
+}
+
+// retry sends request again after connection error with backoff until it's written,
+// error is returned if command can't be retried anymore
+func (c *Client) retry(ctx context.Context, err error) error {
+	for c.retryable(err) {
+		c.retries++
+		if c.opts.Hooks.OnRetry != nil {
+			c.opts.Hooks.OnRetry(c.cmd, c.retries, err)
+		}
+		t := time.NewTimer(c.opts.Retry.Backoff(c.retries - 1))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ErrTimeIsOut
+		case <-t.C:
+		}
+		if err = c.write(ctx); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Idempotent(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{args: []string{"GET", "key", "0"}, want: true},
+		{args: []string{"SET", "key", "value", "0"}, want: true},
+		{args: []string{"SET", "key", "value", "PX", "100", "0"}, want: true},
+		{args: []string{"SET", "key", "value", "NX", "0"}, want: false},
+		{args: []string{"SET", "key", "value", "xx", "0"}, want: false},
+		{args: []string{"SET", "key", "value", "IfEq", "old", "0"}, want: false},
+		{args: []string{"ADD", "key", "0"}, want: false},
+	}
+	for _, tt := range tests {
+		vals := make([][]byte, len(tt.args))
+		for i, arg := range tt.args {
+			vals[i] = []byte(arg)
+		}
+		require.Equal(t, tt.want, idempotent(vals), tt.args)
+	}
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+)
+
+var (
+	CommandSentinel = "SENTINEL"
+	// ErrMasterNotFound returned when none of the sentinels knows the primary
+	ErrMasterNotFound = errors.New("primary is not found")
+)
+
+const (
+	sentinelGetMasterAddr      = "GET-MASTER-ADDR-BY-NAME"
+	sentinelMaster             = "MASTER"
+	sentinelIsMasterDownByAddr = "IS-MASTER-DOWN-BY-ADDR"
+)
+
+// FailoverOptions describes how to discover the primary through sentinels
+type FailoverOptions struct {
+	// MasterName is a name of the primary monitored by sentinels
+	MasterName string
+	// SentinelAddrs are addresses of sentinels, they are asked in order until one knows the primary
+	SentinelAddrs []string
+	// Password is used to connect to the primary
+	Password string
+	// SentinelPassword is used to connect to sentinels
+	SentinelPassword string
+	// Retry, Breaker and Hooks are options of the client, broken connection is dialed
+	// to the primary returned by sentinels at that moment
+	Retry   RetryPolicy
+	Breaker BreakerOptions
+	Hooks   Hooks
+}
+
+// MasterDownReply is a reply of a sentinel to IS-MASTER-DOWN-BY-ADDR
+type MasterDownReply struct {
+	// Down is true if sentinel can't reach the primary
+	Down bool
+	// Leader is run id of the sentinel voted for in LeaderEpoch, "*" if there was no vote
+	Leader      string
+	LeaderEpoch int64
+}
+
+// NewFailover asks sentinels for the address of the current primary and connects to it,
+// connection is checked to be a primary and the next sentinel is asked otherwise
+func NewFailover(ctx context.Context, opts FailoverOptions) (*Client, error) {
+	for _, addr := range opts.SentinelAddrs {
+		masterAddr, err := opts.masterAddr(ctx, addr)
+		if err != nil {
+			continue
+		}
+		c, err := NewWithOptions(ctx, Options{
+			Addr:     masterAddr,
+			Password: opts.Password,
+			Resolve:  opts.resolve,
+			Retry:    opts.Retry,
+			Breaker:  opts.Breaker,
+			Hooks:    opts.Hooks,
+		})
+		if err != nil {
+			continue
+		}
+		role, err := c.Role(ctx)
+		if err != nil || role.Role != RoleMaster {
+			c.Close()
+			continue
+		}
+		return c, nil
+	}
+	return nil, ErrMasterNotFound
+}
+
+// masterAddr asks sentinel with given address for the address of the primary
+func (opts FailoverOptions) masterAddr(ctx context.Context, addr string) (string, error) {
+	sentinel, err := New(ctx, addr, opts.SentinelPassword)
+	if err != nil {
+		return "", err
+	}
+	defer sentinel.Close()
+	return sentinel.SentinelMasterAddr(ctx, opts.MasterName)
+}
+
+// resolve returns address of the primary known by the first sentinel that answers
+func (opts FailoverOptions) resolve(ctx context.Context) (string, error) {
+	for _, addr := range opts.SentinelAddrs {
+		if masterAddr, err := opts.masterAddr(ctx, addr); err == nil {
+			return masterAddr, nil
+		}
+	}
+	return "", ErrMasterNotFound
+}
+
+// SentinelMasterAddr returns address of the primary with given name, client must be connected to a sentinel
+func (c *Client) SentinelMasterAddr(ctx context.Context, name string) (string, error) {
+	list, err := c.sentinelCommand(ctx, sentinelGetMasterAddr, name)
+	if err != nil {
+		return "", err
+	}
+	if len(list) != 2 {
+		return "", ErrOperationFailed
+	}
+	return net.JoinHostPort(string(list[0]), string(list[1])), nil
+}
+
+// SentinelMaster returns state of the primary with given name as field-value pairs
+// ("name", "ip", "port", "config-epoch", "flags"), client must be connected to a sentinel
+func (c *Client) SentinelMaster(ctx context.Context, name string) (map[string]string, error) {
+	list, err := c.sentinelCommand(ctx, sentinelMaster, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(list)%2 != 0 {
+		return nil, ErrOperationFailed
+	}
+	res := make(map[string]string, len(list)/2)
+	for i := 0; i < len(list); i += 2 {
+		res[string(list[i])] = string(list[i+1])
+	}
+	return res, nil
+}
+
+// SentinelIsMasterDownByAddr asks sentinel whether it can reach the primary, if runID is not "*"
+// sentinel is also asked to vote for runID as a failover leader in epoch
+func (c *Client) SentinelIsMasterDownByAddr(ctx context.Context, host, port string, epoch int64, runID string) (*MasterDownReply, error) {
+	list, err := c.sentinelCommand(ctx, sentinelIsMasterDownByAddr, host, port, strconv.FormatInt(epoch, 10), runID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) != 3 {
+		return nil, ErrOperationFailed
+	}
+	leaderEpoch, err := strconv.ParseInt(string(list[2]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &MasterDownReply{
+		Down:        string(list[0]) == "1",
+		Leader:      string(list[1]),
+		LeaderEpoch: leaderEpoch,
+	}, nil
+}
+
+func (c *Client) sentinelCommand(ctx context.Context, subcommand string, args ...string) ([][]byte, error) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if err := c.writeCommand(ctx, CommandSentinel, append([]string{subcommand}, args...)...); err != nil {
+		return nil, err
+	}
+	return c.waitForList(ctx)
+}
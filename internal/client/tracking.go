@@ -0,0 +1,324 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/tidwall/resp"
+)
+
+var (
+	CommandClient = "CLIENT"
+)
+
+// invalidateChannel is a channel of invalidation messages, message is "<db>:<key>" of the changed key
+// or empty message if all keys are invalidated
+const invalidateChannel = "__redis__:invalidate"
+
+// LocalCacheOptions describes client-side caching. Values read with Get and GetBytes are kept in memory
+// of the client and served without contacting the server until server reports that they are changed.
+// Reports are received with a separate connection, cache is flushed when it or the main connection is lost
+type LocalCacheOptions struct {
+	// Size is a maximum number of cached values, random value is evicted when cache is full,
+	// local cache is disabled if it's 0
+	Size int
+	// BCast enables broadcasting mode, server reports changes of all keys that start with one of Prefixes
+	// instead of remembering keys read by the client
+	BCast bool
+	// Prefixes are key prefixes of broadcasting mode, changes of all keys are reported if none is given
+	Prefixes []string
+}
+
+// trackingArgs returns arguments of CLIENT TRACKING that redirects invalidations to connection id
+func (o LocalCacheOptions) trackingArgs(id string) []string {
+	args := []string{"TRACKING", "ON", "REDIRECT", id}
+	if o.BCast {
+		args = append(args, "BCAST")
+		for _, prefix := range o.Prefixes {
+			args = append(args, "PREFIX", prefix)
+		}
+	}
+	return args
+}
+
+// LocalCacheStats are counters of the local cache
+type LocalCacheStats struct {
+	Hits          int64
+	Misses        int64
+	Invalidations int64
+	Entries       int
+}
+
+type cacheKey struct {
+	ind int
+	key string
+}
+
+// localCache keeps values read by the client, conn is a connection that receives invalidations,
+// it's nil until it's dialed and after it's lost
+type localCache struct {
+	opts LocalCacheOptions
+
+	mu      sync.Mutex
+	conn    net.Conn
+	id      string
+	entries map[cacheKey][]byte
+	// fills are reads in progress, value is cached only if it isn't invalidated while it's read
+	fills map[cacheKey]struct{}
+	stats LocalCacheStats
+	// tracked reports whether tracking is enabled for the current connection of the client, it's used with connLock held
+	tracked bool
+}
+
+func newLocalCache(opts LocalCacheOptions) *localCache {
+	return &localCache{
+		opts:    opts,
+		entries: make(map[cacheKey][]byte),
+		fills:   make(map[cacheKey]struct{}),
+	}
+}
+
+func (lc *localCache) get(k cacheKey) ([]byte, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	val, ok := lc.entries[k]
+	if !ok {
+		lc.stats.Misses++
+		return nil, false
+	}
+	lc.stats.Hits++
+	return bytes.Clone(val), true
+}
+
+func (lc *localCache) startFill(k cacheKey) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.fills[k] = struct{}{}
+}
+
+// fill caches value read from the server unless it's invalidated while it was read
+func (lc *localCache) fill(k cacheKey, val []byte, ok bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if _, filling := lc.fills[k]; !filling {
+		return
+	}
+	delete(lc.fills, k)
+	if !ok {
+		return
+	}
+	if len(lc.entries) >= lc.opts.Size {
+		for victim := range lc.entries {
+			delete(lc.entries, victim)
+			break
+		}
+	}
+	lc.entries[k] = bytes.Clone(val)
+}
+
+func (lc *localCache) evict(k cacheKey) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	delete(lc.entries, k)
+	delete(lc.fills, k)
+}
+
+func (lc *localCache) flush() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	clear(lc.entries)
+	clear(lc.fills)
+}
+
+// evictArgs evicts arguments of commands in encoded request, it's called before write commands are sent,
+// so the client reads its own writes before invalidation is received. Arguments that are not keys
+// are evicted too, it only costs another read
+func (lc *localCache) evictArgs(req []byte) {
+	rd := resp.NewReader(bytes.NewReader(req))
+	for {
+		v, _, err := rd.ReadValue()
+		if err != nil {
+			return
+		}
+		args := v.Array()
+		if len(args) < 3 {
+			continue
+		}
+		ind, err := strconv.Atoi(args[len(args)-1].String())
+		if err != nil {
+			continue
+		}
+		for _, arg := range args[1 : len(args)-1] {
+			lc.evict(cacheKey{ind: ind, key: arg.String()})
+		}
+	}
+}
+
+// invalidate handles invalidation message
+func (lc *localCache) invalidate(msg []byte) {
+	if len(msg) == 0 {
+		lc.flush()
+		return
+	}
+	db, key, ok := bytes.Cut(msg, []byte(":"))
+	if !ok {
+		return
+	}
+	ind, err := strconv.Atoi(string(db))
+	if err != nil {
+		return
+	}
+	lc.mu.Lock()
+	lc.stats.Invalidations++
+	lc.mu.Unlock()
+	lc.evict(cacheKey{ind: ind, key: string(key)})
+}
+
+// connect dials connection that receives invalidations if it's not dialed or lost,
+// it returns true if new connection is dialed
+func (lc *localCache) connect(ctx context.Context, addr string, creds []byte) (bool, error) {
+	lc.mu.Lock()
+	connected := lc.conn != nil
+	lc.mu.Unlock()
+	if connected {
+		return false, nil
+	}
+	conn, err := dial(ctx, addr, creds)
+	if err != nil {
+		return false, err
+	}
+	id, err := clientID(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return false, err
+	}
+	if err := confirmSubscription(ctx, conn, CommandSubscribe, []string{invalidateChannel}); err != nil {
+		conn.Close()
+		return false, err
+	}
+	lc.mu.Lock()
+	lc.conn, lc.id = conn, id
+	lc.mu.Unlock()
+	go lc.readLoop(conn)
+	return true, nil
+}
+
+// readLoop reads invalidations until connection is lost, cache is flushed after that
+// as invalidations may be missed
+func (lc *localCache) readLoop(conn net.Conn) {
+	for {
+		list, err := readList(conn)
+		if err != nil {
+			break
+		}
+		if len(list) == 3 && string(list[0]) == pubSubMessage && string(list[1]) == invalidateChannel {
+			lc.invalidate(list[2])
+		}
+	}
+	lc.mu.Lock()
+	if lc.conn == conn {
+		lc.conn = nil
+	}
+	lc.mu.Unlock()
+	lc.flush()
+}
+
+func (lc *localCache) close() error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.conn == nil {
+		return nil
+	}
+	err := lc.conn.Close()
+	lc.conn = nil
+	return err
+}
+
+// clientID returns id of the connection
+func clientID(ctx context.Context, conn net.Conn) (string, error) {
+	if err := writeCommand(conn, CommandClient, "ID"); err != nil {
+		return "", err
+	}
+	ch := make(chan asyncResult[string], 1)
+	go func() {
+		id, err := readResult(conn)
+		ch <- asyncResult[string]{val: id, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		return "", ErrTimeIsOut
+	case res := <-ch:
+		return res.val, res.err
+	}
+}
+
+// track enables tracking of the current connection with invalidations redirected to connection
+// of the local cache, it must be called with connLock held
+func (c *Client) track(ctx context.Context) error {
+	dialed, err := c.local.connect(ctx, c.addr, credentials(c.user, c.password))
+	if err != nil {
+		return err
+	}
+	if dialed {
+		c.local.tracked = false
+	}
+	if c.local.tracked {
+		return nil
+	}
+	c.local.mu.Lock()
+	id := c.local.id
+	c.local.mu.Unlock()
+	if err := c.writeCommand(ctx, CommandClient, c.local.opts.trackingArgs(id)...); err != nil {
+		return err
+	}
+	if _, err := c.waitForResult(ctx); err != nil {
+		return err
+	}
+	c.local.tracked = true
+	return nil
+}
+
+// getLocal returns value of the key from the local cache or reads it from the server and caches it,
+// it must be called with connLock held
+func (c *Client) getLocal(ctx context.Context, key string, ind int) ([]byte, error) {
+	k := cacheKey{ind: ind, key: key}
+	if val, ok := c.local.get(k); ok {
+		return val, nil
+	}
+	if err := c.track(ctx); err != nil {
+		return nil, err
+	}
+	c.local.startFill(k)
+	if err := c.writeRequest(ctx, CommandGet, ind, key); err != nil {
+		c.local.fill(k, nil, false)
+		return nil, err
+	}
+	val, err := c.waitForValue(ctx)
+	c.local.fill(k, val, err == nil)
+	return val, err
+}
+
+// LocalCacheStats returns counters of the local cache, zero stats are returned if it's disabled
+func (c *Client) LocalCacheStats() LocalCacheStats {
+	if c.local == nil {
+		return LocalCacheStats{}
+	}
+	c.local.mu.Lock()
+	defer c.local.mu.Unlock()
+	stats := c.local.stats
+	stats.Entries = len(c.local.entries)
+	return stats
+}
+
+// discardLocal drops the local cache after main connection is dialed again, tracking of the old
+// connection is lost on the server
+func (c *Client) discardLocal() {
+	if c.local == nil {
+		return
+	}
+	c.local.tracked = false
+	c.local.flush()
+}
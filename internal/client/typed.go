@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// BytesCommands are binary-safe commands Typed is built on, they are implemented by Client, Pool and ClusterClient
+type BytesCommands interface {
+	SetBytes(ctx context.Context, key string, value []byte, ind int) error
+	GetBytes(ctx context.Context, key string, ind int) ([]byte, error)
+	MSetBytes(ctx context.Context, pairs map[string][]byte, ind int) error
+	MGetBytes(ctx context.Context, keys []string, ind int) (map[string][]byte, error)
+	LPushBytes(ctx context.Context, key string, value []byte, ind int) error
+	GetLBytes(ctx context.Context, key string, ind int) ([][]byte, error)
+}
+
+// DecodeError is returned when value stored on the server can't be decoded by codec
+type DecodeError struct {
+	Key string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode value of key %q: %v", e.Key, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Typed stores values of type T encoded with codec, e.g.
+//
+//	users := client.NewTyped[User](cl, client.JSONCodec[User]{})
+//	err := users.Set(ctx, "user:1", User{Name: "Bob"}, 0)
+type Typed[T any] struct {
+	cl    BytesCommands
+	codec Codec[T]
+}
+
+// NewTyped returns Typed that stores values with cl, cl may be Client, Pool or ClusterClient
+func NewTyped[T any](cl BytesCommands, codec Codec[T]) *Typed[T] {
+	return &Typed[T]{cl: cl, codec: codec}
+}
+
+// Set sets key with encoded value in database ind
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, ind int) error {
+	data, err := t.encode(key, value)
+	if err != nil {
+		return err
+	}
+	return t.cl.SetBytes(ctx, key, data, ind)
+}
+
+// Get returns decoded value of the key, ErrNotFound is returned if key doesn't exist
+// and DecodeError if value can't be decoded
+func (t *Typed[T]) Get(ctx context.Context, key string, ind int) (T, error) {
+	data, err := t.cl.GetBytes(ctx, key, ind)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return t.decode(key, data)
+}
+
+// MSet sets values of several keys in database ind
+func (t *Typed[T]) MSet(ctx context.Context, values map[string]T, ind int) error {
+	pairs := make(map[string][]byte, len(values))
+	for key, value := range values {
+		data, err := t.encode(key, value)
+		if err != nil {
+			return err
+		}
+		pairs[key] = data
+	}
+	return t.cl.MSetBytes(ctx, pairs, ind)
+}
+
+// MGet returns decoded values of keys from database ind, keys that don't exist are missing in the result
+func (t *Typed[T]) MGet(ctx context.Context, keys []string, ind int) (map[string]T, error) {
+	pairs, err := t.cl.MGetBytes(ctx, keys, ind)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]T, len(pairs))
+	for key, data := range pairs {
+		value, err := t.decode(key, data)
+		if err != nil {
+			return nil, err
+		}
+		res[key] = value
+	}
+	return res, nil
+}
+
+// LPush pushes encoded value to list key in database ind, if list doesn't exists it will be created
+func (t *Typed[T]) LPush(ctx context.Context, key string, value T, ind int) error {
+	data, err := t.encode(key, value)
+	if err != nil {
+		return err
+	}
+	return t.cl.LPushBytes(ctx, key, data, ind)
+}
+
+// GetL returns decoded values of list key
+func (t *Typed[T]) GetL(ctx context.Context, key string, ind int) ([]T, error) {
+	list, err := t.cl.GetLBytes(ctx, key, ind)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]T, 0, len(list))
+	for _, data := range list {
+		value, err := t.decode(key, data)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, value)
+	}
+	return res, nil
+}
+
+func (t *Typed[T]) encode(key string, value T) ([]byte, error) {
+	data, err := t.codec.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value of key %q: %w", key, err)
+	}
+	return data, nil
+}
+
+func (t *Typed[T]) decode(key string, data []byte) (T, error) {
+	value, err := t.codec.Decode(data)
+	if err != nil {
+		var zero T
+		return zero, &DecodeError{Key: key, Err: err}
+	}
+	return value, nil
+}
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/tidwall/resp"
+)
+
+// Subcommands of ACL
+const (
+	ACLSetUser = "SETUSER"
+	ACLGetUser = "GETUSER"
+	ACLList    = "LIST"
+	ACLDelUser = "DELUSER"
+	ACLWhoAmI  = "WHOAMI"
+)
+
+// DefaultUser is a user authenticated by AUTH without user name and by the password sent
+// instead of AUTH when connection is opened
+const DefaultUser = "default"
+
+// AuthCommand authenticates connection as User
+type AuthCommand struct {
+	User     string
+	Password string
+}
+
+func (AuthCommand) Name() string { return CommandAuth }
+
+// ACLCommand manages users, Args are user name and rules for SETUSER, user name for GETUSER
+// and user names for DELUSER
+type ACLCommand struct {
+	Subcommand string
+	Args       []string
+}
+
+func (ACLCommand) Name() string { return CommandACL }
+
+// parseAuth parses AUTH password and AUTH user password
+func parseAuth(args []resp.Value) (Command, error) {
+	switch len(args) {
+	case 2:
+		return AuthCommand{User: DefaultUser, Password: args[1].String()}, nil
+	case 3:
+		return AuthCommand{User: args[1].String(), Password: args[2].String()}, nil
+	}
+	return nil, ErrUnknownCommandArguments
+}
+
+// parseACL parses ACL SETUSER user [rule ...], ACL GETUSER user, ACL LIST, ACL DELUSER user [user ...]
+// and ACL WHOAMI
+func parseACL(args []resp.Value) (Command, error) {
+	cmd := ACLCommand{Subcommand: strings.ToUpper(args[1].String())}
+	for _, arg := range args[2:] {
+		cmd.Args = append(cmd.Args, arg.String())
+	}
+	switch cmd.Subcommand {
+	case ACLSetUser, ACLDelUser:
+		if len(cmd.Args) == 0 {
+			return nil, ErrUnknownCommandArguments
+		}
+	case ACLGetUser:
+		if len(cmd.Args) != 1 {
+			return nil, ErrUnknownCommandArguments
+		}
+	case ACLList, ACLWhoAmI:
+		if len(cmd.Args) != 0 {
+			return nil, ErrUnknownCommandArguments
+		}
+	default:
+		return nil, ErrUnknownCommandArguments
+	}
+	return cmd, nil
+}
@@ -0,0 +1,84 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/resp"
+)
+
+// ClusterCommand is a cluster management command, e.g. CLUSTER ADDSLOTS slot [slot ...]
+type ClusterCommand struct {
+	Subcommand string
+	Args       []string
+}
+
+// AskingCommand allows the next command to access a slot that is being imported
+type AskingCommand struct {
+}
+
+// MigrateCommand moves key of database Index to the node with address Host:Port
+type MigrateCommand struct {
+	Host, Port string
+	Key        []byte
+	Index      int
+	// Timeout is a timeout of the transfer in milliseconds
+	Timeout int
+}
+
+// UnlinkCommand deletes key of any type
+type UnlinkCommand struct {
+	Key   []byte
+	Index int
+}
+
+func (ClusterCommand) Name() string { return CommandCluster }
+func (AskingCommand) Name() string  { return CommandAsking }
+func (MigrateCommand) Name() string { return CommandMigrate }
+func (UnlinkCommand) Name() string  { return CommandUnlink }
+
+// parseCluster parses CLUSTER subcommand [arg ...]
+func parseCluster(args []resp.Value) (Command, error) {
+	if len(args) < 2 {
+		return nil, ErrUnknownCommandArguments
+	}
+	cmd := ClusterCommand{Subcommand: strings.ToUpper(args[1].String())}
+	for _, arg := range args[2:] {
+		cmd.Args = append(cmd.Args, arg.String())
+	}
+	return cmd, nil
+}
+
+// parseMigrate parses MIGRATE host port key index timeout
+func parseMigrate(args []resp.Value) (Command, error) {
+	if len(args) != 6 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[4])
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := strconv.Atoi(args[5].String())
+	if err != nil || timeout < 0 {
+		return nil, ErrUnknownCommandArguments
+	}
+	return MigrateCommand{
+		Host:    args[1].String(),
+		Port:    args[2].String(),
+		Key:     args[3].Bytes(),
+		Index:   ind,
+		Timeout: timeout,
+	}, nil
+}
+
+// parseUnlink parses UNLINK key index
+func parseUnlink(args []resp.Value) (Command, error) {
+	if len(args) != 3 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[2])
+	if err != nil {
+		return nil, err
+	}
+	return UnlinkCommand{Key: args[1].Bytes(), Index: ind}, nil
+}
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
-	"strconv"
+	"time"
 
 	"github.com/tidwall/resp"
 )
@@ -23,14 +23,55 @@ var (
 	CommandDelElemL            = "DELELEML"
 	CommandDelAll              = "DELALL"
 	CommandStop                = "STOP"
+	CommandGeoAdd              = "GEOADD"
+	CommandGeoDist             = "GEODIST"
+	CommandGeoPos              = "GEOPOS"
+	CommandGeoSearch           = "GEOSEARCH"
+	CommandJSONSet             = "JSON.SET"
+	CommandJSONGet             = "JSON.GET"
+	CommandJSONDel             = "JSON.DEL"
+	CommandJSONNumIncrBy       = "JSON.NUMINCRBY"
+	CommandJSONArrAppend       = "JSON.ARRAPPEND"
+	CommandJSONType            = "JSON.TYPE"
+	CommandSubscribe           = "SUBSCRIBE"
+	CommandPSubscribe          = "PSUBSCRIBE"
+	CommandUnsubscribe         = "UNSUBSCRIBE"
+	CommandPUnsubscribe        = "PUNSUBSCRIBE"
+	CommandPublish             = "PUBLISH"
+	CommandReplicaOf           = "REPLICAOF"
+	CommandPSync               = "PSYNC"
+	CommandReplConf            = "REPLCONF"
+	CommandRole                = "ROLE"
+	CommandInfo                = "INFO"
+	CommandPing                = "PING"
+	CommandSentinel            = "SENTINEL"
+	CommandCluster             = "CLUSTER"
+	CommandAsking              = "ASKING"
+	CommandMigrate             = "MIGRATE"
+	CommandUnlink              = "UNLINK"
+	CommandMSet                = "MSET"
+	CommandMGet                = "MGET"
+	CommandDelIfEq             = "DELIFEQ"
+	CommandPExpire             = "PEXPIRE"
+	CommandPExpireAt           = "PEXPIREAT"
+	CommandPTTL                = "PTTL"
+	CommandIncr                = "INCR"
+	CommandRateLimit           = "RATELIMIT"
+	CommandClient              = "CLIENT"
+	CommandCommand             = "COMMAND"
+	CommandAuth                = "AUTH"
+	CommandACL                 = "ACL"
 	ErrUnknownCommand          = errors.New("unknown command")
 	ErrUnknownCommandArguments = errors.New("unknown command arguments")
 	ErrInvalidIndexValue       = errors.New("invalid index value")
 )
 
+// Command is a parsed command, its Name is used to find Spec of the command
 type Command interface {
-	// TODO
+	Name() string
 }
+
+// StopCommand marks the end of the recovery log
 type StopCommand struct {
 }
 type DelAllCommand struct {
@@ -52,6 +93,12 @@ type LPushCommand struct {
 type SetCommand struct {
 	Key, Val []byte
 	Index    int
+	// NX, XX and IfEq are conditions of SET, see parseSet
+	NX, XX bool
+	IfEq   []byte
+	// TTL is set by EX and PX, ExpireAt is set by EXAT and PXAT
+	TTL      time.Duration
+	ExpireAt time.Time
 }
 type GetLCommand struct {
 	Key   []byte
@@ -83,165 +130,114 @@ type DeleteCommand struct {
 	Index int
 }
 
+func (StopCommand) Name() string     { return CommandStop }
+func (DelAllCommand) Name() string   { return CommandDelAll }
+func (DeleteLCommand) Name() string  { return CommandDeleteL }
+func (DelElemLCommand) Name() string { return CommandDelElemL }
+func (LPushCommand) Name() string    { return CommandLPush }
+func (SetCommand) Name() string      { return CommandSet }
+func (GetLCommand) Name() string     { return CommandGetL }
+func (HasCommand) Name() string      { return CommandHas }
+func (GetCommand) Name() string      { return CommandGet }
+func (AddCommand) Name() string      { return CommandAdd }
+func (AddNCommand) Name() string     { return CommandAddN }
+func (HelloCommand) Name() string    { return CommandHello }
+func (DeleteCommand) Name() string   { return CommandDelete }
+
+// ParseCommand parses the first array of rawMsg, see ParseRequest
 func ParseCommand(rawMsg string) (Command, error) {
+	req, err := ParseRequest(rawMsg)
+	if err != nil {
+		return nil, err
+	}
+	return req.Command, nil
+}
+
+// ParseRequest parses the first array of rawMsg to a request
+func ParseRequest(rawMsg string) (Request, error) {
 	rd := resp.NewReader(bytes.NewBufferString(rawMsg))
 	for {
 		v, _, err := rd.ReadValue()
 		if err == io.EOF {
-			break
+			return Request{}, ErrUnknownCommand
 		}
 		if err != nil {
-			return nil, err
+			return Request{}, err
 		}
 		if v.Type() == resp.Array {
-			switch v.Array()[0].String() {
-			case CommandDelAll:
-				if len(v.Array()) != 4 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[3].String())
-				if err != nil {
-					return nil, err
-				}
-				return DelAllCommand{
-					Key:   v.Array()[1].Bytes(),
-					Val:   v.Array()[2].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandDeleteL:
-				if len(v.Array()) != 3 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[2].String())
-				if err != nil {
-					return nil, err
-				}
-				return DeleteLCommand{
-					Key:   v.Array()[1].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandDelElemL:
-				if len(v.Array()) != 4 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[3].String())
-				if err != nil {
-					return nil, err
-				}
-				return DelElemLCommand{
-					Key:   v.Array()[1].Bytes(),
-					Val:   v.Array()[2].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandLPush:
-				if len(v.Array()) != 4 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[3].String())
-				if err != nil {
-					return nil, err
-				}
-				return LPushCommand{
-					Key:   v.Array()[1].Bytes(),
-					Val:   v.Array()[2].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandHas:
-				if len(v.Array()) != 3 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[2].String())
-				if err != nil {
-					return nil, err
-				}
-				return HasCommand{
-					Key:   v.Array()[1].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandGetL:
-				if len(v.Array()) != 3 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[2].String())
-				if err != nil {
-					return nil, err
-				}
-				return GetLCommand{
-					Key:   v.Array()[1].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandSet:
-				if len(v.Array()) != 4 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[3].String())
-				if err != nil {
-					return nil, err
-				}
-				return SetCommand{
-					Key:   v.Array()[1].Bytes(),
-					Val:   v.Array()[2].Bytes(),
-					Index: ind,
-				}, nil
-
-			case CommandGet:
-				if len(v.Array()) != 3 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[2].String())
-				if err != nil {
-					return nil, err
-				}
-				return GetCommand{
-					Key:   v.Array()[1].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandAdd:
-				if len(v.Array()) != 3 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[2].String())
-				if err != nil {
-					return nil, err
-				}
-				return AddCommand{
-					Key:   v.Array()[1].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandAddN:
-				if len(v.Array()) != 4 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[3].String())
-				if err != nil {
-					return nil, err
-				}
-				return AddNCommand{
-					Key:   v.Array()[1].Bytes(),
-					Val:   v.Array()[2].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandDelete:
-				if len(v.Array()) != 3 {
-					return nil, ErrUnknownCommandArguments
-				}
-				ind, err := strconv.Atoi(v.Array()[2].String())
-				if err != nil {
-					return nil, err
-				}
-				return DeleteCommand{
-					Key:   v.Array()[1].Bytes(),
-					Index: ind,
-				}, nil
-			case CommandHello:
-				return HelloCommand{
-					value: v.Array()[1].String(),
-				}, nil
-
-			default:
-				return nil, ErrUnknownCommand
-			}
+			return NewRequest(v.Array())
 		}
 	}
-	return nil, ErrUnknownCommand
+}
+
+// parseKey parses commands with arguments key index, e.g. GET key index
+func parseKey(args []resp.Value) ([]byte, int, error) {
+	ind, err := parseIndex(args[2])
+	if err != nil {
+		return nil, 0, err
+	}
+	return args[1].Bytes(), ind, nil
+}
+
+// parseKeyVal parses commands with arguments key value index, e.g. LPUSH key value index
+func parseKeyVal(args []resp.Value) ([]byte, []byte, int, error) {
+	ind, err := parseIndex(args[3])
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return args[1].Bytes(), args[2].Bytes(), ind, nil
+}
+
+func parseGet(args []resp.Value) (Command, error) {
+	key, ind, err := parseKey(args)
+	return GetCommand{Key: key, Index: ind}, err
+}
+
+func parseAdd(args []resp.Value) (Command, error) {
+	key, ind, err := parseKey(args)
+	return AddCommand{Key: key, Index: ind}, err
+}
+
+func parseAddN(args []resp.Value) (Command, error) {
+	key, val, ind, err := parseKeyVal(args)
+	return AddNCommand{Key: key, Val: val, Index: ind}, err
+}
+
+func parseDelete(args []resp.Value) (Command, error) {
+	key, ind, err := parseKey(args)
+	return DeleteCommand{Key: key, Index: ind}, err
+}
+
+func parseLPush(args []resp.Value) (Command, error) {
+	key, val, ind, err := parseKeyVal(args)
+	return LPushCommand{Key: key, Val: val, Index: ind}, err
+}
+
+func parseGetL(args []resp.Value) (Command, error) {
+	key, ind, err := parseKey(args)
+	return GetLCommand{Key: key, Index: ind}, err
+}
+
+func parseHas(args []resp.Value) (Command, error) {
+	key, ind, err := parseKey(args)
+	return HasCommand{Key: key, Index: ind}, err
+}
+
+func parseDeleteL(args []resp.Value) (Command, error) {
+	key, ind, err := parseKey(args)
+	return DeleteLCommand{Key: key, Index: ind}, err
+}
+
+func parseDelElemL(args []resp.Value) (Command, error) {
+	key, val, ind, err := parseKeyVal(args)
+	return DelElemLCommand{Key: key, Val: val, Index: ind}, err
+}
+
+func parseDelAll(args []resp.Value) (Command, error) {
+	key, val, ind, err := parseKeyVal(args)
+	return DelAllCommand{Key: key, Val: val, Index: ind}, err
+}
+
+func parseHello(args []resp.Value) (Command, error) {
+	return HelloCommand{value: args[1].String()}, nil
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/resp"
 )
 
 func Test_ParseCommmand(t *testing.T) {
@@ -16,3 +17,66 @@ func Test_ParseCommmand(t *testing.T) {
 	require.Nil(t, err)
 	fmt.Println(command)
 }
+
+func Test_Registry(t *testing.T) {
+	for _, spec := range Specs() {
+		require.NotNil(t, spec.Parse, spec.Name)
+		require.Equal(t, spec.Has(FlagWrite), spec.Apply != nil, spec.Name)
+		require.False(t, spec.Has(FlagWrite|FlagReadOnly), spec.Name)
+		// commands without flags are in no category of ACL rules except @all
+		require.NotZero(t, spec.Flags, spec.Name)
+		require.Equal(t, spec.FirstKey == 0, spec.IndexArg == 0, spec.Name)
+		require.Same(t, spec, Lookup(spec.Name))
+	}
+	require.Nil(t, Lookup("get"))
+}
+
+func Test_NewRequest(t *testing.T) {
+	args := func(vals ...string) []resp.Value {
+		res := make([]resp.Value, 0, len(vals))
+		for _, val := range vals {
+			res = append(res, resp.StringValue(val))
+		}
+		return res
+	}
+	tests := []struct {
+		name  string
+		args  []resp.Value
+		keys  []string
+		index int
+		err   error
+	}{
+		{name: "single key", args: args("GET", "key", "3"), keys: []string{"key"}, index: 3},
+		{name: "options", args: args("SET", "key", "val", "PX", "100", "1"), keys: []string{"key"}, index: 1},
+		{name: "key value pairs", args: args("MSET", "a", "1", "b", "2", "0"), keys: []string{"a", "b"}},
+		{name: "several keys", args: args("MGET", "a", "b", "c", "2"), keys: []string{"a", "b", "c"}, index: 2},
+		{name: "key after algorithm", args: args("RATELIMIT", "FIXED", "key", "1", "1000", "0"), keys: []string{"key"}},
+		{name: "index before timeout", args: args("MIGRATE", "host", "7000", "key", "4", "100"), keys: []string{"key"}, index: 4},
+		{name: "no database", args: args("PUBLISH", "channel", "msg"), index: -1},
+		{name: "unknown command", args: args("NOPE", "key", "0"), err: ErrUnknownCommand},
+		{name: "empty", args: args(), err: ErrUnknownCommand},
+		{name: "wrong arity", args: args("GET", "key"), err: ErrUnknownCommandArguments},
+		{name: "too few arguments", args: args("HELLO"), err: ErrUnknownCommandArguments},
+		{name: "invalid index", args: args("GET", "key", "x"), err: ErrInvalidIndexValue},
+		{name: "password only", args: args("AUTH", "pass"), index: -1},
+		{name: "too many credentials", args: args("AUTH", "user", "pass", "extra"), err: ErrUnknownCommandArguments},
+		{name: "unknown subcommand", args: args("ACL", "NOPE"), err: ErrUnknownCommandArguments},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := NewRequest(tt.args)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, req.Spec.Name, req.Command.Name())
+			var keys []string
+			for _, key := range req.Keys() {
+				keys = append(keys, string(key))
+			}
+			require.Equal(t, tt.keys, keys)
+			require.Equal(t, tt.index, req.Index())
+		})
+	}
+}
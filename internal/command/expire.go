@@ -0,0 +1,199 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+	"github.com/tidwall/resp"
+)
+
+// DelIfEqCommand deletes key if its value is equal to Val
+type DelIfEqCommand struct {
+	Key, Val []byte
+	Index    int
+}
+
+// PExpireCommand sets expiration of the key, TTL is set by PEXPIRE and ExpireAt by PEXPIREAT
+type PExpireCommand struct {
+	Key      []byte
+	TTL      time.Duration
+	ExpireAt time.Time
+	Index    int
+}
+
+// PTTLCommand returns time to live of the key in milliseconds
+type PTTLCommand struct {
+	Key   []byte
+	Index int
+}
+
+// IncrCommand increments integer value of the key, key that doesn't exist is set to 1
+type IncrCommand struct {
+	Key   []byte
+	Index int
+}
+
+func (DelIfEqCommand) Name() string { return CommandDelIfEq }
+func (PTTLCommand) Name() string    { return CommandPTTL }
+func (IncrCommand) Name() string    { return CommandIncr }
+
+// Name returns PEXPIRE for relative expiration and PEXPIREAT for absolute one
+func (cmd PExpireCommand) Name() string {
+	if cmd.TTL > 0 {
+		return CommandPExpire
+	}
+	return CommandPExpireAt
+}
+
+// Options converts conditions and expiration of SET, relative TTL is counted from now
+func (cmd SetCommand) Options(now time.Time) storage.SetOptions {
+	opts := storage.SetOptions{
+		NX:       cmd.NX,
+		XX:       cmd.XX,
+		IfEq:     cmd.IfEq,
+		ExpireAt: cmd.ExpireAt,
+	}
+	if cmd.TTL > 0 {
+		opts.ExpireAt = now.Add(cmd.TTL)
+	}
+	return opts
+}
+
+// At returns expiration time of the key, relative TTL is counted from now
+func (cmd PExpireCommand) At(now time.Time) time.Time {
+	if cmd.TTL > 0 {
+		return now.Add(cmd.TTL)
+	}
+	return cmd.ExpireAt
+}
+
+// parseSet parses SET key value [NX | XX] [IFEQ value] [EX seconds | PX milliseconds |
+// EXAT unix-seconds | PXAT unix-milliseconds] index, NX can't be combined with XX and IFEQ
+func parseSet(args []resp.Value) (Command, error) {
+	if len(args) < 4 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	cmd := SetCommand{
+		Key:   args[1].Bytes(),
+		Val:   args[2].Bytes(),
+		Index: ind,
+	}
+	expiry := false
+	opts := args[3 : len(args)-1]
+	for i := 0; i < len(opts); i++ {
+		opt := strings.ToUpper(opts[i].String())
+		switch opt {
+		case "NX":
+			cmd.NX = true
+			continue
+		case "XX":
+			cmd.XX = true
+			continue
+		case "IFEQ", "EX", "PX", "EXAT", "PXAT":
+		default:
+			return nil, ErrUnknownCommandArguments
+		}
+		if i+1 == len(opts) {
+			return nil, ErrUnknownCommandArguments
+		}
+		i++
+		if opt == "IFEQ" {
+			cmd.IfEq = opts[i].Bytes()
+			continue
+		}
+		if expiry {
+			return nil, ErrUnknownCommandArguments
+		}
+		expiry = true
+		n, err := parsePositive(opts[i])
+		if err != nil {
+			return nil, err
+		}
+		switch opt {
+		case "EX":
+			cmd.TTL = time.Duration(n) * time.Second
+		case "PX":
+			cmd.TTL = time.Duration(n) * time.Millisecond
+		case "EXAT":
+			cmd.ExpireAt = time.Unix(n, 0)
+		case "PXAT":
+			cmd.ExpireAt = time.UnixMilli(n)
+		}
+	}
+	if cmd.NX && (cmd.XX || cmd.IfEq != nil) {
+		return nil, ErrUnknownCommandArguments
+	}
+	return cmd, nil
+}
+
+// parseDelIfEq parses DELIFEQ key value index
+func parseDelIfEq(args []resp.Value) (Command, error) {
+	if len(args) != 4 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[3])
+	if err != nil {
+		return nil, err
+	}
+	return DelIfEqCommand{Key: args[1].Bytes(), Val: args[2].Bytes(), Index: ind}, nil
+}
+
+// parsePExpire parses PEXPIRE key milliseconds index and PEXPIREAT key unix-milliseconds index
+func parsePExpire(args []resp.Value) (Command, error) {
+	if len(args) != 4 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[3])
+	if err != nil {
+		return nil, err
+	}
+	n, err := parsePositive(args[2])
+	if err != nil {
+		return nil, err
+	}
+	cmd := PExpireCommand{Key: args[1].Bytes(), Index: ind}
+	if args[0].String() == CommandPExpireAt {
+		cmd.ExpireAt = time.UnixMilli(n)
+	} else {
+		cmd.TTL = time.Duration(n) * time.Millisecond
+	}
+	return cmd, nil
+}
+
+// parsePTTL parses PTTL key index
+func parsePTTL(args []resp.Value) (Command, error) {
+	if len(args) != 3 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[2])
+	if err != nil {
+		return nil, err
+	}
+	return PTTLCommand{Key: args[1].Bytes(), Index: ind}, nil
+}
+
+// parseIncr parses INCR key index
+func parseIncr(args []resp.Value) (Command, error) {
+	if len(args) != 3 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[2])
+	if err != nil {
+		return nil, err
+	}
+	return IncrCommand{Key: args[1].Bytes(), Index: ind}, nil
+}
+
+func parsePositive(v resp.Value) (int64, error) {
+	n, err := strconv.ParseInt(v.String(), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, ErrUnknownCommandArguments
+	}
+	return n, nil
+}
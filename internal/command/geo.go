@@ -0,0 +1,286 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+	"github.com/tidwall/resp"
+)
+
+const (
+	GeoSortAsc  = "ASC"
+	GeoSortDesc = "DESC"
+)
+
+// geoUnits contains number of meters in supported distance units
+var geoUnits = map[string]float64{
+	"m":  1,
+	"km": 1000,
+	"mi": 1609.34,
+	"ft": 0.3048,
+}
+
+type GeoLocation struct {
+	Longitude float64
+	Latitude  float64
+	Member    []byte
+}
+type GeoAddCommand struct {
+	Key       []byte
+	Locations []GeoLocation
+	Index     int
+}
+type GeoDistCommand struct {
+	Key              []byte
+	Member1, Member2 []byte
+	// Unit is a number of meters in the unit of the result
+	Unit  float64
+	Index int
+}
+type GeoPosCommand struct {
+	Key     []byte
+	Members [][]byte
+	Index   int
+}
+type GeoSearchCommand struct {
+	Key        []byte
+	FromMember []byte
+	Longitude  float64
+	Latitude   float64
+	// Radius, Width and Height are in meters, Radius is zero for search by box
+	Radius    float64
+	Width     float64
+	Height    float64
+	Unit      float64
+	Sort      string
+	Count     int
+	Any       bool
+	WithCoord bool
+	WithDist  bool
+	WithHash  bool
+	Index     int
+}
+
+func (GeoAddCommand) Name() string    { return CommandGeoAdd }
+func (GeoDistCommand) Name() string   { return CommandGeoDist }
+func (GeoPosCommand) Name() string    { return CommandGeoPos }
+func (GeoSearchCommand) Name() string { return CommandGeoSearch }
+
+// GeoPoints converts command locations to storage points
+func GeoPoints(locations []GeoLocation) []storage.GeoPoint {
+	points := make([]storage.GeoPoint, 0, len(locations))
+	for _, l := range locations {
+		points = append(points, storage.GeoPoint{
+			Member:    l.Member,
+			Longitude: l.Longitude,
+			Latitude:  l.Latitude,
+		})
+	}
+	return points
+}
+
+func parseIndex(v resp.Value) (int, error) {
+	ind, err := strconv.Atoi(v.String())
+	if err != nil {
+		return 0, ErrInvalidIndexValue
+	}
+	return ind, nil
+}
+
+func parseFloat(v resp.Value) (float64, error) {
+	f, err := strconv.ParseFloat(v.String(), 64)
+	if err != nil {
+		return 0, ErrUnknownCommandArguments
+	}
+	return f, nil
+}
+
+func parseUnit(v resp.Value) (float64, error) {
+	unit, ok := geoUnits[strings.ToLower(v.String())]
+	if !ok {
+		return 0, ErrUnknownCommandArguments
+	}
+	return unit, nil
+}
+
+// parseGeoAdd parses GEOADD key longitude latitude member [longitude latitude member ...] index
+func parseGeoAdd(args []resp.Value) (Command, error) {
+	if len(args) < 6 || (len(args)-3)%3 != 0 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	cmd := GeoAddCommand{
+		Key:   args[1].Bytes(),
+		Index: ind,
+	}
+	for i := 2; i < len(args)-1; i += 3 {
+		lon, err := parseFloat(args[i])
+		if err != nil {
+			return nil, err
+		}
+		lat, err := parseFloat(args[i+1])
+		if err != nil {
+			return nil, err
+		}
+		cmd.Locations = append(cmd.Locations, GeoLocation{
+			Longitude: lon,
+			Latitude:  lat,
+			Member:    args[i+2].Bytes(),
+		})
+	}
+	return cmd, nil
+}
+
+// parseGeoDist parses GEODIST key member1 member2 [unit] index
+func parseGeoDist(args []resp.Value) (Command, error) {
+	if len(args) != 5 && len(args) != 6 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	unit := 1.0
+	if len(args) == 6 {
+		unit, err = parseUnit(args[4])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return GeoDistCommand{
+		Key:     args[1].Bytes(),
+		Member1: args[2].Bytes(),
+		Member2: args[3].Bytes(),
+		Unit:    unit,
+		Index:   ind,
+	}, nil
+}
+
+// parseGeoPos parses GEOPOS key member [member ...] index
+func parseGeoPos(args []resp.Value) (Command, error) {
+	if len(args) < 4 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	cmd := GeoPosCommand{
+		Key:   args[1].Bytes(),
+		Index: ind,
+	}
+	for _, m := range args[2 : len(args)-1] {
+		cmd.Members = append(cmd.Members, m.Bytes())
+	}
+	return cmd, nil
+}
+
+// parseGeoSearch parses GEOSEARCH key <FROMMEMBER member | FROMLONLAT longitude latitude>
+// <BYRADIUS radius unit | BYBOX width height unit> [ASC | DESC] [COUNT count [ANY]]
+// [WITHCOORD] [WITHDIST] [WITHHASH] index
+func parseGeoSearch(args []resp.Value) (Command, error) {
+	if len(args) < 7 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	cmd := GeoSearchCommand{
+		Key:   args[1].Bytes(),
+		Index: ind,
+	}
+	var hasFrom, hasBy bool
+	opts := args[2 : len(args)-1]
+	for i := 0; i < len(opts); i++ {
+		left := len(opts) - i - 1
+		switch strings.ToUpper(opts[i].String()) {
+		case "FROMMEMBER":
+			if hasFrom || left < 1 {
+				return nil, ErrUnknownCommandArguments
+			}
+			cmd.FromMember = opts[i+1].Bytes()
+			hasFrom = true
+			i++
+		case "FROMLONLAT":
+			if hasFrom || left < 2 {
+				return nil, ErrUnknownCommandArguments
+			}
+			if cmd.Longitude, err = parseFloat(opts[i+1]); err != nil {
+				return nil, err
+			}
+			if cmd.Latitude, err = parseFloat(opts[i+2]); err != nil {
+				return nil, err
+			}
+			hasFrom = true
+			i += 2
+		case "BYRADIUS":
+			if hasBy || left < 2 {
+				return nil, ErrUnknownCommandArguments
+			}
+			radius, err := parseFloat(opts[i+1])
+			if err != nil || radius <= 0 {
+				return nil, ErrUnknownCommandArguments
+			}
+			if cmd.Unit, err = parseUnit(opts[i+2]); err != nil {
+				return nil, err
+			}
+			cmd.Radius = radius * cmd.Unit
+			hasBy = true
+			i += 2
+		case "BYBOX":
+			if hasBy || left < 3 {
+				return nil, ErrUnknownCommandArguments
+			}
+			width, err := parseFloat(opts[i+1])
+			if err != nil || width <= 0 {
+				return nil, ErrUnknownCommandArguments
+			}
+			height, err := parseFloat(opts[i+2])
+			if err != nil || height <= 0 {
+				return nil, ErrUnknownCommandArguments
+			}
+			if cmd.Unit, err = parseUnit(opts[i+3]); err != nil {
+				return nil, err
+			}
+			cmd.Width = width * cmd.Unit
+			cmd.Height = height * cmd.Unit
+			hasBy = true
+			i += 3
+		case GeoSortAsc:
+			cmd.Sort = GeoSortAsc
+		case GeoSortDesc:
+			cmd.Sort = GeoSortDesc
+		case "COUNT":
+			if left < 1 {
+				return nil, ErrUnknownCommandArguments
+			}
+			count, err := strconv.Atoi(opts[i+1].String())
+			if err != nil || count <= 0 {
+				return nil, ErrUnknownCommandArguments
+			}
+			cmd.Count = count
+			i++
+			if i+1 < len(opts) && strings.ToUpper(opts[i+1].String()) == "ANY" {
+				cmd.Any = true
+				i++
+			}
+		case "WITHCOORD":
+			cmd.WithCoord = true
+		case "WITHDIST":
+			cmd.WithDist = true
+		case "WITHHASH":
+			cmd.WithHash = true
+		default:
+			return nil, ErrUnknownCommandArguments
+		}
+	}
+	if !hasFrom || !hasBy {
+		return nil, ErrUnknownCommandArguments
+	}
+	return cmd, nil
+}
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/tidwall/resp"
+)
+
+// Subcommands of COMMAND, empty subcommand lists all commands
+const (
+	IntrospectionCount   = "COUNT"
+	IntrospectionInfo    = "INFO"
+	IntrospectionDocs    = "DOCS"
+	IntrospectionGetKeys = "GETKEYS"
+)
+
+// flagNames are names of flags reported by COMMAND
+var flagNames = []struct {
+	flag Flags
+	name string
+}{
+	{FlagWrite, "write"},
+	{FlagReadOnly, "readonly"},
+	{FlagAdmin, "admin"},
+	{FlagBlocking, "blocking"},
+	{FlagPubSub, "pubsub"},
+	{FlagConnection, "connection"},
+	{FlagNoReply, "noreply"},
+}
+
+// IntrospectionCommand describes commands of the server, Args are names of commands for INFO and DOCS
+// and a full command for GETKEYS
+type IntrospectionCommand struct {
+	Subcommand string
+	Args       [][]byte
+}
+
+func (IntrospectionCommand) Name() string { return CommandCommand }
+
+// Names returns names of the flags, e.g. "write"
+func (f Flags) Names() []string {
+	var res []string
+	for _, fn := range flagNames {
+		if f&fn.flag != 0 {
+			res = append(res, fn.name)
+		}
+	}
+	return res
+}
+
+// parseIntrospection parses COMMAND, COMMAND COUNT, COMMAND INFO [name ...], COMMAND DOCS [name ...]
+// and COMMAND GETKEYS command [arg ...]
+func parseIntrospection(args []resp.Value) (Command, error) {
+	if len(args) == 1 {
+		return IntrospectionCommand{}, nil
+	}
+	cmd := IntrospectionCommand{Subcommand: strings.ToUpper(args[1].String())}
+	for _, arg := range args[2:] {
+		cmd.Args = append(cmd.Args, arg.Bytes())
+	}
+	switch cmd.Subcommand {
+	case IntrospectionCount:
+		if len(cmd.Args) != 0 {
+			return nil, ErrUnknownCommandArguments
+		}
+	case IntrospectionInfo, IntrospectionDocs:
+	case IntrospectionGetKeys:
+		if len(cmd.Args) == 0 {
+			return nil, ErrUnknownCommandArguments
+		}
+	default:
+		return nil, ErrUnknownCommandArguments
+	}
+	return cmd, nil
+}
@@ -0,0 +1,157 @@
+package command
+
+import "github.com/tidwall/resp"
+
+const (
+	// JSONRootPath is used when JSON.DEL path is omitted
+	JSONRootPath = "$"
+	// JSONLegacyRootPath is used when JSON.GET or JSON.TYPE path is omitted
+	JSONLegacyRootPath = "."
+)
+
+type JSONSetCommand struct {
+	Key, Path, Val []byte
+	Index          int
+}
+type JSONGetCommand struct {
+	Key   []byte
+	Paths [][]byte
+	Index int
+}
+type JSONDelCommand struct {
+	Key, Path []byte
+	Index     int
+}
+type JSONNumIncrByCommand struct {
+	Key, Path, Val []byte
+	Index          int
+}
+type JSONArrAppendCommand struct {
+	Key, Path []byte
+	Values    [][]byte
+	Index     int
+}
+type JSONTypeCommand struct {
+	Key, Path []byte
+	Index     int
+}
+
+func (JSONSetCommand) Name() string       { return CommandJSONSet }
+func (JSONGetCommand) Name() string       { return CommandJSONGet }
+func (JSONDelCommand) Name() string       { return CommandJSONDel }
+func (JSONNumIncrByCommand) Name() string { return CommandJSONNumIncrBy }
+func (JSONArrAppendCommand) Name() string { return CommandJSONArrAppend }
+func (JSONTypeCommand) Name() string      { return CommandJSONType }
+
+// parseJSONSet parses JSON.SET key path value index
+func parseJSONSet(args []resp.Value) (Command, error) {
+	if len(args) != 5 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[4])
+	if err != nil {
+		return nil, err
+	}
+	return JSONSetCommand{
+		Key:   args[1].Bytes(),
+		Path:  args[2].Bytes(),
+		Val:   args[3].Bytes(),
+		Index: ind,
+	}, nil
+}
+
+// parseJSONGet parses JSON.GET key [path ...] index
+func parseJSONGet(args []resp.Value) (Command, error) {
+	if len(args) < 3 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	cmd := JSONGetCommand{
+		Key:   args[1].Bytes(),
+		Index: ind,
+	}
+	for _, p := range args[2 : len(args)-1] {
+		cmd.Paths = append(cmd.Paths, p.Bytes())
+	}
+	return cmd, nil
+}
+
+// parseJSONDel parses JSON.DEL key [path] index
+func parseJSONDel(args []resp.Value) (Command, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	path := []byte(JSONRootPath)
+	if len(args) == 4 {
+		path = args[2].Bytes()
+	}
+	return JSONDelCommand{
+		Key:   args[1].Bytes(),
+		Path:  path,
+		Index: ind,
+	}, nil
+}
+
+// parseJSONNumIncrBy parses JSON.NUMINCRBY key path value index
+func parseJSONNumIncrBy(args []resp.Value) (Command, error) {
+	if len(args) != 5 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[4])
+	if err != nil {
+		return nil, err
+	}
+	return JSONNumIncrByCommand{
+		Key:   args[1].Bytes(),
+		Path:  args[2].Bytes(),
+		Val:   args[3].Bytes(),
+		Index: ind,
+	}, nil
+}
+
+// parseJSONArrAppend parses JSON.ARRAPPEND key path value [value ...] index
+func parseJSONArrAppend(args []resp.Value) (Command, error) {
+	if len(args) < 5 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	cmd := JSONArrAppendCommand{
+		Key:   args[1].Bytes(),
+		Path:  args[2].Bytes(),
+		Index: ind,
+	}
+	for _, v := range args[3 : len(args)-1] {
+		cmd.Values = append(cmd.Values, v.Bytes())
+	}
+	return cmd, nil
+}
+
+// parseJSONType parses JSON.TYPE key [path] index
+func parseJSONType(args []resp.Value) (Command, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	path := []byte(JSONLegacyRootPath)
+	if len(args) == 4 {
+		path = args[2].Bytes()
+	}
+	return JSONTypeCommand{
+		Key:   args[1].Bytes(),
+		Path:  path,
+		Index: ind,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package command
+
+import "github.com/tidwall/resp"
+
+// MSetCommand sets values of several keys, Keys and Vals have the same length
+type MSetCommand struct {
+	Keys, Vals [][]byte
+	Index      int
+}
+
+// MGetCommand gets values of several keys
+type MGetCommand struct {
+	Keys  [][]byte
+	Index int
+}
+
+func (MSetCommand) Name() string { return CommandMSet }
+func (MGetCommand) Name() string { return CommandMGet }
+
+// parseMSet parses MSET key value [key value ...] index
+func parseMSet(args []resp.Value) (Command, error) {
+	if len(args) < 4 || len(args)%2 != 0 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	cmd := MSetCommand{Index: ind}
+	for i := 1; i < len(args)-1; i += 2 {
+		cmd.Keys = append(cmd.Keys, args[i].Bytes())
+		cmd.Vals = append(cmd.Vals, args[i+1].Bytes())
+	}
+	return cmd, nil
+}
+
+// parseMGet parses MGET key [key ...] index
+func parseMGet(args []resp.Value) (Command, error) {
+	if len(args) < 3 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	cmd := MGetCommand{Index: ind}
+	for _, arg := range args[1 : len(args)-1] {
+		cmd.Keys = append(cmd.Keys, arg.Bytes())
+	}
+	return cmd, nil
+}
@@ -0,0 +1,57 @@
+package command
+
+import "github.com/tidwall/resp"
+
+// Pub/sub commands are not bound to a database, so they don't have index argument
+
+type SubscribeCommand struct {
+	Channels [][]byte
+}
+type PSubscribeCommand struct {
+	Patterns [][]byte
+}
+type UnsubscribeCommand struct {
+	Channels [][]byte
+}
+type PUnsubscribeCommand struct {
+	Patterns [][]byte
+}
+type PublishCommand struct {
+	Channel, Message []byte
+}
+
+func (SubscribeCommand) Name() string    { return CommandSubscribe }
+func (PSubscribeCommand) Name() string   { return CommandPSubscribe }
+func (UnsubscribeCommand) Name() string  { return CommandUnsubscribe }
+func (PUnsubscribeCommand) Name() string { return CommandPUnsubscribe }
+func (PublishCommand) Name() string      { return CommandPublish }
+
+// parseSubscription parses SUBSCRIBE channel [channel ...], PSUBSCRIBE pattern [pattern ...],
+// UNSUBSCRIBE [channel ...] and PUNSUBSCRIBE [pattern ...]
+func parseSubscription(args []resp.Value) (Command, error) {
+	var names [][]byte
+	for _, arg := range args[1:] {
+		names = append(names, arg.Bytes())
+	}
+	switch args[0].String() {
+	case CommandSubscribe:
+		if len(names) == 0 {
+			return nil, ErrUnknownCommandArguments
+		}
+		return SubscribeCommand{Channels: names}, nil
+	case CommandPSubscribe:
+		if len(names) == 0 {
+			return nil, ErrUnknownCommandArguments
+		}
+		return PSubscribeCommand{Patterns: names}, nil
+	case CommandUnsubscribe:
+		return UnsubscribeCommand{Channels: names}, nil
+	default:
+		return PUnsubscribeCommand{Patterns: names}, nil
+	}
+}
+
+// parsePublish parses PUBLISH channel message
+func parsePublish(args []resp.Value) (Command, error) {
+	return PublishCommand{Channel: args[1].Bytes(), Message: args[2].Bytes()}, nil
+}
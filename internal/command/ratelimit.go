@@ -0,0 +1,71 @@
+package command
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+	"github.com/tidwall/resp"
+)
+
+// Algorithms of RATELIMIT
+const (
+	RateLimitFixed   = "FIXED"
+	RateLimitSliding = "SLIDING"
+	RateLimitBucket  = "BUCKET"
+)
+
+// RateLimitAlgorithms maps algorithms of RATELIMIT to algorithms of storage
+var RateLimitAlgorithms = map[string]storage.RateLimitAlgorithm{
+	RateLimitFixed:   storage.FixedWindow,
+	RateLimitSliding: storage.SlidingLog,
+	RateLimitBucket:  storage.TokenBucket,
+}
+
+// RateLimitCommand takes Cost requests from the limiter stored in Key, limiter allows Limit requests in Period
+type RateLimitCommand struct {
+	Algorithm string
+	Key       []byte
+	Limit     int64
+	Period    time.Duration
+	Cost      int64
+	Index     int
+}
+
+func (RateLimitCommand) Name() string { return CommandRateLimit }
+
+// parseRateLimit parses RATELIMIT FIXED|SLIDING|BUCKET key limit period-milliseconds [cost] index
+func parseRateLimit(args []resp.Value) (Command, error) {
+	if len(args) != 6 && len(args) != 7 {
+		return nil, ErrUnknownCommandArguments
+	}
+	ind, err := parseIndex(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	cmd := RateLimitCommand{
+		Algorithm: strings.ToUpper(args[1].String()),
+		Key:       args[2].Bytes(),
+		Cost:      1,
+		Index:     ind,
+	}
+	switch cmd.Algorithm {
+	case RateLimitFixed, RateLimitSliding, RateLimitBucket:
+	default:
+		return nil, ErrUnknownCommandArguments
+	}
+	if cmd.Limit, err = parsePositive(args[3]); err != nil {
+		return nil, err
+	}
+	period, err := parsePositive(args[4])
+	if err != nil {
+		return nil, err
+	}
+	cmd.Period = time.Duration(period) * time.Millisecond
+	if len(args) == 7 {
+		if cmd.Cost, err = parsePositive(args[5]); err != nil {
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
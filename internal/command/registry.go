@@ -0,0 +1,206 @@
+package command
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+	"github.com/tidwall/resp"
+)
+
+// Flags describe how command uses the server, they are shared by dispatching, recovery, replication and ACL checks
+type Flags uint
+
+const (
+	// FlagWrite marks commands that modify data set, such commands are written to the recovery log,
+	// propagated to replicas and rejected by replicas
+	FlagWrite Flags = 1 << iota
+	// FlagReadOnly marks commands that only read keys
+	FlagReadOnly
+	// FlagAdmin marks commands that manage replication, cluster and failover
+	FlagAdmin
+	// FlagBlocking marks commands that switch connection to a mode where it waits for pushed messages
+	FlagBlocking
+	// FlagPubSub marks commands of publish/subscribe
+	FlagPubSub
+	// FlagConnection marks commands that check or set up the connection
+	FlagConnection
+	// FlagNoReply marks commands that are never answered, their errors aren't written to the client either
+	FlagNoReply
+)
+
+// Spec describes a command, every command supported by the server has a spec in the registry
+type Spec struct {
+	Name string
+	// Arity is a number of arguments including the name, negative arity is a minimal number of arguments
+	Arity int
+	Flags Flags
+	// FirstKey, LastKey and KeyStep are positions of keys in arguments, negative LastKey is counted
+	// from the end, e.g. -2 is the argument before index. FirstKey is 0 for commands without keys
+	FirstKey, LastKey, KeyStep int
+	// IndexArg is a position of database index, -1 is the last argument. It's 0 for commands
+	// that are not bound to a database
+	IndexArg int
+	// Summary and Syntax document the command, they are reported by COMMAND DOCS
+	Summary, Syntax string
+	// Parse parses arguments of the command, their number is already checked against Arity
+	Parse func(args []resp.Value) (Command, error)
+	// Exec executes the command and writes response to the client from, it's nil for commands
+	// that are not executed by the data server, e.g. SENTINEL
+	Exec func(h Handler, from string, cmd Command) error
+	// Apply executes write command without response, it's used for data recovery, replication and
+	// by the embedded database. It's nil for commands that are never written to the recovery log
+	Apply func(st *storage.Storage, cmd Command) error
+}
+
+// Handler executes commands of clients, it's implemented by the server
+type Handler interface {
+	Set(from string, cmd SetCommand) error
+	Get(from string, key []byte, index int) error
+	MSet(from string, keys, vals [][]byte, index int) error
+	MGet(from string, keys [][]byte, index int) error
+	Add(from string, key []byte, index int) error
+	AddN(from string, key []byte, value []byte, index int) error
+	Delete(from string, key []byte, index int) error
+	Unlink(from string, key []byte, index int) error
+	DelIfEq(from string, key, val []byte, index int) error
+	PExpire(from string, cmd PExpireCommand) error
+	PTTL(from string, key []byte, index int) error
+	Incr(from string, key []byte, index int) error
+	RateLimit(from string, cmd RateLimitCommand) error
+	LPush(from string, key, val []byte, index int) error
+	GetL(from string, key []byte, index int) error
+	Has(from string, key []byte, index int) error
+	DeleteL(from string, key []byte, index int) error
+	DelElemL(from string, key []byte, value []byte, index int) error
+	DelAll(from string, key []byte, value []byte, index int) error
+	GeoAdd(from string, key []byte, locations []GeoLocation, index int) error
+	GeoDist(from string, key, member1, member2 []byte, unit float64, index int) error
+	GeoPos(from string, key []byte, members [][]byte, index int) error
+	GeoSearch(from string, cmd GeoSearchCommand) error
+	JSONSet(from string, key, path, value []byte, index int) error
+	JSONGet(from string, key []byte, paths [][]byte, index int) error
+	JSONDel(from string, key, path []byte, index int) error
+	JSONNumIncrBy(from string, key, path, value []byte, index int) error
+	JSONArrAppend(from string, key, path []byte, values [][]byte, index int) error
+	JSONType(from string, key, path []byte, index int) error
+	Subscribe(from string, channels [][]byte) error
+	PSubscribe(from string, patterns [][]byte) error
+	Unsubscribe(from string, channels [][]byte) error
+	PUnsubscribe(from string, patterns [][]byte) error
+	Publish(from string, channel, message []byte) error
+	ReplicaOf(from string, host, port string, noOne bool) error
+	ReplConf(from string, option, value string) error
+	PSync(from string, replID string, offset int64) error
+	Role(from string) error
+	Info(from string, section string) error
+	Ping(from string) error
+	Hello(from string) error
+	Cluster(from string, subcommand string, args []string) error
+	Asking(from string) error
+	Migrate(from string, cmd MigrateCommand) error
+	ClientID(from string) error
+	ClientTracking(from string, cmd ClientTrackingCommand) error
+	Command(from string, cmd IntrospectionCommand) error
+	Auth(from string, cmd AuthCommand) error
+	ACL(from string, cmd ACLCommand) error
+}
+
+// registry contains specs of all commands by name
+var registry = func() map[string]*Spec {
+	m := make(map[string]*Spec, len(specs))
+	for i := range specs {
+		m[specs[i].Name] = &specs[i]
+	}
+	return m
+}()
+
+// Lookup returns spec of the command with name, nil is returned for unknown command
+func Lookup(name string) *Spec {
+	return registry[name]
+}
+
+// Specs returns specs of all commands sorted by name
+func Specs() []*Spec {
+	res := make([]*Spec, 0, len(registry))
+	for _, spec := range registry {
+		res = append(res, spec)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Has reports whether all flags are set for the command
+func (s *Spec) Has(flags Flags) bool {
+	return s.Flags&flags == flags
+}
+
+// checkArity reports whether n arguments are accepted by the command
+func (s *Spec) checkArity(n int) bool {
+	if s.Arity < 0 {
+		return n >= -s.Arity
+	}
+	return n == s.Arity
+}
+
+// Request is a parsed command with its spec and raw arguments
+type Request struct {
+	Spec    *Spec
+	Args    []resp.Value
+	Command Command
+}
+
+// NewRequest finds spec of the command args[0], checks number of arguments and parses them
+func NewRequest(args []resp.Value) (Request, error) {
+	if len(args) == 0 {
+		return Request{}, ErrUnknownCommand
+	}
+	spec := Lookup(args[0].String())
+	if spec == nil {
+		return Request{}, ErrUnknownCommand
+	}
+	if !spec.checkArity(len(args)) {
+		return Request{}, ErrUnknownCommandArguments
+	}
+	cmd, err := spec.Parse(args)
+	if err != nil {
+		return Request{}, err
+	}
+	return Request{Spec: spec, Args: args, Command: cmd}, nil
+}
+
+// Keys returns keys accessed by the request, they are used to route commands in cluster mode,
+// to track keys read by clients and to check key patterns of ACL
+func (r Request) Keys() [][]byte {
+	return keys(r.Spec, r.Args)
+}
+
+// Index returns database index of the request, -1 is returned for commands
+// that are not bound to a database
+func (r Request) Index() int {
+	pos := r.Spec.IndexArg
+	if pos == 0 {
+		return -1
+	}
+	if pos < 0 {
+		pos += len(r.Args)
+	}
+	// index is already validated by the parser
+	ind, _ := strconv.Atoi(r.Args[pos].String())
+	return ind
+}
+
+func keys(spec *Spec, args []resp.Value) [][]byte {
+	if spec.FirstKey == 0 {
+		return nil
+	}
+	last := spec.LastKey
+	if last < 0 {
+		last += len(args)
+	}
+	var res [][]byte
+	for i := spec.FirstKey; i <= last && i < len(args); i += spec.KeyStep {
+		res = append(res, args[i].Bytes())
+	}
+	return res
+}
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/resp"
+)
+
+// Replication commands are not bound to a database, so they don't have index argument
+
+type ReplicaOfCommand struct {
+	Host, Port string
+	// NoOne is set by REPLICAOF NO ONE, it turns replica into a primary
+	NoOne bool
+}
+
+// PSyncCommand is sent by replica to start replication, ReplID "?" and Offset -1 request full sync
+type PSyncCommand struct {
+	ReplID string
+	Offset int64
+}
+type ReplConfCommand struct {
+	Option, Value string
+}
+type RoleCommand struct {
+}
+type InfoCommand struct {
+	Section string
+}
+
+func (ReplicaOfCommand) Name() string { return CommandReplicaOf }
+func (PSyncCommand) Name() string     { return CommandPSync }
+func (ReplConfCommand) Name() string  { return CommandReplConf }
+func (RoleCommand) Name() string      { return CommandRole }
+func (InfoCommand) Name() string      { return CommandInfo }
+
+// parseReplicaOf parses REPLICAOF host port and REPLICAOF NO ONE
+func parseReplicaOf(args []resp.Value) (Command, error) {
+	if len(args) != 3 {
+		return nil, ErrUnknownCommandArguments
+	}
+	host, port := args[1].String(), args[2].String()
+	if strings.EqualFold(host, "NO") && strings.EqualFold(port, "ONE") {
+		return ReplicaOfCommand{NoOne: true}, nil
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return nil, ErrUnknownCommandArguments
+	}
+	return ReplicaOfCommand{Host: host, Port: port}, nil
+}
+
+// parsePSync parses PSYNC replid offset
+func parsePSync(args []resp.Value) (Command, error) {
+	if len(args) != 3 {
+		return nil, ErrUnknownCommandArguments
+	}
+	offset, err := strconv.ParseInt(args[2].String(), 10, 64)
+	if err != nil {
+		return nil, ErrUnknownCommandArguments
+	}
+	return PSyncCommand{ReplID: args[1].String(), Offset: offset}, nil
+}
+
+// parseReplConf parses REPLCONF option value
+func parseReplConf(args []resp.Value) (Command, error) {
+	if len(args) != 3 {
+		return nil, ErrUnknownCommandArguments
+	}
+	return ReplConfCommand{Option: strings.ToLower(args[1].String()), Value: args[2].String()}, nil
+}
+
+// parseInfo parses INFO [section]
+func parseInfo(args []resp.Value) (Command, error) {
+	switch len(args) {
+	case 1:
+		return InfoCommand{}, nil
+	case 2:
+		return InfoCommand{Section: strings.ToLower(args[1].String())}, nil
+	}
+	return nil, ErrUnknownCommandArguments
+}
@@ -0,0 +1,31 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/tidwall/resp"
+)
+
+type PingCommand struct {
+}
+
+// SentinelCommand is a command of the failover coordinator, e.g. SENTINEL GET-MASTER-ADDR-BY-NAME name
+type SentinelCommand struct {
+	Subcommand string
+	Args       []string
+}
+
+func (PingCommand) Name() string     { return CommandPing }
+func (SentinelCommand) Name() string { return CommandSentinel }
+
+// parseSentinel parses SENTINEL subcommand [arg ...]
+func parseSentinel(args []resp.Value) (Command, error) {
+	if len(args) < 2 {
+		return nil, ErrUnknownCommandArguments
+	}
+	cmd := SentinelCommand{Subcommand: strings.ToUpper(args[1].String())}
+	for _, arg := range args[2:] {
+		cmd.Args = append(cmd.Args, arg.String())
+	}
+	return cmd, nil
+}
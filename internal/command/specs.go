@@ -0,0 +1,593 @@
+package command
+
+import (
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+	"github.com/tidwall/resp"
+)
+
+// specs is the table of all commands, commands bound to a database have index as the last argument
+// except MIGRATE which has timeout after it
+var specs = []Spec{
+	// strings and counters
+	{
+		Name: CommandSet, Arity: -4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Sets string value of the key, optionally with a condition and expiration",
+		Syntax:  "SET key value [NX | XX] [IFEQ value] [EX seconds | PX milliseconds | EXAT unix-seconds | PXAT unix-milliseconds] index",
+		Parse:   parseSet,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Set(from, cmd.(SetCommand))
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(SetCommand)
+			_, err := st.SetWith(v.Key, v.Val, v.Options(st.Now()), v.Index)
+			return err
+		},
+	},
+	{
+		Name: CommandGet, Arity: 3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Returns string value of the key",
+		Syntax:  "GET key index",
+		Parse:   parseGet,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(GetCommand)
+			return h.Get(from, v.Key, v.Index)
+		},
+	},
+	{
+		Name: CommandMSet, Arity: -4, Flags: FlagWrite, FirstKey: 1, LastKey: -3, KeyStep: 2, IndexArg: -1,
+		Summary: "Sets string values of several keys",
+		Syntax:  "MSET key value [key value ...] index",
+		Parse:   parseMSet,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(MSetCommand)
+			return h.MSet(from, v.Keys, v.Vals, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(MSetCommand)
+			for i := range v.Keys {
+				if err := st.Set(v.Keys[i], v.Vals[i], v.Index); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Name: CommandMGet, Arity: -3, Flags: FlagReadOnly, FirstKey: 1, LastKey: -2, KeyStep: 1, IndexArg: -1,
+		Summary: "Returns string values of several keys, missing keys are skipped",
+		Syntax:  "MGET key [key ...] index",
+		Parse:   parseMGet,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(MGetCommand)
+			return h.MGet(from, v.Keys, v.Index)
+		},
+	},
+	{
+		Name: CommandAdd, Arity: 3, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Increments integer value of the key by one",
+		Syntax:  "ADD key index",
+		Parse:   parseAdd,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(AddCommand)
+			return h.Add(from, v.Key, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(AddCommand)
+			return st.Add(v.Key, v.Index)
+		},
+	},
+	{
+		Name: CommandAddN, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Adds number to integer value of the key",
+		Syntax:  "ADDN key value index",
+		Parse:   parseAddN,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(AddNCommand)
+			return h.AddN(from, v.Key, v.Val, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(AddNCommand)
+			return st.AddN(v.Key, v.Val, v.Index)
+		},
+	},
+	{
+		Name: CommandIncr, Arity: 3, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Increments integer value of the key and returns the new value, missing key is set to 1",
+		Syntax:  "INCR key index",
+		Parse:   parseIncr,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(IncrCommand)
+			return h.Incr(from, v.Key, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(IncrCommand)
+			_, err := st.Incr(v.Key, v.Index)
+			return err
+		},
+	},
+	{
+		Name: CommandDelete, Arity: 3, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Deletes key of any type",
+		Syntax:  "DEL key index",
+		Parse:   parseDelete,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(DeleteCommand)
+			return h.Delete(from, v.Key, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(DeleteCommand)
+			return st.Delete(v.Key, v.Index)
+		},
+	},
+	{
+		Name: CommandUnlink, Arity: 3, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Deletes key of any type and returns number of deleted keys",
+		Syntax:  "UNLINK key index",
+		Parse:   parseUnlink,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(UnlinkCommand)
+			return h.Unlink(from, v.Key, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(UnlinkCommand)
+			_, err := st.Unlink(v.Key, v.Index)
+			return err
+		},
+	},
+	{
+		Name: CommandDelIfEq, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Deletes the key if its value is equal to the given one",
+		Syntax:  "DELIFEQ key value index",
+		Parse:   parseDelIfEq,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(DelIfEqCommand)
+			return h.DelIfEq(from, v.Key, v.Val, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(DelIfEqCommand)
+			_, err := st.DelIfEq(v.Key, v.Val, v.Index)
+			return err
+		},
+	},
+	{
+		Name: CommandPExpire, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Sets time to live of the key in milliseconds",
+		Syntax:  "PEXPIRE key milliseconds index",
+		Parse:   parsePExpire,
+		Exec:    execPExpire,
+		Apply:   applyPExpire,
+	},
+	{
+		Name: CommandPExpireAt, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Sets expiration of the key as unix time in milliseconds",
+		Syntax:  "PEXPIREAT key unix-milliseconds index",
+		Parse:   parsePExpire,
+		Exec:    execPExpire,
+		Apply:   applyPExpire,
+	},
+	{
+		Name: CommandPTTL, Arity: 3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Returns time to live of the key in milliseconds",
+		Syntax:  "PTTL key index",
+		Parse:   parsePTTL,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(PTTLCommand)
+			return h.PTTL(from, v.Key, v.Index)
+		},
+	},
+	{
+		Name: CommandRateLimit, Arity: -6, Flags: FlagWrite, FirstKey: 2, LastKey: 2, KeyStep: 1, IndexArg: -1,
+		Summary: "Takes requests from the rate limiter stored in the key",
+		Syntax:  "RATELIMIT FIXED | SLIDING | BUCKET key limit period-milliseconds [cost] index",
+		Parse:   parseRateLimit,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.RateLimit(from, cmd.(RateLimitCommand))
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(RateLimitCommand)
+			_, _, err := st.RateLimit(v.Key, RateLimitAlgorithms[v.Algorithm], v.Limit, v.Period, v.Cost, v.Index)
+			return err
+		},
+	},
+	// lists
+	{
+		Name: CommandLPush, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Appends value to the list",
+		Syntax:  "LPUSH key value index",
+		Parse:   parseLPush,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(LPushCommand)
+			return h.LPush(from, v.Key, v.Val, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(LPushCommand)
+			return st.LPush(v.Key, v.Val, v.Index)
+		},
+	},
+	{
+		Name: CommandGetL, Arity: 3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Returns all values of the list",
+		Syntax:  "GETL key index",
+		Parse:   parseGetL,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(GetLCommand)
+			return h.GetL(from, v.Key, v.Index)
+		},
+	},
+	{
+		Name: CommandHas, Arity: 3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Reports whether key of any type exists",
+		Syntax:  "HAS key index",
+		Parse:   parseHas,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(HasCommand)
+			return h.Has(from, v.Key, v.Index)
+		},
+	},
+	{
+		Name: CommandDeleteL, Arity: 3, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Deletes the list",
+		Syntax:  "DELL key index",
+		Parse:   parseDeleteL,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(DeleteLCommand)
+			return h.DeleteL(from, v.Key, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(DeleteLCommand)
+			return st.DeleteL(v.Key, v.Index)
+		},
+	},
+	{
+		Name: CommandDelElemL, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Deletes the first occurrence of value from the list",
+		Syntax:  "DELELEML key value index",
+		Parse:   parseDelElemL,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(DelElemLCommand)
+			return h.DelElemL(from, v.Key, v.Val, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(DelElemLCommand)
+			return st.DelElemL(v.Key, v.Val, v.Index)
+		},
+	},
+	{
+		Name: CommandDelAll, Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Deletes all occurrences of value from the list",
+		Syntax:  "DELALL key value index",
+		Parse:   parseDelAll,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(DelAllCommand)
+			return h.DelAll(from, v.Key, v.Val, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(DelAllCommand)
+			return st.DelAll(v.Key, v.Val, v.Index)
+		},
+	},
+	// geo
+	{
+		Name: CommandGeoAdd, Arity: -6, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Adds locations of members to the geo set",
+		Syntax:  "GEOADD key longitude latitude member [longitude latitude member ...] index",
+		Parse:   parseGeoAdd,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(GeoAddCommand)
+			return h.GeoAdd(from, v.Key, v.Locations, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(GeoAddCommand)
+			_, err := st.GeoAdd(v.Key, GeoPoints(v.Locations), v.Index)
+			return err
+		},
+	},
+	{
+		Name: CommandGeoDist, Arity: -5, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Returns distance between two members of the geo set",
+		Syntax:  "GEODIST key member1 member2 [m | km | mi | ft] index",
+		Parse:   parseGeoDist,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(GeoDistCommand)
+			return h.GeoDist(from, v.Key, v.Member1, v.Member2, v.Unit, v.Index)
+		},
+	},
+	{
+		Name: CommandGeoPos, Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Returns coordinates of members of the geo set",
+		Syntax:  "GEOPOS key member [member ...] index",
+		Parse:   parseGeoPos,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(GeoPosCommand)
+			return h.GeoPos(from, v.Key, v.Members, v.Index)
+		},
+	},
+	{
+		Name: CommandGeoSearch, Arity: -7, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Returns members of the geo set inside a circle or a box",
+		Syntax:  "GEOSEARCH key FROMMEMBER member | FROMLONLAT longitude latitude BYRADIUS radius unit | BYBOX width height unit [ASC | DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH] index",
+		Parse:   parseGeoSearch,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.GeoSearch(from, cmd.(GeoSearchCommand))
+		},
+	},
+	// JSON
+	{
+		Name: CommandJSONSet, Arity: 5, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Sets JSON value at the path of the document",
+		Syntax:  "JSON.SET key path value index",
+		Parse:   parseJSONSet,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(JSONSetCommand)
+			return h.JSONSet(from, v.Key, v.Path, v.Val, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(JSONSetCommand)
+			return st.JSONSet(v.Key, v.Path, v.Val, v.Index)
+		},
+	},
+	{
+		Name: CommandJSONGet, Arity: -3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Returns JSON values at the paths of the document",
+		Syntax:  "JSON.GET key [path ...] index",
+		Parse:   parseJSONGet,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(JSONGetCommand)
+			return h.JSONGet(from, v.Key, v.Paths, v.Index)
+		},
+	},
+	{
+		Name: CommandJSONDel, Arity: -3, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Deletes JSON values at the path of the document",
+		Syntax:  "JSON.DEL key [path] index",
+		Parse:   parseJSONDel,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(JSONDelCommand)
+			return h.JSONDel(from, v.Key, v.Path, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(JSONDelCommand)
+			_, err := st.JSONDel(v.Key, v.Path, v.Index)
+			return err
+		},
+	},
+	{
+		Name: CommandJSONNumIncrBy, Arity: 5, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Increments numbers at the path of the document",
+		Syntax:  "JSON.NUMINCRBY key path value index",
+		Parse:   parseJSONNumIncrBy,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(JSONNumIncrByCommand)
+			return h.JSONNumIncrBy(from, v.Key, v.Path, v.Val, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(JSONNumIncrByCommand)
+			_, err := st.JSONNumIncrBy(v.Key, v.Path, v.Val, v.Index)
+			return err
+		},
+	},
+	{
+		Name: CommandJSONArrAppend, Arity: -5, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Appends values to arrays at the path of the document",
+		Syntax:  "JSON.ARRAPPEND key path value [value ...] index",
+		Parse:   parseJSONArrAppend,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(JSONArrAppendCommand)
+			return h.JSONArrAppend(from, v.Key, v.Path, v.Values, v.Index)
+		},
+		Apply: func(st *storage.Storage, cmd Command) error {
+			v := cmd.(JSONArrAppendCommand)
+			_, err := st.JSONArrAppend(v.Key, v.Path, v.Values, v.Index)
+			return err
+		},
+	},
+	{
+		Name: CommandJSONType, Arity: -3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, IndexArg: -1,
+		Summary: "Returns types of JSON values at the path of the document",
+		Syntax:  "JSON.TYPE key [path] index",
+		Parse:   parseJSONType,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(JSONTypeCommand)
+			return h.JSONType(from, v.Key, v.Path, v.Index)
+		},
+	},
+	// pub/sub
+	{
+		Name: CommandSubscribe, Arity: -2, Flags: FlagPubSub | FlagBlocking,
+		Summary: "Subscribes the connection to channels",
+		Syntax:  "SUBSCRIBE channel [channel ...]",
+		Parse:   parseSubscription,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Subscribe(from, cmd.(SubscribeCommand).Channels)
+		},
+	},
+	{
+		Name: CommandPSubscribe, Arity: -2, Flags: FlagPubSub | FlagBlocking,
+		Summary: "Subscribes the connection to channels matching patterns",
+		Syntax:  "PSUBSCRIBE pattern [pattern ...]",
+		Parse:   parseSubscription,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.PSubscribe(from, cmd.(PSubscribeCommand).Patterns)
+		},
+	},
+	{
+		Name: CommandUnsubscribe, Arity: -1, Flags: FlagPubSub,
+		Summary: "Unsubscribes the connection from channels, from all channels if none is given",
+		Syntax:  "UNSUBSCRIBE [channel ...]",
+		Parse:   parseSubscription,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Unsubscribe(from, cmd.(UnsubscribeCommand).Channels)
+		},
+	},
+	{
+		Name: CommandPUnsubscribe, Arity: -1, Flags: FlagPubSub,
+		Summary: "Unsubscribes the connection from patterns, from all patterns if none is given",
+		Syntax:  "PUNSUBSCRIBE [pattern ...]",
+		Parse:   parseSubscription,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.PUnsubscribe(from, cmd.(PUnsubscribeCommand).Patterns)
+		},
+	},
+	{
+		Name: CommandPublish, Arity: 3, Flags: FlagPubSub,
+		Summary: "Posts message to the channel and returns number of receivers",
+		Syntax:  "PUBLISH channel message",
+		Parse:   parsePublish,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(PublishCommand)
+			return h.Publish(from, v.Channel, v.Message)
+		},
+	},
+	// replication
+	{
+		Name: CommandReplicaOf, Arity: 3, Flags: FlagAdmin,
+		Summary: "Makes the server a replica of another server or turns it into a primary",
+		Syntax:  "REPLICAOF host port | NO ONE",
+		Parse:   parseReplicaOf,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(ReplicaOfCommand)
+			return h.ReplicaOf(from, v.Host, v.Port, v.NoOne)
+		},
+	},
+	{
+		Name: CommandPSync, Arity: 3, Flags: FlagAdmin | FlagBlocking,
+		Summary: "Starts replication stream, it's sent by replicas",
+		Syntax:  "PSYNC replid offset",
+		Parse:   parsePSync,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(PSyncCommand)
+			return h.PSync(from, v.ReplID, v.Offset)
+		},
+	},
+	{
+		Name: CommandReplConf, Arity: 3, Flags: FlagAdmin,
+		Summary: "Configures replication stream, it's sent by replicas",
+		Syntax:  "REPLCONF option value",
+		Parse:   parseReplConf,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(ReplConfCommand)
+			return h.ReplConf(from, v.Option, v.Value)
+		},
+	},
+	{
+		Name: CommandRole, Arity: 1, Flags: FlagAdmin,
+		Summary: "Returns replication role of the server",
+		Syntax:  "ROLE",
+		Parse:   func([]resp.Value) (Command, error) { return RoleCommand{}, nil },
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Role(from)
+		},
+	},
+	{
+		Name: CommandInfo, Arity: -1, Flags: FlagAdmin,
+		Summary: "Returns information about the server",
+		Syntax:  "INFO [section]",
+		Parse:   parseInfo,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Info(from, cmd.(InfoCommand).Section)
+		},
+	},
+	// connection
+	{
+		Name: CommandPing, Arity: -1, Flags: FlagConnection,
+		Summary: "Checks that the server is alive",
+		Syntax:  "PING",
+		Parse:   func([]resp.Value) (Command, error) { return PingCommand{}, nil },
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Ping(from)
+		},
+	},
+	{
+		Name: CommandHello, Arity: 2, Flags: FlagConnection | FlagNoReply,
+		Summary: "Greets the server after authentication",
+		Syntax:  "HELLO value",
+		Parse:   parseHello,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Hello(from)
+		},
+	},
+	{
+		Name: CommandClient, Arity: -2, Flags: FlagConnection,
+		Summary: "Returns id of the connection or configures client-side caching",
+		Syntax:  "CLIENT ID | TRACKING ON | OFF [REDIRECT id] [BCAST] [PREFIX prefix ...] [NOLOOP]",
+		Parse:   parseClient,
+		Exec: func(h Handler, from string, cmd Command) error {
+			if v, ok := cmd.(ClientTrackingCommand); ok {
+				return h.ClientTracking(from, v)
+			}
+			return h.ClientID(from)
+		},
+	},
+	{
+		Name: CommandCommand, Arity: -1, Flags: FlagConnection,
+		Summary: "Returns arity, flags, key positions and documentation of commands",
+		Syntax:  "COMMAND [COUNT | INFO [name ...] | DOCS [name ...] | GETKEYS command [arg ...]]",
+		Parse:   parseIntrospection,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Command(from, cmd.(IntrospectionCommand))
+		},
+	},
+	{
+		Name: CommandAuth, Arity: -2, Flags: FlagConnection,
+		Summary: "Authenticates connection as the user, default user is used if user isn't given",
+		Syntax:  "AUTH [user] password",
+		Parse:   parseAuth,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Auth(from, cmd.(AuthCommand))
+		},
+	},
+	{
+		Name: CommandACL, Arity: -2, Flags: FlagAdmin,
+		Summary: "Manages users, their passwords, permitted commands and key patterns",
+		Syntax:  "ACL SETUSER user [rule ...] | GETUSER user | LIST | DELUSER user [user ...] | WHOAMI",
+		Parse:   parseACL,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.ACL(from, cmd.(ACLCommand))
+		},
+	},
+	// cluster and failover
+	{
+		Name: CommandSentinel, Arity: -2, Flags: FlagAdmin,
+		Summary: "Queries the failover coordinator, it's executed by sentinels only",
+		Syntax:  "SENTINEL subcommand [arg ...]",
+		Parse:   parseSentinel,
+	},
+	{
+		Name: CommandCluster, Arity: -2, Flags: FlagAdmin,
+		Summary: "Manages the cluster",
+		Syntax:  "CLUSTER subcommand [arg ...]",
+		Parse:   parseCluster,
+		Exec: func(h Handler, from string, cmd Command) error {
+			v := cmd.(ClusterCommand)
+			return h.Cluster(from, v.Subcommand, v.Args)
+		},
+	},
+	{
+		Name: CommandAsking, Arity: 1, Flags: FlagConnection,
+		Summary: "Allows the next command to access a slot that is being imported",
+		Syntax:  "ASKING",
+		Parse:   func([]resp.Value) (Command, error) { return AskingCommand{}, nil },
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Asking(from)
+		},
+	},
+	{
+		Name: CommandMigrate, Arity: 6, Flags: FlagAdmin, FirstKey: 3, LastKey: 3, KeyStep: 1, IndexArg: 4,
+		Summary: "Moves the key to another node of the cluster",
+		Syntax:  "MIGRATE host port key index timeout-milliseconds",
+		Parse:   parseMigrate,
+		Exec: func(h Handler, from string, cmd Command) error {
+			return h.Migrate(from, cmd.(MigrateCommand))
+		},
+	},
+}
+
+func execPExpire(h Handler, from string, cmd Command) error {
+	return h.PExpire(from, cmd.(PExpireCommand))
+}
+
+func applyPExpire(st *storage.Storage, cmd Command) error {
+	v := cmd.(PExpireCommand)
+	_, err := st.ExpireAt(v.Key, v.At(st.Now()), v.Index)
+	return err
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/tidwall/resp"
+)
+
+// Client commands are not bound to a database, so they don't have index argument
+
+// ClientIDCommand returns id of the connection, it's used as REDIRECT of CLIENT TRACKING
+type ClientIDCommand struct {
+}
+
+// ClientTrackingCommand enables or disables client-side caching of the connection, invalidation
+// messages are sent to the connection Redirect. In broadcasting mode server reports changes of all keys
+// that start with one of Prefixes instead of keys read by the connection, NoLoop skips changes made by the connection
+type ClientTrackingCommand struct {
+	On       bool
+	Redirect string
+	BCast    bool
+	Prefixes [][]byte
+	NoLoop   bool
+}
+
+func (ClientIDCommand) Name() string       { return CommandClient }
+func (ClientTrackingCommand) Name() string { return CommandClient }
+
+// parseClient parses CLIENT ID and CLIENT TRACKING ON|OFF [REDIRECT id] [BCAST] [PREFIX prefix ...] [NOLOOP],
+// REDIRECT is required to enable tracking
+func parseClient(args []resp.Value) (Command, error) {
+	if len(args) < 2 {
+		return nil, ErrUnknownCommandArguments
+	}
+	switch strings.ToUpper(args[1].String()) {
+	case "ID":
+		if len(args) != 2 {
+			return nil, ErrUnknownCommandArguments
+		}
+		return ClientIDCommand{}, nil
+	case "TRACKING":
+		return parseClientTracking(args[2:])
+	}
+	return nil, ErrUnknownCommandArguments
+}
+
+func parseClientTracking(args []resp.Value) (Command, error) {
+	if len(args) == 0 {
+		return nil, ErrUnknownCommandArguments
+	}
+	cmd := ClientTrackingCommand{}
+	switch strings.ToUpper(args[0].String()) {
+	case "ON":
+		cmd.On = true
+	case "OFF":
+		if len(args) != 1 {
+			return nil, ErrUnknownCommandArguments
+		}
+		return cmd, nil
+	default:
+		return nil, ErrUnknownCommandArguments
+	}
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i].String()) {
+		case "REDIRECT":
+			if i++; i == len(args) {
+				return nil, ErrUnknownCommandArguments
+			}
+			cmd.Redirect = args[i].String()
+		case "PREFIX":
+			if i++; i == len(args) {
+				return nil, ErrUnknownCommandArguments
+			}
+			cmd.Prefixes = append(cmd.Prefixes, args[i].Bytes())
+		case "BCAST":
+			cmd.BCast = true
+		case "NOLOOP":
+			cmd.NoLoop = true
+		default:
+			return nil, ErrUnknownCommandArguments
+		}
+	}
+	if len(cmd.Redirect) == 0 || (len(cmd.Prefixes) > 0 && !cmd.BCast) {
+		return nil, ErrUnknownCommandArguments
+	}
+	return cmd, nil
+}
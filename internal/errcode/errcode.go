@@ -0,0 +1,91 @@
+// Package errcode maps errors of storage, commands and ACL to error codes sent by server and error codes back to
+// errors, it's shared by the server, the client and the embedded database so all of them report the same errors
+package errcode
+
+import (
+	"errors"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/acl"
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+)
+
+// error codes sent at the beginning of error messages
+const (
+	Err       = "ERR"
+	NotFound  = "NOTFOUND"
+	WrongType = "WRONGTYPE"
+	NotInt    = "NOTINT"
+	Index     = "INDEX"
+	Syntax    = "SYNTAX"
+	ReadOnly  = "READONLY"
+	NoPerm    = "NOPERM"
+	WrongPass = "WRONGPASS"
+)
+
+// errors returned to users of the client and of the embedded database
+var (
+	ErrOperationFailed = errors.New("operation failed")
+	ErrTimeIsOut       = errors.New("time is out")
+	ErrInvalidIndex    = errors.New("invalid index value")
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrClosed          = errors.New("client is closed")
+	ErrNotFound        = errors.New("not found")
+	ErrWrongType       = errors.New("wrong type")
+	ErrNotInteger      = errors.New("value is not an integer")
+	ErrSyntax          = errors.New("syntax error")
+	ErrReadOnly        = errors.New("read only replica")
+	ErrNoPerm          = errors.New("no permissions")
+)
+
+// codeErrors maps error codes to sentinel errors
+var codeErrors = map[string]error{
+	NotFound:  ErrNotFound,
+	WrongType: ErrWrongType,
+	NotInt:    ErrNotInteger,
+	Index:     ErrInvalidIndex,
+	Syntax:    ErrSyntax,
+	ReadOnly:  ErrReadOnly,
+	NoPerm:    ErrNoPerm,
+	WrongPass: ErrInvalidPassword,
+}
+
+// Sentinel returns sentinel error of code, nil is returned for codes without sentinel error
+func Sentinel(code string) error {
+	return codeErrors[code]
+}
+
+// Code returns error code of err, ERR is returned for errors without specific code
+func Code(err error) string {
+	switch {
+	case errors.Is(err, storage.ErrKeyDoNotExists), errors.Is(err, storage.ErrMemberDoNotExists),
+		errors.Is(err, storage.ErrJSONPathDoNotExists):
+		return NotFound
+	case errors.Is(err, storage.ErrJSONWrongType), errors.Is(err, storage.ErrInvalidRateLimitState),
+		errors.Is(err, storage.ErrWrongType):
+		return WrongType
+	case errors.Is(err, storage.ErrUnableToConvertToInt):
+		return NotInt
+	case errors.Is(err, storage.ErrInvalidDatabaseIndex), errors.Is(err, command.ErrInvalidIndexValue):
+		return Index
+	case errors.Is(err, command.ErrUnknownCommandArguments), errors.Is(err, storage.ErrInvalidJSONPath),
+		errors.Is(err, storage.ErrInvalidJSON), errors.Is(err, storage.ErrJSONNewRoot),
+		errors.Is(err, storage.ErrInvalidCoordinates):
+		return Syntax
+	case errors.Is(err, acl.ErrNoPerm), errors.Is(err, acl.ErrNoPermKey):
+		return NoPerm
+	case errors.Is(err, acl.ErrWrongPass):
+		return WrongPass
+	case errors.Is(err, acl.ErrInvalidRule):
+		return Syntax
+	}
+	return Err
+}
+
+// Text returns message of the innermost error of err, so "op" prefixes of wrapped errors aren't sent to clients
+func Text(err error) string {
+	for next := errors.Unwrap(err); next != nil; next = errors.Unwrap(err) {
+		err = next
+	}
+	return err.Error()
+}
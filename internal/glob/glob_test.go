@@ -0,0 +1,35 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Match(t *testing.T) {
+	cases := []struct {
+		pattern, str string
+		want         bool
+	}{
+		{"*", "anything", true},
+		{"*", "", true},
+		{"user:*", "user:42", true},
+		{"user:*", "order:42", false},
+		{"__keyspace@0__:*", "__keyspace@0__:foo", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{"a\\*b", "a*b", true},
+		{"a\\*b", "aab", false},
+		{"*:*:end", "a:b:c:end", true},
+		{"[abc", "a", false},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, Match(c.pattern, c.str), "%s %s", c.pattern, c.str)
+	}
+}
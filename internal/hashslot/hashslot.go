@@ -0,0 +1,41 @@
+// Package hashslot maps keys to cluster hash slots the same way as Redis Cluster does
+package hashslot
+
+// Count is a number of hash slots
+const Count = 16384
+
+// crc16 is CRC16-CCITT (XMODEM), polynomial 0x1021
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Slot returns hash slot of the key, if key contains non empty {hashtag}
+// only the hashtag is hashed, so related keys can be placed in the same slot
+func Slot(key []byte) int {
+	for i, c := range key {
+		if c != '{' {
+			continue
+		}
+		for j := i + 1; j < len(key); j++ {
+			if key[j] == '}' {
+				if j > i+1 {
+					key = key[i+1 : j]
+				}
+				return int(crc16(key)) % Count
+			}
+		}
+		break
+	}
+	return int(crc16(key)) % Count
+}
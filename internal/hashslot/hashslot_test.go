@@ -0,0 +1,19 @@
+package hashslot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Slot(t *testing.T) {
+	// values are taken from CLUSTER KEYSLOT of Redis
+	require.Equal(t, 12739, Slot([]byte("123456789")))
+	require.Equal(t, 12182, Slot([]byte("foo")))
+	require.Equal(t, 5061, Slot([]byte("bar")))
+	require.Equal(t, Slot([]byte("user1000")), Slot([]byte("{user1000}.following")))
+	require.Equal(t, Slot([]byte("user1000")), Slot([]byte("foo{user1000}{bar}")))
+	// empty or not closed hashtag is ignored
+	require.Equal(t, int(crc16([]byte("foo{}{bar}")))%Count, Slot([]byte("foo{}{bar}")))
+	require.Equal(t, int(crc16([]byte("foo{bar")))%Count, Slot([]byte("foo{bar")))
+}
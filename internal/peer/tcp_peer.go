@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"io"
 	"net"
+
+	"github.com/tidwall/resp"
 )
 
 type TCPPeer struct {
-	Conn   net.Conn
+	Conn net.Conn
+	// rd reads commands from Conn, it may hold data read before the peer is created
+	rd     *resp.Reader
 	msgCh  chan Message
 	dropCh chan string
 }
@@ -17,9 +21,11 @@ type Message struct {
 	Payload []byte
 }
 
-func NewTCPPeer(conn net.Conn, msgch chan Message, dropCh chan string) *TCPPeer {
+// NewTCPPeer returns peer that reads commands of conn with rd
+func NewTCPPeer(conn net.Conn, rd *resp.Reader, msgch chan Message, dropCh chan string) *TCPPeer {
 	return &TCPPeer{
 		Conn:   conn,
+		rd:     rd,
 		msgCh:  msgch,
 		dropCh: dropCh,
 	}
@@ -29,20 +35,23 @@ func (t *TCPPeer) Addr() string {
 	return t.Conn.RemoteAddr().String()
 }
 
+// ReadLoop reads commands from the connection, every command is sent as a separate message
+// even if it came in several reads or several commands came in one read
 func (t *TCPPeer) ReadLoop() error {
 	const op = "peer.ReadLoop"
-	buf := make([]byte, 1024)
 	for {
-		n, err := t.Conn.Read(buf)
+		v, _, err := t.rd.ReadValue()
 		if err != nil {
-			if errors.Is(err, io.EOF) {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 				t.dropCh <- t.Addr()
-				return fmt.Errorf("%s:%w", op, err)
+				return fmt.Errorf("%s:%w", op, io.EOF)
 			}
 			return fmt.Errorf("%s:%w", op, err)
 		}
-		msgBuf := make([]byte, n)
-		copy(msgBuf, buf[:n])
+		msgBuf, err := v.MarshalRESP()
+		if err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
 		msg := Message{
 			From:    t.Addr(),
 			Payload: msgBuf,
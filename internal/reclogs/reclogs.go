@@ -2,19 +2,33 @@ package reclogs
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/tidwall/resp"
 )
 
+// header is the first line of logs with escaped values, logs without it were written before values were escaped,
+// they are split by separators as is and rewritten with header before the first read or write
+const header = "#reclogs v2"
+
+// maxRecordSize is a limit of a single record of the log, record of large value is slightly larger
+// than the value because of escaping
+const maxRecordSize = 1 << 30
+
 type RecoveryLogger struct {
 	mu       sync.Mutex
 	FileName string
 	recData  chan command.Command
+	// prepared is set after the log is checked for header
+	prepared bool
 }
 
 func New(filename string, ch chan command.Command) *RecoveryLogger {
@@ -27,17 +41,19 @@ func (r *RecoveryLogger) WriteLog(operation string, ind int, args ...[]byte) err
 	const op = "reclogs.WriteLog"
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if err := r.prepare(); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
 	f, err := os.OpenFile(r.FileName, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.ModePerm)
 	if err != nil {
 		return fmt.Errorf("%s:%w", op, err)
 	}
 	defer f.Close()
-	_ = f
 	var log string
 	index := strconv.Itoa(ind)
 	log += fmt.Sprintf("%s#%s#", operation, index)
 	for _, val := range args {
-		log += fmt.Sprintf("%s#", string(val))
+		log += fmt.Sprintf("%s#", escape(val))
 	}
 	log += "\n"
 	_, err = f.Write([]byte(log))
@@ -46,16 +62,43 @@ func (r *RecoveryLogger) WriteLog(operation string, ind int, args ...[]byte) err
 	}
 	return nil
 }
+
+// Reset truncates the log, it's used when the whole data set is replaced
+func (r *RecoveryLogger) Reset() error {
+	const op = "reclogs.Reset"
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, err := os.OpenFile(r.FileName, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	defer f.Close()
+	if _, err := io.WriteString(f, header+"\n"); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	r.prepared = true
+	return nil
+}
 func (r *RecoveryLogger) ReadLog() error {
 	const op = "reclogs.ReadLog"
+	r.mu.Lock()
+	err := r.prepare()
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
 	f, err := os.OpenFile(r.FileName, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.ModePerm)
 	if err != nil {
 		return fmt.Errorf("%s:%w", op, err)
 	}
 	defer f.Close()
 	scaner := bufio.NewScanner(f)
+	scaner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRecordSize)
 	for scaner.Scan() {
-		attrs := strings.Split(scaner.Text(), "#")
+		if scaner.Text() == header {
+			continue
+		}
+		attrs := split(scaner.Text())
 		if len(attrs) < 4 {
 			break
 		}
@@ -67,92 +110,150 @@ func (r *RecoveryLogger) ReadLog() error {
 		r.recData <- cmd
 	}
 	if err := scaner.Err(); err != nil {
-		return err
+		return fmt.Errorf("%s:%w", op, err)
 	}
 	r.recData <- command.StopCommand{}
 	return nil
 }
 
-func parseCommand(attrs []string) (command.Command, error) {
-	switch attrs[0] {
-	case command.CommandSet:
-		ind, err := strconv.Atoi(attrs[1])
-		if err != nil {
-			return nil, command.ErrInvalidIndexValue
-		}
-		return command.SetCommand{
-			Key:   []byte(attrs[2]),
-			Val:   []byte(attrs[3]),
-			Index: ind,
-		}, nil
-	case command.CommandAdd:
-		ind, err := strconv.Atoi(attrs[1])
-		if err != nil {
-			return nil, command.ErrInvalidIndexValue
-		}
-		return command.AddCommand{
-			Key:   []byte(attrs[2]),
-			Index: ind,
-		}, nil
-	case command.CommandAddN:
-		ind, err := strconv.Atoi(attrs[1])
-		if err != nil {
-			return nil, command.ErrInvalidIndexValue
-		}
-		return command.AddNCommand{
-			Key:   []byte(attrs[2]),
-			Val:   []byte(attrs[3]),
-			Index: ind,
-		}, nil
-	case command.CommandDelete:
-		ind, err := strconv.Atoi(attrs[1])
-		if err != nil {
-			return nil, command.ErrInvalidIndexValue
+// prepare writes header to the new log and rewrites log without header, it must be called with mu held
+func (r *RecoveryLogger) prepare() error {
+	if r.prepared {
+		return nil
+	}
+	f, err := os.OpenFile(r.FileName, os.O_CREATE|os.O_RDWR, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	rd := bufio.NewReader(f)
+	first, err := rd.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		f.Close()
+		return err
+	}
+	switch {
+	case len(first) == 0:
+		_, err = io.WriteString(f, header+"\n")
+	case strings.TrimSuffix(first, "\n") != header:
+		err = migrate(r.FileName, io.MultiReader(strings.NewReader(first), rd))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	r.prepared = true
+	return nil
+}
+
+// migrate rewrites log without header from old, its lines are split by separators as is
+// and values are written escaped to a temporary file that replaces the log
+func migrate(name string, old io.Reader) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), "reclogs-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		tmp.Close()
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	w.WriteString(header + "\n")
+	rd := bufio.NewReader(old)
+	for {
+		line, err := rd.ReadString('\n')
+		if line = strings.TrimSuffix(line, "\n"); len(line) != 0 {
+			attrs := strings.Split(line, "#")
+			for _, attr := range attrs[:len(attrs)-1] {
+				w.WriteString(escape([]byte(attr)) + "#")
+			}
+			w.WriteString("\n")
 		}
-		return command.DeleteCommand{
-			Key:   []byte(attrs[2]),
-			Index: ind,
-		}, nil
-	case command.CommandLPush:
-		ind, err := strconv.Atoi(attrs[1])
-		if err != nil {
-			return nil, command.ErrInvalidIndexValue
+		if errors.Is(err, io.EOF) {
+			break
 		}
-		return command.LPushCommand{
-			Key:   []byte(attrs[2]),
-			Val:   []byte(attrs[3]),
-			Index: ind,
-		}, nil
-	case command.CommandDelElemL:
-		ind, err := strconv.Atoi(attrs[1])
 		if err != nil {
-			return nil, command.ErrInvalidIndexValue
+			tmp.Close()
+			return err
 		}
-		return command.DelElemLCommand{
-			Key:   []byte(attrs[2]),
-			Val:   []byte(attrs[3]),
-			Index: ind,
-		}, nil
-	case command.CommandDeleteL:
-		ind, err := strconv.Atoi(attrs[1])
-		if err != nil {
-			return nil, command.ErrInvalidIndexValue
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// escape escapes separators and line breaks, so any value can be written to the log
+func escape(val []byte) string {
+	var b strings.Builder
+	for _, c := range val {
+		switch c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '#':
+			b.WriteString(`\#`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			b.WriteByte(c)
 		}
-		return command.DeleteLCommand{
-			Key:   []byte(attrs[2]),
-			Index: ind,
-		}, nil
-	case command.CommandDelAll:
-		ind, err := strconv.Atoi(attrs[1])
-		if err != nil {
-			return nil, command.ErrInvalidIndexValue
+	}
+	return b.String()
+}
+
+// split splits log line by separators and unescapes values
+func split(line string) []string {
+	var attrs []string
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			switch line[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(line[i])
+			}
+		case c == '#':
+			attrs = append(attrs, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(c)
 		}
-		return command.DelAllCommand{
-			Key:   []byte(attrs[2]),
-			Val:   []byte(attrs[3]),
-			Index: ind,
-		}, nil
-	default:
+	}
+	return append(attrs, b.String())
+}
+
+// parseCommand parses NAME#index#args...# log line, arguments are reordered to NAME args... index
+// and parsed like a command of a client, only write commands are accepted
+func parseCommand(attrs []string) (command.Command, error) {
+	args := make([]resp.Value, 0, len(attrs))
+	args = append(args, resp.StringValue(attrs[0]))
+	for _, attr := range attrs[2:] {
+		args = append(args, resp.StringValue(attr))
+	}
+	args = append(args, resp.StringValue(attrs[1]))
+	req, err := command.NewRequest(args)
+	if err != nil {
+		return nil, err
+	}
+	if req.Spec.Apply == nil {
 		return nil, command.ErrUnknownCommand
 	}
+	return req.Command, nil
 }
@@ -2,6 +2,8 @@ package reclogs
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
@@ -29,7 +31,7 @@ func Test_WriteLog(t *testing.T) {
 
 		}
 	}()
-	r := New("test", ch)
+	r := New(filepath.Join(t.TempDir(), "logs"), ch)
 	err := r.WriteLog("SET", 0, []byte("my_key"), []byte("myval"))
 	require.Nil(t, err)
 	err = r.WriteLog("ADD", 0, []byte("my_key"))
@@ -38,3 +40,59 @@ func Test_WriteLog(t *testing.T) {
 	require.Nil(t, err)
 	wg.Wait()
 }
+
+func Test_EscapeSplit(t *testing.T) {
+	vals := []string{"plain", "with#hash", "with\\slash", "multi\r\nline", ""}
+	line := "SET#0#"
+	for _, val := range vals {
+		line += escape([]byte(val)) + "#"
+	}
+	attrs := split(line)
+	require.Equal(t, append([]string{"SET", "0"}, append(vals, "")...), attrs)
+}
+
+// readAll reads commands of the log until StopCommand
+func readAll(t *testing.T, r *RecoveryLogger, ch chan command.Command) []command.Command {
+	errCh := make(chan error, 1)
+	go func() { errCh <- r.ReadLog() }()
+	var cmds []command.Command
+	for cmd := range ch {
+		if _, ok := cmd.(command.StopCommand); ok {
+			break
+		}
+		cmds = append(cmds, cmd)
+	}
+	require.Nil(t, <-errCh)
+	return cmds
+}
+
+func Test_MigrateLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "logs")
+	// log written before values were escaped
+	require.Nil(t, os.WriteFile(name, []byte("SET#0#dir#C:\\tmp#\nADD#1#counter#\n"), 0o644))
+	ch := make(chan command.Command)
+	r := New(name, ch)
+	cmds := readAll(t, r, ch)
+	require.Len(t, cmds, 2)
+	set := cmds[0].(command.SetCommand)
+	require.Equal(t, "C:\\tmp", string(set.Val))
+	require.Equal(t, command.AddCommand{Key: []byte("counter"), Index: 1}, cmds[1])
+	data, err := os.ReadFile(name)
+	require.Nil(t, err)
+	require.Equal(t, header+"\nSET#0#dir#C:\\\\tmp#\nADD#1#counter#\n", string(data))
+	info, err := os.Stat(name)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+	require.Nil(t, r.WriteLog("SET", 0, []byte("hash"), []byte("a#b\\c")))
+	cmds = readAll(t, New(name, ch), ch)
+	require.Len(t, cmds, 3)
+	require.Equal(t, "a#b\\c", string(cmds[2].(command.SetCommand).Val))
+
+	// new log starts with header
+	name = filepath.Join(t.TempDir(), "logs")
+	require.Nil(t, New(name, ch).WriteLog("ADD", 0, []byte("counter")))
+	data, err = os.ReadFile(name)
+	require.Nil(t, err)
+	require.Equal(t, header+"\nADD#0#counter#\n", string(data))
+}
@@ -0,0 +1,308 @@
+package sentinel
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+)
+
+func (s *Sentinel) monitorLoop() {
+	ticker := time.NewTicker(s.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.quitCh:
+			return
+		case <-ticker.C:
+			s.tick()
+		}
+	}
+}
+
+// tick checks the primary, replicas are reconfigured while the primary is reachable
+// and failover is started when sentinels agree that it's down
+func (s *Sentinel) tick() {
+	reached := s.pullConfig()
+	s.checkMaster()
+	s.mu.Lock()
+	down := s.subjectivelyDown()
+	if !down {
+		s.nextElection = time.Time{}
+	}
+	s.mu.Unlock()
+	if !down {
+		// sentinel that can't reach other sentinels may have outdated configuration
+		if reached+1 >= s.Quorum {
+			s.reconcile()
+		}
+		return
+	}
+	if s.objectivelyDown() {
+		s.tryFailover()
+	}
+}
+
+func (s *Sentinel) requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.PingInterval)
+}
+
+// pullConfig adopts configuration of other sentinels if it's produced by later failover,
+// returns number of sentinels that responded
+func (s *Sentinel) pullConfig() int {
+	const op = "sentinel.pullConfig"
+	log := s.Log.With(slog.String("op", op))
+	reached := 0
+	for _, addr := range s.Sentinels {
+		ctx, cancel := s.requestContext()
+		info, err := s.sentinelMaster(ctx, addr)
+		cancel()
+		if err != nil {
+			continue
+		}
+		reached++
+		epoch, err := strconv.ParseInt(info["config-epoch"], 10, 64)
+		if err != nil {
+			continue
+		}
+		s.mu.Lock()
+		if epoch > s.configEpoch {
+			s.switchMaster(info["ip"], info["port"], epoch)
+			log.Info("adopted configuration of other sentinel", slog.String("sentinel", addr),
+				slog.String("primary", net.JoinHostPort(s.masterHost, s.masterPort)), slog.Int64("epoch", epoch))
+		}
+		s.mu.Unlock()
+	}
+	return reached
+}
+
+func (s *Sentinel) sentinelMaster(ctx context.Context, addr string) (map[string]string, error) {
+	c, err := s.client(ctx, addr, s.SentinelPassword)
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.SentinelMaster(ctx, s.MasterName)
+	if err != nil {
+		s.dropClient(addr)
+		return nil, err
+	}
+	return info, nil
+}
+
+// switchMaster makes server with given address the primary, former primary is kept as a replica
+// to be reconfigured when it's back, must be called with mu locked
+func (s *Sentinel) switchMaster(host, port string, epoch int64) {
+	s.replicas[net.JoinHostPort(s.masterHost, s.masterPort)] = struct{}{}
+	delete(s.replicas, net.JoinHostPort(host, port))
+	s.masterHost, s.masterPort = host, port
+	s.configEpoch = epoch
+	s.currentEpoch = max(s.currentEpoch, epoch)
+	s.lastOK = time.Now()
+	s.nextElection = time.Time{}
+}
+
+// checkMaster asks the primary for its role, replicas of the primary are remembered
+func (s *Sentinel) checkMaster() {
+	addr := s.MasterAddr()
+	ctx, cancel := s.requestContext()
+	defer cancel()
+	role, err := s.role(ctx, addr, s.MasterPassword)
+	if err != nil || role.Role != client.RoleMaster {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastOK = time.Now()
+	for _, r := range role.Replicas {
+		s.replicas[net.JoinHostPort(r.Host, r.Port)] = struct{}{}
+	}
+}
+
+func (s *Sentinel) role(ctx context.Context, addr, password string) (*client.Role, error) {
+	c, err := s.client(ctx, addr, password)
+	if err != nil {
+		return nil, err
+	}
+	role, err := c.Role(ctx)
+	if err != nil {
+		s.dropClient(addr)
+		return nil, err
+	}
+	return role, nil
+}
+
+// objectivelyDown asks other sentinels whether they can reach the primary,
+// primary is down if at least Quorum sentinels agree
+func (s *Sentinel) objectivelyDown() bool {
+	s.mu.Lock()
+	host, port, epoch := s.masterHost, s.masterPort, s.currentEpoch
+	s.mu.Unlock()
+	votes := 1
+	for _, addr := range s.Sentinels {
+		reply, err := s.askSentinel(addr, host, port, epoch, "*")
+		if err == nil && reply.Down {
+			votes++
+		}
+	}
+	return votes >= s.Quorum
+}
+
+func (s *Sentinel) askSentinel(addr, host, port string, epoch int64, runID string) (*client.MasterDownReply, error) {
+	ctx, cancel := s.requestContext()
+	defer cancel()
+	c, err := s.client(ctx, addr, s.SentinelPassword)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.SentinelIsMasterDownByAddr(ctx, host, port, epoch, runID)
+	if err != nil {
+		s.dropClient(addr)
+		return nil, err
+	}
+	return reply, nil
+}
+
+// randDuration returns random duration in [0, d), it's used to avoid split votes
+func randDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(d)))
+}
+
+// tryFailover starts election in a new epoch, failover is done if sentinel gets votes
+// of the majority of sentinels and at least Quorum votes
+func (s *Sentinel) tryFailover() {
+	const op = "sentinel.tryFailover"
+	log := s.Log.With(slog.String("op", op))
+	s.mu.Lock()
+	now := time.Now()
+	if s.nextElection.IsZero() {
+		s.nextElection = now.Add(randDuration(s.FailoverTimeout / 4))
+	}
+	if now.Before(s.nextElection) {
+		s.mu.Unlock()
+		return
+	}
+	s.currentEpoch++
+	epoch := s.currentEpoch
+	s.leader, s.leaderEpoch = s.runID, epoch
+	s.nextElection = now.Add(s.FailoverTimeout + randDuration(s.FailoverTimeout/2))
+	host, port := s.masterHost, s.masterPort
+	s.mu.Unlock()
+	log.Info("primary is down, starting election", slog.String("primary", net.JoinHostPort(host, port)), slog.Int64("epoch", epoch))
+	votes := 1
+	for _, addr := range s.Sentinels {
+		reply, err := s.askSentinel(addr, host, port, epoch, s.runID)
+		if err == nil && reply.Leader == s.runID && reply.LeaderEpoch == epoch {
+			votes++
+		}
+	}
+	needed := max(s.Quorum, (len(s.Sentinels)+1)/2+1)
+	if votes < needed {
+		log.Info("election is lost", slog.Int("votes", votes), slog.Int64("epoch", epoch))
+		return
+	}
+	log.Info("election is won", slog.Int("votes", votes), slog.Int64("epoch", epoch))
+	if err := s.failover(epoch); err != nil {
+		log.Error("failover failed", slog.String("error", err.Error()))
+	}
+}
+
+// failover promotes the best replica and reconfigures other replicas to replicate it
+func (s *Sentinel) failover(epoch int64) error {
+	const op = "sentinel.failover"
+	log := s.Log.With(slog.String("op", op))
+	candidate, err := s.bestReplica()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.requestContext()
+	defer cancel()
+	c, err := s.client(ctx, candidate, s.MasterPassword)
+	if err != nil {
+		return err
+	}
+	if err := c.ReplicaOfNoOne(ctx); err != nil {
+		s.dropClient(candidate)
+		return err
+	}
+	role, err := c.Role(ctx)
+	if err != nil {
+		s.dropClient(candidate)
+		return err
+	}
+	if role.Role != client.RoleMaster {
+		return ErrPromotionIsNotDone
+	}
+	host, port, _ := net.SplitHostPort(candidate)
+	s.mu.Lock()
+	s.switchMaster(host, port, epoch)
+	s.mu.Unlock()
+	log.Info("replica is promoted", slog.String("primary", candidate), slog.Int64("epoch", epoch))
+	s.reconcile()
+	return nil
+}
+
+// bestReplica returns address of reachable replica with the greatest replication offset
+func (s *Sentinel) bestReplica() (string, error) {
+	best, bestOffset := "", int64(-1)
+	for _, addr := range s.knownReplicas() {
+		ctx, cancel := s.requestContext()
+		role, err := s.role(ctx, addr, s.MasterPassword)
+		cancel()
+		if err != nil || role.Role != client.RoleReplica {
+			continue
+		}
+		if role.Offset > bestOffset {
+			best, bestOffset = addr, role.Offset
+		}
+	}
+	if len(best) == 0 {
+		return "", ErrNoCandidate
+	}
+	return best, nil
+}
+
+// knownReplicas returns sorted addresses of known servers other than the primary
+func (s *Sentinel) knownReplicas() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]string, 0, len(s.replicas))
+	for addr := range s.replicas {
+		res = append(res, addr)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// reconcile makes every reachable server other than the primary replicate the primary
+func (s *Sentinel) reconcile() {
+	const op = "sentinel.reconcile"
+	log := s.Log.With(slog.String("op", op))
+	s.mu.Lock()
+	host, port := s.masterHost, s.masterPort
+	s.mu.Unlock()
+	for _, addr := range s.knownReplicas() {
+		ctx, cancel := s.requestContext()
+		role, err := s.role(ctx, addr, s.MasterPassword)
+		if err == nil && (role.Role != client.RoleReplica || role.MasterHost != host || role.MasterPort != port) {
+			c, err := s.client(ctx, addr, s.MasterPassword)
+			if err == nil {
+				err = c.ReplicaOf(ctx, host, port)
+			}
+			if err != nil {
+				s.dropClient(addr)
+				log.Error("failed to reconfigure replica", slog.String("replica", addr), slog.String("error", err.Error()))
+			} else {
+				log.Info("replica is reconfigured", slog.String("replica", addr), slog.String("primary", net.JoinHostPort(host, port)))
+			}
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,412 @@
+package sentinel
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/tidwall/resp"
+)
+
+const (
+	defaultPassword        = "secret"
+	defaultMasterName      = "mymaster"
+	defaultPingInterval    = time.Second
+	defaultDownAfter       = 5 * time.Second
+	defaultFailoverTimeout = 30 * time.Second
+)
+
+var (
+	DefaultAddress        = ":26379"
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrUnknownMaster      = errors.New("unknown primary name")
+	ErrUnknownSubcommand  = errors.New("unknown sentinel subcommand")
+	ErrInvalidMasterAddr  = errors.New("invalid primary address")
+	ErrNoCandidate        = errors.New("no replica can be promoted")
+	ErrPromotionIsNotDone = errors.New("replica is not promoted")
+	ErrSentinelClosed     = errors.New("sentinel closed")
+	ErrUnknownCommand     = errors.New("unknown command")
+)
+
+type Config struct {
+	ListenAddr string
+	// Password is used by clients and other sentinels to connect to the sentinel
+	Password string
+	Log      *slog.Logger
+	// MasterName is a name clients use to discover the primary
+	MasterName string
+	// MasterAddr is an address of the monitored primary ("host:port")
+	MasterAddr string
+	// MasterPassword is used to connect to the primary and its replicas
+	MasterPassword string
+	// Sentinels are addresses of other sentinels monitoring the same primary
+	Sentinels []string
+	// SentinelPassword is used to connect to other sentinels, Password is used if it's empty
+	SentinelPassword string
+	// Quorum is a number of sentinels that have to agree that primary is down to start failover
+	Quorum int
+	// PingInterval is an interval between checks of the primary and its replicas
+	PingInterval time.Duration
+	// DownAfter is a time after which not responding primary is considered down
+	DownAfter time.Duration
+	// FailoverTimeout is a time sentinel waits before starting next failover attempt
+	FailoverTimeout time.Duration
+}
+
+// Sentinel monitors a primary and its replicas and promotes one of the replicas
+// when sentinels agree that the primary is down
+type Sentinel struct {
+	Config
+	runID    string
+	mu       sync.Mutex
+	listener net.Listener
+	quitCh   chan struct{}
+	// masterHost and masterPort are address of the current primary
+	masterHost, masterPort string
+	// configEpoch is an epoch of the failover that produced current configuration
+	configEpoch int64
+	// currentEpoch is the greatest epoch seen by sentinel
+	currentEpoch int64
+	// leader is a sentinel voted for in leaderEpoch
+	leader      string
+	leaderEpoch int64
+	// replicas are known data servers other than the primary, former primaries included
+	replicas     map[string]struct{}
+	lastOK       time.Time
+	nextElection time.Time
+	clients      map[string]*client.Client
+}
+
+// New returns sentinel instance with given Config
+func New(cfg Config) (*Sentinel, error) {
+	if len(cfg.ListenAddr) == 0 {
+		cfg.ListenAddr = DefaultAddress
+	}
+	if len(cfg.Password) == 0 {
+		cfg.Password = defaultPassword
+	}
+	if len(cfg.SentinelPassword) == 0 {
+		cfg.SentinelPassword = cfg.Password
+	}
+	if len(cfg.MasterName) == 0 {
+		cfg.MasterName = defaultMasterName
+	}
+	if cfg.Quorum <= 0 {
+		cfg.Quorum = len(cfg.Sentinels)/2 + 1
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = defaultPingInterval
+	}
+	if cfg.DownAfter <= 0 {
+		cfg.DownAfter = defaultDownAfter
+	}
+	if cfg.FailoverTimeout <= 0 {
+		cfg.FailoverTimeout = defaultFailoverTimeout
+	}
+	host, port, err := net.SplitHostPort(cfg.MasterAddr)
+	if err != nil {
+		return nil, fmt.Errorf("%w:%w", ErrInvalidMasterAddr, err)
+	}
+	return &Sentinel{
+		Config:     cfg,
+		runID:      newRunID(),
+		quitCh:     make(chan struct{}),
+		masterHost: resolveHost(host),
+		masterPort: port,
+		replicas:   make(map[string]struct{}),
+		lastOK:     time.Now(),
+		clients:    make(map[string]*client.Client),
+	}, nil
+}
+
+// resolveHost returns IP address of the host, servers are identified by addresses
+// that primary reports for its replicas, so host names are resolved
+func resolveHost(host string) string {
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return host
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.String()
+		}
+	}
+	return ips[0].String()
+}
+
+func newRunID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// MasterAddr returns address of the current primary
+func (s *Sentinel) MasterAddr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return net.JoinHostPort(s.masterHost, s.masterPort)
+}
+
+// Start starts monitoring and serving sentinel commands on ListenAddr
+func (s *Sentinel) Start() error {
+	const op = "sentinel.Start"
+	ln, err := net.Listen("tcp", s.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return s.Serve(ln)
+}
+
+// Serve starts monitoring and serving sentinel commands accepted from ln, e.g. from listener
+// on ephemeral port which address is known before sentinel starts, ln is closed by Stop
+func (s *Sentinel) Serve(ln net.Listener) error {
+	const op = "sentinel.Serve"
+	log := s.Log.With(slog.String("op", op))
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+	log.Info("starting monitoring", slog.String("address", s.ListenAddr), slog.String("primary", s.MasterAddr()))
+	go s.monitorLoop()
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return ErrSentinelClosed
+		}
+		if err != nil {
+			log.Error("got error while accepting connections", slog.String("error", err.Error()))
+			continue
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Stop stops monitoring and closes listener
+func (s *Sentinel) Stop() {
+	close(s.quitCh)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for addr, c := range s.clients {
+		c.Close()
+		delete(s.clients, addr)
+	}
+}
+
+func (s *Sentinel) handleConn(conn net.Conn) error {
+	const op = "sentinel.handleConn"
+	log := s.Log.With(slog.String("op", op), slog.String("connection address", conn.RemoteAddr().String()))
+	defer conn.Close()
+	rd := resp.NewReader(conn)
+	password, err := readPassword(rd)
+	if err != nil && !errors.Is(err, ErrInvalidPassword) {
+		log.Error("failed to read password from peer")
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err != nil || s.Password != password {
+		log.Error("peer with wrong password")
+		if err := binary.Write(conn, binary.BigEndian, false); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, ErrInvalidPassword)
+	}
+	if err := binary.Write(conn, binary.BigEndian, true); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	for {
+		v, _, err := rd.ReadValue()
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		raw, err := v.MarshalRESP()
+		if err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		if err := s.handleCommand(conn, raw); err != nil {
+			log.Error("got error while handling command", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// readPassword reads AUTH command sent by peer before the first command and returns its password,
+// ErrInvalidPassword is returned for other commands since sentinel has no users except the default one
+func readPassword(rd *resp.Reader) (string, error) {
+	v, _, err := rd.ReadValue()
+	if err != nil {
+		return "", err
+	}
+	raw, err := v.MarshalRESP()
+	if err != nil {
+		return "", err
+	}
+	cmd, err := command.ParseCommand(string(raw))
+	if err != nil {
+		return "", ErrInvalidPassword
+	}
+	auth, ok := cmd.(command.AuthCommand)
+	if !ok || auth.User != command.DefaultUser {
+		return "", ErrInvalidPassword
+	}
+	return auth.Password, nil
+}
+
+// handleCommand executes PING and SENTINEL commands, other commands are rejected
+func (s *Sentinel) handleCommand(w io.Writer, raw []byte) error {
+	cmd, err := command.ParseCommand(string(raw))
+	if err != nil {
+		if err := binary.Write(w, binary.BigEndian, false); err != nil {
+			return err
+		}
+		return err
+	}
+	var list [][]byte
+	switch v := cmd.(type) {
+	case command.PingCommand:
+		return binary.Write(w, binary.BigEndian, true)
+	case command.SentinelCommand:
+		list, err = s.sentinelCommand(v)
+	default:
+		err = ErrUnknownCommand
+	}
+	if err != nil {
+		if err := binary.Write(w, binary.BigEndian, false); err != nil {
+			return err
+		}
+		return err
+	}
+	return writeListResponse(w, list)
+}
+
+func (s *Sentinel) sentinelCommand(cmd command.SentinelCommand) ([][]byte, error) {
+	switch cmd.Subcommand {
+	case "GET-MASTER-ADDR-BY-NAME":
+		if len(cmd.Args) != 1 || cmd.Args[0] != s.MasterName {
+			return nil, ErrUnknownMaster
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return [][]byte{[]byte(s.masterHost), []byte(s.masterPort)}, nil
+	case "MASTER":
+		if len(cmd.Args) != 1 || cmd.Args[0] != s.MasterName {
+			return nil, ErrUnknownMaster
+		}
+		return s.masterInfo(), nil
+	case "IS-MASTER-DOWN-BY-ADDR":
+		if len(cmd.Args) != 4 {
+			return nil, command.ErrUnknownCommandArguments
+		}
+		epoch, err := strconv.ParseInt(cmd.Args[2], 10, 64)
+		if err != nil {
+			return nil, command.ErrUnknownCommandArguments
+		}
+		return s.isMasterDown(cmd.Args[0], cmd.Args[1], epoch, cmd.Args[3]), nil
+	}
+	return nil, ErrUnknownSubcommand
+}
+
+// masterInfo returns state of the primary as field-value pairs
+func (s *Sentinel) masterInfo() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flags := "master"
+	if s.subjectivelyDown() {
+		flags = "s_down,master"
+	}
+	return [][]byte{
+		[]byte("name"), []byte(s.MasterName),
+		[]byte("ip"), []byte(s.masterHost),
+		[]byte("port"), []byte(s.masterPort),
+		[]byte("config-epoch"), []byte(strconv.FormatInt(s.configEpoch, 10)),
+		[]byte("flags"), []byte(flags),
+	}
+}
+
+// isMasterDown replies whether primary with given address is down in the view of this sentinel
+// and votes for the candidate if it's the first request in the epoch
+func (s *Sentinel) isMasterDown(host, port string, epoch int64, runID string) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	down := "0"
+	if host == s.masterHost && port == s.masterPort && s.subjectivelyDown() {
+		down = "1"
+	}
+	if epoch > s.currentEpoch {
+		s.currentEpoch = epoch
+	}
+	if runID != "*" && epoch > s.leaderEpoch {
+		s.leader = runID
+		s.leaderEpoch = epoch
+		s.Log.Info("voted for failover leader", slog.String("leader", runID), slog.Int64("epoch", epoch))
+	}
+	leader := s.leader
+	if len(leader) == 0 {
+		leader = "*"
+	}
+	return [][]byte{[]byte(down), []byte(leader), []byte(strconv.FormatInt(s.leaderEpoch, 10))}
+}
+
+// subjectivelyDown reports whether primary didn't respond for DownAfter, must be called with mu locked
+func (s *Sentinel) subjectivelyDown() bool {
+	return time.Since(s.lastOK) > s.DownAfter
+}
+
+// client returns cached connection to the server or sentinel with given address
+func (s *Sentinel) client(ctx context.Context, addr, password string) (*client.Client, error) {
+	s.mu.Lock()
+	c, ok := s.clients[addr]
+	s.mu.Unlock()
+	if ok {
+		return c, nil
+	}
+	c, err := client.New(ctx, addr, password)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.clients[addr]; ok {
+		c.Close()
+		return old, nil
+	}
+	s.clients[addr] = c
+	return c, nil
+}
+
+// dropClient closes connection after failed request, so the next request reconnects
+func (s *Sentinel) dropClient(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.clients[addr]; ok {
+		c.Close()
+		delete(s.clients, addr)
+	}
+}
+
+// writeListResponse writes successful response with a list of values
+func writeListResponse(w io.Writer, list [][]byte) error {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, true)
+	binary.Write(buf, binary.BigEndian, int64(len(list)))
+	for _, val := range list {
+		binary.Write(buf, binary.BigEndian, int64(len(val)))
+		buf.Write(val)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,111 @@
+package sentinel
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+	"github.com/ArtemNovok/simpleRedisCl/internal/server"
+	"github.com/ArtemNovok/simpleRedisCl/internal/testserver"
+	"github.com/stretchr/testify/require"
+)
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("condition is not met")
+}
+
+func Test_Failover(t *testing.T) {
+	ctx := context.Background()
+	ind := 0
+	primary := testserver.Start(t, server.Config{})
+	testserver.Start(t, server.Config{PrimaryAddr: primary.Addr})
+	testserver.Start(t, server.Config{PrimaryAddr: primary.Addr})
+
+	// sentinels listen before any of them starts, so they know addresses of each other
+	listeners := make([]net.Listener, 3)
+	addrs := make([]string, len(listeners))
+	for i := range listeners {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+		listeners[i], addrs[i] = ln, ln.Addr().String()
+	}
+	for i, ln := range listeners {
+		var peers []string
+		for j, peer := range addrs {
+			if j != i {
+				peers = append(peers, peer)
+			}
+		}
+		s, err := New(Config{
+			ListenAddr:      addrs[i],
+			Log:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+			MasterAddr:      primary.Addr,
+			Sentinels:       peers,
+			Quorum:          2,
+			PingInterval:    100 * time.Millisecond,
+			DownAfter:       500 * time.Millisecond,
+			FailoverTimeout: 2 * time.Second,
+		})
+		require.Nil(t, err)
+		go s.Serve(ln)
+		t.Cleanup(s.Stop)
+	}
+	opts := client.FailoverOptions{MasterName: "mymaster", SentinelAddrs: addrs}
+	var cl *client.Client
+	waitFor(t, 5*time.Second, func() bool {
+		var err error
+		cl, err = client.NewFailover(ctx, opts)
+		return err == nil
+	})
+	require.Nil(t, cl.Set(ctx, "key", "value", ind))
+	waitFor(t, 5*time.Second, func() bool {
+		role, err := cl.Role(ctx)
+		require.Nil(t, err)
+		return len(role.Replicas) == 2 && role.Replicas[0].Offset == role.Offset && role.Replicas[1].Offset == role.Offset
+	})
+	cl.Close()
+
+	primary.Stop()
+	var newPrimary string
+	waitFor(t, 15*time.Second, func() bool {
+		for _, addr := range addrs {
+			c, err := client.New(ctx, addr, "")
+			if err != nil {
+				return false
+			}
+			masterAddr, err := c.SentinelMasterAddr(ctx, "mymaster")
+			c.Close()
+			if err != nil || masterAddr == primary.Addr || (newPrimary != "" && masterAddr != newPrimary) {
+				return false
+			}
+			newPrimary = masterAddr
+		}
+		return true
+	})
+
+	cl, err := client.NewFailover(ctx, opts)
+	require.Nil(t, err)
+	defer cl.Close()
+	val, err := cl.Get(ctx, "key", ind)
+	require.Nil(t, err)
+	require.Equal(t, "value", val)
+	require.Nil(t, cl.Set(ctx, "key", "new value", ind))
+	// the other replica is reconfigured to replicate the new primary
+	waitFor(t, 5*time.Second, func() bool {
+		role, err := cl.Role(ctx)
+		require.Nil(t, err)
+		return len(role.Replicas) == 1 && role.Replicas[0].Offset == role.Offset
+	})
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/acl"
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/tidwall/resp"
+)
+
+// authenticate reads AUTH command sent by peer before the first command and checks its credentials.
+// Name of the user is returned even if credentials are invalid
+func (s *Server) authenticate(rd *resp.Reader) (string, error) {
+	v, _, err := rd.ReadValue()
+	if err != nil {
+		return command.DefaultUser, err
+	}
+	raw, err := v.MarshalRESP()
+	if err != nil {
+		return command.DefaultUser, err
+	}
+	req, err := command.ParseRequest(string(raw))
+	if err != nil {
+		return command.DefaultUser, err
+	}
+	auth, ok := req.Command.(command.AuthCommand)
+	if !ok {
+		return command.DefaultUser, acl.ErrWrongPass
+	}
+	return auth.User, s.acl.Authenticate(auth.User, auth.Password)
+}
+
+// authRequest encodes AUTH command with password of the default user, it's sent by connections
+// to other nodes and to the primary
+func authRequest(password string) []byte {
+	buf := &bytes.Buffer{}
+	// writing to buffer doesn't fail
+	_ = resp.NewWriter(buf).WriteArray([]resp.Value{resp.StringValue(command.CommandAuth), resp.StringValue(password)})
+	return buf.Bytes()
+}
+
+// checkACL checks that user of the peer may run the request, failure is written to the peer
+// unless the command is never answered
+func (s *Server) checkACL(from string, req command.Request) error {
+	s.mu.RLock()
+	user := s.users[from]
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	err := s.acl.Check(user, req)
+	if err == nil {
+		return nil
+	}
+	s.Log.Info("command rejected by ACL", slog.String("op", "server.checkACL"), slog.String("peer address", from),
+		slog.String("user", user), slog.String("command", req.Spec.Name))
+	if ok && !req.Spec.Has(command.FlagNoReply) {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			s.Log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+	}
+	return err
+}
+
+// Auth authenticates connection of the peer as user of cmd, the peer keeps its user if credentials are invalid
+func (s *Server) Auth(from string, cmd command.AuthCommand) error {
+	const op = "server.Auth"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from), slog.String("user", cmd.User))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if err := s.acl.Authenticate(cmd.User, cmd.Password); err != nil {
+		log.Error("peer failed to authenticate")
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	s.mu.Lock()
+	s.users[from] = cmd.User
+	s.mu.Unlock()
+	if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// ACL manages users. SETUSER writes status, GETUSER writes pairs of field name and value for flags, passwords,
+// commands and keys, values with many items are separated by space. LIST writes rules of every user,
+// DELUSER writes number of deleted users and WHOAMI writes user of the peer
+func (s *Server) ACL(from string, cmd command.ACLCommand) error {
+	const op = "server.ACL"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	whoami := s.users[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	var respond func() error
+	var err error
+	switch cmd.Subcommand {
+	case command.ACLSetUser:
+		err = s.acl.SetUser(cmd.Args[0], cmd.Args[1:]...)
+		respond = func() error { return binary.Write(peer.Conn, binary.BigEndian, true) }
+	case command.ACLGetUser:
+		var user acl.User
+		user, err = s.acl.GetUser(cmd.Args[0])
+		respond = func() error {
+			return writeListResponse(peer.Conn, [][]byte{
+				[]byte("flags"), []byte(strings.Join(user.Flags, " ")),
+				[]byte("passwords"), []byte(strings.Join(user.Passwords, " ")),
+				[]byte("commands"), []byte(user.Commands),
+				[]byte("keys"), []byte(strings.Join(user.Keys, " ")),
+			})
+		}
+	case command.ACLList:
+		respond = func() error {
+			var list [][]byte
+			for _, rules := range s.acl.List() {
+				list = append(list, []byte(rules))
+			}
+			return writeListResponse(peer.Conn, list)
+		}
+	case command.ACLDelUser:
+		var n int
+		n, err = s.acl.DelUser(cmd.Args...)
+		respond = func() error { return writeValueResponse(peer.Conn, []byte(strconv.Itoa(n))) }
+	case command.ACLWhoAmI:
+		respond = func() error { return writeValueResponse(peer.Conn, []byte(whoami)) }
+	}
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := respond(); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	return nil
+}
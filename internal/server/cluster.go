@@ -0,0 +1,918 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/ArtemNovok/simpleRedisCl/internal/hashslot"
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+)
+
+const (
+	defaultClusterPingInterval = time.Second
+	defaultClusterNodeTimeout  = 5 * time.Second
+
+	clusterDialTimeout = time.Second
+
+	// subcommands of CLUSTER
+	clusterPing                = "PING"
+	clusterMeet                = "MEET"
+	clusterAddSlots            = "ADDSLOTS"
+	clusterAddSlotsRange       = "ADDSLOTSRANGE"
+	clusterNodes               = "NODES"
+	clusterSlots               = "SLOTS"
+	clusterInfo                = "INFO"
+	clusterMyID                = "MYID"
+	clusterKeySlot             = "KEYSLOT"
+	clusterCountKeysInSlot     = "COUNTKEYSINSLOT"
+	clusterGetKeysInSlot       = "GETKEYSINSLOT"
+	clusterSetSlot             = "SETSLOT"
+	clusterSetSlotImporting    = "IMPORTING"
+	clusterSetSlotMigrating    = "MIGRATING"
+	clusterSetSlotNode         = "NODE"
+	clusterSetSlotStable       = "STABLE"
+	clusterErrDisabled         = "ERR This instance has cluster support disabled"
+	clusterErrCrossSlot        = "CROSSSLOT Keys in request don't hash to the same slot"
+	clusterErrSlotNotServed    = "CLUSTERDOWN Hash slot not served"
+	clusterErrUnknownSubcomand = "ERR Unknown CLUSTER subcommand"
+)
+
+var (
+	ErrInvalidSlot      = errors.New("invalid or out of range slot")
+	ErrSlotIsBusy       = errors.New("slot is already assigned")
+	ErrUnknownNode      = errors.New("unknown node")
+	ErrSlotIsNotMine    = errors.New("slot is not served by this node")
+	ErrSlotIsNotEmpty   = errors.New("slot still has keys")
+	ErrInvalidGossip    = errors.New("invalid gossip message")
+	ErrMigrationFailed  = errors.New("key migration failed")
+	ErrClusterArguments = errors.New("invalid CLUSTER arguments")
+)
+
+// clusterNode is a member of the cluster as it's seen by this node
+type clusterNode struct {
+	id          string
+	addr        string
+	configEpoch int64
+	pingSent    time.Time
+	// lastPong is the time of the last gossip received from the node,
+	// it's set to the time node is learned about before the first one
+	lastPong time.Time
+	myself   bool
+}
+
+// clusterState is a view of the cluster, it's guarded by clusterMu
+type clusterState struct {
+	myself *clusterNode
+	nodes  map[string]*clusterNode
+	slots  [hashslot.Count]*clusterNode
+	// slotEpochs are config epochs of owners when they claimed slots, slot released by its owner
+	// has epoch -1, so it's taken by any node that claims it
+	slotEpochs [hashslot.Count]int64
+	// migrating and importing are slots that are moved from or to this node
+	migrating map[int]*clusterNode
+	importing map[int]*clusterNode
+	// pending are addresses of nodes added by CLUSTER MEET, they are removed when node replies
+	pending map[string]struct{}
+	// asking are peers that sent ASKING, flag is reset by the next command
+	asking       map[string]struct{}
+	currentEpoch int64
+}
+
+// gossipMsg is a state of the node that nodes exchange with CLUSTER PING
+type gossipMsg struct {
+	ID           string       `json:"id"`
+	Addr         string       `json:"addr"`
+	ConfigEpoch  int64        `json:"config_epoch"`
+	CurrentEpoch int64        `json:"current_epoch"`
+	Slots        [][2]int     `json:"slots"`
+	Nodes        []gossipNode `json:"nodes"`
+}
+
+type gossipNode struct {
+	ID   string `json:"id"`
+	Addr string `json:"addr"`
+}
+
+// newClusterState returns state of a new cluster with the only node that doesn't serve any slots
+func newClusterState(addr string) *clusterState {
+	myself := &clusterNode{id: newReplID(), addr: addr, lastPong: time.Now(), myself: true}
+	return &clusterState{
+		myself:    myself,
+		nodes:     map[string]*clusterNode{myself.id: myself},
+		migrating: make(map[int]*clusterNode),
+		importing: make(map[int]*clusterNode),
+		pending:   make(map[string]struct{}),
+		asking:    make(map[string]struct{}),
+	}
+}
+
+// clusterAddr returns address announced to other nodes, host is 127.0.0.1 if it isn't set
+func clusterAddr(cfg Config) (string, error) {
+	if len(cfg.ClusterAnnounceAddr) != 0 {
+		return cfg.ClusterAnnounceAddr, nil
+	}
+	host, port, err := net.SplitHostPort(cfg.ListenAddr)
+	if err != nil {
+		return "", err
+	}
+	if len(host) == 0 {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// clusterEnabledInfo returns cluster section of INFO in Redis format
+func (s *Server) clusterEnabledInfo() string {
+	enabled := 0
+	if s.cluster != nil {
+		enabled = 1
+	}
+	return fmt.Sprintf("# Cluster\r\ncluster_enabled:%d\r\n", enabled)
+}
+
+// failing reports whether node didn't respond to gossip for too long
+func (s *Server) failing(n *clusterNode) bool {
+	return !n.myself && time.Since(n.lastPong) > s.ClusterNodeTimeout
+}
+
+// slotRanges returns sorted ranges of slots served by the node, must be called with clusterMu locked
+func (c *clusterState) slotRanges(n *clusterNode) [][2]int {
+	var res [][2]int
+	for slot := 0; slot < hashslot.Count; slot++ {
+		if c.slots[slot] != n {
+			continue
+		}
+		if len(res) != 0 && res[len(res)-1][1] == slot-1 {
+			res[len(res)-1][1] = slot
+			continue
+		}
+		res = append(res, [2]int{slot, slot})
+	}
+	return res
+}
+
+// gossip returns state of this node for other nodes, must be called with clusterMu locked
+func (c *clusterState) gossip() gossipMsg {
+	msg := gossipMsg{
+		ID:           c.myself.id,
+		Addr:         c.myself.addr,
+		ConfigEpoch:  c.myself.configEpoch,
+		CurrentEpoch: c.currentEpoch,
+		Slots:        c.slotRanges(c.myself),
+	}
+	for _, n := range c.nodes {
+		if !n.myself {
+			msg.Nodes = append(msg.Nodes, gossipNode{ID: n.id, Addr: n.addr})
+		}
+	}
+	return msg
+}
+
+// merge updates cluster view with the state of other node, slot is reassigned to the sender
+// if it isn't served, released or claimed with smaller config epoch, must be called with clusterMu locked
+func (c *clusterState) merge(msg gossipMsg) error {
+	if len(msg.ID) == 0 || len(msg.Addr) == 0 || msg.ID == c.myself.id {
+		return ErrInvalidGossip
+	}
+	now := time.Now()
+	c.currentEpoch = max(c.currentEpoch, msg.CurrentEpoch)
+	sender, ok := c.nodes[msg.ID]
+	if !ok {
+		sender = &clusterNode{id: msg.ID}
+		c.nodes[msg.ID] = sender
+	}
+	delete(c.pending, msg.Addr)
+	sender.addr = msg.Addr
+	sender.configEpoch = msg.ConfigEpoch
+	sender.lastPong = now
+	// nodes can't share config epoch, otherwise slot conflicts can't be resolved,
+	// the node with greater id takes a new one
+	if sender.configEpoch == c.myself.configEpoch && sender.id < c.myself.id {
+		c.currentEpoch++
+		c.myself.configEpoch = c.currentEpoch
+	}
+	var claimed [hashslot.Count]bool
+	for _, r := range msg.Slots {
+		if r[0] < 0 || r[1] >= hashslot.Count || r[0] > r[1] {
+			return ErrInvalidGossip
+		}
+		for slot := r[0]; slot <= r[1]; slot++ {
+			claimed[slot] = true
+		}
+	}
+	for slot := range claimed {
+		owner := c.slots[slot]
+		switch {
+		case owner == sender && claimed[slot]:
+			c.slotEpochs[slot] = sender.configEpoch
+		case owner == sender:
+			c.slotEpochs[slot] = -1
+		case !claimed[slot]:
+		case owner == nil || c.claimEpoch(slot) < sender.configEpoch:
+			c.assign(slot, sender)
+		}
+	}
+	for _, n := range msg.Nodes {
+		if _, ok := c.nodes[n.ID]; !ok && n.ID != c.myself.id && len(n.ID) != 0 && len(n.Addr) != 0 {
+			c.nodes[n.ID] = &clusterNode{id: n.ID, addr: n.Addr, lastPong: now}
+		}
+	}
+	return nil
+}
+
+// claimEpoch returns config epoch of the slot's claim, must be called with clusterMu locked
+func (c *clusterState) claimEpoch(slot int) int64 {
+	if c.slots[slot] == c.myself {
+		return c.myself.configEpoch
+	}
+	return c.slotEpochs[slot]
+}
+
+// assign makes the node owner of the slot, must be called with clusterMu locked
+func (c *clusterState) assign(slot int, n *clusterNode) {
+	if c.slots[slot] == c.myself && n != c.myself {
+		delete(c.migrating, slot)
+	}
+	c.slots[slot] = n
+	c.slotEpochs[slot] = n.configEpoch
+}
+
+// clusterLoop periodically exchanges state with all known nodes
+func (s *Server) clusterLoop() {
+	const op = "server.clusterLoop"
+	log := s.Log.With(slog.String("op", op))
+	conns := make(map[string]net.Conn)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	ticker := time.NewTicker(s.ClusterPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.quitCh:
+			return
+		case <-ticker.C:
+		}
+		s.clusterMu.Lock()
+		msg := s.cluster.gossip()
+		addrs := make([]string, 0, len(s.cluster.nodes)+len(s.cluster.pending))
+		for _, n := range s.cluster.nodes {
+			if !n.myself {
+				n.pingSent = time.Now()
+				addrs = append(addrs, n.addr)
+			}
+		}
+		for addr := range s.cluster.pending {
+			addrs = append(addrs, addr)
+		}
+		s.clusterMu.Unlock()
+		data, err := json.Marshal(msg)
+		if err != nil {
+			log.Error("failed to encode gossip", slog.String("error", err.Error()))
+			continue
+		}
+		for _, addr := range addrs {
+			reply, err := s.clusterPing(conns, addr, data)
+			if err != nil {
+				log.Debug("node didn't respond to gossip", slog.String("node", addr), slog.String("error", err.Error()))
+				continue
+			}
+			s.clusterMu.Lock()
+			err = s.cluster.merge(reply)
+			s.clusterMu.Unlock()
+			if err != nil {
+				log.Error("got invalid gossip", slog.String("node", addr), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// clusterPing sends gossip to the node and returns its state, connections are reused between pings
+func (s *Server) clusterPing(conns map[string]net.Conn, addr string, data []byte) (gossipMsg, error) {
+	var reply gossipMsg
+	conn, ok := conns[addr]
+	if !ok {
+		var err error
+		conn, err = s.dialNode(addr, clusterDialTimeout)
+		if err != nil {
+			return reply, err
+		}
+		conns[addr] = conn
+	}
+	conn.SetDeadline(time.Now().Add(s.ClusterNodeTimeout))
+	val, err := func() ([]byte, error) {
+		if err := writeCommand(conn, command.CommandCluster, clusterPing, string(data)); err != nil {
+			return nil, err
+		}
+		return readValue(conn, ErrInvalidGossip)
+	}()
+	if err != nil {
+		conn.Close()
+		delete(conns, addr)
+		return reply, err
+	}
+	if err := json.Unmarshal(val, &reply); err != nil {
+		return reply, ErrInvalidGossip
+	}
+	return reply, nil
+}
+
+// dialNode connects to other node of the cluster, nodes share the password
+func (s *Server) dialNode(addr string, timeout time.Duration) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(authRequest(s.Password)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := readStatus(conn, ErrInvalidPassword); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// clusterRedirect checks that keys of the command are served by this node, client is redirected
+// with MOVED or ASK error otherwise, returns true if command must not be executed. Admin commands
+// such as MIGRATE are executed by the node they are sent to
+func (s *Server) clusterRedirect(from string, req command.Request) (bool, error) {
+	const op = "server.clusterRedirect"
+	if s.cluster == nil || req.Spec.Has(command.FlagAdmin) {
+		return false, nil
+	}
+	keys := req.Keys()
+	if len(keys) == 0 {
+		return false, nil
+	}
+	slot := hashslot.Slot(keys[0])
+	s.clusterMu.Lock()
+	_, asking := s.cluster.asking[from]
+	delete(s.cluster.asking, from)
+	var msg string
+	owner := s.cluster.slots[slot]
+	switch {
+	case !sameSlot(keys, slot):
+		msg = clusterErrCrossSlot
+	case owner == s.cluster.myself:
+		// keys that are already moved are served by the target of migration
+		if target, ok := s.cluster.migrating[slot]; ok && !s.Storage.Exists(keys[0], req.Index()) {
+			msg = fmt.Sprintf("ASK %d %s", slot, target.addr)
+		}
+	case asking && s.cluster.importing[slot] != nil:
+	case owner == nil:
+		msg = clusterErrSlotNotServed
+	default:
+		msg = fmt.Sprintf("MOVED %d %s", slot, owner.addr)
+	}
+	s.clusterMu.Unlock()
+	if len(msg) == 0 {
+		return false, nil
+	}
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return true, fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if err := writeErrorResponse(peer.Conn, msg); err != nil {
+		return true, fmt.Errorf("%s:%w", op, err)
+	}
+	s.Log.Debug("command is redirected", slog.String("op", op), slog.String("peer address", from), slog.String("reply", msg))
+	return true, nil
+}
+
+func sameSlot(keys [][]byte, slot int) bool {
+	for _, key := range keys {
+		if hashslot.Slot(key) != slot {
+			return false
+		}
+	}
+	return true
+}
+
+// dropClusterPeer forgets ASKING flag of disconnected peer
+func (s *Server) dropClusterPeer(from string) {
+	if s.cluster == nil {
+		return
+	}
+	s.clusterMu.Lock()
+	defer s.clusterMu.Unlock()
+	delete(s.cluster.asking, from)
+}
+
+// Asking allows the next command of the peer to access keys of a slot that is being imported
+func (s *Server) Asking(from string) error {
+	const op = "server.Asking"
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if s.cluster == nil {
+		return writeErrorResponse(peer.Conn, clusterErrDisabled)
+	}
+	s.clusterMu.Lock()
+	s.cluster.asking[from] = struct{}{}
+	s.clusterMu.Unlock()
+	if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+// Cluster handles CLUSTER subcommands, their replies are the same as in Redis:
+// values for NODES, INFO, MYID, KEYSLOT, COUNTKEYSINSLOT and PING, lists for SLOTS and GETKEYSINSLOT
+// and status for others
+func (s *Server) Cluster(from string, subcommand string, args []string) error {
+	const op = "server.Cluster"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if s.cluster == nil {
+		return writeErrorResponse(peer.Conn, clusterErrDisabled)
+	}
+	var (
+		val  []byte
+		list [][]byte
+		err  error
+	)
+	isList := false
+	s.clusterMu.Lock()
+	switch subcommand {
+	case clusterPing:
+		val, err = s.clusterPingReply(args)
+	case clusterMeet:
+		err = s.clusterMeet(args)
+	case clusterAddSlots:
+		err = s.clusterAddSlots(args, false)
+	case clusterAddSlotsRange:
+		err = s.clusterAddSlots(args, true)
+	case clusterNodes:
+		val = []byte(s.clusterNodes())
+	case clusterSlots:
+		list, isList = s.clusterSlots(), true
+	case clusterInfo:
+		val = []byte(s.clusterInfo())
+	case clusterMyID:
+		val = []byte(s.cluster.myself.id)
+	case clusterKeySlot:
+		if len(args) != 1 {
+			err = ErrClusterArguments
+			break
+		}
+		val = []byte(strconv.Itoa(hashslot.Slot([]byte(args[0]))))
+	case clusterCountKeysInSlot:
+		var slot int
+		if slot, err = parseSlot(args, 1); err == nil {
+			val = []byte(strconv.Itoa(len(s.keysInSlot(slot, -1))))
+		}
+	case clusterGetKeysInSlot:
+		list, err = s.clusterGetKeysInSlot(args)
+		isList = true
+	case clusterSetSlot:
+		err = s.clusterSetSlot(args)
+	default:
+		s.clusterMu.Unlock()
+		if err := writeErrorResponse(peer.Conn, clusterErrUnknownSubcomand); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		return fmt.Errorf("%s:%w", op, command.ErrUnknownCommand)
+	}
+	s.clusterMu.Unlock()
+	switch {
+	case err != nil:
+		if err := writeErrorResponse(peer.Conn, "ERR "+err.Error()); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	case isList:
+		err = writeListResponse(peer.Conn, list)
+	case val != nil:
+		err = writeValueResponse(peer.Conn, val)
+	default:
+		err = binary.Write(peer.Conn, binary.BigEndian, true)
+	}
+	if err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Debug("cluster command is done", slog.String("subcommand", subcommand))
+	return nil
+}
+
+// clusterPingReply merges gossip of other node and returns state of this node
+func (s *Server) clusterPingReply(args []string) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, ErrClusterArguments
+	}
+	var msg gossipMsg
+	if err := json.Unmarshal([]byte(args[0]), &msg); err != nil {
+		return nil, ErrInvalidGossip
+	}
+	if err := s.cluster.merge(msg); err != nil {
+		return nil, err
+	}
+	return json.Marshal(s.cluster.gossip())
+}
+
+// clusterMeet adds node to the cluster, node is added when it responds to gossip
+func (s *Server) clusterMeet(args []string) error {
+	if len(args) != 2 {
+		return ErrClusterArguments
+	}
+	if _, err := strconv.Atoi(args[1]); err != nil {
+		return ErrClusterArguments
+	}
+	addr := net.JoinHostPort(args[0], args[1])
+	if addr != s.cluster.myself.addr {
+		s.cluster.pending[addr] = struct{}{}
+	}
+	return nil
+}
+
+// parseSlot parses slot that is the first of n arguments
+func parseSlot(args []string, n int) (int, error) {
+	if len(args) != n {
+		return 0, ErrClusterArguments
+	}
+	slot, err := strconv.Atoi(args[0])
+	if err != nil || slot < 0 || slot >= hashslot.Count {
+		return 0, ErrInvalidSlot
+	}
+	return slot, nil
+}
+
+// clusterAddSlots assigns slots or ranges of slots to this node, none is assigned if any of them is busy
+func (s *Server) clusterAddSlots(args []string, ranges bool) error {
+	if len(args) == 0 || (ranges && len(args)%2 != 0) {
+		return ErrClusterArguments
+	}
+	var slots []int
+	for i := 0; i < len(args); i++ {
+		start, err := strconv.Atoi(args[i])
+		if err != nil || start < 0 || start >= hashslot.Count {
+			return ErrInvalidSlot
+		}
+		end := start
+		if ranges {
+			i++
+			end, err = strconv.Atoi(args[i])
+			if err != nil || end < start || end >= hashslot.Count {
+				return ErrInvalidSlot
+			}
+		}
+		for slot := start; slot <= end; slot++ {
+			if s.cluster.slots[slot] != nil {
+				return ErrSlotIsBusy
+			}
+			slots = append(slots, slot)
+		}
+	}
+	for _, slot := range slots {
+		s.cluster.assign(slot, s.cluster.myself)
+	}
+	return nil
+}
+
+// sortedNodes returns nodes sorted by id, must be called with clusterMu locked
+func (c *clusterState) sortedNodes() []*clusterNode {
+	res := make([]*clusterNode, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		res = append(res, n)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].id < res[j].id
+	})
+	return res
+}
+
+// clusterNodes returns CLUSTER NODES reply, a line per node:
+// <id> <ip:port@cport> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> ...
+func (s *Server) clusterNodes() string {
+	b := &strings.Builder{}
+	for _, n := range s.cluster.sortedNodes() {
+		flags := "master"
+		if n.myself {
+			flags = "myself,master"
+		} else if s.failing(n) {
+			flags = "master,fail?"
+		}
+		link := "connected"
+		if s.failing(n) {
+			link = "disconnected"
+		}
+		var pingSent, pongRecv int64
+		if !n.myself {
+			if !n.pingSent.IsZero() {
+				pingSent = n.pingSent.UnixMilli()
+			}
+			pongRecv = n.lastPong.UnixMilli()
+		}
+		_, port, _ := net.SplitHostPort(n.addr)
+		fmt.Fprintf(b, "%s %s@%s %s - %d %d %d %s", n.id, n.addr, port, flags, pingSent, pongRecv, n.configEpoch, link)
+		for _, r := range s.cluster.slotRanges(n) {
+			if r[0] == r[1] {
+				fmt.Fprintf(b, " %d", r[0])
+			} else {
+				fmt.Fprintf(b, " %d-%d", r[0], r[1])
+			}
+		}
+		if n.myself {
+			for _, slot := range sortedSlots(s.cluster.migrating) {
+				fmt.Fprintf(b, " [%d->-%s]", slot, s.cluster.migrating[slot].id)
+			}
+			for _, slot := range sortedSlots(s.cluster.importing) {
+				fmt.Fprintf(b, " [%d-<-%s]", slot, s.cluster.importing[slot].id)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func sortedSlots(m map[int]*clusterNode) []int {
+	res := make([]int, 0, len(m))
+	for slot := range m {
+		res = append(res, slot)
+	}
+	sort.Ints(res)
+	return res
+}
+
+// clusterSlots returns CLUSTER SLOTS reply flattened to a list: start, end, ip, port and id of every range
+func (s *Server) clusterSlots() [][]byte {
+	var list [][]byte
+	for slot := 0; slot < hashslot.Count; {
+		owner := s.cluster.slots[slot]
+		end := slot
+		for end+1 < hashslot.Count && s.cluster.slots[end+1] == owner {
+			end++
+		}
+		if owner != nil {
+			host, port, _ := net.SplitHostPort(owner.addr)
+			list = append(list, []byte(strconv.Itoa(slot)), []byte(strconv.Itoa(end)),
+				[]byte(host), []byte(port), []byte(owner.id))
+		}
+		slot = end + 1
+	}
+	return list
+}
+
+// clusterInfo returns CLUSTER INFO reply, cluster is ok if all slots are served by reachable nodes
+func (s *Server) clusterInfo() string {
+	assigned, pfail := 0, 0
+	size := make(map[*clusterNode]struct{})
+	for _, owner := range s.cluster.slots {
+		if owner == nil {
+			continue
+		}
+		assigned++
+		size[owner] = struct{}{}
+		if s.failing(owner) {
+			pfail++
+		}
+	}
+	state := "ok"
+	if assigned != hashslot.Count || pfail != 0 {
+		state = "fail"
+	}
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "cluster_state:%s\r\n", state)
+	fmt.Fprintf(b, "cluster_slots_assigned:%d\r\n", assigned)
+	fmt.Fprintf(b, "cluster_slots_ok:%d\r\n", assigned-pfail)
+	fmt.Fprintf(b, "cluster_slots_pfail:%d\r\n", pfail)
+	fmt.Fprintf(b, "cluster_slots_fail:%d\r\n", 0)
+	fmt.Fprintf(b, "cluster_known_nodes:%d\r\n", len(s.cluster.nodes))
+	fmt.Fprintf(b, "cluster_size:%d\r\n", len(size))
+	fmt.Fprintf(b, "cluster_current_epoch:%d\r\n", s.cluster.currentEpoch)
+	fmt.Fprintf(b, "cluster_my_epoch:%d\r\n", s.cluster.myself.configEpoch)
+	return b.String()
+}
+
+// slotKey is a key of the slot in the database with index
+type slotKey struct {
+	index int
+	key   []byte
+}
+
+// keysInSlot returns keys of the slot from all databases, at most count keys are returned if it's not negative
+func (s *Server) keysInSlot(slot int, count int) []slotKey {
+	var res []slotKey
+	for index := range s.Storage.DBS {
+		for _, key := range s.Storage.Keys(index) {
+			if count >= 0 && len(res) >= count {
+				return res
+			}
+			if hashslot.Slot(key) == slot {
+				res = append(res, slotKey{index: index, key: key})
+			}
+		}
+	}
+	return res
+}
+
+// clusterGetKeysInSlot returns CLUSTER GETKEYSINSLOT slot count reply as a list of index and key pairs
+func (s *Server) clusterGetKeysInSlot(args []string) ([][]byte, error) {
+	if len(args) != 2 {
+		return nil, ErrClusterArguments
+	}
+	slot, err := parseSlot(args[:1], 1)
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(args[1])
+	if err != nil || count < 0 {
+		return nil, ErrClusterArguments
+	}
+	var list [][]byte
+	for _, k := range s.keysInSlot(slot, count) {
+		list = append(list, []byte(strconv.Itoa(k.index)), k.key)
+	}
+	return list, nil
+}
+
+// clusterSetSlot handles CLUSTER SETSLOT slot IMPORTING|MIGRATING|NODE node-id and CLUSTER SETSLOT slot STABLE
+func (s *Server) clusterSetSlot(args []string) error {
+	if len(args) < 2 {
+		return ErrClusterArguments
+	}
+	slot, err := parseSlot(args[:1], 1)
+	if err != nil {
+		return err
+	}
+	state := strings.ToUpper(args[1])
+	if state == clusterSetSlotStable {
+		delete(s.cluster.migrating, slot)
+		delete(s.cluster.importing, slot)
+		return nil
+	}
+	if len(args) != 3 {
+		return ErrClusterArguments
+	}
+	n, ok := s.cluster.nodes[args[2]]
+	if !ok {
+		return ErrUnknownNode
+	}
+	myself := s.cluster.myself
+	switch state {
+	case clusterSetSlotMigrating:
+		if s.cluster.slots[slot] != myself {
+			return ErrSlotIsNotMine
+		}
+		if n == myself {
+			return ErrClusterArguments
+		}
+		s.cluster.migrating[slot] = n
+	case clusterSetSlotImporting:
+		if s.cluster.slots[slot] == myself || n == myself {
+			return ErrClusterArguments
+		}
+		s.cluster.importing[slot] = n
+	case clusterSetSlotNode:
+		if s.cluster.slots[slot] == myself && n != myself && len(s.keysInSlot(slot, 1)) != 0 {
+			return ErrSlotIsNotEmpty
+		}
+		// node that takes the slot needs the greatest config epoch, so other nodes accept its claim
+		if n == myself && s.cluster.slots[slot] != myself {
+			s.cluster.currentEpoch++
+			myself.configEpoch = s.cluster.currentEpoch
+		}
+		s.cluster.assign(slot, n)
+		delete(s.cluster.migrating, slot)
+		delete(s.cluster.importing, slot)
+	default:
+		return ErrClusterArguments
+	}
+	return nil
+}
+
+// Migrate moves key to other node, the key is sent with ASKING, so it's accepted by the node that imports
+// its slot, it blocks the server until transfer is done or timeout is over like in Redis
+func (s *Server) Migrate(from string, cmd command.MigrateCommand) error {
+	const op = "server.Migrate"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	snap, err := s.Storage.KeySnapshot(cmd.Key, cmd.Index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if snap.Empty() {
+		if err := writeValueResponse(peer.Conn, []byte("NOKEY")); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		return nil
+	}
+	target := net.JoinHostPort(cmd.Host, cmd.Port)
+	if err := s.transferKey(target, cmd, snap); err != nil {
+		log.Error("failed to migrate key", slog.String("target", target), slog.String("error", err.Error()))
+		if err := writeErrorResponse(peer.Conn, "IOERR "+err.Error()); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if _, err := s.Storage.Unlink(cmd.Key, cmd.Index); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	s.invalidate(from, cmd.Index, [][]byte{cmd.Key})
+	if err := s.propagate(command.CommandUnlink, cmd.Index, cmd.Key); err != nil {
+		log.Error("got error while logging", slog.String("error", err.Error()))
+	}
+	if err := writeValueResponse(peer.Conn, []byte("OK")); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("key is migrated", slog.String("key", string(cmd.Key)), slog.String("target", target))
+	return nil
+}
+
+// transferKey replaces key on the target node with the snapshot of the key
+func (s *Server) transferKey(target string, cmd command.MigrateCommand, snap storage.Snapshot) error {
+	timeout := time.Duration(cmd.Timeout) * time.Millisecond
+	conn, err := s.dialNode(target, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	cmds := append([][]byte{encodeCommand(command.CommandUnlink, cmd.Index, cmd.Key)}, encodeSnapshot(snap)...)
+	for i, data := range cmds {
+		if err := writeCommand(conn, command.CommandAsking); err != nil {
+			return err
+		}
+		if err := readStatus(conn, ErrMigrationFailed); err != nil {
+			return err
+		}
+		if _, err := conn.Write(data); err != nil {
+			return err
+		}
+		// UNLINK replies with number of removed keys
+		if i == 0 {
+			_, err = readValue(conn, ErrMigrationFailed)
+		} else {
+			err = readStatus(conn, ErrMigrationFailed)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unlink deletes key of any type and writes number of deleted keys
+func (s *Server) Unlink(from string, key []byte, index int) error {
+	const op = "server.Unlink"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	existed, err := s.Storage.Unlink(key, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	n := "0"
+	if existed {
+		n = "1"
+	}
+	if err := writeValueResponse(peer.Conn, []byte(n)); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	if existed {
+		if err := s.propagate(command.CommandUnlink, index, key); err != nil {
+			log.Error("got error while logging", slog.String("error", err.Error()))
+		}
+	}
+	log.Info("key is unlinked", slog.String("key", string(key)))
+	return nil
+}
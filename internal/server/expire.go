@@ -0,0 +1,161 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+)
+
+// activeExpireInterval is an interval of deleting expired keys, keys are also checked on access
+const activeExpireInterval = 100 * time.Millisecond
+
+// setLogArgs returns arguments of unconditional SET, expiration is written as absolute PXAT,
+// so replicas and data recovery expire the key at the same time
+func setLogArgs(key, val []byte, expireAt time.Time) [][]byte {
+	args := [][]byte{key, val}
+	if !expireAt.IsZero() {
+		args = append(args, []byte("PXAT"), strconv.AppendInt(nil, expireAt.UnixMilli(), 10))
+	}
+	return args
+}
+
+// activeExpire deletes expired keys of all databases, deletion is propagated to replicas which
+// don't expire keys themselves and only hide them on access
+func (s *Server) activeExpire() {
+	const op = "server.activeExpire"
+	if s.isReplica() {
+		return
+	}
+	for index := range s.Storage.DBS {
+		for _, key := range s.Storage.DeleteExpired(index) {
+			s.notifyKeyspaceEvent(notifyExpired, "expired", key, index)
+			s.invalidate("", index, [][]byte{key})
+			if err := s.logWrite(command.CommandDelete, index, key); err != nil {
+				s.Log.Error("got error while logging", slog.String("op", op), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// DelIfEq deletes key if its value is equal to val and writes number of deleted keys,
+// it's used to release a lock only by its owner
+func (s *Server) DelIfEq(from string, key, val []byte, index int) error {
+	const op = "server.DelIfEq"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	deleted, err := s.Storage.DelIfEq(key, val, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	n := "0"
+	if deleted {
+		n = "1"
+	}
+	if err := writeValueResponse(peer.Conn, []byte(n)); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	if deleted {
+		if err := s.propagate(command.CommandDelete, index, key); err != nil {
+			log.Error("got error while logging", slog.String("error", err.Error()))
+		}
+	}
+	log.Info("conditional delete is done", slog.String("key", string(key)), slog.Bool("deleted", deleted))
+	return nil
+}
+
+// PExpire sets expiration of the key and writes 1 if it's set or 0 if key doesn't exist
+func (s *Server) PExpire(from string, cmd command.PExpireCommand) error {
+	const op = "server.PExpire"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	at := cmd.At(s.Storage.Now())
+	set, err := s.Storage.ExpireAt(cmd.Key, at, cmd.Index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	n := "0"
+	if set {
+		n = "1"
+	}
+	if err := writeValueResponse(peer.Conn, []byte(n)); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	if set {
+		err := s.propagate(command.CommandPExpireAt, cmd.Index, cmd.Key, strconv.AppendInt(nil, at.UnixMilli(), 10))
+		if err != nil {
+			log.Error("got error while logging", slog.String("error", err.Error()))
+		}
+	}
+	log.Info("expiration is set", slog.String("key", string(cmd.Key)), slog.Bool("set", set))
+	return nil
+}
+
+// PTTL writes time to live of the key in milliseconds, -1 if key doesn't expire and -2 if it doesn't exist
+func (s *Server) PTTL(from string, key []byte, index int) error {
+	const op = "server.PTTL"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	ttl, err := s.Storage.PTTL(key, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := writeValueResponse(peer.Conn, strconv.AppendInt(nil, ttl, 10)); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// Incr increments value of the key and writes the new value
+func (s *Server) Incr(from string, key []byte, index int) error {
+	const op = "server.Incr"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	n, err := s.Storage.Incr(key, index)
+	if err != nil {
+		log.Error("failed to increment value", slog.String("key", string(key)))
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := writeValueResponse(peer.Conn, strconv.AppendInt(nil, n, 10)); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	if err := s.propagate(command.CommandIncr, index, key); err != nil {
+		log.Error("got error while logging", slog.String("error", err.Error()))
+	}
+	log.Info("value is incremented", slog.String("key", string(key)))
+	return nil
+}
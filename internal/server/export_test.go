@@ -0,0 +1,18 @@
+package server
+
+// StatusError is a status of error responses, it's exported for tests of server_test package
+const StatusError = statusError
+
+// PeerCount returns number of connected peers
+func (s *Server) PeerCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.peers)
+}
+
+// DropMasterLink closes connection of replica to its primary as if it was lost
+func (s *Server) DropMasterLink() {
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	s.master.conn.Close()
+}
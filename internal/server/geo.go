@@ -0,0 +1,170 @@
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+)
+
+// geoLogArgs returns recovery log arguments for GEOADD command
+func geoLogArgs(key []byte, locations []command.GeoLocation) [][]byte {
+	args := [][]byte{key}
+	for _, l := range locations {
+		args = append(args,
+			[]byte(strconv.FormatFloat(l.Longitude, 'f', -1, 64)),
+			[]byte(strconv.FormatFloat(l.Latitude, 'f', -1, 64)),
+			l.Member,
+		)
+	}
+	return args
+}
+
+func formatDist(dist float64) []byte {
+	return []byte(strconv.FormatFloat(dist, 'f', 4, 64))
+}
+
+func formatCoord(coord float64) []byte {
+	return []byte(strconv.FormatFloat(coord, 'f', -1, 64))
+}
+
+// GeoAdd adds locations of the members to geo set key
+func (s *Server) GeoAdd(from string, key []byte, locations []command.GeoLocation, index int) error {
+	const op = "server.GeoAdd"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	n, err := s.Storage.GeoAdd(key, command.GeoPoints(locations), index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	err = s.propagate(command.CommandGeoAdd, index, geoLogArgs(key, locations)...)
+	if err != nil {
+		log.Error("got error while logging", slog.String("error", err.Error()))
+	}
+	log.Info("locations are added", slog.Int("new members", n))
+	return nil
+}
+
+// GeoDist writes distance between two members in requested unit
+func (s *Server) GeoDist(from string, key, member1, member2 []byte, unit float64, index int) error {
+	const op = "server.GeoDist"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	dist, err := s.Storage.GeoDist(key, member1, member2, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := writeValueResponse(peer.Conn, formatDist(dist/unit)); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("distance is sended")
+	return nil
+}
+
+// GeoPos writes longitude and latitude of every member, empty values are written for unknown members
+func (s *Server) GeoPos(from string, key []byte, members [][]byte, index int) error {
+	const op = "server.GeoPos"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	positions, err := s.Storage.GeoPos(key, members, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	list := make([][]byte, 0, 2*len(positions))
+	for _, p := range positions {
+		if p == nil {
+			list = append(list, nil, nil)
+			continue
+		}
+		list = append(list, formatCoord(p.Longitude), formatCoord(p.Latitude))
+	}
+	if err := writeListResponse(peer.Conn, list); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("positions are sended")
+	return nil
+}
+
+// GeoSearch writes members that are located within given area, every found member is followed
+// by its distance, hash and coordinates if they are requested
+func (s *Server) GeoSearch(from string, cmd command.GeoSearchCommand) error {
+	const op = "server.GeoSearch"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	q := storage.GeoQuery{
+		FromMember: cmd.FromMember,
+		Longitude:  cmd.Longitude,
+		Latitude:   cmd.Latitude,
+		Radius:     cmd.Radius,
+		Width:      cmd.Width,
+		Height:     cmd.Height,
+		Count:      cmd.Count,
+		Any:        cmd.Any,
+	}
+	switch cmd.Sort {
+	case command.GeoSortAsc:
+		q.Sort = storage.GeoSortAsc
+	case command.GeoSortDesc:
+		q.Sort = storage.GeoSortDesc
+	}
+	res, err := s.Storage.GeoSearch(cmd.Key, q, cmd.Index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	var list [][]byte
+	for _, r := range res {
+		list = append(list, []byte(r.Member))
+		if cmd.WithDist {
+			list = append(list, formatDist(r.Dist/cmd.Unit))
+		}
+		if cmd.WithHash {
+			list = append(list, []byte(strconv.FormatUint(r.Hash, 10)))
+		}
+		if cmd.WithCoord {
+			list = append(list, formatCoord(r.Longitude), formatCoord(r.Latitude))
+		}
+	}
+	if err := writeListResponse(peer.Conn, list); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("search result is sended", slog.Int("members", len(res)))
+	return nil
+}
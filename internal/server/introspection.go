@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/tidwall/resp"
+)
+
+// Command describes commands executed by the server. COMMAND and COMMAND INFO write name, arity, flags separated
+// by space, first key, last key and key step of every command, COMMAND DOCS writes name, summary and syntax,
+// COMMAND COUNT writes number of commands and COMMAND GETKEYS writes keys of the given command
+func (s *Server) Command(from string, cmd command.IntrospectionCommand) error {
+	const op = "server.Command"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if cmd.Subcommand == command.IntrospectionCount {
+		if err := writeValueResponse(peer.Conn, []byte(strconv.Itoa(len(executableSpecs())))); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return nil
+	}
+	var list [][]byte
+	var err error
+	switch cmd.Subcommand {
+	case "", command.IntrospectionInfo:
+		var specs []*command.Spec
+		specs, err = lookupSpecs(cmd.Args)
+		for _, spec := range specs {
+			list = append(list, commandInfo(spec)...)
+		}
+	case command.IntrospectionDocs:
+		var specs []*command.Spec
+		specs, err = lookupSpecs(cmd.Args)
+		for _, spec := range specs {
+			list = append(list, []byte(spec.Name), []byte(spec.Summary), []byte(spec.Syntax))
+		}
+	case command.IntrospectionGetKeys:
+		list, err = commandKeys(cmd.Args)
+	}
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := writeListResponse(peer.Conn, list); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// executableSpecs returns specs of commands executed by the server sorted by name
+func executableSpecs() []*command.Spec {
+	var res []*command.Spec
+	for _, spec := range command.Specs() {
+		if spec.Exec != nil {
+			res = append(res, spec)
+		}
+	}
+	return res
+}
+
+// lookupSpecs returns specs of commands with names, specs of all commands are returned if no name is given
+func lookupSpecs(names [][]byte) ([]*command.Spec, error) {
+	if len(names) == 0 {
+		return executableSpecs(), nil
+	}
+	res := make([]*command.Spec, 0, len(names))
+	for _, name := range names {
+		spec := command.Lookup(strings.ToUpper(string(name)))
+		if spec == nil || spec.Exec == nil {
+			return nil, command.ErrUnknownCommand
+		}
+		res = append(res, spec)
+	}
+	return res, nil
+}
+
+func commandInfo(spec *command.Spec) [][]byte {
+	return [][]byte{
+		[]byte(spec.Name),
+		[]byte(strconv.Itoa(spec.Arity)),
+		[]byte(strings.Join(spec.Flags.Names(), " ")),
+		[]byte(strconv.Itoa(spec.FirstKey)),
+		[]byte(strconv.Itoa(spec.LastKey)),
+		[]byte(strconv.Itoa(spec.KeyStep)),
+	}
+}
+
+// commandKeys parses args as a command and returns its keys
+func commandKeys(args [][]byte) ([][]byte, error) {
+	vals := make([]resp.Value, 0, len(args))
+	vals = append(vals, resp.StringValue(strings.ToUpper(string(args[0]))))
+	for _, arg := range args[1:] {
+		vals = append(vals, resp.BytesValue(arg))
+	}
+	req, err := command.NewRequest(vals)
+	if err != nil {
+		return nil, err
+	}
+	if req.Spec.Exec == nil {
+		return nil, command.ErrUnknownCommand
+	}
+	return req.Keys(), nil
+}
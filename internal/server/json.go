@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+)
+
+// JSONSet sets JSON value at the path of the document key
+func (s *Server) JSONSet(from string, key, path, value []byte, index int) error {
+	const op = "server.JSONSet"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if err := s.Storage.JSONSet(key, path, value, index); err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	err := s.propagate(command.CommandJSONSet, index, key, path, value)
+	if err != nil {
+		log.Error("got error while logging", slog.String("error", err.Error()))
+	}
+	log.Info("json value is set", slog.String("path", string(path)))
+	return nil
+}
+
+// JSONGet writes JSON values matched by the paths of the document key
+func (s *Server) JSONGet(from string, key []byte, paths [][]byte, index int) error {
+	const op = "server.JSONGet"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	res, err := s.Storage.JSONGet(key, paths, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := writeValueResponse(peer.Conn, res); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("json value is sended")
+	return nil
+}
+
+// JSONDel deletes JSON values matched by the path and writes number of deleted values
+func (s *Server) JSONDel(from string, key, path []byte, index int) error {
+	const op = "server.JSONDel"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	n, err := s.Storage.JSONDel(key, path, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := writeValueResponse(peer.Conn, []byte(strconv.Itoa(n))); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	if n > 0 {
+		err = s.propagate(command.CommandJSONDel, index, key, path)
+		if err != nil {
+			log.Error("got error while logging", slog.String("error", err.Error()))
+		}
+	}
+	log.Info("json values are deleted", slog.Int("deleted", n))
+	return nil
+}
+
+// JSONNumIncrBy increments numbers matched by the path and writes new values
+func (s *Server) JSONNumIncrBy(from string, key, path, value []byte, index int) error {
+	const op = "server.JSONNumIncrBy"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	res, err := s.Storage.JSONNumIncrBy(key, path, value, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := writeValueResponse(peer.Conn, res); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	err = s.propagate(command.CommandJSONNumIncrBy, index, key, path, value)
+	if err != nil {
+		log.Error("got error while logging", slog.String("error", err.Error()))
+	}
+	log.Info("json numbers are incremented", slog.String("value", string(value)))
+	return nil
+}
+
+// JSONArrAppend appends values to arrays matched by the path and writes their new lengths
+func (s *Server) JSONArrAppend(from string, key, path []byte, values [][]byte, index int) error {
+	const op = "server.JSONArrAppend"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	lens, err := s.Storage.JSONArrAppend(key, path, values, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	list := make([][]byte, 0, len(lens))
+	for _, n := range lens {
+		list = append(list, []byte(strconv.Itoa(n)))
+	}
+	if err := writeListResponse(peer.Conn, list); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	err = s.propagate(command.CommandJSONArrAppend, index, append([][]byte{key, path}, values...)...)
+	if err != nil {
+		log.Error("got error while logging", slog.String("error", err.Error()))
+	}
+	log.Info("values are appended to json arrays")
+	return nil
+}
+
+// JSONType writes types of JSON values matched by the path
+func (s *Server) JSONType(from string, key, path []byte, index int) error {
+	const op = "server.JSONType"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	types, err := s.Storage.JSONType(key, path, index)
+	if err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	list := make([][]byte, 0, len(types))
+	for _, t := range types {
+		list = append(list, []byte(t))
+	}
+	if err := writeListResponse(peer.Conn, list); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("json types are sended")
+	return nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+)
+
+// Keyspace event classes, see NotifyKeyspaceEvents in Config
+const (
+	notifyKeyspace = 1 << iota
+	notifyKeyevent
+	notifyGeneric
+	notifyString
+	notifyList
+	notifyGeo
+	notifyExpired
+	notifyEvicted
+	notifyJSON
+	notifyAll = notifyGeneric | notifyString | notifyList | notifyGeo | notifyExpired | notifyEvicted | notifyJSON
+)
+
+var (
+	ErrInvalidNotifyFlags = errors.New("invalid keyspace events flags")
+)
+
+type keyspaceEvent struct {
+	class int
+	name  string
+}
+
+// writeEvents maps commands of the recovery log and the replication stream to keyspace events
+var writeEvents = map[string]keyspaceEvent{
+	command.CommandSet:           {notifyString, "set"},
+	command.CommandAdd:           {notifyString, "incrby"},
+	command.CommandAddN:          {notifyString, "incrby"},
+	command.CommandIncr:          {notifyString, "incrby"},
+	command.CommandDelete:        {notifyGeneric, "del"},
+	command.CommandDeleteL:       {notifyGeneric, "del"},
+	command.CommandUnlink:        {notifyGeneric, "del"},
+	command.CommandPExpireAt:     {notifyGeneric, "expire"},
+	command.CommandLPush:         {notifyList, "lpush"},
+	command.CommandDelAll:        {notifyList, "lrem"},
+	command.CommandDelElemL:      {notifyList, "lrem"},
+	command.CommandGeoAdd:        {notifyGeo, "geoadd"},
+	command.CommandJSONSet:       {notifyJSON, "json.set"},
+	command.CommandJSONDel:       {notifyJSON, "json.del"},
+	command.CommandJSONNumIncrBy: {notifyJSON, "json.numincrby"},
+	command.CommandJSONArrAppend: {notifyJSON, "json.arrappend"},
+}
+
+// parseNotifyFlags parses keyspace events flags:
+// K - keyspace events (__keyspace@<db>__:<key> channel, event name is a message),
+// E - keyevent events (__keyevent@<db>__:<event> channel, key is a message),
+// g - generic commands (del), $ - string commands, l - list commands, z - geo commands,
+// x - expired events, e - evicted events, d - JSON commands, A - alias for "g$lzxed"
+func parseNotifyFlags(flags string) (int, error) {
+	res := 0
+	for _, c := range flags {
+		switch c {
+		case 'K':
+			res |= notifyKeyspace
+		case 'E':
+			res |= notifyKeyevent
+		case 'g':
+			res |= notifyGeneric
+		case '$':
+			res |= notifyString
+		case 'l':
+			res |= notifyList
+		case 'z':
+			res |= notifyGeo
+		case 'x':
+			res |= notifyExpired
+		case 'e':
+			res |= notifyEvicted
+		case 'd':
+			res |= notifyJSON
+		case 'A':
+			res |= notifyAll
+		default:
+			return 0, ErrInvalidNotifyFlags
+		}
+	}
+	return res, nil
+}
+
+// notifyKeyspaceEvent publishes keyspace and keyevent notifications
+// if class of the event is enabled by configuration
+func (s *Server) notifyKeyspaceEvent(class int, event string, key []byte, index int) {
+	if s.notifyFlags&class == 0 {
+		return
+	}
+	if s.notifyFlags&notifyKeyspace != 0 {
+		channel := fmt.Sprintf("__keyspace@%d__:%s", index, key)
+		s.publish([]byte(channel), []byte(event))
+	}
+	if s.notifyFlags&notifyKeyevent != 0 {
+		channel := fmt.Sprintf("__keyevent@%d__:%s", index, event)
+		s.publish([]byte(channel), key)
+	}
+}
+
+// notifyWrite publishes keyspace events of the write command, key is the first argument of every
+// write command and SET with PXAT also sets expiration of the key
+func (s *Server) notifyWrite(operation string, index int, args [][]byte) {
+	ev, ok := writeEvents[operation]
+	if !ok || len(args) == 0 {
+		return
+	}
+	s.notifyKeyspaceEvent(ev.class, ev.name, args[0], index)
+	if operation == command.CommandSet && len(args) > 2 {
+		s.notifyKeyspaceEvent(notifyGeneric, "expire", args[0], index)
+	}
+}
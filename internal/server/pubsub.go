@@ -0,0 +1,195 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/glob"
+)
+
+const (
+	pubSubSubscribe    = "subscribe"
+	pubSubPSubscribe   = "psubscribe"
+	pubSubUnsubscribe  = "unsubscribe"
+	pubSubPUnsubscribe = "punsubscribe"
+	pubSubMessage      = "message"
+	pubSubPMessage     = "pmessage"
+)
+
+// subscriptions returns number of channels and patterns peer is subscribed to,
+// must be called with psMu locked
+func (s *Server) subscriptions(from string) int {
+	n := 0
+	for _, peers := range s.channels {
+		if _, ok := peers[from]; ok {
+			n++
+		}
+	}
+	for _, peers := range s.patterns {
+		if _, ok := peers[from]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Subscribe subscribes peer to the channels, every subscription is confirmed with separate response
+func (s *Server) Subscribe(from string, channels [][]byte) error {
+	return s.subscribe(from, channels, s.channels, pubSubSubscribe)
+}
+
+// PSubscribe subscribes peer to the channels matched by the patterns
+func (s *Server) PSubscribe(from string, patterns [][]byte) error {
+	return s.subscribe(from, patterns, s.patterns, pubSubPSubscribe)
+}
+
+func (s *Server) subscribe(from string, names [][]byte, subs map[string]map[string]struct{}, kind string) error {
+	const op = "server.subscribe"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	s.psMu.Lock()
+	defer s.psMu.Unlock()
+	for _, name := range names {
+		peers, ok := subs[string(name)]
+		if !ok {
+			peers = make(map[string]struct{})
+			subs[string(name)] = peers
+		}
+		peers[from] = struct{}{}
+		count := strconv.Itoa(s.subscriptions(from))
+		if err := writeListResponse(peer.Conn, [][]byte{[]byte(kind), name, []byte(count)}); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		log.Info("peer is subscribed", slog.String(kind, string(name)))
+	}
+	return nil
+}
+
+// Unsubscribe unsubscribes peer from the channels or from all channels if none is given
+func (s *Server) Unsubscribe(from string, channels [][]byte) error {
+	return s.unsubscribe(from, channels, s.channels, pubSubUnsubscribe)
+}
+
+// PUnsubscribe unsubscribes peer from the patterns or from all patterns if none is given
+func (s *Server) PUnsubscribe(from string, patterns [][]byte) error {
+	return s.unsubscribe(from, patterns, s.patterns, pubSubPUnsubscribe)
+}
+
+func (s *Server) unsubscribe(from string, names [][]byte, subs map[string]map[string]struct{}, kind string) error {
+	const op = "server.unsubscribe"
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	s.psMu.Lock()
+	defer s.psMu.Unlock()
+	if len(names) == 0 {
+		for name, peers := range subs {
+			if _, ok := peers[from]; ok {
+				names = append(names, []byte(name))
+			}
+		}
+		sort.Slice(names, func(i, j int) bool { return string(names[i]) < string(names[j]) })
+	}
+	if len(names) == 0 {
+		count := strconv.Itoa(s.subscriptions(from))
+		if err := writeListResponse(peer.Conn, [][]byte{[]byte(kind), nil, []byte(count)}); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		return nil
+	}
+	for _, name := range names {
+		if peers, ok := subs[string(name)]; ok {
+			delete(peers, from)
+			if len(peers) == 0 {
+				delete(subs, string(name))
+			}
+		}
+		count := strconv.Itoa(s.subscriptions(from))
+		if err := writeListResponse(peer.Conn, [][]byte{[]byte(kind), name, []byte(count)}); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+	}
+	return nil
+}
+
+// dropSubscriptions removes all subscriptions of disconnected peer
+func (s *Server) dropSubscriptions(from string) {
+	s.psMu.Lock()
+	defer s.psMu.Unlock()
+	for _, subs := range []map[string]map[string]struct{}{s.channels, s.patterns} {
+		for name, peers := range subs {
+			delete(peers, from)
+			if len(peers) == 0 {
+				delete(subs, name)
+			}
+		}
+	}
+}
+
+// Publish sends message to all subscribers of the channel and writes number of receivers
+func (s *Server) Publish(from string, channel, message []byte) error {
+	const op = "server.Publish"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	n := s.publish(channel, message)
+	if err := writeValueResponse(peer.Conn, []byte(strconv.Itoa(n))); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("message is published", slog.String("channel", string(channel)), slog.Int("receivers", n))
+	return nil
+}
+
+// publish sends message to subscribers of the channel and subscribers of matching patterns,
+// returns number of receivers
+func (s *Server) publish(channel, message []byte) int {
+	const op = "server.publish"
+	log := s.Log.With(slog.String("op", op))
+	s.psMu.Lock()
+	defer s.psMu.Unlock()
+	n := 0
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for from := range s.channels[string(channel)] {
+		peer, ok := s.peers[from]
+		if !ok {
+			continue
+		}
+		if err := writeListResponse(peer.Conn, [][]byte{[]byte(pubSubMessage), channel, message}); err != nil {
+			log.Error("failed to deliver message", slog.String("peer address", from), slog.String("error", err.Error()))
+			continue
+		}
+		n++
+	}
+	for pattern, peers := range s.patterns {
+		if !glob.Match(pattern, string(channel)) {
+			continue
+		}
+		for from := range peers {
+			peer, ok := s.peers[from]
+			if !ok {
+				continue
+			}
+			if err := writeListResponse(peer.Conn, [][]byte{[]byte(pubSubPMessage), []byte(pattern), channel, message}); err != nil {
+				log.Error("failed to deliver message", slog.String("peer address", from), slog.String("error", err.Error()))
+				continue
+			}
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+)
+
+// RateLimit takes requests from the limiter and writes list with decision (1 if allowed, 0 otherwise),
+// number of remaining requests, retry after and reset after in milliseconds. New state of the limiter
+// is propagated as SET with absolute expiration, so it doesn't depend on the clock of replicas
+func (s *Server) RateLimit(from string, cmd command.RateLimitCommand) error {
+	const op = "server.RateLimit"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	res, state, err := s.Storage.RateLimit(cmd.Key, command.RateLimitAlgorithms[cmd.Algorithm], cmd.Limit, cmd.Period, cmd.Cost, cmd.Index)
+	if err != nil {
+		log.Error("failed to take requests from the limiter", slog.String("key", string(cmd.Key)))
+		if err := writeFailure(peer.Conn, err); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	allowed := "0"
+	if res.Allowed {
+		allowed = "1"
+	}
+	reply := [][]byte{
+		[]byte(allowed),
+		strconv.AppendInt(nil, res.Remaining, 10),
+		strconv.AppendInt(nil, res.RetryAfter.Milliseconds(), 10),
+		strconv.AppendInt(nil, res.ResetAfter.Milliseconds(), 10),
+	}
+	if err := writeListResponse(peer.Conn, reply); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	if state != nil {
+		err := s.propagate(command.CommandSet, cmd.Index, setLogArgs(state.Key, state.Val, state.ExpireAt)...)
+		if err != nil {
+			log.Error("got error while logging", slog.String("error", err.Error()))
+		}
+	}
+	log.Info("rate limit is checked", slog.String("key", string(cmd.Key)), slog.Bool("allowed", res.Allowed))
+	return nil
+}
@@ -0,0 +1,877 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+	"github.com/tidwall/resp"
+)
+
+const (
+	defaultReplBacklogSize = 1 << 20
+	replicaBufferSize      = 1024
+	replDialTimeout        = 5 * time.Second
+	replRetryInterval      = time.Second
+	replAckInterval        = time.Second
+
+	replRoleMaster  = "master"
+	replRoleReplica = "slave"
+
+	// states of the link with primary, the same as in Redis ROLE reply
+	replStateConnect    = "connect"
+	replStateConnecting = "connecting"
+	replStateSync       = "sync"
+	replStateConnected  = "connected"
+
+	replFullResync = "FULLRESYNC"
+	replContinue   = "CONTINUE"
+
+	replConfListeningPort = "listening-port"
+	replConfAck           = "ack"
+)
+
+var (
+	ErrReadOnlyReplica   = errors.New("can't write against a read only replica")
+	ErrInvalidPSyncReply = errors.New("invalid reply to PSYNC")
+	ErrReplicaExists     = errors.New("peer is already a replica")
+	ErrUnknownReplConf   = errors.New("unknown REPLCONF option")
+	errReplStopped       = errors.New("replication is stopped")
+)
+
+// replBacklog keeps the tail of the replication stream, so replicas that lost connection
+// can continue from their offset without full sync
+type replBacklog struct {
+	size int
+	data []byte
+	// start is replication offset of the first byte in data
+	start int64
+}
+
+func newReplBacklog(size int) *replBacklog {
+	return &replBacklog{size: size}
+}
+
+// offset returns replication offset, that is number of bytes ever written to the stream
+func (b *replBacklog) offset() int64 {
+	return b.start + int64(len(b.data))
+}
+
+func (b *replBacklog) write(p []byte) {
+	b.data = append(b.data, p...)
+	// backlog is trimmed only when it's twice as big as its size, so writes don't copy it every time
+	if len(b.data) > 2*b.size {
+		n := len(b.data) - b.size
+		b.data = append([]byte(nil), b.data[n:]...)
+		b.start += int64(n)
+	}
+}
+
+// since returns the stream starting from offset, false is returned if offset isn't in the backlog
+func (b *replBacklog) since(offset int64) ([]byte, bool) {
+	if offset < b.start || offset > b.offset() {
+		return nil, false
+	}
+	return append([]byte(nil), b.data[offset-b.start:]...), true
+}
+
+func (b *replBacklog) reset(offset int64) {
+	b.data = nil
+	b.start = offset
+}
+
+// replica is a replica connected to this server
+type replica struct {
+	host, port string
+	conn       net.Conn
+	// online is set after PSYNC, only online replicas receive the stream
+	online  bool
+	ch      chan []byte
+	ack     int64
+	ackTime time.Time
+}
+
+// writeLoop writes the stream to the replica, so slow replicas don't block the server loop
+func (r *replica) writeLoop() {
+	for data := range r.ch {
+		if _, err := r.conn.Write(data); err != nil {
+			// peer is dropped by its read loop after connection is closed
+			r.conn.Close()
+		}
+	}
+}
+
+// masterLink is a connection of this server with its primary
+type masterLink struct {
+	host, port string
+	state      string
+	conn       net.Conn
+	quit       chan struct{}
+}
+
+func (l *masterLink) addr() string {
+	return net.JoinHostPort(l.host, l.port)
+}
+
+// messages sent by replication goroutine to the server loop, they are ignored if the link
+// is not current anymore
+type replSync struct {
+	link     *masterLink
+	replID   string
+	offset   int64
+	snapshot []byte
+}
+type replContinued struct {
+	link   *masterLink
+	replID string
+}
+type replCommand struct {
+	link  *masterLink
+	value resp.Value
+}
+
+func newReplID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// encodeCommand encodes write command the same way as clients send it
+func encodeCommand(operation string, index int, args ...[]byte) []byte {
+	vals := make([]resp.Value, 0, len(args)+2)
+	vals = append(vals, resp.StringValue(operation))
+	for _, arg := range args {
+		vals = append(vals, resp.BytesValue(arg))
+	}
+	vals = append(vals, resp.StringValue(strconv.Itoa(index)))
+	data, _ := resp.ArrayValue(vals).MarshalRESP()
+	return data
+}
+
+// propagate publishes keyspace events of the command, writes it to the recovery log and sends it
+// to replicas, every write of clients and every command applied from primary goes through it
+func (s *Server) propagate(operation string, index int, args ...[]byte) error {
+	s.notifyWrite(operation, index, args)
+	return s.logWrite(operation, index, args...)
+}
+
+// logWrite is propagate without keyspace events, it's used by writes with their own events like expiration
+func (s *Server) logWrite(operation string, index int, args ...[]byte) error {
+	s.feedReplicas(encodeCommand(operation, index, args...))
+	return s.recoveryLogger.WriteLog(operation, index, args...)
+}
+
+func (s *Server) feedReplicas(data []byte) {
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	s.backlog.write(data)
+	for addr, r := range s.replicas {
+		if !r.online {
+			continue
+		}
+		select {
+		case r.ch <- data:
+		default:
+			s.Log.Error("replica can't keep up with the stream, dropping it", slog.String("replica address", addr))
+			r.conn.Close()
+			s.removeReplica(addr)
+		}
+	}
+}
+
+// removeReplica must be called with replMu locked
+func (s *Server) removeReplica(addr string) {
+	r, ok := s.replicas[addr]
+	if !ok {
+		return
+	}
+	if r.ch != nil {
+		close(r.ch)
+	}
+	delete(s.replicas, addr)
+}
+
+func (s *Server) dropReplica(addr string) {
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	s.removeReplica(addr)
+}
+
+// isReplica reports whether server replicates some primary
+func (s *Server) isReplica() bool {
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	return s.master != nil
+}
+
+// rejectWrite responds with failure to write command sent to a replica
+func (s *Server) rejectWrite(from string) error {
+	const op = "server.rejectWrite"
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if err := writeFailure(peer.Conn, ErrReadOnlyReplica); err != nil {
+		s.Log.Error("got error after sending response", slog.String("op", op), slog.String("error", err.Error()))
+	}
+	return fmt.Errorf("%s:%w", op, ErrReadOnlyReplica)
+}
+
+// snapshotCommands encodes the whole data set as write commands
+func (s *Server) snapshotCommands() ([]byte, error) {
+	snaps, err := s.Storage.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	for _, snap := range snaps {
+		for _, cmd := range encodeSnapshot(snap) {
+			buf.Write(cmd)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeSnapshot encodes database snapshot as write commands
+func encodeSnapshot(snap storage.Snapshot) [][]byte {
+	var res [][]byte
+	for _, kv := range snap.KV {
+		res = append(res, encodeCommand(command.CommandSet, snap.Index, setLogArgs(kv.Key, kv.Val, kv.ExpireAt)...))
+	}
+	for _, l := range snap.LST {
+		for _, val := range l.Values {
+			res = append(res, encodeCommand(command.CommandLPush, snap.Index, l.Key, val))
+		}
+	}
+	for _, g := range snap.GEO {
+		locations := make([]command.GeoLocation, 0, len(g.Points))
+		for _, p := range g.Points {
+			locations = append(locations, command.GeoLocation{
+				Longitude: p.Longitude,
+				Latitude:  p.Latitude,
+				Member:    p.Member,
+			})
+		}
+		res = append(res, encodeCommand(command.CommandGeoAdd, snap.Index, geoLogArgs(g.Key, locations)...))
+	}
+	for _, doc := range snap.JSON {
+		res = append(res, encodeCommand(command.CommandJSONSet, snap.Index, doc.Key, []byte(command.JSONRootPath), doc.Val))
+	}
+	return res
+}
+
+// applyValue executes write command received from primary, command is written to the recovery log
+// and sent further to replicas of this server if feed is true
+func (s *Server) applyValue(v resp.Value, feed bool) error {
+	raw, err := v.MarshalRESP()
+	if err != nil {
+		return err
+	}
+	req, err := command.ParseRequest(string(raw))
+	if err != nil {
+		return err
+	}
+	if req.Spec.Apply == nil {
+		return command.ErrUnknownCommand
+	}
+	s.invalidate("", req.Index(), req.Keys())
+	if err := s.apply(req.Command); err != nil {
+		return err
+	}
+	arr := v.Array()
+	index, _ := strconv.Atoi(arr[len(arr)-1].String())
+	args := make([][]byte, 0, len(arr)-2)
+	for _, arg := range arr[1 : len(arr)-1] {
+		args = append(args, arg.Bytes())
+	}
+	if feed {
+		return s.propagate(arr[0].String(), index, args...)
+	}
+	return s.recoveryLogger.WriteLog(arr[0].String(), index, args...)
+}
+
+// handleReplMessage handles messages of replication goroutine, it runs in the server loop
+func (s *Server) handleReplMessage(msg any) {
+	const op = "server.handleReplMessage"
+	log := s.Log.With(slog.String("op", op))
+	var link *masterLink
+	switch m := msg.(type) {
+	case replSync:
+		link = m.link
+	case replContinued:
+		link = m.link
+	case replCommand:
+		link = m.link
+	}
+	s.replMu.Lock()
+	current := s.master == link
+	s.replMu.Unlock()
+	if !current {
+		return
+	}
+	switch m := msg.(type) {
+	case replSync:
+		s.fullSync(m)
+	case replContinued:
+		s.replMu.Lock()
+		s.replID = m.replID
+		s.replMu.Unlock()
+		log.Info("continued replication", slog.String("replication id", m.replID))
+	case replCommand:
+		if err := s.applyValue(m.value, true); err != nil {
+			log.Error("failed to apply command from primary", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// fullSync replaces the data set with the snapshot received from primary
+func (s *Server) fullSync(m replSync) {
+	const op = "server.fullSync"
+	log := s.Log.With(slog.String("op", op))
+	s.Storage.Flush()
+	s.invalidateAll()
+	if err := s.recoveryLogger.Reset(); err != nil {
+		log.Error("failed to reset recovery log", slog.String("error", err.Error()))
+	}
+	rd := resp.NewReader(bytes.NewReader(m.snapshot))
+	n := 0
+	for {
+		v, _, err := rd.ReadValue()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Error("failed to read snapshot", slog.String("error", err.Error()))
+			break
+		}
+		if err := s.applyValue(v, false); err != nil {
+			log.Error("failed to apply snapshot command", slog.String("error", err.Error()))
+			continue
+		}
+		n++
+	}
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	s.replID = m.replID
+	s.replID2 = ""
+	s.replID2Offset = -1
+	s.backlog.reset(m.offset)
+	// replicas of this server have to resync with the new data set
+	for addr, r := range s.replicas {
+		r.conn.Close()
+		s.removeReplica(addr)
+	}
+	log.Info("done full sync with primary", slog.Int("commands", n), slog.Int64("offset", m.offset))
+}
+
+// replicaOf starts replication of the primary, it stops replication of the current one if any
+func (s *Server) replicaOf(host, port string) {
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	if s.master != nil {
+		if s.master.host == host && s.master.port == port {
+			return
+		}
+		s.stopMasterLink()
+	}
+	link := &masterLink{
+		host:  host,
+		port:  port,
+		state: replStateConnect,
+		quit:  make(chan struct{}),
+	}
+	s.master = link
+	go s.replicate(link, s.replID, s.backlog.offset())
+}
+
+// promote turns replica into a primary, current replication id is kept as secondary one,
+// so other replicas of the former primary can continue replication from this server
+func (s *Server) promote() {
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	if s.master == nil {
+		return
+	}
+	s.stopMasterLink()
+	s.master = nil
+	s.replID2 = s.replID
+	s.replID2Offset = s.backlog.offset()
+	s.replID = newReplID()
+}
+
+// stopMasterLink must be called with replMu locked
+func (s *Server) stopMasterLink() {
+	close(s.master.quit)
+	if s.master.conn != nil {
+		s.master.conn.Close()
+	}
+}
+
+// setLinkState updates state of the link, returns false if the link is stopped
+func (s *Server) setLinkState(link *masterLink, state string, conn net.Conn) bool {
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	select {
+	case <-link.quit:
+		return false
+	default:
+	}
+	link.state = state
+	link.conn = conn
+	return true
+}
+
+// replicate keeps connection with primary, it reconnects until replication is stopped
+func (s *Server) replicate(link *masterLink, replID string, offset int64) {
+	const op = "server.replicate"
+	log := s.Log.With(slog.String("op", op), slog.String("primary", link.addr()))
+	for {
+		err := s.syncWithMaster(link, &replID, &offset)
+		if errors.Is(err, errReplStopped) {
+			log.Info("replication is stopped")
+			return
+		}
+		log.Error("lost connection with primary", slog.String("error", err.Error()))
+		if !s.setLinkState(link, replStateConnect, nil) {
+			return
+		}
+		select {
+		case <-link.quit:
+			return
+		case <-s.quitCh:
+			return
+		case <-time.After(replRetryInterval):
+		}
+	}
+}
+
+// sendReplMessage passes message to the server loop
+func (s *Server) sendReplMessage(link *masterLink, msg any) error {
+	select {
+	case s.replCh <- msg:
+		return nil
+	case <-link.quit:
+		return errReplStopped
+	case <-s.quitCh:
+		return errReplStopped
+	}
+}
+
+// syncWithMaster connects to primary, synchronizes data set and then applies the stream
+// until connection is lost, replID and offset are updated as stream is received
+func (s *Server) syncWithMaster(link *masterLink, replID *string, offset *int64) error {
+	if !s.setLinkState(link, replStateConnecting, nil) {
+		return errReplStopped
+	}
+	conn, err := net.DialTimeout("tcp", link.addr(), replDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if !s.setLinkState(link, replStateConnecting, conn) {
+		return errReplStopped
+	}
+	reply, err := s.handshake(conn, *replID, *offset)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(reply))
+	switch {
+	case len(fields) == 3 && fields[0] == replFullResync:
+		off, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return ErrInvalidPSyncReply
+		}
+		if !s.setLinkState(link, replStateSync, conn) {
+			return errReplStopped
+		}
+		snapshot, err := readValue(conn, ErrInvalidPSyncReply)
+		if err != nil {
+			return err
+		}
+		*replID, *offset = fields[1], off
+		if err := s.sendReplMessage(link, replSync{link: link, replID: *replID, offset: *offset, snapshot: snapshot}); err != nil {
+			return err
+		}
+	case len(fields) == 2 && fields[0] == replContinue:
+		*replID = fields[1]
+		if err := s.sendReplMessage(link, replContinued{link: link, replID: *replID}); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidPSyncReply
+	}
+	if !s.setLinkState(link, replStateConnected, conn) {
+		return errReplStopped
+	}
+	var acked atomic.Int64
+	acked.Store(*offset)
+	done := make(chan struct{})
+	defer close(done)
+	go ackLoop(conn, &acked, done)
+	rd := resp.NewReader(conn)
+	for {
+		v, n, err := rd.ReadValue()
+		if err != nil {
+			return err
+		}
+		if err := s.sendReplMessage(link, replCommand{link: link, value: v}); err != nil {
+			return err
+		}
+		*offset += int64(n)
+		acked.Store(*offset)
+	}
+}
+
+// handshake authenticates with primary and requests replication from the offset,
+// returns reply to PSYNC
+func (s *Server) handshake(conn net.Conn, replID string, offset int64) ([]byte, error) {
+	password := s.MasterPassword
+	if len(password) == 0 {
+		password = s.Password
+	}
+	if _, err := conn.Write(authRequest(password)); err != nil {
+		return nil, err
+	}
+	if err := readStatus(conn, ErrInvalidPassword); err != nil {
+		return nil, err
+	}
+	_, port, err := net.SplitHostPort(s.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCommand(conn, command.CommandReplConf, replConfListeningPort, port); err != nil {
+		return nil, err
+	}
+	if err := readStatus(conn, ErrUnknownReplConf); err != nil {
+		return nil, err
+	}
+	if err := writeCommand(conn, command.CommandPSync, replID, strconv.FormatInt(offset, 10)); err != nil {
+		return nil, err
+	}
+	return readValue(conn, ErrInvalidPSyncReply)
+}
+
+// ackLoop periodically reports processed offset to primary
+func ackLoop(conn net.Conn, acked *atomic.Int64, done chan struct{}) {
+	ticker := time.NewTicker(replAckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := writeCommand(conn, command.CommandReplConf, replConfAck, strconv.FormatInt(acked.Load(), 10)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeCommand writes command that is not bound to a database
+func writeCommand(w io.Writer, cmd string, args ...string) error {
+	vals := []resp.Value{resp.StringValue(cmd)}
+	for _, arg := range args {
+		vals = append(vals, resp.StringValue(arg))
+	}
+	data, err := resp.ArrayValue(vals).MarshalRESP()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// readStatus reads response status, errFailed is returned if operation failed,
+// message of error response is returned as error
+func readStatus(r io.Reader, errFailed error) error {
+	var status uint8
+	if err := binary.Read(r, binary.BigEndian, &status); err != nil {
+		return err
+	}
+	switch status {
+	case 0:
+		return errFailed
+	case statusError:
+		msg, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", errFailed, msg)
+	}
+	return nil
+}
+
+// readValue reads response with a single value
+func readValue(r io.Reader, errFailed error) ([]byte, error) {
+	if err := readStatus(r, errFailed); err != nil {
+		return nil, err
+	}
+	return readBytes(r)
+}
+
+// readBytes reads value prefixed with its length
+func readBytes(r io.Reader) ([]byte, error) {
+	var n int64
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	val := make([]byte, n)
+	if _, err := io.ReadFull(r, val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// ReplicaOf makes server a replica of the primary or turns it into a primary if noOne is set
+func (s *Server) ReplicaOf(from string, host, port string, noOne bool) error {
+	const op = "server.ReplicaOf"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if noOne {
+		s.promote()
+		log.Info("server is a primary now")
+	} else {
+		s.replicaOf(host, port)
+		log.Info("server is a replica now", slog.String("primary", net.JoinHostPort(host, port)))
+	}
+	if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// ReplConf handles options sent by replica, acknowledgements don't have response
+func (s *Server) ReplConf(from string, option, value string) error {
+	const op = "server.ReplConf"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	switch option {
+	case replConfAck:
+		if r, ok := s.replicas[from]; ok {
+			r.ack, _ = strconv.ParseInt(value, 10, 64)
+			r.ackTime = time.Now()
+		}
+		return nil
+	case replConfListeningPort:
+		host, _, _ := net.SplitHostPort(from)
+		s.replicas[from] = &replica{host: host, port: value, conn: peer.Conn}
+		if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return nil
+	}
+	if err := writeFailure(peer.Conn, ErrUnknownReplConf); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	return fmt.Errorf("%s:%w", op, ErrUnknownReplConf)
+}
+
+// PSync starts replication stream for the replica, partial resync is done if replication id is known
+// and requested offset is still in the backlog, otherwise the whole data set is sent first
+func (s *Server) PSync(from string, replID string, offset int64) error {
+	const op = "server.PSync"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	r, ok := s.replicas[from]
+	if !ok {
+		host, _, _ := net.SplitHostPort(from)
+		r = &replica{host: host, conn: peer.Conn}
+		s.replicas[from] = r
+	}
+	if r.online {
+		if err := writeFailure(peer.Conn, ErrReplicaExists); err != nil {
+			log.Error("got error after sending response", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s:%w", op, ErrReplicaExists)
+	}
+	buf := &bytes.Buffer{}
+	known := replID == s.replID || (replID == s.replID2 && offset <= s.replID2Offset)
+	if data, ok := s.backlog.since(offset); known && ok {
+		if err := writeValueResponse(buf, []byte(fmt.Sprintf("%s %s", replContinue, s.replID))); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		buf.Write(data)
+		s.syncPartialOK++
+		log.Info("partial resync is accepted", slog.Int64("offset", offset))
+	} else {
+		if replID != "?" {
+			s.syncPartialErr++
+		}
+		snapshot, err := s.snapshotCommands()
+		if err != nil {
+			if err := writeFailure(peer.Conn, err); err != nil {
+				log.Error("got error after sending response", slog.String("error", err.Error()))
+			}
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		reply := fmt.Sprintf("%s %s %d", replFullResync, s.replID, s.backlog.offset())
+		if err := writeValueResponse(buf, []byte(reply)); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		if err := writeValueResponse(buf, snapshot); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		s.syncFull++
+		log.Info("full resync is started", slog.Int("snapshot bytes", len(snapshot)))
+	}
+	r.online = true
+	r.ack = offset
+	r.ackTime = time.Now()
+	r.ch = make(chan []byte, replicaBufferSize)
+	go r.writeLoop()
+	r.ch <- buf.Bytes()
+	return nil
+}
+
+// onlineReplicas returns replicas sorted by address, must be called with replMu locked
+func (s *Server) onlineReplicas() []*replica {
+	var res []*replica
+	for _, r := range s.replicas {
+		if r.online {
+			res = append(res, r)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return net.JoinHostPort(res[i].host, res[i].port) < net.JoinHostPort(res[j].host, res[j].port)
+	})
+	return res
+}
+
+// Role writes replication role of the server: "master", offset and replicas (host, port, offset)
+// for a primary and "slave", primary host, port, link state and offset for a replica
+func (s *Server) Role(from string) error {
+	const op = "server.Role"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	s.replMu.Lock()
+	offset := []byte(strconv.FormatInt(s.backlog.offset(), 10))
+	var list [][]byte
+	if s.master == nil {
+		list = [][]byte{[]byte(replRoleMaster), offset}
+		for _, r := range s.onlineReplicas() {
+			list = append(list, []byte(r.host), []byte(r.port), []byte(strconv.FormatInt(r.ack, 10)))
+		}
+	} else {
+		list = [][]byte{[]byte(replRoleReplica), []byte(s.master.host), []byte(s.master.port), []byte(s.master.state), offset}
+	}
+	s.replMu.Unlock()
+	if err := writeListResponse(peer.Conn, list); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("role is sent")
+	return nil
+}
+
+// Info writes information about the server, "replication" and "cluster" sections are supported
+func (s *Server) Info(from string, section string) error {
+	const op = "server.Info"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	var info string
+	switch section {
+	case "", "all", "default", "everything":
+		info = s.replicationInfo() + "\r\n" + s.clusterEnabledInfo()
+	case "replication":
+		info = s.replicationInfo()
+	case "cluster":
+		info = s.clusterEnabledInfo()
+	}
+	if err := writeValueResponse(peer.Conn, []byte(info)); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("info is sent", slog.String("section", section))
+	return nil
+}
+
+// replicationInfo returns replication section of INFO in Redis format
+func (s *Server) replicationInfo() string {
+	s.replMu.Lock()
+	defer s.replMu.Unlock()
+	b := &strings.Builder{}
+	b.WriteString("# Replication\r\n")
+	if s.master == nil {
+		fmt.Fprintf(b, "role:%s\r\n", replRoleMaster)
+	} else {
+		linkStatus := "down"
+		if s.master.state == replStateConnected {
+			linkStatus = "up"
+		}
+		syncing := 0
+		if s.master.state == replStateSync {
+			syncing = 1
+		}
+		fmt.Fprintf(b, "role:%s\r\n", replRoleReplica)
+		fmt.Fprintf(b, "master_host:%s\r\n", s.master.host)
+		fmt.Fprintf(b, "master_port:%s\r\n", s.master.port)
+		fmt.Fprintf(b, "master_link_status:%s\r\n", linkStatus)
+		fmt.Fprintf(b, "master_sync_in_progress:%d\r\n", syncing)
+		fmt.Fprintf(b, "slave_repl_offset:%d\r\n", s.backlog.offset())
+		b.WriteString("slave_read_only:1\r\n")
+	}
+	replicas := s.onlineReplicas()
+	fmt.Fprintf(b, "connected_slaves:%d\r\n", len(replicas))
+	for i, r := range replicas {
+		lag := int64(time.Since(r.ackTime).Seconds())
+		fmt.Fprintf(b, "slave%d:ip=%s,port=%s,state=online,offset=%d,lag=%d\r\n", i, r.host, r.port, r.ack, lag)
+	}
+	replID2 := s.replID2
+	if replID2 == "" {
+		replID2 = strings.Repeat("0", 40)
+	}
+	fmt.Fprintf(b, "master_replid:%s\r\n", s.replID)
+	fmt.Fprintf(b, "master_replid2:%s\r\n", replID2)
+	fmt.Fprintf(b, "master_repl_offset:%d\r\n", s.backlog.offset())
+	fmt.Fprintf(b, "second_repl_offset:%d\r\n", s.replID2Offset)
+	fmt.Fprintf(b, "repl_backlog_size:%d\r\n", s.backlog.size)
+	fmt.Fprintf(b, "repl_backlog_first_byte_offset:%d\r\n", s.backlog.start)
+	fmt.Fprintf(b, "repl_backlog_histlen:%d\r\n", len(s.backlog.data))
+	fmt.Fprintf(b, "sync_full:%d\r\n", s.syncFull)
+	fmt.Fprintf(b, "sync_partial_ok:%d\r\n", s.syncPartialOK)
+	fmt.Fprintf(b, "sync_partial_err:%d\r\n", s.syncPartialErr)
+	return b.String()
+}
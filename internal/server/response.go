@@ -0,0 +1,76 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/errcode"
+)
+
+// statusError is a status byte of failure response that carries error message,
+// the message starts with error code, e.g. "NOTFOUND key doesn't exist"
+const statusError uint8 = 2
+
+// errorCode returns error code of err, ERR is returned for errors without specific code
+func errorCode(err error) string {
+	if errors.Is(err, ErrReadOnlyReplica) {
+		return errcode.ReadOnly
+	}
+	return errcode.Code(err)
+}
+
+// writeFailure writes error response with code and message of err
+func writeFailure(w io.Writer, err error) error {
+	return writeErrorResponse(w, failureMessage(err))
+}
+
+// failureMessage returns message of error response caused by err, it starts with error code and the rest is taken
+// from the innermost error, e.g. "NOTFOUND key doesn't exist"
+func failureMessage(err error) string {
+	return errorCode(err) + " " + errcode.Text(err)
+}
+
+// writeErrorResponse writes failure response with error message, e.g. cluster redirection
+func writeErrorResponse(w io.Writer, msg string) error {
+	if err := binary.Write(w, binary.BigEndian, statusError); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, int64(len(msg))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, msg)
+	return err
+}
+
+// writeValueResponse writes successful response with a single value
+func writeValueResponse(w io.Writer, val []byte) error {
+	if err := binary.Write(w, binary.BigEndian, true); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, int64(len(val))); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, bytes.NewReader(val))
+	return err
+}
+
+// writeListResponse writes successful response with a list of values
+func writeListResponse(w io.Writer, list [][]byte) error {
+	if err := binary.Write(w, binary.BigEndian, true); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, int64(len(list))); err != nil {
+		return err
+	}
+	for _, val := range list {
+		if err := binary.Write(w, binary.BigEndian, int64(len(val))); err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, bytes.NewReader(val)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -9,27 +9,63 @@ import (
 	"log/slog"
 	"net"
 	"sync"
+	"time"
 
+	"github.com/ArtemNovok/simpleRedisCl/internal/acl"
 	"github.com/ArtemNovok/simpleRedisCl/internal/command"
 	Mypeer "github.com/ArtemNovok/simpleRedisCl/internal/peer"
 	"github.com/ArtemNovok/simpleRedisCl/internal/reclogs"
 	"github.com/ArtemNovok/simpleRedisCl/internal/storage"
+	"github.com/tidwall/resp"
 )
 
 const (
-	defaultPassword = "secret"
+	defaultRecoveryLog = "logs"
 )
 
 var (
 	DefaultAddress     = ":6666"
 	ErrUknownPeer      = errors.New("unknown peer")
 	ErrInvalidPassword = errors.New("invalid password")
+	// ErrServerClosed is returned by Start after Stop call
+	ErrServerClosed = errors.New("server closed")
+	// ErrNoPassword is returned by Start if neither Password, ACLFile nor NoPassword is set
+	ErrNoPassword = errors.New("password or ACL file is required")
 )
 
 type Config struct {
 	ListenAddr string
-	Password   string
-	Log        *slog.Logger
+	// Password is a password of the default user, the default user is disabled unless the ACL file enables it
+	// if it's empty. Server doesn't start if neither Password nor ACLFile is set unless NoPassword is set
+	Password string
+	// NoPassword lets anyone connect as the default user with any password if Password is empty,
+	// it's meant for development
+	NoPassword bool
+	// ACLFile is a path of the file with users loaded at startup, every line describes a user with rules
+	// of ACL SETUSER, e.g. "user alice on >pass ~cache:* +@read"
+	ACLFile string
+	Log     *slog.Logger
+	// NotifyKeyspaceEvents enables keyspace notifications for given event classes,
+	// flags are the same as in Redis notify-keyspace-events ("KEA" enables everything),
+	// notifications are disabled if it's empty
+	NotifyKeyspaceEvents string
+	// RecoveryLog is a path of the recovery log file, "logs" by default
+	RecoveryLog string
+	// PrimaryAddr is an address of the primary ("host:port"), server starts as its replica if it's set
+	PrimaryAddr string
+	// MasterPassword is used to authenticate with the primary, Password is used if it's empty
+	MasterPassword string
+	// ReplBacklogSize is a size of replication backlog in bytes, 1MB by default
+	ReplBacklogSize int
+	// ClusterEnabled enables cluster mode, keys are distributed between nodes by hash slots
+	ClusterEnabled bool
+	// ClusterAnnounceAddr is an address of the node for clients and other nodes,
+	// 127.0.0.1 and port of ListenAddr are used by default
+	ClusterAnnounceAddr string
+	// ClusterPingInterval is an interval of gossip between nodes, 1 second by default
+	ClusterPingInterval time.Duration
+	// ClusterNodeTimeout is a time after which node that doesn't respond is considered failing, 5 seconds by default
+	ClusterNodeTimeout time.Duration
 }
 
 // Server represents goRedisClone server
@@ -40,11 +76,36 @@ type Server struct {
 	addPeerCh      chan *Mypeer.TCPPeer
 	dropPeer       chan string
 	quitCh         chan struct{}
+	stopOnce       sync.Once
 	msgCh          chan Mypeer.Message
 	listener       net.Listener
 	Storage        *storage.Storage
 	recCh          chan command.Command
 	recoveryLogger *reclogs.RecoveryLogger
+	psMu           sync.Mutex
+	channels       map[string]map[string]struct{}
+	patterns       map[string]map[string]struct{}
+	notifyFlags    int
+	replMu         sync.Mutex
+	replID         string
+	replID2        string
+	replID2Offset  int64
+	backlog        *replBacklog
+	replicas       map[string]*replica
+	master         *masterLink
+	replCh         chan any
+	syncFull       int64
+	syncPartialOK  int64
+	syncPartialErr int64
+	clusterMu      sync.Mutex
+	cluster        *clusterState
+	// tracking and trackedKeys are client-side caching states of peers and keys read by them,
+	// they are accessed only by the server loop
+	tracking    map[string]*tracking
+	trackedKeys map[string]map[string]struct{}
+	acl         *acl.ACL
+	// users are names of users authenticated by peers, they are guarded by mu
+	users map[string]string
 }
 
 // NewServer returns server instance with given server Config
@@ -52,21 +113,57 @@ func NewServer(cfg Config) *Server {
 	if len(cfg.ListenAddr) == 0 {
 		cfg.ListenAddr = DefaultAddress
 	}
-	if len(cfg.Password) == 0 {
-		cfg.Password = defaultPassword
+	if len(cfg.RecoveryLog) == 0 {
+		cfg.RecoveryLog = defaultRecoveryLog
+	}
+	if cfg.ReplBacklogSize <= 0 {
+		cfg.ReplBacklogSize = defaultReplBacklogSize
 	}
 	s := &Server{
-		Config:    cfg,
-		peers:     make(map[string]*Mypeer.TCPPeer),
-		addPeerCh: make(chan *Mypeer.TCPPeer),
-		dropPeer:  make(chan string),
-		quitCh:    make(chan struct{}),
-		msgCh:     make(chan Mypeer.Message),
-		Storage:   storage.NewStorage(),
-		recCh:     make(chan command.Command),
-	}
-	rclger := reclogs.New("logs", s.recCh)
+		Config:        cfg,
+		peers:         make(map[string]*Mypeer.TCPPeer),
+		addPeerCh:     make(chan *Mypeer.TCPPeer),
+		dropPeer:      make(chan string),
+		quitCh:        make(chan struct{}),
+		msgCh:         make(chan Mypeer.Message),
+		Storage:       storage.NewStorage(),
+		recCh:         make(chan command.Command),
+		channels:      make(map[string]map[string]struct{}),
+		patterns:      make(map[string]map[string]struct{}),
+		replID:        newReplID(),
+		replID2Offset: -1,
+		backlog:       newReplBacklog(cfg.ReplBacklogSize),
+		replicas:      make(map[string]*replica),
+		replCh:        make(chan any),
+		tracking:      make(map[string]*tracking),
+		trackedKeys:   make(map[string]map[string]struct{}),
+		acl:           acl.New(cfg.Password),
+		users:         make(map[string]string),
+	}
+	if len(cfg.Password) == 0 && !cfg.NoPassword {
+		// rules of the default user are valid
+		_ = s.acl.SetUser(command.DefaultUser, "off", "resetpass")
+	}
+	if cfg.ClusterPingInterval <= 0 {
+		s.ClusterPingInterval = defaultClusterPingInterval
+	}
+	if cfg.ClusterNodeTimeout <= 0 {
+		s.ClusterNodeTimeout = defaultClusterNodeTimeout
+	}
+	if cfg.ClusterEnabled {
+		addr, err := clusterAddr(cfg)
+		if err != nil {
+			cfg.Log.Error("invalid cluster address", slog.String("error", err.Error()))
+		}
+		s.cluster = newClusterState(addr)
+	}
+	rclger := reclogs.New(cfg.RecoveryLog, s.recCh)
 	s.recoveryLogger = rclger
+	flags, err := parseNotifyFlags(cfg.NotifyKeyspaceEvents)
+	if err != nil {
+		cfg.Log.Error("keyspace notifications are disabled", slog.String("error", err.Error()))
+	}
+	s.notifyFlags = flags
 	return s
 }
 
@@ -78,27 +175,85 @@ func (s *Server) ShowData() {
 		}
 	}
 }
+
+// Stop stops accepting connections and closes connections of all peers, it may be called more than once
 func (s *Server) Stop() {
-	close(s.quitCh)
+	s.stopOnce.Do(func() {
+		close(s.quitCh)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if s.listener != nil {
+			s.listener.Close()
+		}
+		for _, peer := range s.peers {
+			peer.Conn.Close()
+		}
+	})
 }
 
-// Start starts server
+// Start recovers data and starts listening on ListenAddr
 func (s *Server) Start() error {
+	return s.start(func() (net.Listener, error) {
+		return net.Listen("tcp", s.ListenAddr)
+	})
+}
+
+// Serve recovers data and accepts connections from ln, e.g. from listener on ephemeral port
+// which address is known before server starts, ln is closed by Stop
+func (s *Server) Serve(ln net.Listener) error {
+	return s.start(func() (net.Listener, error) {
+		return ln, nil
+	})
+}
+
+func (s *Server) start(listen func() (net.Listener, error)) error {
 	const op = "server.Start"
 	log := s.Log.With("op", op)
+	if len(s.Password) == 0 {
+		switch {
+		case s.NoPassword:
+			log.Warn("default user accepts any password, set password or ACL file to protect the server")
+		case len(s.ACLFile) == 0:
+			return fmt.Errorf("%s:%w", op, ErrNoPassword)
+		}
+	}
+	if len(s.ACLFile) != 0 {
+		if err := s.acl.LoadFile(s.ACLFile); err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+	}
 	// starting data recovery
-	go s.recoveryLogger.ReadLog()
 	if err := s.dataRecoveryLoop(); err != nil {
 		log.Error("got error", slog.String("error", err.Error()))
 		return fmt.Errorf("%s:%w", op, err)
 	}
 	// starting listening
-	ln, err := net.Listen("tcp", s.ListenAddr)
+	ln, err := listen()
 	if err != nil {
 		return fmt.Errorf("%s:%w", op, err)
 	}
-	log.Info("starting listening", slog.String("address", s.ListenAddr))
+	log.Info("starting listening", slog.String("address", ln.Addr().String()))
+	s.mu.Lock()
+	select {
+	case <-s.quitCh:
+		// server is stopped during data recovery
+		s.mu.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	default:
+	}
 	s.listener = ln
+	s.mu.Unlock()
+	if len(s.PrimaryAddr) != 0 {
+		host, port, err := net.SplitHostPort(s.PrimaryAddr)
+		if err != nil {
+			return fmt.Errorf("%s:%w", op, err)
+		}
+		s.replicaOf(host, port)
+	}
+	if s.cluster != nil {
+		go s.clusterLoop()
+	}
 	go s.loop()
 	return s.listenLoop()
 }
@@ -106,50 +261,62 @@ func (s *Server) dataRecoveryLoop() error {
 	const op = "server.dataRecoveryLoop"
 	log := s.Log.With(slog.String("op", op))
 	log.Info("starting recover data")
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.recoveryLogger.ReadLog()
+	}()
 	for {
-		msg := <-s.recCh
-		switch v := msg.(type) {
-		case command.SetCommand:
-			if err := s.RSet(v.Key, v.Val, v.Index); err != nil {
-				return fmt.Errorf("%s:%w", op, err)
-			}
-		case command.AddCommand:
-			if err := s.RAdd(v.Key, v.Index); err != nil {
-				return fmt.Errorf("%s:%w", op, err)
-			}
-		case command.AddNCommand:
-			if err := s.RAddN(v.Key, v.Val, v.Index); err != nil {
-				return fmt.Errorf("%s:%w", op, err)
-			}
-		case command.DeleteCommand:
-			if err := s.RDelete(v.Key, v.Index); err != nil {
-				return fmt.Errorf("%s:%w", op, err)
-			}
-		case command.LPushCommand:
-			if err := s.RLPush(v.Key, v.Val, v.Index); err != nil {
-				return fmt.Errorf("%s:%w", op, err)
+		select {
+		case msg := <-s.recCh:
+			if _, ok := msg.(command.StopCommand); ok {
+				log.Info("done data recovey")
+				return nil
 			}
-		case command.DeleteLCommand:
-			if err := s.RDeleteL(v.Key, v.Index); err != nil {
+			if err := s.apply(msg); err != nil {
+				go s.drainRecovery(errCh)
 				return fmt.Errorf("%s:%w", op, err)
 			}
-		case command.DelAllCommand:
-			if err := s.RDelAll(v.Key, v.Val, v.Index); err != nil {
+		case err := <-errCh:
+			// ReadLog stops without StopCommand if the log can't be read
+			if err != nil {
 				return fmt.Errorf("%s:%w", op, err)
 			}
-		case command.DelElemLCommand:
-			if err := s.RDelElemL(v.Key, v.Val, v.Index); err != nil {
-				return fmt.Errorf("%s:%w", op, err)
+		}
+	}
+}
+
+// drainRecovery receives commands until reading of the log is finished, so reader doesn't block forever
+func (s *Server) drainRecovery(errCh chan error) {
+	for {
+		select {
+		case msg := <-s.recCh:
+			if _, ok := msg.(command.StopCommand); ok {
+				return
 			}
-		case command.StopCommand:
-			log.Info("done data recovey")
-			return nil
+		case <-errCh:
+			return
 		}
 	}
 }
+
+// apply executes write command without response to a client,
+// it's used for data recovery and for commands received from primary
+func (s *Server) apply(cmd command.Command) error {
+	const op = "server.apply"
+	spec := command.Lookup(cmd.Name())
+	if spec == nil || spec.Apply == nil {
+		return nil
+	}
+	if err := spec.Apply(s.Storage, cmd); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
 func (s *Server) loop() {
 	const op = "server.loop"
 	log := s.Log.With("op", op)
+	expireTicker := time.NewTicker(activeExpireInterval)
+	defer expireTicker.Stop()
 	for {
 		select {
 		case rawMsg := <-s.msgCh:
@@ -159,10 +326,29 @@ func (s *Server) loop() {
 			}
 		case peer := <-s.addPeerCh:
 			log.Info("added new peer", slog.String("peer address", peer.Addr()))
+			s.mu.Lock()
 			s.peers[peer.Addr()] = peer
+			s.mu.Unlock()
 		case from := <-s.dropPeer:
+			s.mu.Lock()
 			delete(s.peers, from)
+			delete(s.users, from)
+			s.mu.Unlock()
+			s.dropSubscriptions(from)
+			s.dropTracking(from)
+			s.dropReplica(from)
+			s.dropClusterPeer(from)
+		case msg := <-s.replCh:
+			s.handleReplMessage(msg)
+		case <-expireTicker.C:
+			s.activeExpire()
 		case <-s.quitCh:
+			// peers added after Stop closed connections are closed here
+			s.mu.RLock()
+			for _, peer := range s.peers {
+				peer.Conn.Close()
+			}
+			s.mu.RUnlock()
 			log.Info("server stopped due to Stop func call")
 			return
 		}
@@ -182,7 +368,7 @@ func (s *Server) LPush(from string, key, val []byte, index int) error {
 	err := s.Storage.LPush(key, val, index)
 	if err != nil {
 		log.Error("failed to append value to a list", slog.String("key", string(key)))
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
 			log.Error("got errors after sending response", slog.String("error", err.Error()))
 		}
 		return fmt.Errorf("%s:%w", op, err)
@@ -190,7 +376,7 @@ func (s *Server) LPush(from string, key, val []byte, index int) error {
 	if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
 		log.Error("got errors after sending response", slog.String("error", err.Error()))
 	}
-	err = s.recoveryLogger.WriteLog(command.CommandLPush, index, key, val)
+	err = s.propagate(command.CommandLPush, index, key, val)
 	if err != nil {
 		log.Error("got error while logging", slog.String("error", err.Error()))
 	}
@@ -198,6 +384,21 @@ func (s *Server) LPush(from string, key, val []byte, index int) error {
 	return nil
 }
 
+// Ping responds with success, it's used to check that server is alive
+func (s *Server) Ping(from string) error {
+	const op = "server.Ping"
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
 // Has checks whether key exists or not
 func (s *Server) Has(from string, key []byte, index int) error {
 	const op = "server.Has"
@@ -227,7 +428,7 @@ func (s *Server) GetL(from string, key []byte, index int) error {
 	}
 	slice, err := s.Storage.GetL(key, index)
 	if err != nil {
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
 			log.Error("got error while logging", slog.String("error", err.Error()))
 		}
 		return fmt.Errorf("%s:%w", op, storage.ErrKeyDoNotExists)
@@ -256,49 +457,10 @@ func (s *Server) GetL(from string, key []byte, index int) error {
 	log.Info("list is sended")
 	return nil
 }
-func (s *Server) RLPush(key, val []byte, index int) error {
-	const op = "server.RLPush"
-	err := s.Storage.LPush(key, val, index)
-	if err != nil {
-		return fmt.Errorf("%s:%w", op, err)
-	}
-	return nil
-}
-func (s *Server) RAddN(key []byte, value []byte, index int) error {
-	const op = "server.RAddN"
-	if err := s.Storage.AddN(key, value, index); err != nil {
-		return fmt.Errorf("%s:%w", op, err)
-	}
-	return nil
-}
-func (s *Server) RAdd(key []byte, index int) error {
-	const op = "server.RAdd"
-	if err := s.Storage.Add(key, index); err != nil {
-		return fmt.Errorf("%s:%w", op, err)
-	}
-	return nil
-}
-func (s *Server) RDelete(key []byte, index int) error {
-	const op = "server.RDelete"
-	err := s.Storage.Delete(key, index)
-	if err != nil {
-		return fmt.Errorf("%s:%w", op, err)
-	}
-	return nil
-}
 
-// RSet sets key and value but don't write response to client, used for data recovery
-func (s *Server) RSet(key, val []byte, index int) error {
-	const op = "server.RSet"
-	err := s.Storage.Set(key, val, index)
-	if err != nil {
-		return fmt.Errorf("%s:%w", op, err)
-	}
-	return nil
-}
-
-// Set sets the key value and write response to the client with info about operation result
-func (s *Server) Set(from string, key, val []byte, index int) error {
+// Set sets the key value and write response to the client with info about operation result,
+// false is written if condition of the command isn't met
+func (s *Server) Set(from string, cmd command.SetCommand) error {
 	const op = "server.Set"
 	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
 	s.mu.RLock()
@@ -308,19 +470,24 @@ func (s *Server) Set(from string, key, val []byte, index int) error {
 	if !ok {
 		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
 	}
-	err := s.Storage.Set(key, val, index)
+	opts := cmd.Options(s.Storage.Now())
+	ok, err := s.Storage.SetWith(cmd.Key, cmd.Val, opts, cmd.Index)
 	if err != nil {
-		log.Error("failed to set a key", slog.String("key", string(key)))
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		log.Error("failed to set a key", slog.String("key", string(cmd.Key)))
+		if err := writeFailure(peer.Conn, err); err != nil {
 
 			log.Error("got error after sending response", slog.String("error", err.Error()))
 		}
 		return fmt.Errorf("%s:%w", op, err)
 	}
-	if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
+	if err := binary.Write(peer.Conn, binary.BigEndian, ok); err != nil {
 		log.Error("got error after sending response", slog.String("error", err.Error()))
 	}
-	err = s.recoveryLogger.WriteLog(command.CommandSet, index, key, val)
+	if !ok {
+		log.Info("key is not set, condition isn't met", slog.String("key", string(cmd.Key)))
+		return nil
+	}
+	err = s.propagate(command.CommandSet, cmd.Index, setLogArgs(cmd.Key, cmd.Val, opts.ExpireAt)...)
 	if err != nil {
 		log.Error("got error while logging", slog.String("error", err.Error()))
 	}
@@ -339,12 +506,16 @@ func (s *Server) Get(from string, key []byte, index int) error {
 		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
 	}
 	val, ok := s.Storage.Get(key, index)
-	log.Info("got value for a peer", slog.String("value", string(val)))
+	log.Info("got value for a peer", slog.String("key", string(key)), slog.Int("size", len(val)))
 	if !ok {
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		err := storage.ErrKeyDoNotExists
+		if index < 0 || index >= len(s.Storage.DBS) {
+			err = storage.ErrInvalidDatabaseIndex
+		}
+		if err := writeFailure(peer.Conn, err); err != nil {
 			log.Error("got error after sending response", slog.String("error", err.Error()))
 		}
-		return fmt.Errorf("%s:%w", op, storage.ErrKeyDoNotExists)
+		return fmt.Errorf("%s:%w", op, err)
 	}
 	n := int64(len(val))
 	err := binary.Write(peer.Conn, binary.BigEndian, true)
@@ -364,6 +535,60 @@ func (s *Server) Get(from string, key []byte, index int) error {
 	return nil
 }
 
+// MSet sets values of several keys and writes response about success of the operation
+func (s *Server) MSet(from string, keys, vals [][]byte, index int) error {
+	const op = "server.MSet"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	for i, key := range keys {
+		if err := s.Storage.Set(key, vals[i], index); err != nil {
+			if err := writeFailure(peer.Conn, err); err != nil {
+				log.Error("got error after sending response", slog.String("error", err.Error()))
+			}
+			return fmt.Errorf("%s:%w", op, err)
+		}
+	}
+	if err := binary.Write(peer.Conn, binary.BigEndian, true); err != nil {
+		log.Error("got error after sending response", slog.String("error", err.Error()))
+	}
+	// every key is logged as SET, so recovery and replicas don't need to know MSET
+	for i, key := range keys {
+		if err := s.propagate(command.CommandSet, index, key, vals[i]); err != nil {
+			log.Error("got error while logging", slog.String("error", err.Error()))
+		}
+	}
+	log.Info("keys are set", slog.Int("keys", len(keys)))
+	return nil
+}
+
+// MGet writes keys and values of existing keys as a list of pairs
+func (s *Server) MGet(from string, keys [][]byte, index int) error {
+	const op = "server.MGet"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	var list [][]byte
+	for _, key := range keys {
+		if val, ok := s.Storage.Get(key, index); ok {
+			list = append(list, key, val)
+		}
+	}
+	if err := writeListResponse(peer.Conn, list); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("values are sent", slog.Int("found", len(list)/2))
+	return nil
+}
+
 // Add increments key value by 1 and writes response about success of the operation
 func (s *Server) Add(from string, key []byte, index int) error {
 	const op = "server.Add"
@@ -375,7 +600,7 @@ func (s *Server) Add(from string, key []byte, index int) error {
 		return ErrUknownPeer
 	}
 	if err := s.Storage.Add(key, index); err != nil {
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
 			log.Error("got error after sending response", slog.String("error", err.Error()))
 		}
 		return fmt.Errorf("%s:%w", op, err)
@@ -384,7 +609,7 @@ func (s *Server) Add(from string, key []byte, index int) error {
 		log.Error("got error after sending response", slog.String("error", err.Error()))
 	}
 	log.Info("key value is increment by one")
-	err := s.recoveryLogger.WriteLog(command.CommandAdd, index, key)
+	err := s.propagate(command.CommandAdd, index, key)
 	if err != nil {
 		log.Error("got error while logging", slog.String("error", err.Error()))
 	}
@@ -402,7 +627,7 @@ func (s *Server) AddN(from string, key []byte, value []byte, index int) error {
 		return ErrUknownPeer
 	}
 	if err := s.Storage.AddN(key, value, index); err != nil {
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
 			log.Error("got error after sending response", slog.String("error", err.Error()))
 		}
 		return fmt.Errorf("%s:%w", op, err)
@@ -411,21 +636,13 @@ func (s *Server) AddN(from string, key []byte, value []byte, index int) error {
 		log.Error("got error after sending response", slog.String("error", err.Error()))
 	}
 	log.Info("key value is increment by", slog.String("value", string(value)))
-	err := s.recoveryLogger.WriteLog(command.CommandAddN, index, key, value)
+	err := s.propagate(command.CommandAddN, index, key, value)
 	if err != nil {
 		log.Error("got error while logging", slog.String("error", err.Error()))
 	}
 	return nil
 }
 
-func (s *Server) RDelAll(key []byte, value []byte, index int) error {
-	const op = "server.RDelAll"
-	err := s.Storage.DelAll(key, value, index)
-	if err != nil {
-		return fmt.Errorf("%s:%w", op, err)
-	}
-	return nil
-}
 func (s *Server) DelAll(from string, key []byte, value []byte, index int) error {
 	const op = "server.DelAll"
 	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
@@ -437,7 +654,7 @@ func (s *Server) DelAll(from string, key []byte, value []byte, index int) error
 	}
 	err := s.Storage.DelAll(key, value, index)
 	if err != nil {
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
 
 			log.Error("got error after sending response", slog.String("error", err.Error()))
 		}
@@ -447,7 +664,7 @@ func (s *Server) DelAll(from string, key []byte, value []byte, index int) error
 
 		log.Error("got error after sending response", slog.String("error", err.Error()))
 	}
-	err = s.recoveryLogger.WriteLog(command.CommandDelAll, index, key, value)
+	err = s.propagate(command.CommandDelAll, index, key, value)
 	if err != nil {
 		log.Error("got error while logging", slog.String("error", err.Error()))
 	}
@@ -467,7 +684,7 @@ func (s *Server) Delete(from string, key []byte, index int) error {
 	}
 	err := s.Storage.Delete(key, index)
 	if err != nil {
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
 
 			log.Error("got error after sending response", slog.String("error", err.Error()))
 		}
@@ -478,20 +695,12 @@ func (s *Server) Delete(from string, key []byte, index int) error {
 		log.Error("got error after sending response", slog.String("error", err.Error()))
 	}
 	log.Info("key is deleted")
-	err = s.recoveryLogger.WriteLog(command.CommandDelete, index, key)
+	err = s.propagate(command.CommandDelete, index, key)
 	if err != nil {
 		log.Error("got error while logging", slog.String("error", err.Error()))
 	}
 	return nil
 }
-func (s *Server) RDeleteL(key []byte, index int) error {
-	const op = "server.RDeleteL"
-	err := s.Storage.DeleteL(key, index)
-	if err != nil {
-		return fmt.Errorf("%s:%w", op, err)
-	}
-	return nil
-}
 func (s *Server) DeleteL(from string, key []byte, index int) error {
 	const op = "server.DeleteL"
 	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
@@ -503,7 +712,7 @@ func (s *Server) DeleteL(from string, key []byte, index int) error {
 	}
 	err := s.Storage.DeleteL(key, index)
 	if err != nil {
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
 
 			log.Error("got error after sending response", slog.String("error", err.Error()))
 		}
@@ -513,21 +722,13 @@ func (s *Server) DeleteL(from string, key []byte, index int) error {
 
 		log.Error("got error after sending response", slog.String("error", err.Error()))
 	}
-	err = s.recoveryLogger.WriteLog(command.CommandDeleteL, index, key)
+	err = s.propagate(command.CommandDeleteL, index, key)
 	if err != nil {
 		log.Error("got error while logging", slog.String("error", err.Error()))
 	}
 	log.Info("list is deleted")
 	return nil
 }
-func (s *Server) RDelElemL(key []byte, value []byte, index int) error {
-	const op = "storage.DelElemL"
-	err := s.Storage.DelElemL(key, value, index)
-	if err != nil {
-		return fmt.Errorf("%s:%w", op, err)
-	}
-	return nil
-}
 func (s *Server) DelElemL(from string, key []byte, value []byte, index int) error {
 	const op = "storage.DelElemL"
 	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
@@ -539,7 +740,7 @@ func (s *Server) DelElemL(from string, key []byte, value []byte, index int) erro
 	}
 	err := s.Storage.DelElemL(key, value, index)
 	if err != nil {
-		if err := binary.Write(peer.Conn, binary.BigEndian, false); err != nil {
+		if err := writeFailure(peer.Conn, err); err != nil {
 
 			log.Error("got error after sending response", slog.String("error", err.Error()))
 		}
@@ -550,7 +751,7 @@ func (s *Server) DelElemL(from string, key []byte, value []byte, index int) erro
 
 		log.Error("got error after sending response", slog.String("error", err.Error()))
 	}
-	err = s.recoveryLogger.WriteLog(command.CommandDelElemL, index, key, value)
+	err = s.propagate(command.CommandDelElemL, index, key, value)
 	if err != nil {
 		log.Error("got error while logging", slog.String("error", err.Error()))
 	}
@@ -563,37 +764,38 @@ func (s *Server) handleRawMessage(from string, msg []byte) error {
 	const op = "server.handleRawMessage"
 	log := s.Log.With("op", op)
 	log.Info("start parsing the commnad")
-	cmd, err := command.ParseCommand(string(msg))
+	req, err := command.ParseRequest(string(msg))
+	if err == nil && req.Spec.Exec == nil {
+		err = command.ErrUnknownCommand
+	}
 	if err != nil {
 		log.Error("got error while parsing command", slog.String("error", err.Error()))
+		s.mu.RLock()
+		peer, ok := s.peers[from]
+		s.mu.RUnlock()
+		if ok {
+			if err := writeFailure(peer.Conn, err); err != nil {
+				log.Error("got error after sending response", slog.String("error", err.Error()))
+			}
+		}
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := s.checkACL(from, req); err != nil {
 		return fmt.Errorf("%s:%w", op, err)
 	}
-	switch v := cmd.(type) {
-	case command.DelAllCommand:
-		return s.DelAll(from, v.Key, v.Val, v.Index)
-	case command.DelElemLCommand:
-		return s.DelElemL(from, v.Key, v.Val, v.Index)
-	case command.DeleteLCommand:
-		return s.DeleteL(from, v.Key, v.Index)
-	case command.LPushCommand:
-		return s.LPush(from, v.Key, v.Val, v.Index)
-	case command.GetLCommand:
-		return s.GetL(from, v.Key, v.Index)
-	case command.HasCommand:
-		return s.Has(from, v.Key, v.Index)
-	case command.SetCommand:
-		return s.Set(from, v.Key, v.Val, v.Index)
-	case command.GetCommand:
-		return s.Get(from, v.Key, v.Index)
-	case command.HelloCommand:
-		log.Info("got hello command")
-	case command.AddCommand:
-		return s.Add(from, v.Key, v.Index)
-	case command.AddNCommand:
-		return s.AddN(from, v.Key, v.Val, v.Index)
-	case command.DeleteCommand:
-		return s.Delete(from, v.Key, v.Index)
+	if redirected, err := s.clusterRedirect(from, req); redirected {
+		return err
+	}
+	if req.Spec.Has(command.FlagWrite) && s.isReplica() {
+		return s.rejectWrite(from)
 	}
+	s.trackCommand(from, req)
+	return req.Spec.Exec(s, from, req.Command)
+}
+
+// Hello is sent by client after authentication, it doesn't have response
+func (s *Server) Hello(from string) error {
+	s.Log.Info("got hello command", slog.String("op", "server.Hello"), slog.String("peer address", from))
 	return nil
 }
 
@@ -602,6 +804,9 @@ func (s *Server) listenLoop() error {
 	log := s.Log.With("op", op)
 	for {
 		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return ErrServerClosed
+		}
 		if err != nil {
 			log.Error("got error while accepting connections", slog.String("error", err.Error()))
 			continue
@@ -613,28 +818,30 @@ func (s *Server) listenLoop() error {
 func (s *Server) handleConn(conn net.Conn) error {
 	const op = "server.handleConn"
 	log := s.Log.With(slog.String("op", op), slog.String("connection address", conn.RemoteAddr().String()))
-	buf := make([]byte, 1024)
-	n, err := conn.Read(buf)
+	// the reader is kept by the peer, so commands sent right after credentials aren't lost
+	rd := resp.NewReader(conn)
+	user, err := s.authenticate(rd)
 	if err != nil {
-		log.Error("failed to read password from peer")
-		return fmt.Errorf("%s:%w", op, err)
-	}
-	strPass := string(buf[:n])
-	if s.Password != strPass {
-		log.Error("peer with wrong password", slog.String("password", strPass))
+		log.Error("peer failed to authenticate", slog.String("user", user))
 		if err := binary.Write(conn, binary.BigEndian, false); err != nil {
-
 			log.Error("got error after sending response", slog.String("error", err.Error()))
 		}
 		return fmt.Errorf("%s:%w", op, ErrInvalidPassword)
 	}
 	if err := binary.Write(conn, binary.BigEndian, true); err != nil {
-
 		log.Error("got error after sending response", slog.String("error", err.Error()))
 	}
+	s.mu.Lock()
+	s.users[conn.RemoteAddr().String()] = user
+	s.mu.Unlock()
 	log.Info("starting handling connection", slog.String("address", conn.RemoteAddr().String()))
-	peer := Mypeer.NewTCPPeer(conn, s.msgCh, s.dropPeer)
-	s.addPeerCh <- peer
+	peer := Mypeer.NewTCPPeer(conn, rd, s.msgCh, s.dropPeer)
+	select {
+	case s.addPeerCh <- peer:
+	case <-s.quitCh:
+		conn.Close()
+		return fmt.Errorf("%s:%w", op, ErrServerClosed)
+	}
 	if err := peer.ReadLoop(); err != nil {
 		if errors.Is(err, io.EOF) {
 			slog.Info("done handling peer", slog.String("address", conn.RemoteAddr().String()))
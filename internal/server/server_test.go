@@ -1,38 +1,35 @@
-package server
+package server_test
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
-	"log"
+	"io"
 	"log/slog"
 	"math/rand"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+	"github.com/ArtemNovok/simpleRedisCl/internal/server"
+	"github.com/ArtemNovok/simpleRedisCl/internal/testserver"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func Test_ServerAndClients(t *testing.T) {
-	ctx := context.Background()
-	p := ""
-	logger := setUpLogger()
-	addr := ":5555"
 	ind := 0
-	s := SetUpServer(logger, addr)
-	go func() {
-		log.Fatal(s.Start())
-	}()
-	time.Sleep(1 * time.Second)
-	cl, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
+	s := testserver.Start(t, server.Config{})
+	cl := s.Client
 	wg := sync.WaitGroup{}
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		for i := 0; i < 5; i++ {
 			key := fmt.Sprintf("key2_%v", i)
 			val := fmt.Sprintf("val2_%v", i)
@@ -66,27 +63,15 @@ func Test_ServerAndClients(t *testing.T) {
 }
 
 func Test_TwoClientWriteOneValue(t *testing.T) {
-	ctx := context.Background()
-	p := ""
 	wg2 := sync.WaitGroup{}
-	logger := setUpLogger()
-	addr := ":8888"
 	ind := 0
-	s := SetUpServer(logger, addr)
-	go func() {
-		log.Fatal(s.Start())
-	}()
-	time.Sleep(1 * time.Second)
-	cl, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
-	cl2, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
+	s := testserver.Start(t, server.Config{})
+	cl := s.Client
+	cl2 := s.NewClient(client.Options{})
 	startChan := make(chan struct{})
+	wg2.Add(1)
 	go func() {
+		defer wg2.Done()
 		<-startChan
 		for i := 0; i < 5; i++ {
 			key := fmt.Sprintf("key_%v", i)
@@ -99,7 +84,9 @@ func Test_TwoClientWriteOneValue(t *testing.T) {
 			}()
 		}
 	}()
+	wg2.Add(1)
 	go func() {
+		defer wg2.Done()
 		<-startChan
 		for i := 0; i < 5; i++ {
 			key := fmt.Sprintf("key_%v", i)
@@ -115,34 +102,22 @@ func Test_TwoClientWriteOneValue(t *testing.T) {
 	time.Sleep(2 * time.Millisecond)
 	close(startChan)
 	wg2.Wait()
-	time.Sleep(3 * time.Millisecond)
 	cl.Close()
-	time.Sleep(1 * time.Second)
-	require.Equal(t, len(s.peers), 1)
+	require.Eventually(t, func() bool {
+		return s.PeerCount() == 1
+	}, time.Second, 10*time.Millisecond)
 	s.ShowData()
 }
 func Test_TwoClientWritesAndReadOneValue(t *testing.T) {
-	ctx := context.Background()
-	p := ""
 	wg := sync.WaitGroup{}
-	logger := setUpLogger()
-	addr := ":3333"
 	ind := 0
-	s := SetUpServer(logger, addr)
-	go func() {
-		log.Fatal(s.Start())
-	}()
-	time.Sleep(1 * time.Second)
-	cl, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
-	cl2, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
+	s := testserver.Start(t, server.Config{})
+	cl := s.Client
+	cl2 := s.NewClient(client.Options{})
 	startChan := make(chan struct{})
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		<-startChan
 		for i := 0; i < 50; i++ {
 			key := fmt.Sprintf("key_%v", i)
@@ -157,7 +132,9 @@ func Test_TwoClientWritesAndReadOneValue(t *testing.T) {
 			}()
 		}
 	}()
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		<-startChan
 		for i := 0; i < 50; i++ {
 			key := fmt.Sprintf("key_%v", i)
@@ -179,39 +156,18 @@ func Test_TwoClientWritesAndReadOneValue(t *testing.T) {
 }
 
 func Test_FiveClient(t *testing.T) {
-	ctx := context.Background()
-	p := ""
 	wg := sync.WaitGroup{}
-	logger := setUpLogger()
-	addr := ":4444"
 	ind := 0
-	s := SetUpServer(logger, addr)
-	go func() {
-		log.Fatal(s.Start())
-	}()
-	time.Sleep(1 * time.Second)
-	cl, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
-	cl2, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
-	cl3, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
-	cl4, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
-	cl5, err := client.New(ctx, fmt.Sprintf("localhost%s", addr), p)
-	if err != nil {
-		log.Fatal(err)
-	}
+	s := testserver.Start(t, server.Config{})
+	cl := s.Client
+	cl2 := s.NewClient(client.Options{})
+	cl3 := s.NewClient(client.Options{})
+	cl4 := s.NewClient(client.Options{})
+	cl5 := s.NewClient(client.Options{})
 	startChan := make(chan struct{})
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		<-startChan
 		for i := 0; i < 50; i++ {
 			key := fmt.Sprintf("key_%v", i)
@@ -222,15 +178,19 @@ func Test_FiveClient(t *testing.T) {
 				err := cl.Set(context.Background(), key, val, ind)
 				require.Nil(t, err)
 				n := rand.Intn(3)
+				wg.Add(1)
 				go func() {
+					defer wg.Done()
 					time.Sleep(time.Duration(n*100) * time.Millisecond)
-					_, err = cl.Get(context.Background(), key, ind)
-					require.Nil(t, err)
+					_, err := cl.Get(context.Background(), key, ind)
+					assert.Nil(t, err)
 				}()
 			}()
 		}
 	}()
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		<-startChan
 		for i := 0; i < 50; i++ {
 			key := fmt.Sprintf("key_%v", i)
@@ -241,15 +201,19 @@ func Test_FiveClient(t *testing.T) {
 				err := cl2.Set(context.Background(), key, val, ind)
 				require.Nil(t, err)
 				n := rand.Intn(3)
+				wg.Add(1)
 				go func() {
+					defer wg.Done()
 					time.Sleep(time.Duration(n*100) * time.Millisecond)
-					_, err = cl2.Get(context.Background(), key, ind)
-					require.Nil(t, err)
+					_, err := cl2.Get(context.Background(), key, ind)
+					assert.Nil(t, err)
 				}()
 			}()
 		}
 	}()
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		<-startChan
 		for i := 0; i < 50; i++ {
 			key := fmt.Sprintf("key_%v", i)
@@ -260,15 +224,19 @@ func Test_FiveClient(t *testing.T) {
 				err := cl3.Set(context.Background(), key, val, ind)
 				require.Nil(t, err)
 				n := rand.Intn(3)
+				wg.Add(1)
 				go func() {
+					defer wg.Done()
 					time.Sleep(time.Duration(n*100) * time.Millisecond)
-					_, err = cl3.Get(context.Background(), key, ind)
-					require.Nil(t, err)
+					_, err := cl3.Get(context.Background(), key, ind)
+					assert.Nil(t, err)
 				}()
 			}()
 		}
 	}()
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		<-startChan
 		for i := 0; i < 50; i++ {
 			key := fmt.Sprintf("key_%v", i)
@@ -279,15 +247,19 @@ func Test_FiveClient(t *testing.T) {
 				err := cl4.Set(context.Background(), key, val, ind)
 				require.Nil(t, err)
 				n := rand.Intn(3)
+				wg.Add(1)
 				go func() {
+					defer wg.Done()
 					time.Sleep(time.Duration(n*100) * time.Millisecond)
-					_, err = cl4.Get(context.Background(), key, ind)
-					require.Nil(t, err)
+					_, err := cl4.Get(context.Background(), key, ind)
+					assert.Nil(t, err)
 				}()
 			}()
 		}
 	}()
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		<-startChan
 		for i := 0; i < 50; i++ {
 			key := fmt.Sprintf("key_%v", i)
@@ -298,51 +270,692 @@ func Test_FiveClient(t *testing.T) {
 				err := cl5.Set(context.Background(), key, val, ind)
 				require.Nil(t, err)
 				n := rand.Intn(3)
+				wg.Add(1)
 				go func() {
+					defer wg.Done()
 					time.Sleep(time.Duration(n*100) * time.Millisecond)
-					_, err = cl5.Get(context.Background(), key, ind)
-					require.Nil(t, err)
+					_, err := cl5.Get(context.Background(), key, ind)
+					assert.Nil(t, err)
 				}()
 			}()
 		}
 	}()
 	close(startChan)
 	wg.Wait()
-	time.Sleep(6 * time.Millisecond)
 	s.ShowData()
 }
 func Test_PasswordSupport(t *testing.T) {
-	logger := setUpLogger()
-	addr := "localhost:1234"
-	s := SetUpServerWithPassword(logger, ":1234", "mypassword")
-	go func() {
-		log.Fatal(s.Start())
-	}()
-	_, err := client.New(context.Background(), addr, "mypassword")
+	addr := testserver.Start(t, server.Config{Password: "mypassword"}).Addr
+	cl, err := client.New(context.Background(), addr, "mypassword")
 	require.Nil(t, err)
+	defer cl.Close()
 	_, err = client.New(context.Background(), addr, "")
 	require.ErrorIs(t, err, client.ErrInvalidPassword)
-	ctx, _ := context.WithTimeout(context.Background(), 1*time.Microsecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Microsecond)
+	defer cancel()
 	_, err = client.New(ctx, addr, "mypassword")
 	require.ErrorIs(t, err, client.ErrTimeIsOut)
+
+	// server without credentials doesn't start unless the default user is explicitly left without password
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	err = server.NewServer(server.Config{Log: slog.New(slog.NewTextHandler(io.Discard, nil))}).Serve(ln)
+	require.ErrorIs(t, err, server.ErrNoPassword)
+	addr = testserver.Start(t, server.Config{NoPassword: true}).Addr
+	cl, err = client.New(context.Background(), addr, "anything")
+	require.Nil(t, err)
+	defer cl.Close()
+	// the default user is disabled if only ACL file is given and it doesn't enable it
+	aclFile := filepath.Join(t.TempDir(), "users.acl")
+	require.Nil(t, os.WriteFile(aclFile, []byte("user app on >apppass +@all ~*\n"), 0o600))
+	aclLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	aclServer := server.NewServer(server.Config{
+		Log:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ACLFile:     aclFile,
+		RecoveryLog: filepath.Join(t.TempDir(), "logs"),
+	})
+	go aclServer.Serve(aclLn)
+	t.Cleanup(aclServer.Stop)
+	addr = aclLn.Addr().String()
+	_, err = client.New(context.Background(), addr, "")
+	require.ErrorIs(t, err, client.ErrInvalidPassword)
+	cl, err = client.NewWithOptions(context.Background(), client.Options{Addr: addr, User: "app", Password: "apppass"})
+	require.Nil(t, err)
+	defer cl.Close()
+
+	// credentials longer than a single read are accepted
+	long := strings.Repeat("p", 4096)
+	addr = testserver.Start(t, server.Config{Password: long}).Addr
+	cl, err = client.New(context.Background(), addr, long)
+	require.Nil(t, err)
+	defer cl.Close()
+	require.Nil(t, cl.Ping(context.Background()))
+
+	// AUTH split into several writes is read completely and the command sent with its tail isn't lost
+	conn, err := net.Dial("tcp", addr)
+	require.Nil(t, err)
+	defer conn.Close()
+	auth := fmt.Sprintf("*2\r\n$4\r\nAUTH\r\n$%d\r\n%s\r\n", len(long), long)
+	_, err = conn.Write([]byte(auth[:10]))
+	require.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	_, err = conn.Write([]byte(auth[10:] + "*1\r\n$4\r\nPING\r\n"))
+	require.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		var ok bool
+		require.Nil(t, binary.Read(conn, binary.BigEndian, &ok))
+		require.True(t, ok)
+	}
 }
-func setUpLogger() *slog.Logger {
-	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	return log
+func Test_Geo(t *testing.T) {
+	ctx := context.Background()
+	ind := 0
+	key := "drivers"
+	cl := testserver.Start(t, server.Config{}).Client
+	err := cl.GeoAdd(ctx, key, []client.GeoLocation{
+		{Name: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+		{Name: "Catania", Longitude: 15.087269, Latitude: 37.502669},
+		{Name: "edge1", Longitude: 12.758489, Latitude: 38.788135},
+		{Name: "edge2", Longitude: 17.241510, Latitude: 38.788135},
+	}, ind)
+	require.Nil(t, err)
+	dist, err := cl.GeoDist(ctx, key, "Palermo", "Catania", client.Kilometers, ind)
+	require.Nil(t, err)
+	require.InDelta(t, 166.2742, dist, 0.0001)
+	_, err = cl.GeoDist(ctx, key, "Palermo", "unknown", client.Kilometers, ind)
+	require.ErrorIs(t, err, client.ErrOperationFailed)
+	pos, err := cl.GeoPos(ctx, key, []string{"Palermo", "unknown"}, ind)
+	require.Nil(t, err)
+	require.Len(t, pos, 2)
+	require.InDelta(t, 13.361389, pos[0].Longitude, 0.00001)
+	require.InDelta(t, 38.115556, pos[0].Latitude, 0.00001)
+	require.Nil(t, pos[1])
+	res, err := cl.GeoSearch(ctx, key, &client.GeoSearchQuery{
+		Longitude: 15,
+		Latitude:  37,
+		Radius:    200,
+		Unit:      client.Kilometers,
+		Sort:      "ASC",
+		WithDist:  true,
+		WithCoord: true,
+	}, ind)
+	require.Nil(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, "Catania", res[0].Name)
+	require.InDelta(t, 56.4413, res[0].Dist, 0.0001)
+	require.InDelta(t, 15.087269, res[0].Longitude, 0.00001)
+	require.Equal(t, "Palermo", res[1].Name)
+	require.InDelta(t, 190.4424, res[1].Dist, 0.0001)
+	res, err = cl.GeoSearch(ctx, key, &client.GeoSearchQuery{
+		Member:    "Catania",
+		BoxWidth:  400,
+		BoxHeight: 400,
+		Unit:      client.Kilometers,
+		Count:     2,
+		WithHash:  true,
+	}, ind)
+	require.Nil(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, "Catania", res[0].Name)
+	require.NotZero(t, res[0].GeoHash)
 }
+func Test_JSON(t *testing.T) {
+	ctx := context.Background()
+	ind := 1
+	key := fmt.Sprintf("order_%v", rand.Int())
+	logs := filepath.Join(t.TempDir(), "logs")
+	s := testserver.Start(t, server.Config{RecoveryLog: logs})
+	cl := s.Client
+	cl2 := s.NewClient(client.Options{})
+	note := strings.Repeat("#note\n", 300)
+	doc := fmt.Sprintf("{\"status\":\"new\",\n\"total\":0,\"items\":[],\"note\":%q}", note)
+	err := cl.JSONSet(ctx, key, "$", doc, ind)
+	require.Nil(t, err)
+	err = cl.JSONSet(ctx, key, "$.courier", `{"name":"bob"}`, ind)
+	require.Nil(t, err)
+	err = cl.JSONSet(ctx, key, "$.missing.name", `"x"`, ind)
+	require.ErrorIs(t, err, client.ErrOperationFailed)
+	wg := sync.WaitGroup{}
+	for _, c := range []*client.Client{cl, cl2} {
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := c.JSONNumIncrBy(context.Background(), key, "$.total", 2, ind)
+				assert.Nil(t, err)
+				_, err = c.JSONArrAppend(context.Background(), key, "$.items", []string{`{"id":1}`}, ind)
+				assert.Nil(t, err)
+			}()
+		}
+	}
+	wg.Wait()
+	val, err := cl.JSONGet(ctx, key, ind, "$.total")
+	require.Nil(t, err)
+	require.Equal(t, "[200]", val)
+	val, err = cl.JSONGet(ctx, key, ind, ".courier.name")
+	require.Nil(t, err)
+	require.Equal(t, `"bob"`, val)
+	types, err := cl.JSONType(ctx, key, "$.items", ind)
+	require.Nil(t, err)
+	require.Equal(t, []string{"array"}, types)
+	lens, err := cl.JSONArrAppend(ctx, key, "$.items", []string{"1", "2"}, ind)
+	require.Nil(t, err)
+	require.Equal(t, []int64{102}, lens)
+	n, err := cl.JSONDel(ctx, key, "$.items[*]", ind)
+	require.Nil(t, err)
+	require.Equal(t, int64(102), n)
+	want, err := cl.JSONGet(ctx, key, ind)
+	require.Nil(t, err)
+	require.Contains(t, want, `#note\n#note`)
+
+	// recovery log is replayed by the new server
+	cl3 := testserver.Start(t, server.Config{RecoveryLog: logs}).Client
+	val, err = cl3.JSONGet(ctx, key, ind)
+	require.Nil(t, err)
+	require.Equal(t, want, val)
+}
+func Test_KeyspaceNotifications(t *testing.T) {
+	ctx := context.Background()
+	ind := 3
+	cl := testserver.Start(t, server.Config{NotifyKeyspaceEvents: "KEA"}).Client
+	keyspace, err := cl.SubscribeKeyspace(ctx, "user:*", ind)
+	require.Nil(t, err)
+	defer keyspace.Close()
+	keyevent, err := cl.SubscribeKeyevent(ctx, ind, "del")
+	require.Nil(t, err)
+	defer keyevent.Close()
+	require.Nil(t, cl.Set(ctx, "user:1", "bob", ind))
+	require.Nil(t, cl.Set(ctx, "order:1", "new", ind))
+	require.Nil(t, cl.LPush(ctx, "user:list", "bob", ind))
+	require.Nil(t, cl.Delete(ctx, "user:1", ind))
+	want := []client.KeyspaceEvent{
+		{Index: ind, Key: "user:1", Event: "set"},
+		{Index: ind, Key: "user:list", Event: "lpush"},
+		{Index: ind, Key: "user:1", Event: "del"},
+	}
+	for _, w := range want {
+		select {
+		case msg := <-keyspace.Channel():
+			ev, ok := msg.KeyspaceEvent()
+			require.True(t, ok)
+			require.Equal(t, w, ev)
+		case <-time.After(time.Second):
+			t.Fatal("keyspace notification is not received")
+		}
+	}
+	select {
+	case msg := <-keyevent.Channel():
+		ev, ok := msg.KeyspaceEvent()
+		require.True(t, ok)
+		require.Equal(t, client.KeyspaceEvent{Index: ind, Key: "user:1", Event: "del"}, ev)
+	case <-time.After(time.Second):
+		t.Fatal("keyevent notification is not received")
+	}
 
-func SetUpServer(logger *slog.Logger, addr string) *Server {
-	cfg := Config{
-		Log:        logger,
-		ListenAddr: addr,
+	// rate limiter state is written as SET with expiration
+	_, err = cl.RateLimit(ctx, client.RateLimitFixedWindow, "user:limit", 2, time.Hour, 1, ind)
+	require.Nil(t, err)
+	for _, w := range []string{"set", "expire"} {
+		select {
+		case msg := <-keyspace.Channel():
+			ev, ok := msg.KeyspaceEvent()
+			require.True(t, ok)
+			require.Equal(t, client.KeyspaceEvent{Index: ind, Key: "user:limit", Event: w}, ev)
+		case <-time.After(time.Second):
+			t.Fatal("keyspace notification is not received")
+		}
 	}
-	return NewServer(cfg)
+
+	sub, err := cl.Subscribe(ctx, "news")
+	require.Nil(t, err)
+	n, err := cl.Publish(ctx, "news", "hello")
+	require.Nil(t, err)
+	require.Equal(t, int64(1), n)
+	msg := <-sub.Channel()
+	require.Equal(t, &client.Message{Channel: "news", Payload: "hello"}, msg)
+	require.Nil(t, sub.Close())
+	time.Sleep(100 * time.Millisecond)
+	n, err = cl.Publish(ctx, "news", "hello")
+	require.Nil(t, err)
+	require.Equal(t, int64(0), n)
 }
-func SetUpServerWithPassword(logger *slog.Logger, addr string, password string) *Server {
-	cfg := Config{
-		Log:        logger,
-		ListenAddr: addr,
-		Password:   password,
+func Test_KeyspaceNotificationsReplica(t *testing.T) {
+	ctx := context.Background()
+	ind := 3
+	primary := testserver.Start(t, server.Config{})
+	replica := testserver.Start(t, server.Config{PrimaryAddr: primary.Addr, NotifyKeyspaceEvents: "KEA"})
+	require.Eventually(t, func() bool {
+		role, err := replica.Client.Role(ctx)
+		return err == nil && role.State == "connected"
+	}, 5*time.Second, 50*time.Millisecond)
+	keyspace, err := replica.Client.SubscribeKeyspace(ctx, "user:*", ind)
+	require.Nil(t, err)
+	defer keyspace.Close()
+	pcl := primary.Client
+	require.Nil(t, pcl.Set(ctx, "user:1", "bob", ind))
+	require.Nil(t, pcl.LPush(ctx, "user:list", "bob", ind))
+	require.Nil(t, pcl.Delete(ctx, "user:1", ind))
+	_, err = pcl.RateLimit(ctx, client.RateLimitFixedWindow, "user:limit", 2, time.Hour, 1, ind)
+	require.Nil(t, err)
+	want := []client.KeyspaceEvent{
+		{Index: ind, Key: "user:1", Event: "set"},
+		{Index: ind, Key: "user:list", Event: "lpush"},
+		{Index: ind, Key: "user:1", Event: "del"},
+		{Index: ind, Key: "user:limit", Event: "set"},
+		{Index: ind, Key: "user:limit", Event: "expire"},
+	}
+	for _, w := range want {
+		select {
+		case msg := <-keyspace.Channel():
+			ev, ok := msg.KeyspaceEvent()
+			require.True(t, ok)
+			require.Equal(t, w, ev)
+		case <-time.After(time.Second):
+			t.Fatal("keyspace notification is not received on replica")
+		}
+	}
+}
+func Test_Replication(t *testing.T) {
+	ctx := context.Background()
+	ind := 2
+	dir := t.TempDir()
+	primary := testserver.Start(t, server.Config{RecoveryLog: filepath.Join(dir, "primary")})
+	replica := testserver.Start(t, server.Config{RecoveryLog: filepath.Join(dir, "replica")})
+	pcl, rcl := primary.Client, replica.Client
+	host, port, err := net.SplitHostPort(primary.Addr)
+	require.Nil(t, err)
+	_, replicaPort, err := net.SplitHostPort(replica.Addr)
+	require.Nil(t, err)
+	waitFor := func(cond func() bool) {
+		t.Helper()
+		for i := 0; i < 50; i++ {
+			if cond() {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatal("condition is not met")
 	}
-	return NewServer(cfg)
+	require.Nil(t, pcl.Set(ctx, "k1", "v1", ind))
+	require.Nil(t, pcl.LPush(ctx, "list", "a", ind))
+	require.Nil(t, pcl.LPush(ctx, "list", "b", ind))
+	require.Nil(t, pcl.JSONSet(ctx, "doc", "$", `{"a":[1,2]}`, ind))
+	require.Nil(t, pcl.GeoAdd(ctx, "places", []client.GeoLocation{{Name: "Palermo", Longitude: 13.361389, Latitude: 38.115556}}, ind))
+	require.Nil(t, rcl.Set(ctx, "stale", "value", ind))
+
+	// full sync
+	require.Nil(t, rcl.ReplicaOf(ctx, host, port))
+	waitFor(func() bool {
+		role, err := rcl.Role(ctx)
+		require.Nil(t, err)
+		return role.State == "connected"
+	})
+	val, err := rcl.Get(ctx, "k1", ind)
+	require.Nil(t, err)
+	require.Equal(t, "v1", val)
+	list, err := rcl.GetL(ctx, "list", ind)
+	require.Nil(t, err)
+	require.Equal(t, []string{"a", "b"}, list)
+	doc, err := rcl.JSONGet(ctx, "doc", ind)
+	require.Nil(t, err)
+	require.Equal(t, `{"a":[1,2]}`, doc)
+	dist, err := rcl.GeoDist(ctx, "places", "Palermo", "Palermo", client.Meters, ind)
+	require.Nil(t, err)
+	require.Equal(t, float64(0), dist)
+	ok, err := rcl.Has(ctx, "stale", ind)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	// replica is read only and receives the stream
+	require.ErrorIs(t, rcl.Set(ctx, "k2", "replica", ind), client.ErrOperationFailed)
+	require.Nil(t, pcl.Set(ctx, "k2", "1", ind))
+	require.Nil(t, pcl.AddN(ctx, "k2", "10", ind))
+	waitFor(func() bool {
+		val, err := rcl.Get(ctx, "k2", ind)
+		return err == nil && val == "11"
+	})
+
+	role, err := pcl.Role(ctx)
+	require.Nil(t, err)
+	require.Equal(t, client.RoleMaster, role.Role)
+	require.Len(t, role.Replicas, 1)
+	require.Equal(t, replicaPort, role.Replicas[0].Port)
+	role, err = rcl.Role(ctx)
+	require.Nil(t, err)
+	require.Equal(t, client.RoleReplica, role.Role)
+	require.Equal(t, host, role.MasterHost)
+	require.Equal(t, port, role.MasterPort)
+	info, err := rcl.Info(ctx, "replication")
+	require.Nil(t, err)
+	require.Contains(t, info, "role:slave")
+	require.Contains(t, info, "master_link_status:up")
+
+	// partial resync after lost connection
+	replica.DropMasterLink()
+	require.Nil(t, pcl.Set(ctx, "k3", "v3", ind))
+	waitFor(func() bool {
+		val, err := rcl.Get(ctx, "k3", ind)
+		return err == nil && val == "v3"
+	})
+	info, err = pcl.Info(ctx, "replication")
+	require.Nil(t, err)
+	require.Contains(t, info, "sync_full:1")
+	require.Contains(t, info, "sync_partial_ok:1")
+	poffset, err := pcl.Role(ctx)
+	require.Nil(t, err)
+	roffset, err := rcl.Role(ctx)
+	require.Nil(t, err)
+	require.Equal(t, poffset.Offset, roffset.Offset)
+
+	// replicated commands are written to the recovery log of replica
+	data, err := os.ReadFile(filepath.Join(dir, "replica"))
+	require.Nil(t, err)
+	require.Contains(t, string(data), "SET#2#k3#v3#")
+	require.NotContains(t, string(data), "stale")
+
+	// promoted replica accepts writes
+	require.Nil(t, rcl.ReplicaOfNoOne(ctx))
+	require.Nil(t, rcl.Set(ctx, "k4", "v4", ind))
+	role, err = rcl.Role(ctx)
+	require.Nil(t, err)
+	require.Equal(t, client.RoleMaster, role.Role)
+}
+func Test_Cluster(t *testing.T) {
+	ctx := context.Background()
+	ind := 3
+	addrs := make([]string, 3)
+	ports := make([]string, 3)
+	clients := make([]*client.Client, 3)
+	ids := make([]string, 3)
+	for i := range clients {
+		s := testserver.Start(t, server.Config{ClusterEnabled: true, ClusterPingInterval: 100 * time.Millisecond})
+		addrs[i], clients[i] = s.Addr, s.Client
+		_, ports[i], _ = net.SplitHostPort(s.Addr)
+		var err error
+		ids[i], err = clients[i].ClusterMyID(ctx)
+		require.Nil(t, err)
+		require.Len(t, ids[i], 40)
+	}
+	waitFor := func(cond func() bool) {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			if cond() {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatal("condition is not met")
+	}
+
+	_, err := clients[0].Get(ctx, "key", ind)
+	require.ErrorContains(t, err, "CLUSTERDOWN")
+	require.Nil(t, clients[0].ClusterMeet(ctx, "127.0.0.1", ports[1]))
+	require.Nil(t, clients[0].ClusterMeet(ctx, "127.0.0.1", ports[2]))
+	require.Nil(t, clients[0].ClusterAddSlotsRange(ctx, 0, 5460))
+	require.Nil(t, clients[1].ClusterAddSlotsRange(ctx, 5461, 10922))
+	require.Nil(t, clients[2].ClusterAddSlotsRange(ctx, 10923, 16383))
+	require.ErrorIs(t, clients[2].ClusterAddSlots(ctx, 16383), client.ErrOperationFailed)
+	for _, cl := range clients {
+		waitFor(func() bool {
+			info, err := cl.ClusterInfo(ctx)
+			require.Nil(t, err)
+			return strings.Contains(info, "cluster_state:ok\r\n") && strings.Contains(info, "cluster_known_nodes:3\r\n")
+		})
+	}
+	info, err := clients[1].Info(ctx, "cluster")
+	require.Nil(t, err)
+	require.Contains(t, info, "cluster_enabled:1")
+	slots, err := clients[1].ClusterSlots(ctx)
+	require.Nil(t, err)
+	require.Equal(t, []client.SlotRange{
+		{Start: 0, End: 5460, Addr: addrs[0], ID: ids[0]},
+		{Start: 5461, End: 10922, Addr: addrs[1], ID: ids[1]},
+		{Start: 10923, End: 16383, Addr: addrs[2], ID: ids[2]},
+	}, slots)
+	nodes, err := clients[0].ClusterNodes(ctx)
+	require.Nil(t, err)
+	require.Contains(t, nodes, fmt.Sprintf("%s %s@%s myself,master - 0 0", ids[0], addrs[0], ports[0]))
+	require.Contains(t, nodes, "connected 10923-16383\n")
+
+	// "foo" is in slot 12182 served by the third node, keys with the same hash tag share the slot
+	slot, err := clients[0].ClusterKeySlot(ctx, "foo")
+	require.Nil(t, err)
+	require.Equal(t, 12182, slot)
+	err = clients[0].Set(ctx, "foo", "bar", ind)
+	require.Equal(t, &client.RedirectError{Slot: 12182, Addr: addrs[2]}, err)
+	require.ErrorIs(t, err, client.ErrOperationFailed)
+	require.Nil(t, clients[2].Set(ctx, "foo", "bar", ind))
+	require.Nil(t, clients[2].Set(ctx, "{foo}.second", "baz", ind))
+	require.Nil(t, clients[2].LPush(ctx, "{foo}.list", "a", ind))
+	_, err = clients[1].Has(ctx, "{foo}.list", ind)
+	require.IsType(t, &client.RedirectError{}, err)
+	count, err := clients[2].ClusterCountKeysInSlot(ctx, slot)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), count)
+
+	// slot is moved from the third node to the first one
+	require.Nil(t, clients[0].ClusterSetSlot(ctx, slot, client.SlotImporting, ids[2]))
+	require.Nil(t, clients[2].ClusterSetSlot(ctx, slot, client.SlotMigrating, ids[0]))
+	moved, err := clients[2].Migrate(ctx, "127.0.0.1", ports[0], "foo", ind, time.Second)
+	require.Nil(t, err)
+	require.True(t, moved)
+	// migrated key is served by the first node only after ASKING
+	err = clients[2].Set(ctx, "foo", "new", ind)
+	require.Equal(t, &client.RedirectError{Ask: true, Slot: slot, Addr: addrs[0]}, err)
+	_, err = clients[0].Get(ctx, "foo", ind)
+	require.Equal(t, &client.RedirectError{Slot: slot, Addr: addrs[2]}, err)
+	require.Nil(t, clients[0].Asking(ctx))
+	val, err := clients[0].Get(ctx, "foo", ind)
+	require.Nil(t, err)
+	require.Equal(t, "bar", val)
+	// keys that are not moved yet are still served by the third node
+	val, err = clients[2].Get(ctx, "{foo}.second", ind)
+	require.Nil(t, err)
+	require.Equal(t, "baz", val)
+
+	keys, err := clients[2].ClusterGetKeysInSlot(ctx, slot, 10)
+	require.Nil(t, err)
+	require.Equal(t, []client.SlotKey{{Key: "{foo}.list", Index: ind}, {Key: "{foo}.second", Index: ind}}, keys)
+	for _, k := range keys {
+		moved, err := clients[2].Migrate(ctx, "127.0.0.1", ports[0], k.Key, k.Index, time.Second)
+		require.Nil(t, err)
+		require.True(t, moved)
+	}
+	require.Nil(t, clients[0].ClusterSetSlot(ctx, slot, client.SlotNode, ids[0]))
+	require.Nil(t, clients[2].ClusterSetSlot(ctx, slot, client.SlotNode, ids[0]))
+	for _, cl := range clients {
+		waitFor(func() bool {
+			slots, err := cl.ClusterSlots(ctx)
+			require.Nil(t, err)
+			for _, r := range slots {
+				if r.Start <= slot && slot <= r.End {
+					return r.ID == ids[0]
+				}
+			}
+			return false
+		})
+	}
+	_, err = clients[2].Get(ctx, "foo", ind)
+	require.Equal(t, &client.RedirectError{Slot: slot, Addr: addrs[0]}, err)
+	list, err := clients[0].GetL(ctx, "{foo}.list", ind)
+	require.Nil(t, err)
+	require.Equal(t, []string{"a"}, list)
+	count, err = clients[2].ClusterCountKeysInSlot(ctx, slot)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), count)
+}
+
+func Test_ErrorResponses(t *testing.T) {
+	ctx := context.Background()
+	ind := 10
+	s := testserver.Start(t, server.Config{})
+	addr, cl := s.Addr, s.Client
+	require.Nil(t, cl.Set(ctx, "str", "value", ind))
+
+	// commands that can't be parsed get error response as well
+	conn, err := net.Dial("tcp", addr)
+	require.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("*2\r\n$4\r\nAUTH\r\n$6\r\nsecret\r\n"))
+	require.Nil(t, err)
+	var authorized bool
+	require.Nil(t, binary.Read(conn, binary.BigEndian, &authorized))
+	require.True(t, authorized)
+	for req, msg := range map[string]string{
+		"*3\r\n$3\r\nGET\r\n$3\r\nkey\r\n$2\r\n40\r\n": "INDEX invalid data base index",
+		"*1\r\n$4\r\nNOPE\r\n":                         "ERR unknown command",
+	} {
+		_, err = conn.Write([]byte(req))
+		require.Nil(t, err)
+		var status uint8
+		require.Nil(t, binary.Read(conn, binary.BigEndian, &status))
+		require.Equal(t, server.StatusError, status)
+		var size int64
+		require.Nil(t, binary.Read(conn, binary.BigEndian, &size))
+		buf := make([]byte, size)
+		_, err = io.ReadFull(conn, buf)
+		require.Nil(t, err)
+		require.Equal(t, msg, string(buf))
+	}
+
+	// connection is still usable after error responses
+	require.Nil(t, cl.Ping(ctx))
+	val, err := cl.Get(ctx, "str", ind)
+	require.Nil(t, err)
+	require.Equal(t, "value", val)
+}
+
+func Test_Command(t *testing.T) {
+	ctx := context.Background()
+	cl := testserver.Start(t, server.Config{}).Client
+
+	specs, err := cl.Command(ctx)
+	require.Nil(t, err)
+	n, err := cl.CommandCount(ctx)
+	require.Nil(t, err)
+	require.Equal(t, len(specs), n)
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	require.Contains(t, names, "GET")
+	require.Contains(t, names, "COMMAND")
+	require.NotContains(t, names, "SENTINEL")
+
+	specs, err = cl.CommandInfo(ctx, "mset", "GET", "PUBLISH")
+	require.Nil(t, err)
+	require.Equal(t, []client.CommandSpec{
+		{Name: "MSET", Arity: -4, Flags: []string{"write"}, FirstKey: 1, LastKey: -3, KeyStep: 2},
+		{Name: "GET", Arity: 3, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, KeyStep: 1},
+		{Name: "PUBLISH", Arity: 3, Flags: []string{"pubsub"}},
+	}, specs)
+	_, err = cl.CommandInfo(ctx, "NOPE")
+	require.NotNil(t, err)
+
+	docs, err := cl.CommandDocs(ctx, "GET")
+	require.Nil(t, err)
+	require.Equal(t, []client.CommandDoc{{Name: "GET", Summary: "Returns string value of the key", Syntax: "GET key index"}}, docs)
+	docs, err = cl.CommandDocs(ctx)
+	require.Nil(t, err)
+	require.Len(t, docs, n)
+
+	keys, err := cl.CommandGetKeys(ctx, "MSET", "a", "1", "b", "2", "0")
+	require.Nil(t, err)
+	require.Equal(t, []string{"a", "b"}, keys)
+	keys, err = cl.CommandGetKeys(ctx, "PING")
+	require.Nil(t, err)
+	require.Empty(t, keys)
+	_, err = cl.CommandGetKeys(ctx, "GET", "key")
+	require.ErrorIs(t, err, client.ErrSyntax)
+}
+
+func Test_ACL(t *testing.T) {
+	ctx := context.Background()
+	aclFile := filepath.Join(t.TempDir(), "users.acl")
+	require.Nil(t, os.WriteFile(aclFile, []byte("user reader on >readpass ~cache:* +@read +subscribe\n"), 0o600))
+	s := testserver.Start(t, server.Config{Password: "adminpass", ACLFile: aclFile})
+	addr := s.Addr
+	_, err := client.New(ctx, addr, "wrong")
+	require.ErrorIs(t, err, client.ErrInvalidPassword)
+	_, err = client.NewWithOptions(ctx, client.Options{Addr: addr, User: "reader", Password: "adminpass"})
+	require.ErrorIs(t, err, client.ErrInvalidPassword)
+
+	admin := s.Client
+	require.Nil(t, admin.Set(ctx, "cache:1", "v", 0))
+	require.Nil(t, admin.Set(ctx, "user:1", "v", 0))
+	who, err := admin.ACLWhoAmI(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "default", who)
+
+	reader, err := client.NewWithOptions(ctx, client.Options{Addr: addr, User: "reader", Password: "readpass"})
+	require.Nil(t, err)
+	defer reader.Close()
+	_, err = reader.ACLWhoAmI(ctx)
+	require.ErrorIs(t, err, client.ErrNoPerm)
+	// PING and HELLO are permitted to every user and rejected commands without reply don't shift replies
+	require.Nil(t, reader.Ping(ctx))
+	require.Nil(t, reader.Hello(ctx, map[string]string{"name": "reader"}))
+	_, err = reader.Do(ctx, "PUBLISH", "news", "hi")
+	require.ErrorIs(t, err, client.ErrNoPerm)
+	val, err := reader.Get(ctx, "cache:1", 0)
+	require.Nil(t, err)
+	require.Equal(t, "v", val)
+	_, err = reader.Get(ctx, "user:1", 0)
+	require.ErrorIs(t, err, client.ErrNoPerm)
+	err = reader.Set(ctx, "cache:1", "x", 0)
+	require.ErrorIs(t, err, client.ErrNoPerm)
+
+	require.ErrorIs(t, reader.Auth(ctx, "", "wrong"), client.ErrInvalidPassword)
+	require.Nil(t, reader.Auth(ctx, "", "adminpass"))
+	who, err = reader.ACLWhoAmI(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "default", who)
+
+	// connections of the pool and its subscriptions are authenticated as the user of the pool,
+	// connection authenticated as other user by Conn isn't reused
+	p, err := client.NewPool(ctx, client.PoolOptions{Addr: addr, User: "reader", Password: "readpass", PoolSize: 1})
+	require.Nil(t, err)
+	defer p.Close()
+	val, err = p.Get(ctx, "cache:1", 0)
+	require.Nil(t, err)
+	require.Equal(t, "v", val)
+	require.Nil(t, p.Conn(ctx, func(cl *client.Client) error {
+		return cl.Auth(ctx, "", "adminpass")
+	}))
+	_, err = p.Get(ctx, "user:1", 0)
+	require.ErrorIs(t, err, client.ErrNoPerm)
+	sub, err := p.Subscribe(ctx, "news")
+	require.Nil(t, err)
+	defer sub.Close()
+	_, err = client.NewPool(ctx, client.PoolOptions{Addr: addr, User: "reader", Password: "adminpass"})
+	require.ErrorIs(t, err, client.ErrInvalidPassword)
+
+	require.Nil(t, admin.ACLSetUser(ctx, "writer", "on", ">writepass", "~user:*", "+@write"))
+	user, err := admin.ACLGetUser(ctx, "writer")
+	require.Nil(t, err)
+	require.Equal(t, []string{"on"}, user.Flags)
+	require.Len(t, user.Passwords, 1)
+	require.Equal(t, "+@write", user.Commands)
+	require.Equal(t, []string{"user:*"}, user.Keys)
+	require.ErrorIs(t, admin.ACLSetUser(ctx, "writer", "+nope"), client.ErrSyntax)
+	_, err = admin.ACLGetUser(ctx, "nobody")
+	require.ErrorIs(t, err, client.ErrOperationFailed)
+
+	writer, err := client.NewWithOptions(ctx, client.Options{Addr: addr, User: "writer", Password: "writepass"})
+	require.Nil(t, err)
+	defer writer.Close()
+	require.Nil(t, writer.Set(ctx, "user:2", "v", 0))
+	require.ErrorIs(t, writer.Set(ctx, "cache:2", "v", 0), client.ErrNoPerm)
+
+	list, err := admin.ACLList(ctx)
+	require.Nil(t, err)
+	require.Len(t, list, 3)
+	require.True(t, strings.HasPrefix(list[0], "user default on #"))
+	require.True(t, strings.HasPrefix(list[1], "user reader on #"))
+	n, err := admin.ACLDelUser(ctx, "writer", "nobody")
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+	require.ErrorIs(t, writer.Set(ctx, "user:2", "v", 0), client.ErrNoPerm)
 }
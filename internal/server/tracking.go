@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/command"
+)
+
+// invalidateChannel is a channel of invalidation messages, message is "<db>:<key>" of the changed key,
+// empty message means that all keys are invalidated, e.g. after full sync with primary
+const invalidateChannel = "__redis__:invalidate"
+
+var (
+	ErrInvalidRedirect = errors.New("redirect peer doesn't exist or isn't subscribed to " + invalidateChannel)
+)
+
+// tracking is a client-side caching state of a peer
+type tracking struct {
+	redirect string
+	bcast    bool
+	prefixes [][]byte
+	noloop   bool
+}
+
+// matches reports whether change of the key is reported to peer in broadcasting mode
+func (t *tracking) matches(key []byte) bool {
+	if len(t.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range t.prefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackingKey returns key of the tracking table
+func trackingKey(index int, key []byte) string {
+	return strconv.Itoa(index) + ":" + string(key)
+}
+
+// ClientID writes id of the peer, it's used as REDIRECT of CLIENT TRACKING
+func (s *Server) ClientID(from string) error {
+	const op = "server.ClientID"
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	if err := writeValueResponse(peer.Conn, []byte(from)); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+// ClientTracking enables or disables client-side caching of the peer, redirect peer must be
+// subscribed to invalidation channel. Tracking state is accessed only by the server loop
+func (s *Server) ClientTracking(from string, cmd command.ClientTrackingCommand) error {
+	const op = "server.ClientTracking"
+	log := s.Log.With(slog.String("op", op), slog.String("peer address", from))
+	s.mu.RLock()
+	peer, ok := s.peers[from]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s:%w", op, ErrUknownPeer)
+	}
+	s.dropTracking(from)
+	if cmd.On {
+		s.psMu.Lock()
+		_, subscribed := s.channels[invalidateChannel][cmd.Redirect]
+		s.psMu.Unlock()
+		if !subscribed {
+			if err := writeFailure(peer.Conn, ErrInvalidRedirect); err != nil {
+				log.Error("got error after sending response", slog.String("error", err.Error()))
+			}
+			return fmt.Errorf("%s:%w", op, ErrInvalidRedirect)
+		}
+		s.tracking[from] = &tracking{
+			redirect: cmd.Redirect,
+			bcast:    cmd.BCast,
+			prefixes: cmd.Prefixes,
+			noloop:   cmd.NoLoop,
+		}
+	}
+	if err := writeValueResponse(peer.Conn, []byte("OK")); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	log.Info("tracking is changed", slog.Bool("on", cmd.On), slog.String("redirect", cmd.Redirect),
+		slog.Bool("bcast", cmd.BCast))
+	return nil
+}
+
+// trackCommand remembers keys read by the peer in default tracking mode and invalidates keys changed by write command,
+// keys are invalidated before command is executed, so invalidation is never delivered after the reply
+func (s *Server) trackCommand(from string, req command.Request) {
+	if len(s.tracking) == 0 {
+		return
+	}
+	if req.Spec.Has(command.FlagWrite) {
+		s.invalidate(from, req.Index(), req.Keys())
+		return
+	}
+	t, ok := s.tracking[from]
+	if !ok || t.bcast || !req.Spec.Has(command.FlagReadOnly) {
+		return
+	}
+	index := req.Index()
+	for _, key := range req.Keys() {
+		tk := trackingKey(index, key)
+		peers, ok := s.trackedKeys[tk]
+		if !ok {
+			peers = make(map[string]struct{})
+			s.trackedKeys[tk] = peers
+		}
+		peers[from] = struct{}{}
+	}
+}
+
+// invalidate sends invalidation messages about keys to peers that track them, key is forgotten
+// after invalidation until it's read again. Empty from is used for changes that are not made by peers
+func (s *Server) invalidate(from string, index int, keys [][]byte) {
+	if len(s.tracking) == 0 {
+		return
+	}
+	for _, key := range keys {
+		tk := trackingKey(index, key)
+		targets := s.trackedKeys[tk]
+		delete(s.trackedKeys, tk)
+		for addr, t := range s.tracking {
+			if _, ok := targets[addr]; !ok && !(t.bcast && t.matches(key)) {
+				continue
+			}
+			if t.noloop && addr == from {
+				continue
+			}
+			s.sendInvalidation(t.redirect, []byte(tk))
+		}
+	}
+}
+
+// invalidateAll sends invalidation of all keys to every tracking peer
+func (s *Server) invalidateAll() {
+	for _, t := range s.tracking {
+		s.sendInvalidation(t.redirect, nil)
+	}
+	clear(s.trackedKeys)
+}
+
+// sendInvalidation writes invalidation message to redirect peer if it's still subscribed to invalidations
+func (s *Server) sendInvalidation(redirect string, msg []byte) {
+	const op = "server.sendInvalidation"
+	s.psMu.Lock()
+	defer s.psMu.Unlock()
+	if _, ok := s.channels[invalidateChannel][redirect]; !ok {
+		return
+	}
+	s.mu.RLock()
+	peer, ok := s.peers[redirect]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if err := writeListResponse(peer.Conn, [][]byte{[]byte(pubSubMessage), []byte(invalidateChannel), msg}); err != nil {
+		s.Log.Error("failed to deliver invalidation", slog.String("op", op), slog.String("peer address", redirect),
+			slog.String("error", err.Error()))
+	}
+}
+
+// dropTracking removes tracking state and tracked keys of the peer
+func (s *Server) dropTracking(from string) {
+	if _, ok := s.tracking[from]; !ok {
+		return
+	}
+	delete(s.tracking, from)
+	for tk, peers := range s.trackedKeys {
+		delete(peers, from)
+		if len(peers) == 0 {
+			delete(s.trackedKeys, tk)
+		}
+	}
+}
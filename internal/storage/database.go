@@ -3,6 +3,8 @@ package storage
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 )
 
 var (
@@ -13,22 +15,29 @@ type DataBase struct {
 	Index int
 	KV    *KeyValue
 	LST   *List
+	GEO   *Geo
+	JSON  *JSONDocs
 }
 type Storage struct {
-	DBS [40]*DataBase
+	DBS     [40]*DataBase
+	clockMu sync.RWMutex
+	clock   func() time.Time
 }
 
 func NewStorage() *Storage {
-	s := Storage{}
+	s := &Storage{clock: time.Now}
 	for i := 0; i < 40; i++ {
 		db := DataBase{
 			Index: i,
 			KV:    NreKeyValue(),
 			LST:   NewList(),
+			GEO:   NewGeo(),
+			JSON:  NewJSONDocs(),
 		}
+		db.KV.now = s.Now
 		s.DBS[i] = &db
 	}
-	return &s
+	return s
 }
 
 func (s *Storage) Set(key []byte, value []byte, index int) error {
@@ -36,6 +45,7 @@ func (s *Storage) Set(key []byte, value []byte, index int) error {
 	if index > 39 || index < 0 {
 		return fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
 	}
+	s.DBS[index].deleteGeo(key)
 	err := s.DBS[index].KV.Set(key, value)
 	if err != nil {
 		return fmt.Errorf("%s:%w", op, err)
@@ -55,6 +65,9 @@ func (s *Storage) Add(key []byte, index int) error {
 	if index > 39 || index < 0 {
 		return fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
 	}
+	if err := s.checkNotGeo(key, index); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
 	err := s.DBS[index].KV.Add(key)
 	if err != nil {
 		return fmt.Errorf("%s:%w", op, err)
@@ -67,6 +80,9 @@ func (s *Storage) AddN(key []byte, value []byte, index int) error {
 	if index > 39 || index < 0 {
 		return fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
 	}
+	if err := s.checkNotGeo(key, index); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
 	err := s.DBS[index].KV.AddN(key, value)
 	if err != nil {
 		return fmt.Errorf("%s:%w", op, err)
@@ -74,12 +90,13 @@ func (s *Storage) AddN(key []byte, value []byte, index int) error {
 	return nil
 }
 
+// Delete deletes key of any type
 func (s *Storage) Delete(key []byte, index int) error {
 	const op = "storage.Delete"
-	if index > 39 || index < 0 {
-		return fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	if _, err := s.Unlink(key, index); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
 	}
-	return s.DBS[index].KV.Delete(key)
+	return nil
 }
 
 func (s *Storage) LPush(key []byte, value []byte, index int) error {
@@ -87,13 +104,15 @@ func (s *Storage) LPush(key []byte, value []byte, index int) error {
 	if index > 39 || index < 0 {
 		return fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
 	}
+	if err := s.checkNotGeo(key, index); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
 	return s.DBS[index].LST.LPush(key, value)
 }
+
+// Has reports whether key of any type exists
 func (s *Storage) Has(key []byte, index int) bool {
-	if index > 39 || index < 0 {
-		return false
-	}
-	return s.DBS[index].LST.Has(key)
+	return s.Exists(key, index)
 }
 func (s *Storage) GetL(key []byte, index int) ([][]byte, error) {
 	const op = "storage.GetL"
@@ -126,3 +145,124 @@ func (s *Storage) DelAll(key []byte, value []byte, index int) error {
 	}
 	return s.DBS[index].LST.DelAll(key, value)
 }
+
+func (s *Storage) GeoAdd(key []byte, points []GeoPoint, index int) (int, error) {
+	const op = "storage.GeoAdd"
+	if index > 39 || index < 0 {
+		return 0, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	if s.Exists(key, index) && !s.DBS[index].holdsGeo(key) {
+		return 0, fmt.Errorf("%s:%w", op, ErrWrongType)
+	}
+	n, err := s.DBS[index].GEO.GeoAdd(key, points)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	return n, nil
+}
+
+func (s *Storage) GeoPos(key []byte, members [][]byte, index int) ([]*GeoPoint, error) {
+	const op = "storage.GeoPos"
+	if index > 39 || index < 0 {
+		return nil, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	return s.DBS[index].GEO.GeoPos(key, members), nil
+}
+
+func (s *Storage) GeoDist(key, member1, member2 []byte, index int) (float64, error) {
+	const op = "storage.GeoDist"
+	if index > 39 || index < 0 {
+		return 0, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	dist, err := s.DBS[index].GEO.GeoDist(key, member1, member2)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	return dist, nil
+}
+
+func (s *Storage) GeoSearch(key []byte, q GeoQuery, index int) ([]GeoResult, error) {
+	const op = "storage.GeoSearch"
+	if index > 39 || index < 0 {
+		return nil, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	res, err := s.DBS[index].GEO.GeoSearch(key, q)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return res, nil
+}
+
+func (s *Storage) JSONSet(key, path, value []byte, index int) error {
+	const op = "storage.JSONSet"
+	if index > 39 || index < 0 {
+		return fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	if err := s.checkNotGeo(key, index); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	if err := s.DBS[index].JSON.Set(key, path, value); err != nil {
+		return fmt.Errorf("%s:%w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) JSONGet(key []byte, paths [][]byte, index int) ([]byte, error) {
+	const op = "storage.JSONGet"
+	if index > 39 || index < 0 {
+		return nil, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	res, err := s.DBS[index].JSON.Get(key, paths)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return res, nil
+}
+
+func (s *Storage) JSONDel(key, path []byte, index int) (int, error) {
+	const op = "storage.JSONDel"
+	if index > 39 || index < 0 {
+		return 0, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	n, err := s.DBS[index].JSON.Del(key, path)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	return n, nil
+}
+
+func (s *Storage) JSONNumIncrBy(key, path, value []byte, index int) ([]byte, error) {
+	const op = "storage.JSONNumIncrBy"
+	if index > 39 || index < 0 {
+		return nil, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	res, err := s.DBS[index].JSON.NumIncrBy(key, path, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return res, nil
+}
+
+func (s *Storage) JSONArrAppend(key, path []byte, values [][]byte, index int) ([]int, error) {
+	const op = "storage.JSONArrAppend"
+	if index > 39 || index < 0 {
+		return nil, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	res, err := s.DBS[index].JSON.ArrAppend(key, path, values)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return res, nil
+}
+
+func (s *Storage) JSONType(key, path []byte, index int) ([]string, error) {
+	const op = "storage.JSONType"
+	if index > 39 || index < 0 {
+		return nil, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	res, err := s.DBS[index].JSON.Type(key, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return res, nil
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SetOptions are conditions and expiration of SetWith
+type SetOptions struct {
+	// NX sets the key only if it doesn't exist, XX only if it exists
+	NX, XX bool
+	// IfEq sets the key only if its current value is equal to IfEq, it's ignored if nil
+	IfEq []byte
+	// ExpireAt is an expiration time of the key, TTL of the key is removed if it's zero
+	ExpireAt time.Time
+}
+
+// SetClock sets function that returns current time, it's used to expire keys, time.Now is used by default
+func (s *Storage) SetClock(now func() time.Time) {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+	s.clock = now
+}
+
+// Now returns current time of the storage clock
+func (s *Storage) Now() time.Time {
+	s.clockMu.RLock()
+	defer s.clockMu.RUnlock()
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock()
+}
+
+// SetWith sets value of the key if conditions of opts are met, returns false if key is not set
+func (s *Storage) SetWith(key, value []byte, opts SetOptions, index int) (bool, error) {
+	const op = "storage.SetWith"
+	if index > 39 || index < 0 {
+		return false, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	ok := s.DBS[index].KV.SetWith(key, value, opts)
+	if ok {
+		s.DBS[index].deleteGeo(key)
+	}
+	return ok, nil
+}
+
+// DelIfEq deletes key if its value is equal to value, returns false if key is not deleted
+func (s *Storage) DelIfEq(key, value []byte, index int) (bool, error) {
+	const op = "storage.DelIfEq"
+	if index > 39 || index < 0 {
+		return false, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	return s.DBS[index].KV.DelIfEq(key, value), nil
+}
+
+// ExpireAt sets expiration time of the key, returns false if key doesn't exist, only string keys may expire
+func (s *Storage) ExpireAt(key []byte, at time.Time, index int) (bool, error) {
+	const op = "storage.ExpireAt"
+	if index > 39 || index < 0 {
+		return false, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	return s.DBS[index].KV.ExpireAt(key, at), nil
+}
+
+// PTTL returns time to live of the key in milliseconds, -1 if key doesn't expire and -2 if it doesn't exist
+func (s *Storage) PTTL(key []byte, index int) (int64, error) {
+	const op = "storage.PTTL"
+	if index > 39 || index < 0 {
+		return 0, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	return s.DBS[index].KV.PTTL(key), nil
+}
+
+// Incr increments integer value of the key and returns the new value, key that doesn't exist is set to 1
+func (s *Storage) Incr(key []byte, index int) (int64, error) {
+	const op = "storage.Incr"
+	if index > 39 || index < 0 {
+		return 0, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	if err := s.checkNotGeo(key, index); err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	n, err := s.DBS[index].KV.Incr(key)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%w", op, err)
+	}
+	return n, nil
+}
+
+// DeleteExpired deletes expired keys of database index and returns them
+func (s *Storage) DeleteExpired(index int) [][]byte {
+	if index > 39 || index < 0 {
+		return nil
+	}
+	return s.DBS[index].KV.DeleteExpired()
+}
+
+func (kv *KeyValue) SetWith(key, value []byte, opts SetOptions) bool {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.removeExpired(string(key))
+	cur, ok := kv.Data[string(key)]
+	if (opts.NX && ok) || (opts.XX && !ok) {
+		return false
+	}
+	if opts.IfEq != nil && (!ok || !bytes.Equal(cur, opts.IfEq)) {
+		return false
+	}
+	kv.Data[string(key)] = value
+	if opts.ExpireAt.IsZero() {
+		delete(kv.expires, string(key))
+	} else {
+		kv.expires[string(key)] = opts.ExpireAt
+	}
+	return true
+}
+
+func (kv *KeyValue) DelIfEq(key, value []byte) bool {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.removeExpired(string(key))
+	cur, ok := kv.Data[string(key)]
+	if !ok || !bytes.Equal(cur, value) {
+		return false
+	}
+	delete(kv.Data, string(key))
+	delete(kv.expires, string(key))
+	return true
+}
+
+func (kv *KeyValue) ExpireAt(key []byte, at time.Time) bool {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.removeExpired(string(key))
+	if _, ok := kv.Data[string(key)]; !ok {
+		return false
+	}
+	kv.expires[string(key)] = at
+	return true
+}
+
+func (kv *KeyValue) PTTL(key []byte) int64 {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+	if _, ok := kv.Data[string(key)]; !ok || kv.expired(string(key)) {
+		return -2
+	}
+	at, ok := kv.expires[string(key)]
+	if !ok {
+		return -1
+	}
+	return at.Sub(kv.now()).Milliseconds()
+}
+
+// Incr increments value of the key, TTL of the key is kept
+func (kv *KeyValue) Incr(key []byte) (int64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.removeExpired(string(key))
+	var n int64
+	if val, ok := kv.Data[string(key)]; ok {
+		var err error
+		n, err = strconv.ParseInt(string(val), 10, 64)
+		if err != nil {
+			return 0, ErrUnableToConvertToInt
+		}
+	}
+	n++
+	kv.Data[string(key)] = strconv.AppendInt(nil, n, 10)
+	return n, nil
+}
+
+func (kv *KeyValue) DeleteExpired() [][]byte {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	var res [][]byte
+	for key := range kv.expires {
+		if kv.removeExpired(key) {
+			res = append(res, []byte(key))
+		}
+	}
+	return res
+}
+
+// expireAt returns expiration time of the key, it's zero if key doesn't expire
+func (kv *KeyValue) expireAt(key string) time.Time {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+	return kv.expires[key]
+}
+
+// expired reports whether key has TTL and it's elapsed, it must be called with mu locked
+func (kv *KeyValue) expired(key string) bool {
+	at, ok := kv.expires[key]
+	return ok && !kv.now().Before(at)
+}
+
+// removeExpired deletes key if it's expired, it must be called with mu locked for writing
+func (kv *KeyValue) removeExpired(key string) bool {
+	if !kv.expired(key) {
+		return false
+	}
+	delete(kv.Data, key)
+	delete(kv.expires, key)
+	return true
+}
@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	GeoSortNone = iota
+	GeoSortAsc
+	GeoSortDesc
+)
+
+var (
+	ErrInvalidCoordinates = errors.New("invalid longitude or latitude")
+	ErrMemberDoNotExists  = errors.New("member doesn't exist")
+)
+
+// GeoPoint is a named location
+type GeoPoint struct {
+	Member    []byte
+	Longitude float64
+	Latitude  float64
+}
+
+// GeoQuery describes area and options of geo search,
+// all distances are in meters
+type GeoQuery struct {
+	// FromMember is used as a center of the search if it is not empty,
+	// otherwise Longitude and Latitude are used
+	FromMember []byte
+	Longitude  float64
+	Latitude   float64
+	// Radius is used for search by radius if it is not zero,
+	// otherwise search is done in the box Width x Height
+	Radius float64
+	Width  float64
+	Height float64
+	Sort   int
+	// Count limits number of results if it is greater than zero
+	Count int
+	// Any allows to return first Count found results instead of the closest ones
+	Any bool
+}
+
+// GeoResult is a found location
+type GeoResult struct {
+	Member    string
+	Longitude float64
+	Latitude  float64
+	Dist      float64
+	Hash      uint64
+}
+
+// Geo keeps locations of members in sorted sets, score of the member is a geohash of its location
+type Geo struct {
+	mu   sync.RWMutex
+	sets map[string]*zset
+}
+
+func NewGeo() *Geo {
+	return &Geo{
+		sets: make(map[string]*zset),
+	}
+}
+
+// GeoAdd adds or updates locations of the members, returns number of new members
+func (g *Geo) GeoAdd(key []byte, points []GeoPoint) (int, error) {
+	for _, p := range points {
+		if !validCoordinates(p.Longitude, p.Latitude) {
+			return 0, ErrInvalidCoordinates
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	set, ok := g.sets[string(key)]
+	if !ok {
+		set = newZSet()
+		g.sets[string(key)] = set
+	}
+	added := 0
+	for _, p := range points {
+		if set.add(string(p.Member), geoEncode(p.Longitude, p.Latitude, geoStepMax)) {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// GeoPos returns locations of the members, nil is returned for unknown members
+func (g *Geo) GeoPos(key []byte, members [][]byte) []*GeoPoint {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	res := make([]*GeoPoint, len(members))
+	set, ok := g.sets[string(key)]
+	if !ok {
+		return res
+	}
+	for i, m := range members {
+		score, ok := set.score(string(m))
+		if !ok {
+			continue
+		}
+		lon, lat := geoDecode(score)
+		res[i] = &GeoPoint{
+			Member:    m,
+			Longitude: lon,
+			Latitude:  lat,
+		}
+	}
+	return res
+}
+
+// GeoDist returns distance in meters between two members
+func (g *Geo) GeoDist(key, member1, member2 []byte) (float64, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	set, ok := g.sets[string(key)]
+	if !ok {
+		return 0, ErrKeyDoNotExists
+	}
+	score1, ok := set.score(string(member1))
+	if !ok {
+		return 0, ErrMemberDoNotExists
+	}
+	score2, ok := set.score(string(member2))
+	if !ok {
+		return 0, ErrMemberDoNotExists
+	}
+	lon1, lat1 := geoDecode(score1)
+	lon2, lat2 := geoDecode(score2)
+	return geoDistance(lon1, lat1, lon2, lat2), nil
+}
+
+// GeoSearch returns members located within the area described by the query, result is empty if key doesn't exist
+func (g *Geo) GeoSearch(key []byte, q GeoQuery) ([]GeoResult, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	set, ok := g.sets[string(key)]
+	if !ok {
+		return nil, nil
+	}
+	lon, lat := q.Longitude, q.Latitude
+	if len(q.FromMember) != 0 {
+		score, ok := set.score(string(q.FromMember))
+		if !ok {
+			return nil, ErrMemberDoNotExists
+		}
+		lon, lat = geoDecode(score)
+	} else if !validCoordinates(lon, lat) {
+		return nil, ErrInvalidCoordinates
+	}
+	halfWidth, halfHeight := q.Radius, q.Radius
+	radius := q.Radius
+	if q.Radius == 0 {
+		halfWidth, halfHeight = q.Width/2, q.Height/2
+		radius = math.Sqrt(halfWidth*halfWidth + halfHeight*halfHeight)
+	}
+	sortBy := q.Sort
+	if q.Count > 0 && !q.Any && sortBy == GeoSortNone {
+		sortBy = GeoSortAsc
+	}
+	var res []GeoResult
+	for _, entries := range geoCandidates(set, lon, lat, radius, geoBoundingBox(lon, lat, halfWidth, halfHeight)) {
+		for _, e := range entries {
+			plon, plat := geoDecode(e.score)
+			var dist float64
+			if q.Radius != 0 {
+				dist = geoDistance(lon, lat, plon, plat)
+				if dist > q.Radius {
+					continue
+				}
+			} else {
+				if earthRadius*math.Abs(degRad(plat)-degRad(lat)) > halfHeight {
+					continue
+				}
+				if geoDistance(lon, plat, plon, plat) > halfWidth {
+					continue
+				}
+				dist = geoDistance(lon, lat, plon, plat)
+			}
+			res = append(res, GeoResult{
+				Member:    e.member,
+				Longitude: plon,
+				Latitude:  plat,
+				Dist:      dist,
+				Hash:      e.score,
+			})
+			if q.Any && q.Count > 0 && len(res) == q.Count {
+				break
+			}
+		}
+		if q.Any && q.Count > 0 && len(res) == q.Count {
+			break
+		}
+	}
+	switch sortBy {
+	case GeoSortAsc:
+		sort.SliceStable(res, func(i, j int) bool { return res[i].Dist < res[j].Dist })
+	case GeoSortDesc:
+		sort.SliceStable(res, func(i, j int) bool { return res[i].Dist > res[j].Dist })
+	}
+	if q.Count > 0 && len(res) > q.Count {
+		res = res[:q.Count]
+	}
+	return res, nil
+}
+
+// geoCandidates returns ranges of the sorted set that may contain members located in the box,
+// ranges are taken from the grid cell that contains the center and its neighbors
+func geoCandidates(set *zset, lon, lat, radius float64, box geoCell) [][]zentry {
+	box.latMin = math.Max(box.latMin, geoLatMin)
+	box.latMax = math.Min(box.latMax, geoLatMax)
+	for step := geoEstimateStep(radius, lat); step > 0; step-- {
+		lonIdx, latIdx := geoCellIndexes(lon, lat, step)
+		cell := geoCellBounds(lonIdx, latIdx, step)
+		cellWidth, cellHeight := cell.lonMax-cell.lonMin, cell.latMax-cell.latMin
+		if box.lonMin < cell.lonMin-cellWidth || box.lonMax > cell.lonMax+cellWidth ||
+			box.latMin < cell.latMin-cellHeight || box.latMax > cell.latMax+cellHeight {
+			continue
+		}
+		cells := int64(1) << step
+		shift := geoHashBitsTotal - 2*step
+		seen := make(map[uint64]bool)
+		var ranges [][]zentry
+		for dy := int64(-1); dy <= 1; dy++ {
+			y := int64(latIdx) + dy
+			if y < 0 || y >= cells {
+				continue
+			}
+			for dx := int64(-1); dx <= 1; dx++ {
+				x := (int64(lonIdx) + dx + cells) % cells
+				hash := interleave(uint32(y), uint32(x))
+				if seen[hash] {
+					continue
+				}
+				seen[hash] = true
+				ranges = append(ranges, set.rangeByScore(hash<<shift, (hash+1)<<shift))
+			}
+		}
+		return ranges
+	}
+	return [][]zentry{set.entries}
+}
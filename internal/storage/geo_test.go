@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sicily(t *testing.T) *Geo {
+	g := NewGeo()
+	n, err := g.GeoAdd([]byte("Sicily"), []GeoPoint{
+		{Member: []byte("Palermo"), Longitude: 13.361389, Latitude: 38.115556},
+		{Member: []byte("Catania"), Longitude: 15.087269, Latitude: 37.502669},
+		{Member: []byte("edge1"), Longitude: 12.758489, Latitude: 38.788135},
+		{Member: []byte("edge2"), Longitude: 17.241510, Latitude: 38.788135},
+	})
+	require.Nil(t, err)
+	require.Equal(t, 4, n)
+	return g
+}
+
+func Test_GeoPosAndDist(t *testing.T) {
+	g := sicily(t)
+	pos := g.GeoPos([]byte("Sicily"), [][]byte{[]byte("Palermo"), []byte("unknown")})
+	require.NotNil(t, pos[0])
+	require.InDelta(t, 13.361389, pos[0].Longitude, 0.00001)
+	require.InDelta(t, 38.115556, pos[0].Latitude, 0.00001)
+	require.Nil(t, pos[1])
+	dist, err := g.GeoDist([]byte("Sicily"), []byte("Palermo"), []byte("Catania"))
+	require.Nil(t, err)
+	require.InDelta(t, 166274.1516, dist, 0.01)
+	_, err = g.GeoDist([]byte("Sicily"), []byte("Palermo"), []byte("unknown"))
+	require.ErrorIs(t, err, ErrMemberDoNotExists)
+	_, err = g.GeoAdd([]byte("Sicily"), []GeoPoint{{Member: []byte("pole"), Longitude: 0, Latitude: 89}})
+	require.ErrorIs(t, err, ErrInvalidCoordinates)
+}
+
+func Test_GeoSearch(t *testing.T) {
+	g := sicily(t)
+	res, err := g.GeoSearch([]byte("Sicily"), GeoQuery{
+		Longitude: 15,
+		Latitude:  37,
+		Radius:    200000,
+		Sort:      GeoSortAsc,
+	})
+	require.Nil(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, "Catania", res[0].Member)
+	require.InDelta(t, 56441.3, res[0].Dist, 0.1)
+	require.Equal(t, "Palermo", res[1].Member)
+	res, err = g.GeoSearch([]byte("Sicily"), GeoQuery{
+		Longitude: 15,
+		Latitude:  37,
+		Width:     400000,
+		Height:    400000,
+		Sort:      GeoSortDesc,
+	})
+	require.Nil(t, err)
+	require.Len(t, res, 4)
+	require.Equal(t, "edge1", res[0].Member)
+	require.Equal(t, "Catania", res[3].Member)
+	res, err = g.GeoSearch([]byte("Sicily"), GeoQuery{
+		FromMember: []byte("Palermo"),
+		Radius:     200000,
+		Count:      1,
+	})
+	require.Nil(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, "Palermo", res[0].Member)
+	require.InDelta(t, 0, res[0].Dist, 0.0001)
+	res, err = g.GeoSearch([]byte("missing"), GeoQuery{Longitude: 15, Latitude: 37, Radius: 200000})
+	require.Nil(t, err)
+	require.Empty(t, res)
+}
+
+func Test_GeoSharedKeyspace(t *testing.T) {
+	s := NewStorage()
+	ind := 2
+	palermo := []GeoPoint{{Member: []byte("Palermo"), Longitude: 13.361389, Latitude: 38.115556}}
+	_, err := s.GeoAdd([]byte("geo"), palermo, ind)
+	require.Nil(t, err)
+	require.True(t, s.Has([]byte("geo"), ind))
+	require.ErrorIs(t, s.LPush([]byte("geo"), []byte("a"), ind), ErrWrongType)
+	require.ErrorIs(t, s.Add([]byte("geo"), ind), ErrWrongType)
+	require.ErrorIs(t, s.JSONSet([]byte("geo"), []byte("$"), []byte(`1`), ind), ErrWrongType)
+	_, err = s.Incr([]byte("geo"), ind)
+	require.ErrorIs(t, err, ErrWrongType)
+	require.Nil(t, s.Delete([]byte("geo"), ind))
+	require.False(t, s.Has([]byte("geo"), ind))
+
+	require.Nil(t, s.Set([]byte("str"), []byte("value"), ind))
+	_, err = s.GeoAdd([]byte("str"), palermo, ind)
+	require.ErrorIs(t, err, ErrWrongType)
+	// SET replaces geo set
+	_, err = s.GeoAdd([]byte("geo"), palermo, ind)
+	require.Nil(t, err)
+	require.Nil(t, s.Set([]byte("geo"), []byte("value"), ind))
+	pos, err := s.GeoPos([]byte("geo"), [][]byte{[]byte("Palermo")}, ind)
+	require.Nil(t, err)
+	require.Nil(t, pos[0])
+	require.Equal(t, [][]byte{[]byte("geo"), []byte("str")}, s.Keys(ind))
+}
+
+func Test_GeoSearchMatchesFullScan(t *testing.T) {
+	g := NewGeo()
+	key := []byte("points")
+	points := make([]GeoPoint, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		points = append(points, GeoPoint{
+			Member:    []byte(fmt.Sprintf("p_%v", i)),
+			Longitude: rand.Float64()*360 - 180,
+			Latitude:  rand.Float64()*170 - 85,
+		})
+	}
+	_, err := g.GeoAdd(key, points)
+	require.Nil(t, err)
+	for i := 0; i < 50; i++ {
+		lon, lat := rand.Float64()*360-180, rand.Float64()*170-85
+		radius := rand.Float64() * 3000000
+		res, err := g.GeoSearch(key, GeoQuery{Longitude: lon, Latitude: lat, Radius: radius})
+		require.Nil(t, err)
+		want := 0
+		for _, e := range g.sets[string(key)].entries {
+			plon, plat := geoDecode(e.score)
+			if geoDistance(lon, lat, plon, plat) <= radius {
+				want++
+			}
+		}
+		require.Equal(t, want, len(res))
+	}
+}
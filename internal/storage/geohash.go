@@ -0,0 +1,154 @@
+package storage
+
+import "math"
+
+const (
+	geoLatMin        = -85.05112878
+	geoLatMax        = 85.05112878
+	geoLonMin        = -180.0
+	geoLonMax        = 180.0
+	geoStepMax       = 26
+	earthRadius      = 6372797.560856
+	mercatorMax      = 20037726.37
+	geoHashBitsTotal = geoStepMax * 2
+)
+
+// geoCell is an area of the geohash grid
+type geoCell struct {
+	lonMin, lonMax float64
+	latMin, latMax float64
+}
+
+// validCoordinates reports whether coordinates can be indexed
+func validCoordinates(lon, lat float64) bool {
+	return lon >= geoLonMin && lon <= geoLonMax && lat >= geoLatMin && lat <= geoLatMax
+}
+
+// interleave spreads bits of x over even positions and bits of y over odd positions
+func interleave(x, y uint32) uint64 {
+	return spread(x) | spread(y)<<1
+}
+
+// deinterleave is reverse of interleave
+func deinterleave(v uint64) (x, y uint32) {
+	return squash(v), squash(v >> 1)
+}
+
+func spread(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+func squash(v uint64) uint32 {
+	x := v & 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+	return uint32(x)
+}
+
+// geoCellIndexes returns grid coordinates of the cell that contains given point at given step
+func geoCellIndexes(lon, lat float64, step uint) (lonIdx, latIdx uint32) {
+	cells := float64(uint64(1) << step)
+	lonOffset := (lon - geoLonMin) / (geoLonMax - geoLonMin) * cells
+	latOffset := (lat - geoLatMin) / (geoLatMax - geoLatMin) * cells
+	lonIdx = uint32(math.Min(lonOffset, cells-1))
+	latIdx = uint32(math.Min(latOffset, cells-1))
+	return lonIdx, latIdx
+}
+
+// geoEncode returns geohash of the point with given precision (step)
+func geoEncode(lon, lat float64, step uint) uint64 {
+	lonIdx, latIdx := geoCellIndexes(lon, lat, step)
+	return interleave(latIdx, lonIdx)
+}
+
+// geoCellBounds returns area of the cell with given grid coordinates
+func geoCellBounds(lonIdx, latIdx uint32, step uint) geoCell {
+	cells := float64(uint64(1) << step)
+	lonScale := (geoLonMax - geoLonMin) / cells
+	latScale := (geoLatMax - geoLatMin) / cells
+	return geoCell{
+		lonMin: geoLonMin + float64(lonIdx)*lonScale,
+		lonMax: geoLonMin + float64(lonIdx+1)*lonScale,
+		latMin: geoLatMin + float64(latIdx)*latScale,
+		latMax: geoLatMin + float64(latIdx+1)*latScale,
+	}
+}
+
+// geoDecode returns center of the cell that is described by full precision geohash
+func geoDecode(hash uint64) (lon, lat float64) {
+	latIdx, lonIdx := deinterleave(hash)
+	cell := geoCellBounds(lonIdx, latIdx, geoStepMax)
+	lon = math.Max(geoLonMin, math.Min(geoLonMax, (cell.lonMin+cell.lonMax)/2))
+	lat = math.Max(geoLatMin, math.Min(geoLatMax, (cell.latMin+cell.latMax)/2))
+	return lon, lat
+}
+
+func degRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func radDeg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// geoDistance returns distance in meters between two points using haversine formula
+func geoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	lat1r, lon1r := degRad(lat1), degRad(lon1)
+	lat2r, lon2r := degRad(lat2), degRad(lon2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	v := math.Sin((lon2r - lon1r) / 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1r)*math.Cos(lat2r)*v*v))
+}
+
+// geoEstimateStep returns precision of the grid which cells are big enough
+// to cover the search area with a cell and its neighbors
+func geoEstimateStep(radius, lat float64) uint {
+	if radius == 0 {
+		return geoStepMax
+	}
+	step := 1
+	for radius < mercatorMax {
+		radius *= 2
+		step++
+	}
+	step -= 2
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	if step < 1 {
+		step = 1
+	}
+	if step > geoStepMax {
+		step = geoStepMax
+	}
+	return uint(step)
+}
+
+// geoBoundingBox returns area that contains all points within given
+// distances (in meters) to the north/south and to the east/west from the center
+func geoBoundingBox(lon, lat, halfWidth, halfHeight float64) geoCell {
+	latDelta := radDeg(halfHeight / earthRadius)
+	box := geoCell{
+		latMin: lat - latDelta,
+		latMax: lat + latDelta,
+	}
+	lonDelta := math.Max(
+		radDeg(halfWidth/earthRadius/math.Cos(degRad(math.Min(box.latMax, 89.9)))),
+		radDeg(halfWidth/earthRadius/math.Cos(degRad(math.Max(box.latMin, -89.9)))),
+	)
+	box.lonMin = lon - lonDelta
+	box.lonMax = lon + lonDelta
+	return box
+}
@@ -0,0 +1,370 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidJSON         = errors.New("invalid json value")
+	ErrJSONPathDoNotExists = errors.New("json path doesn't exist")
+	ErrJSONNewRoot         = errors.New("new documents must be created at the root")
+	ErrJSONWrongType       = errors.New("json value has wrong type")
+)
+
+// JSONDocs keeps parsed JSON documents, every operation is applied atomically to the whole document
+type JSONDocs struct {
+	mu   sync.RWMutex
+	docs map[string]any
+}
+
+func NewJSONDocs() *JSONDocs {
+	return &JSONDocs{
+		docs: make(map[string]any),
+	}
+}
+
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, ErrInvalidJSON
+	}
+	if dec.More() {
+		return nil, ErrInvalidJSON
+	}
+	return v, nil
+}
+
+func encodeJSON(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonType returns name of the type of decoded JSON value
+func jsonType(v any) string {
+	switch val := v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	default:
+		return "null"
+	}
+}
+
+// setValue replaces value at the location
+func (j *JSONDocs) setValue(key string, loc *jsonLoc, val any) {
+	loc.value = val
+	if loc.holder == nil {
+		j.docs[key] = val
+		return
+	}
+	switch holder := loc.holder.value.(type) {
+	case map[string]any:
+		holder[loc.key] = val
+	case []any:
+		holder[loc.index] = val
+	}
+}
+
+// Set sets value at the path, new document can be created only with the root path,
+// new object keys can be added only as the last segment of the path
+func (j *JSONDocs) Set(key, path, value []byte) error {
+	p, err := parseJSONPath(string(path))
+	if err != nil {
+		return err
+	}
+	val, err := decodeJSON(value)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	doc, ok := j.docs[string(key)]
+	if !ok {
+		if len(p.segments) != 0 {
+			return ErrJSONNewRoot
+		}
+		j.docs[string(key)] = val
+		return nil
+	}
+	locs := p.eval(doc)
+	if len(locs) != 0 {
+		for _, loc := range locs {
+			j.setValue(string(key), loc, val)
+		}
+		return nil
+	}
+	last := p.segments[len(p.segments)-1]
+	if last.kind != segKey || last.recursive {
+		return ErrJSONPathDoNotExists
+	}
+	parent := &jsonPath{segments: p.segments[:len(p.segments)-1]}
+	added := false
+	for _, loc := range parent.eval(doc) {
+		if obj, ok := loc.value.(map[string]any); ok {
+			obj[last.key] = val
+			added = true
+		}
+	}
+	if !added {
+		return ErrJSONPathDoNotExists
+	}
+	return nil
+}
+
+// Get returns values matched by the paths, legacy path returns single value, $ path returns array of matches,
+// several paths return object where every path is mapped to its result
+func (j *JSONDocs) Get(key []byte, paths [][]byte) ([]byte, error) {
+	if len(paths) == 0 {
+		paths = [][]byte{[]byte(".")}
+	}
+	parsed := make([]*jsonPath, 0, len(paths))
+	for _, path := range paths {
+		p, err := parseJSONPath(string(path))
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	doc, ok := j.docs[string(key)]
+	if !ok {
+		return nil, ErrKeyDoNotExists
+	}
+	results := make([]any, 0, len(parsed))
+	for _, p := range parsed {
+		locs := p.eval(doc)
+		if p.legacy {
+			if len(locs) == 0 {
+				return nil, ErrJSONPathDoNotExists
+			}
+			results = append(results, locs[0].value)
+			continue
+		}
+		matches := make([]any, 0, len(locs))
+		for _, loc := range locs {
+			matches = append(matches, loc.value)
+		}
+		results = append(results, matches)
+	}
+	if len(results) == 1 {
+		return encodeJSON(results[0])
+	}
+	obj := make(map[string]any, len(results))
+	for i, res := range results {
+		obj[string(paths[i])] = res
+	}
+	return encodeJSON(obj)
+}
+
+// Del deletes values matched by the path and returns number of deleted values,
+// deleting the root deletes the whole document
+func (j *JSONDocs) Del(key, path []byte) (int, error) {
+	p, err := parseJSONPath(string(path))
+	if err != nil {
+		return 0, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	doc, ok := j.docs[string(key)]
+	if !ok {
+		return 0, nil
+	}
+	if len(p.segments) == 0 {
+		delete(j.docs, string(key))
+		return 1, nil
+	}
+	locs := p.eval(doc)
+	// array elements are collected by their array and removed from the end
+	// so indexes of the remaining elements stay valid
+	arrays := make(map[*jsonLoc][]int)
+	var order []*jsonLoc
+	deleted := 0
+	for _, loc := range locs {
+		switch holder := loc.holder.value.(type) {
+		case map[string]any:
+			if _, ok := holder[loc.key]; ok {
+				delete(holder, loc.key)
+				deleted++
+			}
+		case []any:
+			if _, ok := arrays[loc.holder]; !ok {
+				order = append(order, loc.holder)
+			}
+			arrays[loc.holder] = append(arrays[loc.holder], loc.index)
+		}
+	}
+	for _, holder := range order {
+		indexes := arrays[holder]
+		sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+		arr := holder.value.([]any)
+		for _, index := range indexes {
+			arr = append(arr[:index], arr[index+1:]...)
+			deleted++
+		}
+		j.setValue(string(key), holder, arr)
+	}
+	return deleted, nil
+}
+
+// NumIncrBy increments numbers matched by the path and returns new values,
+// for $ path array of results is returned with null for values that are not numbers
+func (j *JSONDocs) NumIncrBy(key, path, value []byte) ([]byte, error) {
+	p, err := parseJSONPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	incr, err := decodeJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	incrNum, ok := incr.(json.Number)
+	if !ok {
+		return nil, ErrJSONWrongType
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	doc, ok := j.docs[string(key)]
+	if !ok {
+		return nil, ErrKeyDoNotExists
+	}
+	locs := p.eval(doc)
+	if p.legacy && len(locs) == 0 {
+		return nil, ErrJSONPathDoNotExists
+	}
+	results := make([]any, 0, len(locs))
+	for _, loc := range locs {
+		num, ok := loc.value.(json.Number)
+		if !ok {
+			if p.legacy {
+				return nil, ErrJSONWrongType
+			}
+			results = append(results, nil)
+			continue
+		}
+		sum, err := addNumbers(num, incrNum)
+		if err != nil {
+			return nil, err
+		}
+		j.setValue(string(key), loc, sum)
+		results = append(results, sum)
+	}
+	if p.legacy {
+		return encodeJSON(results[0])
+	}
+	return encodeJSON(results)
+}
+
+// addNumbers adds two JSON numbers, result is integer if both numbers are integers
+func addNumbers(a, b json.Number) (json.Number, error) {
+	ai, errA := a.Int64()
+	bi, errB := b.Int64()
+	if errA == nil && errB == nil && !strings.ContainsAny(b.String(), ".eE") {
+		sum := ai + bi
+		if (sum > ai) == (bi > 0) {
+			return json.Number(strconv.FormatInt(sum, 10)), nil
+		}
+	}
+	af, err := a.Float64()
+	if err != nil {
+		return "", ErrUnableToConvertToInt
+	}
+	bf, err := b.Float64()
+	if err != nil {
+		return "", ErrUnableToConvertToInt
+	}
+	sum := af + bf
+	if math.IsInf(sum, 0) || math.IsNaN(sum) {
+		return "", ErrJSONWrongType
+	}
+	return json.Number(strconv.FormatFloat(sum, 'f', -1, 64)), nil
+}
+
+// ArrAppend appends values to arrays matched by the path and returns their new lengths,
+// -1 is returned for matched values that are not arrays
+func (j *JSONDocs) ArrAppend(key, path []byte, values [][]byte) ([]int, error) {
+	p, err := parseJSONPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	vals := make([]any, 0, len(values))
+	for _, value := range values {
+		val, err := decodeJSON(value)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	doc, ok := j.docs[string(key)]
+	if !ok {
+		return nil, ErrKeyDoNotExists
+	}
+	locs := p.eval(doc)
+	if p.legacy && len(locs) == 0 {
+		return nil, ErrJSONPathDoNotExists
+	}
+	res := make([]int, 0, len(locs))
+	for _, loc := range locs {
+		arr, ok := loc.value.([]any)
+		if !ok {
+			if p.legacy {
+				return nil, ErrJSONWrongType
+			}
+			res = append(res, -1)
+			continue
+		}
+		arr = append(arr, vals...)
+		j.setValue(string(key), loc, arr)
+		res = append(res, len(arr))
+	}
+	return res, nil
+}
+
+// Type returns types of the values matched by the path
+func (j *JSONDocs) Type(key, path []byte) ([]string, error) {
+	p, err := parseJSONPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	doc, ok := j.docs[string(key)]
+	if !ok {
+		return nil, ErrKeyDoNotExists
+	}
+	locs := p.eval(doc)
+	if p.legacy && len(locs) > 1 {
+		locs = locs[:1]
+	}
+	res := make([]string, 0, len(locs))
+	for _, loc := range locs {
+		res = append(res, jsonType(loc.value))
+	}
+	return res, nil
+}
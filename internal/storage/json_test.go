@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_JSONPath(t *testing.T) {
+	for _, p := range []string{"$", ".", "", "$.a.b", "a.b", ".a[0]", "$['a b'][-1]", "$..price", "$.store.*", "$[*]", "$..[0]"} {
+		_, err := parseJSONPath(p)
+		require.Nil(t, err, p)
+	}
+	for _, p := range []string{"$.", "$a", "$[", "$['a]", "$[x]", "$.a..", "$.a.[0]"} {
+		_, err := parseJSONPath(p)
+		require.ErrorIs(t, err, ErrInvalidJSONPath, p)
+	}
+}
+
+func Test_JSONDocs(t *testing.T) {
+	j := NewJSONDocs()
+	key := []byte("doc")
+	err := j.Set(key, []byte("$.a"), []byte(`1`))
+	require.ErrorIs(t, err, ErrJSONNewRoot)
+	err = j.Set(key, []byte("$"), []byte(`{"name":"bob","age":30,"tags":["a"],"items":[{"price":10},{"price":2.5}]}`))
+	require.Nil(t, err)
+	res, err := j.Get(key, [][]byte{[]byte("$.name")})
+	require.Nil(t, err)
+	require.Equal(t, `["bob"]`, string(res))
+	res, err = j.Get(key, [][]byte{[]byte(".age")})
+	require.Nil(t, err)
+	require.Equal(t, `30`, string(res))
+	res, err = j.Get(key, [][]byte{[]byte("$..price")})
+	require.Nil(t, err)
+	require.Equal(t, `[10,2.5]`, string(res))
+	res, err = j.Get(key, [][]byte{[]byte("$.name"), []byte(".age")})
+	require.Nil(t, err)
+	require.Equal(t, `{"$.name":["bob"],".age":30}`, string(res))
+
+	err = j.Set(key, []byte("$.address"), []byte(`{"city":"Paris"}`))
+	require.Nil(t, err)
+	err = j.Set(key, []byte("$.missing.city"), []byte(`"x"`))
+	require.ErrorIs(t, err, ErrJSONPathDoNotExists)
+	res, err = j.NumIncrBy(key, []byte("$..price"), []byte("1"))
+	require.Nil(t, err)
+	require.Equal(t, `[11,3.5]`, string(res))
+	res, err = j.NumIncrBy(key, []byte(".age"), []byte("2"))
+	require.Nil(t, err)
+	require.Equal(t, `32`, string(res))
+	_, err = j.NumIncrBy(key, []byte(".name"), []byte("2"))
+	require.ErrorIs(t, err, ErrJSONWrongType)
+	lens, err := j.ArrAppend(key, []byte("$.tags"), [][]byte{[]byte(`"b"`), []byte(`"c"`)})
+	require.Nil(t, err)
+	require.Equal(t, []int{3}, lens)
+	lens, err = j.ArrAppend(key, []byte("$.*"), [][]byte{[]byte(`1`)})
+	require.Nil(t, err)
+	require.Equal(t, []int{-1, -1, 3, -1, 4}, lens)
+	types, err := j.Type(key, []byte("$.*"))
+	require.Nil(t, err)
+	require.Equal(t, []string{"object", "integer", "array", "string", "array"}, types)
+
+	n, err := j.Del(key, []byte("$.tags[0]"))
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+	n, err = j.Del(key, []byte("$.items[*]"))
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+	res, err = j.Get(key, nil)
+	require.Nil(t, err)
+	require.Equal(t, `{"address":{"city":"Paris"},"age":32,"items":[],"name":"bob","tags":["b","c",1]}`, string(res))
+	n, err = j.Del(key, []byte("$"))
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+	_, err = j.Get(key, nil)
+	require.ErrorIs(t, err, ErrKeyDoNotExists)
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidJSONPath = errors.New("invalid json path")
+)
+
+const (
+	segKey = iota
+	segIndex
+	segWildcard
+)
+
+// jsonSegment is a single step of the path, recursive segment matches
+// given key, index or wildcard on the node and all its descendants
+type jsonSegment struct {
+	kind      int
+	key       string
+	index     int
+	recursive bool
+}
+
+// jsonPath is a parsed path, legacy paths (that don't start with $) always refer to a single value
+type jsonPath struct {
+	segments []jsonSegment
+	legacy   bool
+}
+
+// jsonLoc is a location of the value in the document, holder is a location of the container
+// that contains the value, holder is nil for the root
+type jsonLoc struct {
+	holder *jsonLoc
+	key    string
+	index  int
+	value  any
+}
+
+// parseJSONPath parses JSONPath subset: $ root, .key and ['key'] children, [n] array elements
+// (negative n counts from the end), .* and [*] wildcards and ..key recursive descent.
+// Paths that don't start with $ are treated as legacy paths (".a.b" or "a.b")
+func parseJSONPath(p string) (*jsonPath, error) {
+	path := &jsonPath{}
+	switch {
+	case strings.HasPrefix(p, "$"):
+		p = p[1:]
+	case len(p) == 0 || p == ".":
+		path.legacy = true
+		p = ""
+	case strings.HasPrefix(p, ".") || strings.HasPrefix(p, "["):
+		path.legacy = true
+	default:
+		path.legacy = true
+		p = "." + p
+	}
+	for i := 0; i < len(p); {
+		recursive := false
+		switch p[i] {
+		case '.':
+			i++
+			if i < len(p) && p[i] == '.' {
+				recursive = true
+				i++
+			}
+			if i >= len(p) {
+				return nil, ErrInvalidJSONPath
+			}
+			if p[i] == '[' {
+				if !recursive {
+					return nil, ErrInvalidJSONPath
+				}
+				seg, n, err := parseBracket(p[i:])
+				if err != nil {
+					return nil, err
+				}
+				seg.recursive = true
+				path.segments = append(path.segments, seg)
+				i += n
+				continue
+			}
+			if p[i] == '*' {
+				path.segments = append(path.segments, jsonSegment{kind: segWildcard, recursive: recursive})
+				i++
+				continue
+			}
+			end := i
+			for end < len(p) && p[end] != '.' && p[end] != '[' {
+				end++
+			}
+			if end == i {
+				return nil, ErrInvalidJSONPath
+			}
+			path.segments = append(path.segments, jsonSegment{kind: segKey, key: p[i:end], recursive: recursive})
+			i = end
+		case '[':
+			seg, n, err := parseBracket(p[i:])
+			if err != nil {
+				return nil, err
+			}
+			path.segments = append(path.segments, seg)
+			i += n
+		default:
+			return nil, ErrInvalidJSONPath
+		}
+	}
+	return path, nil
+}
+
+// parseBracket parses ['key'], ["key"], [*] or [n] segment and returns number of consumed bytes
+func parseBracket(p string) (jsonSegment, int, error) {
+	if len(p) < 3 {
+		return jsonSegment{}, 0, ErrInvalidJSONPath
+	}
+	if p[1] == '\'' || p[1] == '"' {
+		quote := p[1]
+		var key strings.Builder
+		for i := 2; i < len(p); i++ {
+			switch p[i] {
+			case '\\':
+				if i+1 >= len(p) {
+					return jsonSegment{}, 0, ErrInvalidJSONPath
+				}
+				i++
+				key.WriteByte(p[i])
+			case quote:
+				if i+1 >= len(p) || p[i+1] != ']' {
+					return jsonSegment{}, 0, ErrInvalidJSONPath
+				}
+				return jsonSegment{kind: segKey, key: key.String()}, i + 2, nil
+			default:
+				key.WriteByte(p[i])
+			}
+		}
+		return jsonSegment{}, 0, ErrInvalidJSONPath
+	}
+	end := strings.IndexByte(p, ']')
+	if end < 0 {
+		return jsonSegment{}, 0, ErrInvalidJSONPath
+	}
+	inner := strings.TrimSpace(p[1:end])
+	if inner == "*" {
+		return jsonSegment{kind: segWildcard}, end + 1, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return jsonSegment{}, 0, ErrInvalidJSONPath
+	}
+	return jsonSegment{kind: segIndex, index: index}, end + 1, nil
+}
+
+// eval returns locations of all values matched by the path in the document
+func (p *jsonPath) eval(root any) []*jsonLoc {
+	var res []*jsonLoc
+	evalSegments(&jsonLoc{value: root}, p.segments, &res)
+	return res
+}
+
+func evalSegments(loc *jsonLoc, segments []jsonSegment, res *[]*jsonLoc) {
+	if len(segments) == 0 {
+		*res = append(*res, loc)
+		return
+	}
+	seg := segments[0]
+	for _, child := range matchSegment(loc, seg) {
+		evalSegments(child, segments[1:], res)
+	}
+	if seg.recursive {
+		for _, child := range children(loc) {
+			evalSegments(child, segments, res)
+		}
+	}
+}
+
+// matchSegment returns children of the location matched by the segment
+func matchSegment(loc *jsonLoc, seg jsonSegment) []*jsonLoc {
+	switch seg.kind {
+	case segWildcard:
+		return children(loc)
+	case segKey:
+		obj, ok := loc.value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		val, ok := obj[seg.key]
+		if !ok {
+			return nil
+		}
+		return []*jsonLoc{{holder: loc, key: seg.key, value: val}}
+	case segIndex:
+		arr, ok := loc.value.([]any)
+		if !ok {
+			return nil
+		}
+		index := seg.index
+		if index < 0 {
+			index += len(arr)
+		}
+		if index < 0 || index >= len(arr) {
+			return nil
+		}
+		return []*jsonLoc{{holder: loc, index: index, value: arr[index]}}
+	}
+	return nil
+}
+
+// children returns locations of all direct children of the object or array,
+// object keys are returned in sorted order
+func children(loc *jsonLoc) []*jsonLoc {
+	var res []*jsonLoc
+	switch v := loc.value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			res = append(res, &jsonLoc{holder: loc, key: key, value: v[key]})
+		}
+	case []any:
+		for i, val := range v {
+			res = append(res, &jsonLoc{holder: loc, index: i, value: val})
+		}
+	}
+	return res
+}
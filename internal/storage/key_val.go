@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strconv"
 	"sync"
+	"time"
 )
 
 var (
@@ -14,23 +15,33 @@ var (
 type KeyValue struct {
 	mu   sync.RWMutex
 	Data map[string][]byte
+	// expires are expiration times of keys with TTL
+	expires map[string]time.Time
+	now     func() time.Time
 }
 
 func NreKeyValue() *KeyValue {
 	return &KeyValue{
-		Data: make(map[string][]byte),
+		Data:    make(map[string][]byte),
+		expires: make(map[string]time.Time),
+		now:     time.Now,
 	}
 }
 
+// Set sets value of the key and removes its TTL
 func (kv *KeyValue) Set(key, val []byte) error {
 	kv.mu.Lock()
 	defer kv.mu.Unlock()
 	kv.Data[string(key)] = []byte(val)
+	delete(kv.expires, string(key))
 	return nil
 }
 func (kv *KeyValue) Get(key []byte) ([]byte, bool) {
 	kv.mu.RLock()
 	defer kv.mu.RUnlock()
+	if kv.expired(string(key)) {
+		return nil, false
+	}
 	val, err := kv.Data[string(key)]
 	return val, err
 }
@@ -38,6 +49,7 @@ func (kv *KeyValue) Get(key []byte) ([]byte, bool) {
 func (kv *KeyValue) Add(key []byte) error {
 	kv.mu.Lock()
 	defer kv.mu.Unlock()
+	kv.removeExpired(string(key))
 	val, ok := kv.Data[string(key)]
 	if !ok {
 		return ErrKeyDoNotExists
@@ -54,6 +66,7 @@ func (kv *KeyValue) Add(key []byte) error {
 func (kv *KeyValue) AddN(key []byte, value []byte) error {
 	kv.mu.Lock()
 	defer kv.mu.Unlock()
+	kv.removeExpired(string(key))
 	val, ok := kv.Data[string(key)]
 	if !ok {
 		return ErrKeyDoNotExists
@@ -75,5 +88,6 @@ func (kv *KeyValue) Delete(key []byte) error {
 	kv.mu.Lock()
 	defer kv.mu.Unlock()
 	delete(kv.Data, string(key))
+	delete(kv.expires, string(key))
 	return nil
 }
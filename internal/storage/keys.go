@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrWrongType is returned when key is used by command of another type, geo sets share keys with other types
+// so a key holds either a geo set or values of other types
+var ErrWrongType = errors.New("key holds value of another type")
+
+// holdsGeo reports whether key of the database holds a geo set
+func (db *DataBase) holdsGeo(key []byte) bool {
+	db.GEO.mu.RLock()
+	defer db.GEO.mu.RUnlock()
+	_, ok := db.GEO.sets[string(key)]
+	return ok
+}
+
+// checkNotGeo returns ErrWrongType if key of database index holds a geo set, it's used by writes of other types
+func (s *Storage) checkNotGeo(key []byte, index int) error {
+	if s.DBS[index].holdsGeo(key) {
+		return ErrWrongType
+	}
+	return nil
+}
+
+// deleteGeo deletes geo set of the key, it's used by SET that replaces value of any type
+func (db *DataBase) deleteGeo(key []byte) {
+	db.GEO.mu.Lock()
+	delete(db.GEO.sets, string(key))
+	db.GEO.mu.Unlock()
+}
+
+// Exists reports whether key of any type exists in database index
+func (s *Storage) Exists(key []byte, index int) bool {
+	if index > 39 || index < 0 {
+		return false
+	}
+	db := s.DBS[index]
+	if _, ok := db.KV.Get(key); ok {
+		return true
+	}
+	if db.LST.Has(key) {
+		return true
+	}
+	db.GEO.mu.RLock()
+	_, ok := db.GEO.sets[string(key)]
+	db.GEO.mu.RUnlock()
+	if ok {
+		return true
+	}
+	db.JSON.mu.RLock()
+	_, ok = db.JSON.docs[string(key)]
+	db.JSON.mu.RUnlock()
+	return ok
+}
+
+// Unlink deletes key of any type from database index, returns false if there was no such key
+func (s *Storage) Unlink(key []byte, index int) (bool, error) {
+	const op = "storage.Unlink"
+	if index > 39 || index < 0 {
+		return false, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	existed := s.Exists(key, index)
+	db := s.DBS[index]
+	db.KV.Delete(key)
+	db.LST.DeleteL(key)
+	db.deleteGeo(key)
+	db.JSON.mu.Lock()
+	delete(db.JSON.docs, string(key))
+	db.JSON.mu.Unlock()
+	return existed, nil
+}
+
+// Keys returns sorted keys of all types from database index
+func (s *Storage) Keys(index int) [][]byte {
+	if index > 39 || index < 0 {
+		return nil
+	}
+	db := s.DBS[index]
+	set := make(map[string]struct{})
+	db.KV.mu.RLock()
+	for key := range db.KV.Data {
+		if !db.KV.expired(key) {
+			set[key] = struct{}{}
+		}
+	}
+	db.KV.mu.RUnlock()
+	db.LST.mu.Lock()
+	for key := range db.LST.lists {
+		set[key] = struct{}{}
+	}
+	db.LST.mu.Unlock()
+	db.GEO.mu.RLock()
+	for key := range db.GEO.sets {
+		set[key] = struct{}{}
+	}
+	db.GEO.mu.RUnlock()
+	db.JSON.mu.RLock()
+	for key := range db.JSON.docs {
+		set[key] = struct{}{}
+	}
+	db.JSON.mu.RUnlock()
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	res := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, []byte(key))
+	}
+	return res
+}
+
+// KeySnapshot returns copy of the key of any type from database index
+func (s *Storage) KeySnapshot(key []byte, index int) (Snapshot, error) {
+	const op = "storage.KeySnapshot"
+	if index > 39 || index < 0 {
+		return Snapshot{}, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	db := s.DBS[index]
+	snap := Snapshot{Index: index}
+	if val, ok := db.KV.Get(key); ok {
+		snap.KV = []SnapshotValue{{Key: key, Val: append([]byte(nil), val...), ExpireAt: db.KV.expireAt(string(key))}}
+	}
+	if list, err := db.LST.GetL(key); err == nil {
+		values := make([][]byte, 0, len(list))
+		for _, val := range list {
+			values = append(values, append([]byte(nil), val...))
+		}
+		snap.LST = []SnapshotList{{Key: key, Values: values}}
+	}
+	db.GEO.mu.RLock()
+	if set, ok := db.GEO.sets[string(key)]; ok {
+		snap.GEO = []SnapshotGeo{{Key: key, Points: set.points()}}
+	}
+	db.GEO.mu.RUnlock()
+	db.JSON.mu.RLock()
+	defer db.JSON.mu.RUnlock()
+	if doc, ok := db.JSON.docs[string(key)]; ok {
+		data, err := encodeJSON(doc)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("%s:%w", op, err)
+		}
+		snap.JSON = []SnapshotValue{{Key: key, Val: data}}
+	}
+	return snap, nil
+}
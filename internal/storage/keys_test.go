@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_KeysAndUnlink(t *testing.T) {
+	s := NewStorage()
+	ind := 1
+	require.Nil(t, s.Set([]byte("str"), []byte("value"), ind))
+	require.Nil(t, s.LPush([]byte("list"), []byte("a"), ind))
+	_, err := s.GeoAdd([]byte("geo"), []GeoPoint{{Member: []byte("Palermo"), Longitude: 13.361389, Latitude: 38.115556}}, ind)
+	require.Nil(t, err)
+	require.Nil(t, s.JSONSet([]byte("doc"), []byte("$"), []byte(`{"a":1}`), ind))
+	require.Equal(t, [][]byte{[]byte("doc"), []byte("geo"), []byte("list"), []byte("str")}, s.Keys(ind))
+	require.Empty(t, s.Keys(ind+1))
+
+	snap, err := s.KeySnapshot([]byte("list"), ind)
+	require.Nil(t, err)
+	require.Equal(t, []SnapshotList{{Key: []byte("list"), Values: [][]byte{[]byte("a")}}}, snap.LST)
+	require.Empty(t, snap.KV)
+	snap, err = s.KeySnapshot([]byte("missing"), ind)
+	require.Nil(t, err)
+	require.True(t, snap.Empty())
+
+	for _, key := range []string{"doc", "geo", "list", "str"} {
+		require.True(t, s.Exists([]byte(key), ind))
+		existed, err := s.Unlink([]byte(key), ind)
+		require.Nil(t, err)
+		require.True(t, existed)
+		require.False(t, s.Exists([]byte(key), ind))
+	}
+	existed, err := s.Unlink([]byte("str"), ind)
+	require.Nil(t, err)
+	require.False(t, existed)
+	require.Empty(t, s.Keys(ind))
+}
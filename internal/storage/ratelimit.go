@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidRateLimitState = errors.New("value is not a state of the rate limiter")
+)
+
+// RateLimitAlgorithm is an algorithm of RateLimit
+type RateLimitAlgorithm int
+
+const (
+	// FixedWindow counts requests of the current window, counter expires at the end of the window
+	FixedWindow RateLimitAlgorithm = iota
+	// SlidingLog keeps times of requests of the last period
+	SlidingLog
+	// TokenBucket keeps bucket of limit tokens that is refilled completely in period
+	TokenBucket
+)
+
+// RateLimitResult is a decision of the rate limiter
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining is a number of requests that are allowed right after this one
+	Remaining int64
+	// RetryAfter is a time after which denied request may be allowed, it's zero for allowed requests
+	RetryAfter time.Duration
+	// ResetAfter is a time after which limiter returns to its initial state
+	ResetAfter time.Duration
+}
+
+// RateLimit takes cost requests from the limiter stored in key, limiter allows limit requests in period.
+// State of the limiter is stored as a string value with TTL, it's returned if it's changed, i.e. if request is allowed
+func (s *Storage) RateLimit(key []byte, alg RateLimitAlgorithm, limit int64, period time.Duration, cost int64, index int) (RateLimitResult, *SnapshotValue, error) {
+	const op = "storage.RateLimit"
+	if index > 39 || index < 0 {
+		return RateLimitResult{}, nil, fmt.Errorf("%s:%w", op, ErrInvalidDatabaseIndex)
+	}
+	if err := s.checkNotGeo(key, index); err != nil {
+		return RateLimitResult{}, nil, fmt.Errorf("%s:%w", op, err)
+	}
+	res, state, err := s.DBS[index].KV.RateLimit(key, alg, limit, period, cost)
+	if err != nil {
+		return RateLimitResult{}, nil, fmt.Errorf("%s:%w", op, err)
+	}
+	return res, state, nil
+}
+
+func (kv *KeyValue) RateLimit(key []byte, alg RateLimitAlgorithm, limit int64, period time.Duration, cost int64) (RateLimitResult, *SnapshotValue, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.removeExpired(string(key))
+	now := kv.now()
+	val, ok := kv.Data[string(key)]
+	var (
+		res      RateLimitResult
+		state    []byte
+		expireAt time.Time
+		err      error
+	)
+	switch alg {
+	case FixedWindow:
+		expireAt, ok = kv.expires[string(key)]
+		if !ok {
+			expireAt = now.Add(period)
+		}
+		res, state, err = fixedWindow(val, limit, cost, expireAt.Sub(now))
+	case SlidingLog:
+		res, state, expireAt, err = slidingLog(val, now, limit, period, cost)
+	case TokenBucket:
+		res, state, expireAt, err = tokenBucket(val, ok, now, limit, period, cost)
+	default:
+		return RateLimitResult{}, nil, ErrInvalidRateLimitState
+	}
+	if err != nil || !res.Allowed {
+		return res, nil, err
+	}
+	kv.Data[string(key)] = state
+	kv.expires[string(key)] = expireAt
+	return res, &SnapshotValue{Key: key, Val: state, ExpireAt: expireAt}, nil
+}
+
+// fixedWindow state is a decimal counter of the window
+func fixedWindow(val []byte, limit, cost int64, reset time.Duration) (RateLimitResult, []byte, error) {
+	var count int64
+	if val != nil {
+		var err error
+		if count, err = strconv.ParseInt(string(val), 10, 64); err != nil {
+			return RateLimitResult{}, nil, ErrInvalidRateLimitState
+		}
+	}
+	res := RateLimitResult{ResetAfter: reset}
+	if count+cost <= limit {
+		count += cost
+		res.Allowed = true
+	} else {
+		res.RetryAfter = reset
+	}
+	res.Remaining = max(limit-count, 0)
+	return res, strconv.AppendInt(nil, count, 10), nil
+}
+
+// slidingLog state is a list of "unix-milliseconds:count" entries separated with commas in time order
+func slidingLog(val []byte, now time.Time, limit int64, period time.Duration, cost int64) (RateLimitResult, []byte, time.Time, error) {
+	type entry struct{ at, n int64 }
+	nowMs, periodMs := now.UnixMilli(), period.Milliseconds()
+	var (
+		entries []entry
+		count   int64
+	)
+	if len(val) > 0 {
+		for _, field := range bytes.Split(val, []byte(",")) {
+			at, n, ok := bytes.Cut(field, []byte(":"))
+			if !ok {
+				return RateLimitResult{}, nil, time.Time{}, ErrInvalidRateLimitState
+			}
+			e := entry{}
+			var err1, err2 error
+			e.at, err1 = strconv.ParseInt(string(at), 10, 64)
+			e.n, err2 = strconv.ParseInt(string(n), 10, 64)
+			if err1 != nil || err2 != nil {
+				return RateLimitResult{}, nil, time.Time{}, ErrInvalidRateLimitState
+			}
+			if e.at+periodMs <= nowMs {
+				continue
+			}
+			entries = append(entries, e)
+			count += e.n
+		}
+	}
+	res := RateLimitResult{}
+	if count+cost <= limit {
+		if len(entries) > 0 && entries[len(entries)-1].at == nowMs {
+			entries[len(entries)-1].n += cost
+		} else {
+			entries = append(entries, entry{at: nowMs, n: cost})
+		}
+		count += cost
+		res.Allowed = true
+	} else {
+		// request is allowed when enough of the oldest requests leave the window
+		res.RetryAfter = period
+		need := count + cost - limit
+		for _, e := range entries {
+			if need -= e.n; need <= 0 {
+				res.RetryAfter = time.Duration(e.at+periodMs-nowMs) * time.Millisecond
+				break
+			}
+		}
+	}
+	res.Remaining = max(limit-count, 0)
+	var state []byte
+	for i, e := range entries {
+		if i > 0 {
+			state = append(state, ',')
+		}
+		state = strconv.AppendInt(state, e.at, 10)
+		state = append(state, ':')
+		state = strconv.AppendInt(state, e.n, 10)
+	}
+	expireAt := now
+	if len(entries) > 0 {
+		expireAt = time.UnixMilli(entries[len(entries)-1].at + periodMs)
+		res.ResetAfter = expireAt.Sub(now)
+	}
+	return res, state, expireAt, nil
+}
+
+// tokenBucket state is "tokens:unix-milliseconds" of the last update, bucket is full if key doesn't exist
+func tokenBucket(val []byte, exists bool, now time.Time, limit int64, period time.Duration, cost int64) (RateLimitResult, []byte, time.Time, error) {
+	nowMs := now.UnixMilli()
+	// rate is a number of tokens added per millisecond
+	rate := float64(limit) / float64(period.Milliseconds())
+	tokens := float64(limit)
+	if exists {
+		t, last, ok := bytes.Cut(val, []byte(":"))
+		if !ok {
+			return RateLimitResult{}, nil, time.Time{}, ErrInvalidRateLimitState
+		}
+		var err1, err2 error
+		tokens, err1 = strconv.ParseFloat(string(t), 64)
+		lastMs, err2 := strconv.ParseInt(string(last), 10, 64)
+		if err1 != nil || err2 != nil {
+			return RateLimitResult{}, nil, time.Time{}, ErrInvalidRateLimitState
+		}
+		tokens = math.Min(float64(limit), tokens+float64(max(nowMs-lastMs, 0))*rate)
+	}
+	res := RateLimitResult{}
+	if tokens >= float64(cost) {
+		tokens -= float64(cost)
+		res.Allowed = true
+	} else if cost > limit {
+		res.RetryAfter = period
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((float64(cost)-tokens)/rate)) * time.Millisecond
+	}
+	res.Remaining = int64(tokens)
+	res.ResetAfter = time.Duration(math.Ceil((float64(limit)-tokens)/rate)) * time.Millisecond
+	state := strconv.AppendFloat(nil, tokens, 'g', -1, 64)
+	state = append(state, ':')
+	state = strconv.AppendInt(state, nowMs, 10)
+	return res, state, now.Add(res.ResetAfter), nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RateLimit(t *testing.T) {
+	s := NewStorage()
+	now := time.UnixMilli(1_000_000)
+	s.SetClock(func() time.Time { return now })
+	ind := 2
+	limit := func(key string, alg RateLimitAlgorithm, cost int64) RateLimitResult {
+		res, state, err := s.RateLimit([]byte(key), alg, 3, time.Second, cost, ind)
+		require.Nil(t, err)
+		require.Equal(t, res.Allowed, state != nil)
+		return res
+	}
+
+	// fixed window
+	require.Equal(t, RateLimitResult{Allowed: true, Remaining: 2, ResetAfter: time.Second}, limit("fixed", FixedWindow, 1))
+	now = now.Add(400 * time.Millisecond)
+	require.Equal(t, RateLimitResult{Allowed: true, Remaining: 0, ResetAfter: 600 * time.Millisecond}, limit("fixed", FixedWindow, 2))
+	require.Equal(t, RateLimitResult{RetryAfter: 600 * time.Millisecond, ResetAfter: 600 * time.Millisecond}, limit("fixed", FixedWindow, 1))
+	now = now.Add(600 * time.Millisecond)
+	require.True(t, limit("fixed", FixedWindow, 3).Allowed)
+
+	// sliding log allows request when the oldest requests leave the window
+	now = time.UnixMilli(2_000_000)
+	require.True(t, limit("sliding", SlidingLog, 1).Allowed)
+	now = now.Add(300 * time.Millisecond)
+	require.True(t, limit("sliding", SlidingLog, 2).Allowed)
+	require.Equal(t, RateLimitResult{RetryAfter: 700 * time.Millisecond, ResetAfter: time.Second}, limit("sliding", SlidingLog, 1))
+	now = now.Add(700 * time.Millisecond)
+	require.Equal(t, RateLimitResult{Allowed: true, Remaining: 0, ResetAfter: time.Second}, limit("sliding", SlidingLog, 1))
+	val, ok := s.Get([]byte("sliding"), ind)
+	require.True(t, ok)
+	require.Equal(t, "2000300:2,2001000:1", string(val))
+	require.Equal(t, time.Second, limit("sliding", SlidingLog, 3).RetryAfter)
+
+	// token bucket is refilled continuously
+	now = time.UnixMilli(3_000_000)
+	require.Equal(t, RateLimitResult{Allowed: true, Remaining: 0, ResetAfter: time.Second}, limit("bucket", TokenBucket, 3))
+	res := limit("bucket", TokenBucket, 2)
+	require.False(t, res.Allowed)
+	require.Equal(t, 667*time.Millisecond, res.RetryAfter)
+	now = now.Add(667 * time.Millisecond)
+	require.True(t, limit("bucket", TokenBucket, 2).Allowed)
+	pttl, err := s.PTTL([]byte("bucket"), ind)
+	require.Nil(t, err)
+	require.True(t, pttl > 0)
+	now = now.Add(time.Second)
+	_, ok = s.Get([]byte("bucket"), ind)
+	require.False(t, ok)
+
+	require.Nil(t, s.Set([]byte("text"), []byte("a"), ind))
+	_, _, err = s.RateLimit([]byte("text"), FixedWindow, 3, time.Second, 1, ind)
+	require.ErrorIs(t, err, ErrInvalidRateLimitState)
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// Snapshot is a point-in-time copy of one database, it is used to transfer data set
+// to replicas, keys of every namespace are sorted so snapshots can be compared
+type Snapshot struct {
+	Index int
+	KV    []SnapshotValue
+	LST   []SnapshotList
+	GEO   []SnapshotGeo
+	JSON  []SnapshotValue
+}
+
+// SnapshotValue is a key with a single value, JSON documents are kept encoded
+type SnapshotValue struct {
+	Key, Val []byte
+	// ExpireAt is an expiration time of the key, it's zero if key doesn't expire
+	ExpireAt time.Time
+}
+
+// SnapshotList is a list with values in push order
+type SnapshotList struct {
+	Key    []byte
+	Values [][]byte
+}
+
+// SnapshotGeo is a geo set with its members
+type SnapshotGeo struct {
+	Key    []byte
+	Points []GeoPoint
+}
+
+// Empty reports whether database has no data
+func (s *Snapshot) Empty() bool {
+	return len(s.KV) == 0 && len(s.LST) == 0 && len(s.GEO) == 0 && len(s.JSON) == 0
+}
+
+// Snapshot returns copies of all non empty databases
+func (s *Storage) Snapshot() ([]Snapshot, error) {
+	var res []Snapshot
+	for _, db := range s.DBS {
+		snap := Snapshot{
+			Index: db.Index,
+			KV:    db.KV.snapshot(),
+			LST:   db.LST.snapshot(),
+			GEO:   db.GEO.snapshot(),
+		}
+		docs, err := db.JSON.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		snap.JSON = docs
+		if !snap.Empty() {
+			res = append(res, snap)
+		}
+	}
+	return res, nil
+}
+
+// Flush removes all data from all databases
+func (s *Storage) Flush() {
+	for _, db := range s.DBS {
+		db.KV.mu.Lock()
+		db.KV.Data = make(map[string][]byte)
+		db.KV.expires = make(map[string]time.Time)
+		db.KV.mu.Unlock()
+		db.LST.mu.Lock()
+		db.LST.lists = make(map[string][][]byte)
+		db.LST.mu.Unlock()
+		db.GEO.mu.Lock()
+		db.GEO.sets = make(map[string]*zset)
+		db.GEO.mu.Unlock()
+		db.JSON.mu.Lock()
+		db.JSON.docs = make(map[string]any)
+		db.JSON.mu.Unlock()
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (kv *KeyValue) snapshot() []SnapshotValue {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+	res := make([]SnapshotValue, 0, len(kv.Data))
+	for _, key := range sortedKeys(kv.Data) {
+		if kv.expired(key) {
+			continue
+		}
+		res = append(res, SnapshotValue{
+			Key:      []byte(key),
+			Val:      append([]byte(nil), kv.Data[key]...),
+			ExpireAt: kv.expires[key],
+		})
+	}
+	return res
+}
+
+func (l *List) snapshot() []SnapshotList {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	res := make([]SnapshotList, 0, len(l.lists))
+	for _, key := range sortedKeys(l.lists) {
+		values := make([][]byte, 0, len(l.lists[key]))
+		for _, val := range l.lists[key] {
+			values = append(values, append([]byte(nil), val...))
+		}
+		res = append(res, SnapshotList{Key: []byte(key), Values: values})
+	}
+	return res
+}
+
+func (g *Geo) snapshot() []SnapshotGeo {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	res := make([]SnapshotGeo, 0, len(g.sets))
+	for _, key := range sortedKeys(g.sets) {
+		res = append(res, SnapshotGeo{Key: []byte(key), Points: g.sets[key].points()})
+	}
+	return res
+}
+
+// points returns decoded locations of all members of geo set
+func (z *zset) points() []GeoPoint {
+	points := make([]GeoPoint, 0, z.len())
+	for _, e := range z.entries {
+		lon, lat := geoDecode(e.score)
+		points = append(points, GeoPoint{Member: []byte(e.member), Longitude: lon, Latitude: lat})
+	}
+	return points
+}
+
+func (j *JSONDocs) snapshot() ([]SnapshotValue, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	res := make([]SnapshotValue, 0, len(j.docs))
+	for _, key := range sortedKeys(j.docs) {
+		data, err := encodeJSON(j.docs[key])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, SnapshotValue{Key: []byte(key), Val: data})
+	}
+	return res, nil
+}
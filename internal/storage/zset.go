@@ -0,0 +1,85 @@
+package storage
+
+import "sort"
+
+// zentry is a member of the sorted set together with its score
+type zentry struct {
+	score  uint64
+	member string
+}
+
+// zset is a sorted set of members ordered by score and then by member name
+type zset struct {
+	scores  map[string]uint64
+	entries []zentry
+}
+
+func newZSet() *zset {
+	return &zset{
+		scores: make(map[string]uint64),
+	}
+}
+
+// search returns position where entry with given score and member is or should be placed
+func (z *zset) search(score uint64, member string) int {
+	return sort.Search(len(z.entries), func(i int) bool {
+		e := z.entries[i]
+		if e.score != score {
+			return e.score > score
+		}
+		return e.member >= member
+	})
+}
+
+// add adds member with given score or updates score of the existing one,
+// returns true if member is new
+func (z *zset) add(member string, score uint64) bool {
+	old, ok := z.scores[member]
+	if ok {
+		if old == score {
+			return false
+		}
+		z.remove(member)
+	}
+	z.scores[member] = score
+	i := z.search(score, member)
+	z.entries = append(z.entries, zentry{})
+	copy(z.entries[i+1:], z.entries[i:])
+	z.entries[i] = zentry{score: score, member: member}
+	return !ok
+}
+
+// remove removes member from the set, returns false if there was no such member
+func (z *zset) remove(member string) bool {
+	score, ok := z.scores[member]
+	if !ok {
+		return false
+	}
+	delete(z.scores, member)
+	i := z.search(score, member)
+	z.entries = append(z.entries[:i], z.entries[i+1:]...)
+	return true
+}
+
+func (z *zset) score(member string) (uint64, bool) {
+	score, ok := z.scores[member]
+	return score, ok
+}
+
+func (z *zset) len() int {
+	return len(z.entries)
+}
+
+// rangeByScore returns entries with min <= score < max
+func (z *zset) rangeByScore(min, max uint64) []zentry {
+	start := sort.Search(len(z.entries), func(i int) bool {
+		return z.entries[i].score >= min
+	})
+	end := sort.Search(len(z.entries), func(i int) bool {
+		return z.entries[i].score >= max
+	})
+	if start >= end {
+		return nil
+	}
+	return z.entries[start:end]
+}
@@ -0,0 +1,116 @@
+// Package testserver starts a fully functional server for tests: it listens on an ephemeral port,
+// writes its recovery log to a temporary directory of the test, has a clock that tests can move forward
+// to expire keys and it's stopped together with its clients when the test finishes
+package testserver
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+	"github.com/ArtemNovok/simpleRedisCl/internal/server"
+)
+
+// dialTimeout is a timeout of connecting clients to the server
+const dialTimeout = 5 * time.Second
+
+// Password is a password of the default user of servers started without credentials,
+// it's the password clients send if their password is empty
+const Password = "secret"
+
+// Server is a server started for a test
+type Server struct {
+	*server.Server
+	// Addr is an address of the server, e.g. "127.0.0.1:41235"
+	Addr string
+	// Client is connected to the server and closed when the test finishes
+	Client *client.Client
+
+	t      testing.TB
+	start  time.Time
+	offset atomic.Int64
+	// frozen is unix time in nanoseconds the clock is stopped at, it's 0 if clock isn't frozen
+	frozen atomic.Int64
+}
+
+// Start starts server configured with cfg and connects Client to it, test fails if server can't be started.
+// ListenAddr of cfg is ignored, recovery log is written to a temporary directory if RecoveryLog is empty,
+// logs are discarded if Log is nil and Password is used if neither Password, ACLFile nor NoPassword is set
+func Start(t testing.TB, cfg server.Config) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("testserver: failed to listen: %v", err)
+	}
+	cfg.ListenAddr = ln.Addr().String()
+	if len(cfg.RecoveryLog) == 0 {
+		cfg.RecoveryLog = filepath.Join(t.TempDir(), "logs")
+	}
+	if cfg.Log == nil {
+		cfg.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if len(cfg.Password) == 0 && len(cfg.ACLFile) == 0 && !cfg.NoPassword {
+		cfg.Password = Password
+	}
+	s := &Server{
+		Server: server.NewServer(cfg),
+		Addr:   cfg.ListenAddr,
+		t:      t,
+		start:  time.Now(),
+	}
+	s.Storage.SetClock(s.Now)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Serve(ln)
+	}()
+	t.Cleanup(func() {
+		s.Stop()
+		<-done
+	})
+	s.Client = s.NewClient(client.Options{Password: cfg.Password})
+	return s
+}
+
+// NewClient connects new client to the server, Addr of opts is set to the address of the server.
+// Client is closed when the test finishes, test fails if client can't connect
+func (s *Server) NewClient(opts client.Options) *client.Client {
+	s.t.Helper()
+	opts.Addr = s.Addr
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	cl, err := client.NewWithOptions(ctx, opts)
+	if err != nil {
+		s.t.Fatalf("testserver: failed to connect client: %v", err)
+	}
+	s.t.Cleanup(func() { cl.Close() })
+	return cl
+}
+
+// Now returns current time of the server clock, it's the real time or the time clock is frozen at
+// moved forward by Advance
+func (s *Server) Now() time.Time {
+	now := time.Now()
+	if frozen := s.frozen.Load(); frozen != 0 {
+		now = time.Unix(0, frozen)
+	}
+	return now.Add(time.Duration(s.offset.Load()))
+}
+
+// Freeze stops the server clock, so durations reported by the server are exact,
+// clock is moved only by Advance after that
+func (s *Server) Freeze() {
+	s.frozen.CompareAndSwap(0, time.Now().UnixNano())
+}
+
+// Advance moves the server clock forward by d. Keys that expire within d are hidden
+// at once and they are deleted by the server in background
+func (s *Server) Advance(d time.Duration) {
+	s.offset.Add(int64(d))
+}
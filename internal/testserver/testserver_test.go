@@ -0,0 +1,32 @@
+package testserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ArtemNovok/simpleRedisCl/internal/client"
+	"github.com/ArtemNovok/simpleRedisCl/internal/server"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Advance(t *testing.T) {
+	ctx := context.Background()
+	s := Start(t, server.Config{})
+	ok, err := s.Client.SetNX(ctx, "session", "token", time.Hour, 0)
+	require.Nil(t, err)
+	require.True(t, ok)
+	s.Advance(59 * time.Minute)
+	ttl, err := s.Client.PTTL(ctx, "session", 0)
+	require.Nil(t, err)
+	require.LessOrEqual(t, ttl, time.Minute)
+	s.Advance(time.Minute)
+	_, err = s.Client.Get(ctx, "session", 0)
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	// servers of different tests don't share data
+	other := Start(t, server.Config{Password: "other"})
+	require.NotEqual(t, s.Addr, other.Addr)
+	_, err = other.Client.Get(ctx, "session", 0)
+	require.ErrorIs(t, err, client.ErrNotFound)
+}
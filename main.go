@@ -5,7 +5,9 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/ArtemNovok/simpleRedisCl/internal/sentinel"
 	"github.com/ArtemNovok/simpleRedisCl/internal/server"
 )
 
@@ -18,12 +20,57 @@ func main() {
 	addr := flag.String("listenAddr", server.DefaultAddress, "listen address of the server")
 	lvl := flag.String("loglvl", lvlDebug, "level of the logs ('PROD', 'DEV')")
 	password := flag.String("password", "", "password that used to connect to a server")
+	noPassword := flag.Bool("nopass", false, "let anyone connect as the default user if -password is empty, for development only")
+	aclFile := flag.String("aclfile", "", "path of the file with ACL users loaded at startup")
+	notifyEvents := flag.String("notifyKeyspaceEvents", "", "classes of keyspace events published over pub/sub (e.g. 'KEA'), disabled if empty")
+	replicaOf := flag.String("replicaof", "", "address of the primary ('host:port'), server starts as its replica if it's set")
+	masterPassword := flag.String("masterPassword", "", "password that used to connect to the primary, server password is used if empty")
+	sentinelMode := flag.Bool("sentinel", false, "run as a failover coordinator of the primary given by -monitor")
+	monitor := flag.String("monitor", "", "address of the primary monitored by sentinel ('host:port')")
+	masterName := flag.String("masterName", "", "name clients use to discover the primary through sentinel")
+	quorum := flag.Int("quorum", 0, "number of sentinels that have to agree that primary is down, majority if 0")
+	sentinels := flag.String("sentinels", "", "comma separated addresses of other sentinels")
+	downAfter := flag.Duration("downAfter", 0, "time after which not responding primary is considered down (5s if 0)")
+	clusterEnabled := flag.Bool("cluster", false, "run as a node of the cluster, nodes are joined with CLUSTER MEET")
+	clusterAnnounceAddr := flag.String("clusterAnnounceAddr", "", "address of the node announced to clients and other nodes ('host:port')")
 	flag.Parse()
 	logger := setUpLogger(*lvl)
+	if *sentinelMode {
+		listenAddr := *addr
+		if listenAddr == server.DefaultAddress {
+			listenAddr = sentinel.DefaultAddress
+		}
+		var peers []string
+		if len(*sentinels) != 0 {
+			peers = strings.Split(*sentinels, ",")
+		}
+		s, err := sentinel.New(sentinel.Config{
+			ListenAddr:     listenAddr,
+			Password:       *password,
+			Log:            logger,
+			MasterName:     *masterName,
+			MasterAddr:     *monitor,
+			MasterPassword: *masterPassword,
+			Sentinels:      peers,
+			Quorum:         *quorum,
+			DownAfter:      *downAfter,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Fatal(s.Start())
+	}
 	cfg := server.Config{
-		Log:        logger,
-		ListenAddr: *addr,
-		Password:   *password,
+		Log:                  logger,
+		ListenAddr:           *addr,
+		Password:             *password,
+		NoPassword:           *noPassword,
+		ACLFile:              *aclFile,
+		NotifyKeyspaceEvents: *notifyEvents,
+		PrimaryAddr:          *replicaOf,
+		MasterPassword:       *masterPassword,
+		ClusterEnabled:       *clusterEnabled,
+		ClusterAnnounceAddr:  *clusterAnnounceAddr,
 	}
 	s := server.NewServer(cfg)
 	log.Fatal(s.Start())